@@ -0,0 +1,191 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"bytes"
+	"io"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/util"
+)
+
+// The identifier for Attenuation ShareSet objects.
+const attenuationId = 0x0E
+
+// The number of properties of a sound that an attenuation may drive with a
+// curve.
+const ATTENUATION_CURVE_USES = 7
+
+// A ConeParams describes how a sound is attenuated by the angle between the
+// listener and the direction the sound faces.
+type ConeParams struct {
+	// The angles, in degrees, within which the sound is not attenuated, and
+	// outside of which it is fully attenuated.
+	InsideDegrees  float32
+	OutsideDegrees float32
+	// The volume, in decibels, and the low and high pass filter values applied
+	// outside of the cone.
+	OutsideVolume float32
+	LowPass       float32
+	HighPass      float32
+}
+
+// An AttenuationCurve maps the distance between a sound and the listener
+// (From) to the value of a property of the sound (To).
+type AttenuationCurve struct {
+	// The scaling of the curve's Y axis, such as 2 for curves in decibels.
+	Scaling byte
+	Points  []GraphPoint
+}
+
+// An AttenuationObject represents an Attenuation ShareSet within the HIRC
+// section, which describes how sounds that use it change with distance.
+type AttenuationObject struct {
+	Descriptor *ObjectDescriptor
+	// The cone of the attenuation, or nil if the cone is disabled.
+	Cone *ConeParams
+	// The index into Curves of the curve used for each property, or -1 if the
+	// property is not attenuated. The properties are, in order: the dry volume,
+	// the game and user defined auxiliary send volumes, the low pass filter, the
+	// high pass filter, the spread and the focus.
+	CurveToUse [ATTENUATION_CURVE_USES]int8
+	Curves     []AttenuationCurve
+	RTPCs      []RTPC
+}
+
+// NewAttenuationObject creates a new AttenuationObject, reading from sr, which
+// must be seeked to the start of the object's data. bkhd is the header of the
+// SoundBank containing this object, and may be nil.
+func (desc *ObjectDescriptor) NewAttenuationObject(sr util.ReadSeekerAt,
+	bkhd *BankHeaderSection) (*AttenuationObject, error) {
+	l := layoutOf(bkhd)
+	if !l.known() {
+		return nil, errUnknownLayout
+	}
+	dataLength := int64(desc.Length) - OBJECT_DESCRIPTOR_ID_BYTES
+
+	att := &AttenuationObject{Descriptor: desc}
+	var coneEnabled byte
+	err := readFields(sr, &coneEnabled)
+	if err != nil {
+		return nil, err
+	}
+	if coneEnabled != 0 {
+		att.Cone = new(ConeParams)
+		err = readFields(sr, att.Cone)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var curveCount byte
+	err = readFields(sr, &att.CurveToUse, &curveCount)
+	if err != nil {
+		return nil, err
+	}
+	att.Curves = make([]AttenuationCurve, curveCount)
+	for i := range att.Curves {
+		c := &att.Curves[i]
+		var pointCount uint16
+		err = readFields(sr, &c.Scaling, &pointCount)
+		if err != nil {
+			return nil, err
+		}
+		if int64(pointCount)*RTPC_POINT_BYTES > dataLength {
+			return nil, errUnknownLayout
+		}
+		c.Points = make([]GraphPoint, pointCount)
+		err = readFields(sr, c.Points)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Capture the raw bytes of the RTPCs as they are read.
+	buf := new(bytes.Buffer)
+	err = readRTPC(io.TeeReader(sr, buf), l)
+	if err != nil {
+		return nil, err
+	}
+	att.RTPCs, err = decodeRTPCs(buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return att, nil
+}
+
+// Curve returns the curve used for the property at index i of CurveToUse, or
+// nil if the property is not attenuated.
+func (att *AttenuationObject) Curve(i int) *AttenuationCurve {
+	if i < 0 || i >= len(att.CurveToUse) {
+		return nil
+	}
+	index := int(att.CurveToUse[i])
+	if index < 0 || index >= len(att.Curves) {
+		return nil
+	}
+	return &att.Curves[index]
+}
+
+// MaxDistance returns the largest distance of any point of this attenuation's
+// curves, beyond which sounds that use it are no longer attenuated further.
+func (att *AttenuationObject) MaxDistance() float32 {
+	var max float32
+	for _, c := range att.Curves {
+		for _, p := range c.Points {
+			if p.From > max {
+				max = p.From
+			}
+		}
+	}
+	return max
+}
+
+// WriteTo writes the full contents of this AttenuationObject to the Writer
+// specified by w.
+func (att *AttenuationObject) WriteTo(w io.Writer) (written int64, err error) {
+	rtpcs, err := encodeRTPCs(att.RTPCs)
+	if err != nil {
+		return
+	}
+	fields := []interface{}{att.Descriptor}
+	if att.Cone != nil {
+		fields = append(fields, byte(1), att.Cone)
+	} else {
+		fields = append(fields, byte(0))
+	}
+	fields = append(fields, att.CurveToUse, byte(len(att.Curves)))
+	for _, c := range att.Curves {
+		fields = append(fields, c.Scaling, uint16(len(c.Points)), c.Points)
+	}
+	fields = append(fields, rtpcs)
+	return writeFields(w, fields...)
+}
+
+// Id returns the ID of this object.
+func (att *AttenuationObject) Id() uint32 {
+	return att.Descriptor.ObjectId
+}
+
+// TypeId returns the identifier of the type of this object.
+func (att *AttenuationObject) TypeId() byte {
+	return att.Descriptor.Type
+}
+
+// Size returns the number of bytes that WriteTo would write.
+func (att *AttenuationObject) Size() int64 {
+	return att.Descriptor.objectSize()
+}
+
+// Attenuations returns all Attenuation ShareSets stored in this section, in the
+// order that they appear in the file.
+func (hrc *ObjectHierarchySection) Attenuations() []*AttenuationObject {
+	var atts []*AttenuationObject
+	for _, obj := range hrc.objects {
+		if att, ok := obj.(*AttenuationObject); ok {
+			atts = append(atts, att)
+		}
+	}
+	return atts
+}
@@ -0,0 +1,56 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"io"
+)
+
+// The SoundBank version used by a Builder when none is set.
+const DefaultVersion = 132
+
+// A Builder creates a new SoundBank from a set of wems, for tools that generate
+// SoundBanks rather than edit existing ones. Every method other than Build
+// returns the Builder itself, so that calls can be chained.
+type Builder struct {
+	desc      BankDescriptor
+	alignment int64
+	wems      []WemSource
+}
+
+// NewBuilder creates a new Builder of a SoundBank with DefaultVersion as its
+// version, an ID of 0 and no wems.
+func NewBuilder() *Builder {
+	return &Builder{desc: BankDescriptor{Version: DefaultVersion}}
+}
+
+// SetVersion sets the version of the SoundBank, which must match the version
+// expected by the game that loads it.
+func (b *Builder) SetVersion(version uint32) *Builder {
+	b.desc.Version = version
+	return b
+}
+
+// SetBankID sets the ID of the SoundBank, which is the hash of its name.
+func (b *Builder) SetBankID(id uint32) *Builder {
+	b.desc.BankId = id
+	return b
+}
+
+// SetAlignment sets the number of bytes that the offsets of wems are aligned
+// to. If it is 0, which is the default, wems are aligned as Wwise aligns them.
+func (b *Builder) SetAlignment(n int64) *Builder {
+	b.alignment = n
+	return b
+}
+
+// AddWem adds the wem with the given ID to the SoundBank, reading length bytes
+// from r when the SoundBank is written.
+func (b *Builder) AddWem(id uint32, r io.ReaderAt, length int64) *Builder {
+	b.wems = append(b.wems, WemSource{id, r, length})
+	return b
+}
+
+// Build creates the SoundBank, as described by Create.
+func (b *Builder) Build() (*File, error) {
+	return create(b.desc, b.alignment, b.wems)
+}
@@ -0,0 +1,268 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/util"
+)
+
+// The identifier for Audio Bus objects.
+const busId = 0x08
+
+// The identifier for Auxiliary Bus objects.
+const auxBusId = 0x14
+
+// The parameter types of the volume of an object, and of the volume of a bus
+// applied to the mix of everything routed to it.
+const (
+	parameterVolumeType    = 0x00
+	parameterBusVolumeType = 0x05
+)
+
+// The number of bytes used to describe the voice and channel settings of a bus.
+const BUS_SETTINGS_BYTES = 8
+
+// The number of bytes used to describe a single duck of a bus.
+const DUCK_BYTES = 18
+
+// The number of bytes used to describe a single effect slot of a bus.
+const BUS_EFFECT_BYTES = 7
+
+// A Duck lowers a property of another bus while this bus is playing.
+type Duck struct {
+	BusId  uint32
+	Volume float32
+	// The fade times, in milliseconds.
+	FadeOutTime int32
+	FadeInTime  int32
+	FadeCurve   byte
+	// The property of the target bus that is ducked.
+	TargetProperty byte
+}
+
+// A BusEffect is an effect inserted into one of the effect slots of a bus.
+type BusEffect struct {
+	Index    byte
+	EffectId uint32
+	// 1 if EffectId refers to an Effect ShareSet, and 0 if it refers to a custom
+	// effect.
+	IsShareSet byte
+	IsRendered byte
+}
+
+// A BusObject represents an Audio or Auxiliary Bus within the HIRC section.
+// Buses form a tree through which every sound is mixed.
+type BusObject struct {
+	Descriptor *ObjectDescriptor
+	// The ID of the parent bus, or 0 if this is a master bus.
+	ParentId uint32
+	// The ID of the audio device of a master bus.
+	DeviceId        uint32
+	ParameterTypes  []byte
+	ParameterValues [][4]byte
+	// The undecoded positioning and auxiliary send settings.
+	Positioning []byte
+	Auxiliary   []byte
+	// The undecoded voice and channel settings.
+	Settings      [BUS_SETTINGS_BYTES]byte
+	RecoveryTime  int32
+	MaxDuckVolume float32
+	Ducks         []Duck
+	// A bit mask specifying which effects are bypassed.
+	Bypass  byte
+	Effects []BusEffect
+	// The ID of the mixer plugin, and whether it refers to a ShareSet.
+	MixerId         uint32
+	MixerIsShareSet byte
+	// A reader to read the remaining data of this object.
+	RemainingReader io.Reader
+
+	layout layout
+}
+
+// hasDevice returns true if a bus with the given parent stores the ID of its
+// audio device.
+func (l layout) hasDevice(parentId uint32) bool {
+	return parentId == 0 && l.version > 122
+}
+
+// NewBusObject creates a new BusObject, reading from sr, which must be seeked
+// to the start of the object's data. bkhd is the header of the SoundBank
+// containing this object, and may be nil.
+func (desc *ObjectDescriptor) NewBusObject(sr util.ReadSeekerAt,
+	bkhd *BankHeaderSection) (*BusObject, error) {
+	l := layoutOf(bkhd)
+	if !l.known() {
+		return nil, errUnknownLayout
+	}
+	startOffset, _ := sr.Seek(0, io.SeekCurrent)
+	dataLength := int64(desc.Length) - OBJECT_DESCRIPTOR_ID_BYTES
+
+	bus := &BusObject{Descriptor: desc, layout: l}
+	err := readFields(sr, &bus.ParentId)
+	if err != nil {
+		return nil, err
+	}
+	if l.hasDevice(bus.ParentId) {
+		err = readFields(sr, &bus.DeviceId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var count byte
+	err = readFields(sr, &count)
+	if err != nil {
+		return nil, err
+	}
+	bus.ParameterTypes = make([]byte, count)
+	bus.ParameterValues = make([][4]byte, count)
+	err = readFields(sr, bus.ParameterTypes, bus.ParameterValues)
+	if err != nil {
+		return nil, err
+	}
+
+	if l.version > 122 {
+		readers := []struct {
+			dst  *[]byte
+			read func(io.Reader, layout) error
+		}{
+			{&bus.Positioning, readPositioning},
+			{&bus.Auxiliary, readAuxiliary},
+		}
+		for _, rd := range readers {
+			// Capture the raw bytes of each portion as it is read.
+			buf := new(bytes.Buffer)
+			err := rd.read(io.TeeReader(sr, buf), l)
+			if err != nil {
+				return nil, err
+			}
+			*rd.dst = buf.Bytes()
+		}
+	}
+
+	err = readFields(sr, &bus.Settings, &bus.RecoveryTime, &bus.MaxDuckVolume)
+	if err != nil {
+		return nil, err
+	}
+	ducks, err := readListCount(sr, DUCK_BYTES, dataLength)
+	if err != nil {
+		return nil, err
+	}
+	bus.Ducks = make([]Duck, ducks)
+	err = readFields(sr, bus.Ducks)
+	if err != nil {
+		return nil, err
+	}
+
+	var effects byte
+	err = readFields(sr, &effects)
+	if err != nil {
+		return nil, err
+	}
+	if effects > 0 {
+		err = readFields(sr, &bus.Bypass)
+		if err != nil {
+			return nil, err
+		}
+	}
+	bus.Effects = make([]BusEffect, effects)
+	err = readFields(sr, bus.Effects, &bus.MixerId, &bus.MixerIsShareSet)
+	if err != nil {
+		return nil, err
+	}
+
+	bus.RemainingReader, err = remainingReader(sr, startOffset, dataLength)
+	if err != nil {
+		return nil, err
+	}
+	return bus, nil
+}
+
+// parameter returns the value of the parameter of the given type as a float,
+// and true if this bus sets it.
+func (bus *BusObject) parameter(paramType byte) (float32, bool) {
+	for i, t := range bus.ParameterTypes {
+		if t == paramType {
+			bits := binary.LittleEndian.Uint32(bus.ParameterValues[i][:])
+			return math.Float32frombits(bits), true
+		}
+	}
+	return 0, false
+}
+
+// Volume returns the volume of this bus in decibels, or 0 if it is not set.
+func (bus *BusObject) Volume() float32 {
+	v, _ := bus.parameter(parameterVolumeType)
+	return v
+}
+
+// BusVolume returns the volume, in decibels, applied to the mix of everything
+// routed to this bus, or 0 if it is not set.
+func (bus *BusObject) BusVolume() float32 {
+	v, _ := bus.parameter(parameterBusVolumeType)
+	return v
+}
+
+// IsAuxiliary returns true if this is an Auxiliary Bus.
+func (bus *BusObject) IsAuxiliary() bool {
+	return bus.Descriptor.Type == auxBusId
+}
+
+// WriteTo writes the full contents of this BusObject to the Writer specified by
+// w.
+func (bus *BusObject) WriteTo(w io.Writer) (written int64, err error) {
+	fields := []interface{}{bus.Descriptor, bus.ParentId}
+	if bus.layout.hasDevice(bus.ParentId) {
+		fields = append(fields, bus.DeviceId)
+	}
+	fields = append(fields, byte(len(bus.ParameterTypes)), bus.ParameterTypes,
+		bus.ParameterValues, bus.Positioning, bus.Auxiliary, bus.Settings,
+		bus.RecoveryTime, bus.MaxDuckVolume, uint32(len(bus.Ducks)), bus.Ducks,
+		byte(len(bus.Effects)))
+	if len(bus.Effects) > 0 {
+		fields = append(fields, bus.Bypass)
+	}
+	fields = append(fields, bus.Effects, bus.MixerId, bus.MixerIsShareSet)
+	written, err = writeFields(w, fields...)
+	if err != nil {
+		return
+	}
+
+	n, err := util.CopyAll(w, bus.RemainingReader)
+	written += n
+	return written, err
+}
+
+// Id returns the ID of this object.
+func (bus *BusObject) Id() uint32 {
+	return bus.Descriptor.ObjectId
+}
+
+// TypeId returns the identifier of the type of this object.
+func (bus *BusObject) TypeId() byte {
+	return bus.Descriptor.Type
+}
+
+// Size returns the number of bytes that WriteTo would write.
+func (bus *BusObject) Size() int64 {
+	return bus.Descriptor.objectSize()
+}
+
+// Buses returns all Audio and Auxiliary Buses stored in this section, in the
+// order that they appear in the file.
+func (hrc *ObjectHierarchySection) Buses() []*BusObject {
+	var buses []*BusObject
+	for _, obj := range hrc.objects {
+		if bus, ok := obj.(*BusObject); ok {
+			buses = append(buses, bus)
+		}
+	}
+	return buses
+}
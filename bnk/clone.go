@@ -0,0 +1,96 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"bytes"
+	"io"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/util"
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+// Clone returns a deep copy of this File, including any replacements made so
+// far. Changes made to the copy do not affect this File, and vice versa. The
+// copy reads wems from the same source as this File, so this File must not be
+// closed while the copy is in use.
+func (bnk *File) Clone() (*File, error) {
+	clone := &File{opts: bnk.opts, alignment: bnk.alignment,
+		preservePadding: bnk.preservePadding}
+	for _, s := range bnk.sections {
+		if data, ok := s.(*DataSection); ok {
+			clone.DataSection = data.clone(clone.IndexSection)
+			clone.sections = append(clone.sections, clone.DataSection)
+			continue
+		}
+
+		// Every other section only holds metadata, so it is copied by reading back
+		// its current contents.
+		err := clone.addSectionCopy(s)
+		if err != nil {
+			return nil, err
+		}
+		// A copy of an unknown section keeps the offset it was read from.
+		if copied, ok := clone.lastSection().(*UnknownSection); ok {
+			copied.Offset = -1
+			if unknown, ok := s.(*UnknownSection); ok {
+				copied.Offset = unknown.Offset
+			}
+		}
+	}
+	clone.Warnings = append([]error(nil), bnk.Warnings...)
+	return clone, nil
+}
+
+// addSectionCopy adds a copy of s after the existing sections of this File. The
+// copy is made by reading back the contents written by s.
+func (bnk *File) addSectionCopy(s Section) error {
+	b := new(bytes.Buffer)
+	_, err := s.WriteTo(b)
+	if err != nil {
+		return err
+	}
+	return bnk.addSectionBytes(b.Bytes())
+}
+
+// lastSection returns the last section of this File.
+func (bnk *File) lastSection() Section {
+	return bnk.sections[len(bnk.sections)-1]
+}
+
+// addSectionBytes adds the section stored in b, including its header, after the
+// existing sections of this File.
+func (bnk *File) addSectionBytes(b []byte) error {
+	sr := util.NewResettingReader(bytes.NewReader(b), 0, int64(len(b)))
+	hdr, err := readSectionHeader(sr)
+	if err != nil {
+		return err
+	}
+	return bnk.readSection(hdr, sr)
+}
+
+// clone returns a deep copy of this DataSection, whose wems are described by
+// the descriptors of idx. The wems of the copy read from the same sources as
+// the wems of this DataSection.
+func (data *DataSection) clone(idx *DataIndexSection) *DataSection {
+	hdr := *data.Header
+	sec := &DataSection{&hdr, data.DataStart, nil}
+	for i, wem := range data.Wems {
+		var desc *wwise.WemDescriptor
+		if idx != nil && i < len(idx.Descriptors) {
+			desc = idx.Descriptors[i]
+		}
+		if desc == nil {
+			d := *wem.Descriptor
+			desc = &d
+		}
+		var r io.Reader = wem.Reader
+		if rsa, ok := wem.Reader.(util.ReadSeekerAt); ok {
+			r = util.CloneReader(rsa)
+		}
+		padding := util.CloneReader(wem.Padding)
+		sec.Wems = append(sec.Wems, &wwise.Wem{r, desc, padding})
+	}
+	return sec
+}
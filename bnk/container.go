@@ -0,0 +1,375 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/util"
+)
+
+// The identifier for Random/Sequence container objects.
+const randomSequenceContainerId = 0x05
+
+// The identifier for Switch container objects.
+const switchContainerId = 0x06
+
+// The identifier for Actor-Mixer objects.
+const actorMixerId = 0x07
+
+// The identifier for Blend (Layer) container objects.
+const layerContainerId = 0x09
+
+// The number of bytes used to describe how a single child of a Switch container
+// behaves when the switch changes.
+const SWITCH_PARAM_BYTES = 14
+
+// The number of bytes of container specific parameters, preceding the list of
+// children, for each type of container.
+var containerParameterBytes = map[byte]int{
+	// The loop, transition, avoid repeat and playlist mode parameters.
+	randomSequenceContainerId: 24,
+	// The group type, group ID, default switch and validation parameters.
+	switchContainerId: 10,
+	actorMixerId:      0,
+	layerContainerId:  0,
+}
+
+// A ContainerObject represents an object within the HIRC section that groups
+// other objects: an Actor-Mixer, or a Random/Sequence, Switch or Blend
+// container.
+type ContainerObject struct {
+	Descriptor *ObjectDescriptor
+	Structure  *SoundStructure
+	// The container specific parameters that precede the list of children.
+	Parameters []byte
+	// The IDs of the children of this container.
+	ChildIds []uint32
+	// The children played for each switch or state value. Only set for Switch
+	// containers.
+	SwitchGroups []SwitchGroup
+	// The behaviour of each child when the switch changes. Only set for Switch
+	// containers.
+	SwitchParams []SwitchParam
+	// The layers of children blended by the value of an RTPC. Only set for Blend
+	// containers.
+	Layers []BlendLayer
+	// A reader to read the remaining data of this object.
+	RemainingReader io.Reader
+}
+
+// A SwitchGroup lists the children of a Switch container that are played when
+// its switch or state group is set to the value SwitchId.
+type SwitchGroup struct {
+	SwitchId uint32
+	NodeIds  []uint32
+}
+
+// A SwitchParam describes how a child of a Switch container behaves when the
+// switch changes.
+type SwitchParam struct {
+	NodeId uint32
+	// Whether the child only plays on the first switch, and whether it continues
+	// playing across switches.
+	PlaybackFlags byte
+	// Whether the child is stopped when the switch changes.
+	ModeFlags   byte
+	FadeOutTime int32
+	FadeInTime  int32
+}
+
+// A BlendLayer is a layer of a Blend container, whose children are played with
+// properties that follow the value of an RTPC.
+type BlendLayer struct {
+	Id uint32
+	// The undecoded RTPCs of the layer itself, including their count.
+	RTPC []byte
+	// The ID and type of the RTPC that crossfades between the layer's children.
+	CrossfadeId   uint32
+	CrossfadeType byte
+	Associations  []LayerAssociation
+}
+
+// A LayerAssociation associates a child of a Blend container with a layer. The
+// child is played while the crossfade curve is above zero.
+type LayerAssociation struct {
+	ChildId uint32
+	Curve   []GraphPoint
+}
+
+// NewContainerObject creates a new ContainerObject, reading from sr, which must
+// be seeked to the start of the object's data. bkhd is the header of the
+// SoundBank containing this object, and may be nil. It is an error to call
+// this method on a descriptor of an object which is not a container.
+func (desc *ObjectDescriptor) NewContainerObject(sr util.ReadSeekerAt,
+	bkhd *BankHeaderSection) (*ContainerObject, error) {
+	// Get the offset into the file where the data portion of this object begins.
+	startOffset, _ := sr.Seek(0, io.SeekCurrent)
+	dataLength := int64(desc.Length) - OBJECT_DESCRIPTOR_ID_BYTES
+
+	l := layoutOf(bkhd)
+	ss, err := readSoundStructure(sr, l)
+	if err != nil {
+		return nil, err
+	}
+	if !ss.decoded {
+		return nil, errUnknownLayout
+	}
+
+	paramBytes, ok := containerParameterBytes[desc.Type]
+	if !ok {
+		return nil, errUnknownLayout
+	}
+	params := make([]byte, paramBytes)
+	_, err = io.ReadFull(sr, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var count uint32
+	err = binary.Read(sr, binary.LittleEndian, &count)
+	if err != nil {
+		return nil, err
+	}
+	if int64(count)*4 > dataLength {
+		return nil, errUnknownLayout
+	}
+	children := make([]uint32, count)
+	err = binary.Read(sr, binary.LittleEndian, children)
+	if err != nil {
+		return nil, err
+	}
+
+	ctr := &ContainerObject{Descriptor: desc, Structure: ss, Parameters: params,
+		ChildIds: children}
+	switch desc.Type {
+	case switchContainerId:
+		ctr.SwitchGroups, ctr.SwitchParams, err = readSwitches(sr, dataLength)
+	case layerContainerId:
+		ctr.Layers, err = readBlendLayers(sr, l, dataLength)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a reader over the remaining elements in this object, then seek past
+	// it.
+	ctr.RemainingReader, err = remainingReader(sr, startOffset, dataLength)
+	if err != nil {
+		return nil, err
+	}
+	return ctr, nil
+}
+
+// readSwitches reads the switch groups and parameters of a Switch container.
+func readSwitches(r io.Reader, maxBytes int64) ([]SwitchGroup, []SwitchParam,
+	error) {
+	count, err := readListCount(r, 4+4, maxBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	groups := make([]SwitchGroup, count)
+	for i := range groups {
+		g := &groups[i]
+		err = readFields(r, &g.SwitchId)
+		if err != nil {
+			return nil, nil, err
+		}
+		nodes, err := readListCount(r, 4, maxBytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		g.NodeIds = make([]uint32, nodes)
+		err = readFields(r, g.NodeIds)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	count, err = readListCount(r, SWITCH_PARAM_BYTES, maxBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	params := make([]SwitchParam, count)
+	err = readFields(r, params)
+	if err != nil {
+		return nil, nil, err
+	}
+	return groups, params, nil
+}
+
+// readBlendLayers reads the layers of a Blend container.
+func readBlendLayers(r io.Reader, l layout, maxBytes int64) ([]BlendLayer,
+	error) {
+	count, err := readListCount(r, 4+2+4+1+4, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	layers := make([]BlendLayer, count)
+	for i := range layers {
+		layer := &layers[i]
+		err = readFields(r, &layer.Id)
+		if err != nil {
+			return nil, err
+		}
+		// Capture the raw bytes of the RTPCs as they are read.
+		buf := new(bytes.Buffer)
+		err = readRTPC(io.TeeReader(r, buf), l)
+		if err != nil {
+			return nil, err
+		}
+		layer.RTPC = buf.Bytes()
+		err = readFields(r, &layer.CrossfadeId, &layer.CrossfadeType)
+		if err != nil {
+			return nil, err
+		}
+		assocs, err := readListCount(r, 4+4, maxBytes)
+		if err != nil {
+			return nil, err
+		}
+		layer.Associations = make([]LayerAssociation, assocs)
+		for j := range layer.Associations {
+			a := &layer.Associations[j]
+			err = readFields(r, &a.ChildId)
+			if err != nil {
+				return nil, err
+			}
+			points, err := readListCount(r, RTPC_POINT_BYTES, maxBytes)
+			if err != nil {
+				return nil, err
+			}
+			a.Curve = make([]GraphPoint, points)
+			err = readFields(r, a.Curve)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return layers, nil
+}
+
+// ChildrenOf returns the IDs of the children of this Switch container that are
+// played when its switch or state group is set to switchId, or nil if no
+// children are assigned to it.
+func (ctr *ContainerObject) ChildrenOf(switchId uint32) []uint32 {
+	for _, g := range ctr.SwitchGroups {
+		if g.SwitchId == switchId {
+			return g.NodeIds
+		}
+	}
+	return nil
+}
+
+// writeSwitches writes the switch groups and parameters of a Switch container.
+func (ctr *ContainerObject) writeSwitches(w io.Writer) (written int64,
+	err error) {
+	written, err = writeFields(w, uint32(len(ctr.SwitchGroups)))
+	if err != nil {
+		return
+	}
+	for _, g := range ctr.SwitchGroups {
+		n, err := writeFields(w, g.SwitchId, uint32(len(g.NodeIds)), g.NodeIds)
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	n, err := writeFields(w, uint32(len(ctr.SwitchParams)), ctr.SwitchParams)
+	written += n
+	return written, err
+}
+
+// writeLayers writes the layers of a Blend container.
+func (ctr *ContainerObject) writeLayers(w io.Writer) (written int64,
+	err error) {
+	written, err = writeFields(w, uint32(len(ctr.Layers)))
+	if err != nil {
+		return
+	}
+	for _, layer := range ctr.Layers {
+		n, err := writeFields(w, layer.Id, layer.RTPC, layer.CrossfadeId,
+			layer.CrossfadeType, uint32(len(layer.Associations)))
+		written += n
+		if err != nil {
+			return written, err
+		}
+		for _, a := range layer.Associations {
+			n, err = writeFields(w, a.ChildId, uint32(len(a.Curve)), a.Curve)
+			written += n
+			if err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// WriteTo writes the full contents of this ContainerObject to the Writer
+// specified by w.
+func (ctr *ContainerObject) WriteTo(w io.Writer) (written int64, err error) {
+	err = binary.Write(w, binary.LittleEndian, ctr.Descriptor)
+	if err != nil {
+		return
+	}
+	written = OBJECT_DESCRIPTOR_BYTES
+
+	n, err := ctr.Structure.WriteTo(w)
+	if err != nil {
+		return written, err
+	}
+	written += n
+
+	m, err := w.Write(ctr.Parameters)
+	written += int64(m)
+	if err != nil {
+		return
+	}
+
+	err = binary.Write(w, binary.LittleEndian, uint32(len(ctr.ChildIds)))
+	if err != nil {
+		return
+	}
+	err = binary.Write(w, binary.LittleEndian, ctr.ChildIds)
+	if err != nil {
+		return
+	}
+	written += 4 + int64(len(ctr.ChildIds))*4
+
+	switch ctr.Descriptor.Type {
+	case switchContainerId:
+		n, err = ctr.writeSwitches(w)
+		written += n
+	case layerContainerId:
+		n, err = ctr.writeLayers(w)
+		written += n
+	}
+	if err != nil {
+		return written, err
+	}
+
+	n, err = util.CopyAll(w, ctr.RemainingReader)
+	if err != nil {
+		return written, err
+	}
+	written += n
+
+	return written, nil
+}
+
+// Id returns the ID of this object.
+func (ctr *ContainerObject) Id() uint32 {
+	return ctr.Descriptor.ObjectId
+}
+
+// TypeId returns the identifier of the type of this object.
+func (ctr *ContainerObject) TypeId() byte {
+	return ctr.Descriptor.Type
+}
+
+// Size returns the number of bytes that WriteTo would write.
+func (ctr *ContainerObject) Size() int64 {
+	return ctr.Descriptor.objectSize()
+}
@@ -0,0 +1,201 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/util"
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+// The number of bytes of a created BKHD section that follow its settings,
+// which are left unused.
+const createdBankHeaderRemainingBytes = 8
+
+// The newest SoundBank version that identifies languages by number rather than
+// by the hash of their name.
+const lastNumericLanguageVersion = 122
+
+// The language of SoundBanks that hold sound effects rather than voices.
+const sfxLanguage = "SFX"
+
+// A WemSource describes the contents of a wem to be stored in a new SoundBank.
+type WemSource struct {
+	// The ID of the wem.
+	Id uint32
+	// The reader pointing to the contents of the wem.
+	Reader io.ReaderAt
+	// The number of bytes to read in for this wem.
+	Length int64
+}
+
+// Create creates a new File holding the given wems in ascending order of their
+// ID. The SoundBank is made up of a BKHD section described by desc, followed by
+// a DIDX and a DATA section. Wems are aligned in the DATA section as Wwise
+// aligns them.
+func Create(desc BankDescriptor, wems ...WemSource) (*File, error) {
+	return create(desc, 0, wems)
+}
+
+// create is like Create, but aligns wems to alignment bytes, or as Wwise aligns
+// them if alignment is 0.
+func create(desc BankDescriptor, alignment int64,
+	wems []WemSource) (*File, error) {
+	if alignment < 0 {
+		msg := fmt.Sprintf("%d is not a valid alignment; it must be positive",
+			alignment)
+		return nil, errors.New(msg)
+	}
+	bnk := &File{alignment: alignment}
+	bnk.BankHeaderSection = newBankHeaderSection(desc)
+	bnk.sections = append(bnk.sections, bnk.BankHeaderSection)
+	err := bnk.addWems(wems)
+	if err != nil {
+		return nil, err
+	}
+	return bnk, nil
+}
+
+// addWems adds a DIDX and a DATA section holding the given wems, in ascending
+// order of their ID, after the existing sections of this File.
+func (bnk *File) addWems(wems []WemSource) error {
+	dataStart := bnk.Size() + SECTION_HEADER_BYTES +
+		int64(len(wems))*DIDX_ENTRY_BYTES + SECTION_HEADER_BYTES
+	idx, data, err := newWemSections(wems, dataStart, bnk.Alignment())
+	if err != nil {
+		return err
+	}
+	bnk.IndexSection, bnk.DataSection = idx, data
+	bnk.sections = append(bnk.sections, idx, data)
+	return nil
+}
+
+// newWemSections creates a DIDX and a DATA section holding the given wems, in
+// ascending order of their ID. dataStart is the offset into the file where the
+// data portion of the DATA section will begin. Every wem other than the last is
+// padded so that the wem following it is aligned to alignment bytes.
+func newWemSections(wems []WemSource, dataStart int64,
+	alignment int64) (*DataIndexSection, *DataSection, error) {
+	if len(wems) == 0 {
+		return nil, nil,
+			errors.New("A SoundBank must be created with at least one wem")
+	}
+	wems = append([]WemSource(nil), wems...)
+	sort.Slice(wems, func(i, j int) bool { return wems[i].Id < wems[j].Id })
+
+	idx := &DataIndexSection{
+		&SectionHeader{didxHeaderId, uint32(len(wems) * DIDX_ENTRY_BYTES)},
+		len(wems), nil, make(map[uint32]*wwise.WemDescriptor), nil}
+	data := &DataSection{&SectionHeader{dataHeaderId, 0}, uint32(dataStart), nil}
+
+	offset := int64(0)
+	for i, src := range wems {
+		if _, ok := idx.DescriptorMap[src.Id]; ok {
+			msg := fmt.Sprintf("%d is a repeated wem ID", src.Id)
+			return nil, nil, errors.New(msg)
+		}
+		if src.Length > math.MaxUint32 {
+			return nil, nil, fmt.Errorf("%w: wem %d is %d bytes long",
+				ErrWemTooLarge, src.Id, src.Length)
+		}
+		desc := &wwise.WemDescriptor{src.Id, uint32(offset), uint32(src.Length)}
+		padding := int64(0)
+		if i < len(wems)-1 {
+			padding = (alignment - (offset+src.Length)%alignment) % alignment
+		}
+		wem := &wwise.Wem{util.NewResettingReader(src.Reader, 0, src.Length), desc,
+			util.NewResettingReader(&util.InfiniteReaderAt{0}, 0, padding)}
+
+		idx.WemIds = append(idx.WemIds, src.Id)
+		idx.DescriptorMap[src.Id] = desc
+		idx.Descriptors = append(idx.Descriptors, desc)
+		data.Wems = append(data.Wems, wem)
+		offset += src.Length + padding
+	}
+	if offset > math.MaxUint32 {
+		return nil, nil, fmt.Errorf("%w: the DATA section would be %d bytes long",
+			ErrWemTooLarge, offset)
+	}
+	data.Header.Length = uint32(offset)
+	return idx, data, nil
+}
+
+// AddWem adds the wem described by src to this SoundBank, which must not
+// already hold a wem with the same ID. The wems of this SoundBank are laid out
+// again in ascending order of their ID, so their offsets and indexes may
+// change.
+func (bnk *File) AddWem(src WemSource) error {
+	if bnk.IndexSection == nil || bnk.DataSection == nil {
+		return errors.New("The SoundBank has no DIDX and DATA sections to add " +
+			"the wem to")
+	}
+	if _, ok := bnk.WemByID(src.Id); ok {
+		msg := fmt.Sprintf("The SoundBank already holds wem %d", src.Id)
+		return errors.New(msg)
+	}
+	wems := []WemSource{src}
+	for _, wem := range bnk.Wems() {
+		s, err := sourceOf(wem)
+		if err != nil {
+			return err
+		}
+		wems = append(wems, s)
+	}
+
+	idx, data, err := newWemSections(wems, 0, bnk.Alignment())
+	if err != nil {
+		return err
+	}
+	bnk.setWemSections(idx, data)
+	return nil
+}
+
+// setWemSections replaces the DIDX and DATA sections of this SoundBank with idx
+// and data.
+func (bnk *File) setWemSections(idx *DataIndexSection, data *DataSection) {
+	for i, s := range bnk.sections {
+		switch s {
+		case bnk.IndexSection:
+			bnk.sections[i] = idx
+		case bnk.DataSection:
+			bnk.sections[i] = data
+		}
+	}
+	bnk.IndexSection, bnk.DataSection = idx, data
+	bnk.invalidateWemIndex()
+
+	bnk.updateDataStart()
+}
+
+// sourceOf returns a WemSource describing the current contents of wem.
+func sourceOf(wem *wwise.Wem) (WemSource, error) {
+	r, ok := wem.Reader.(io.ReaderAt)
+	if !ok {
+		msg := fmt.Sprintf("Wem %d does not support random access",
+			wem.Descriptor.WemId)
+		return WemSource{}, errors.New(msg)
+	}
+	return WemSource{wem.Descriptor.WemId, r, int64(wem.Descriptor.Length)}, nil
+}
+
+// newBankHeaderSection creates a new BankHeaderSection described by desc, for a
+// SoundBank of sound effects.
+func newBankHeaderSection(desc BankDescriptor) *BankHeaderSection {
+	settings := new(BankSettings)
+	if desc.Version > lastNumericLanguageVersion {
+		settings.LanguageId = wwise.HashName(sfxLanguage)
+	}
+	remaining := make([]byte, createdBankHeaderRemainingBytes)
+	hdr := &SectionHeader{bkhdHeaderId, BKHD_SECTION_BYTES +
+		BKHD_SETTINGS_BYTES + createdBankHeaderRemainingBytes}
+	r := util.NewResettingReader(bytes.NewReader(remaining), 0,
+		int64(len(remaining)))
+	return &BankHeaderSection{hdr, desc, settings, r}
+}
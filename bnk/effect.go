@@ -0,0 +1,239 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/util"
+)
+
+// The identifier for Effect ShareSet objects.
+const effectShareSetId = 0x12
+
+// The identifier for custom (unshared) effect objects.
+const effectCustomId = 0x13
+
+// The number of bytes used to describe a single media source of an effect.
+const EFFECT_MEDIA_BYTES = 5
+
+// An EffectMedia is a source, such as an impulse response, used by an effect.
+type EffectMedia struct {
+	Index    byte
+	SourceId uint32
+}
+
+// An EffectObject represents an Effect ShareSet or custom effect within the
+// HIRC section. The parameters of the effect are stored in a format specific to
+// its plugin.
+type EffectObject struct {
+	Descriptor *ObjectDescriptor
+	// The ID of the plugin implementing this effect. The ID of a plugin is made
+	// up of its own ID in the high 16 bits, the ID of its company and its type.
+	PluginId uint32
+	// The undecoded parameters of the effect.
+	Params []byte
+	Media  []EffectMedia
+	RTPCs  []RTPC
+	// A reader to read the remaining data of this object.
+	RemainingReader io.Reader
+}
+
+// NewEffectObject creates a new EffectObject, reading from sr, which must be
+// seeked to the start of the object's data. bkhd is the header of the
+// SoundBank containing this object, and may be nil.
+func (desc *ObjectDescriptor) NewEffectObject(sr util.ReadSeekerAt,
+	bkhd *BankHeaderSection) (*EffectObject, error) {
+	l := layoutOf(bkhd)
+	if !l.known() {
+		return nil, errUnknownLayout
+	}
+	startOffset, _ := sr.Seek(0, io.SeekCurrent)
+	dataLength := int64(desc.Length) - OBJECT_DESCRIPTOR_ID_BYTES
+
+	fx := &EffectObject{Descriptor: desc}
+	var size uint32
+	err := readFields(sr, &fx.PluginId, &size)
+	if err != nil {
+		return nil, err
+	}
+	if int64(size) > dataLength {
+		return nil, errUnknownLayout
+	}
+	fx.Params = make([]byte, size)
+	_, err = io.ReadFull(sr, fx.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	var count byte
+	err = readFields(sr, &count)
+	if err != nil {
+		return nil, err
+	}
+	fx.Media = make([]EffectMedia, count)
+	err = readFields(sr, fx.Media)
+	if err != nil {
+		return nil, err
+	}
+
+	// Capture the raw bytes of the RTPCs as they are read.
+	buf := new(bytes.Buffer)
+	err = readRTPC(io.TeeReader(sr, buf), l)
+	if err != nil {
+		return nil, err
+	}
+	fx.RTPCs, err = decodeRTPCs(buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	fx.RemainingReader, err = remainingReader(sr, startOffset, dataLength)
+	if err != nil {
+		return nil, err
+	}
+	return fx, nil
+}
+
+// IsShareSet returns true if this is an Effect ShareSet.
+func (fx *EffectObject) IsShareSet() bool {
+	return fx.Descriptor.Type == effectShareSetId
+}
+
+// WriteTo writes the full contents of this EffectObject to the Writer specified
+// by w.
+func (fx *EffectObject) WriteTo(w io.Writer) (written int64, err error) {
+	rtpcs, err := encodeRTPCs(fx.RTPCs)
+	if err != nil {
+		return
+	}
+	written, err = writeFields(w, fx.Descriptor, fx.PluginId,
+		uint32(len(fx.Params)), fx.Params, byte(len(fx.Media)), fx.Media, rtpcs)
+	if err != nil {
+		return
+	}
+
+	n, err := util.CopyAll(w, fx.RemainingReader)
+	written += n
+	return written, err
+}
+
+// Id returns the ID of this object.
+func (fx *EffectObject) Id() uint32 {
+	return fx.Descriptor.ObjectId
+}
+
+// TypeId returns the identifier of the type of this object.
+func (fx *EffectObject) TypeId() byte {
+	return fx.Descriptor.Type
+}
+
+// Size returns the number of bytes that WriteTo would write.
+func (fx *EffectObject) Size() int64 {
+	return fx.Descriptor.objectSize()
+}
+
+// Effects returns all Effect ShareSets and custom effects stored in this
+// section, in the order that they appear in the file.
+func (hrc *ObjectHierarchySection) Effects() []*EffectObject {
+	var effects []*EffectObject
+	for _, obj := range hrc.objects {
+		if fx, ok := obj.(*EffectObject); ok {
+			effects = append(effects, fx)
+		}
+	}
+	return effects
+}
+
+// An EffectParameter is a single named parameter of a built-in effect.
+type EffectParameter struct {
+	Name string
+	// The value of the parameter, which is a float32, uint32 or byte.
+	Value interface{}
+}
+
+// A builtinEffect describes the parameters of an effect that ships with Wwise.
+type builtinEffect struct {
+	name string
+	// The names of the parameters, in the order that they are stored, and their
+	// zero values, which determine their types.
+	params []EffectParameter
+}
+
+// builtinEffectId returns the plugin ID of the Audiokinetic effect with the
+// given ID.
+func builtinEffectId(id uint32) uint32 {
+	return id<<16 | 0x03
+}
+
+// The built-in effects whose parameters can be decoded, by plugin ID.
+var builtinEffects = map[uint32]builtinEffect{
+	builtinEffectId(0x6A): {"Wwise Delay", []EffectParameter{
+		{"DelayTime", float32(0)}, {"Feedback", float32(0)},
+		{"WetDryMix", float32(0)}, {"OutputLevel", float32(0)},
+		{"FeedbackEnabled", byte(0)}, {"ProcessLFE", byte(0)}}},
+	builtinEffectId(0x6C): {"Wwise Compressor", []EffectParameter{
+		{"Threshold", float32(0)}, {"Ratio", float32(0)}, {"Attack", float32(0)},
+		{"Release", float32(0)}, {"OutputGain", float32(0)},
+		{"ProcessLFE", byte(0)}, {"ChannelLink", byte(0)}}},
+	builtinEffectId(0x6E): {"Wwise Peak Limiter", []EffectParameter{
+		{"Threshold", float32(0)}, {"Ratio", float32(0)},
+		{"LookAhead", float32(0)}, {"Release", float32(0)},
+		{"OutputLevel", float32(0)}, {"ProcessLFE", byte(0)},
+		{"ChannelLink", byte(0)}}},
+	builtinEffectId(0x81): {"Wwise Meter", []EffectParameter{
+		{"Attack", float32(0)}, {"Release", float32(0)}, {"Min", float32(0)},
+		{"Max", float32(0)}, {"Hold", float32(0)}, {"Mode", byte(0)},
+		{"Scope", byte(0)}, {"GameParamId", uint32(0)}}},
+	builtinEffectId(0x8B): {"Wwise Gain", []EffectParameter{
+		{"FullbandGain", float32(0)}, {"LFEGain", float32(0)}}},
+}
+
+// PluginName returns the name of the plugin of this effect, or an empty string
+// if it is not a known built-in effect.
+func (fx *EffectObject) PluginName() string {
+	return builtinEffects[fx.PluginId].name
+}
+
+// Parameters decodes the parameters of this effect. It is an error to call this
+// method on an effect whose plugin is not a known built-in effect.
+func (fx *EffectObject) Parameters() ([]EffectParameter, error) {
+	effect, ok := builtinEffects[fx.PluginId]
+	if !ok {
+		return nil, fmt.Errorf("The parameters of plugin %#x cannot be decoded",
+			fx.PluginId)
+	}
+	r := bytes.NewReader(fx.Params)
+	params := make([]EffectParameter, len(effect.params))
+	for i, p := range effect.params {
+		var err error
+		switch p.Value.(type) {
+		case float32:
+			var bits uint32
+			err = binary.Read(r, binary.LittleEndian, &bits)
+			p.Value = math.Float32frombits(bits)
+		case uint32:
+			var v uint32
+			err = binary.Read(r, binary.LittleEndian, &v)
+			p.Value = v
+		case byte:
+			var v byte
+			err = binary.Read(r, binary.LittleEndian, &v)
+			p.Value = v
+		}
+		if err != nil {
+			return nil, fmt.Errorf("The parameters of %s are truncated", effect.name)
+		}
+		params[i] = p
+	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("%d bytes remain after the parameters of %s",
+			r.Len(), effect.name)
+	}
+	return params, nil
+}
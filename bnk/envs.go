@@ -0,0 +1,159 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var envsHeaderId = [4]byte{'E', 'N', 'V', 'S'}
+
+// The number of properties of a sound, its volume, low-pass filter and
+// high-pass filter, that obstruction and occlusion may each drive with a curve.
+const ENVIRONMENT_CURVE_USES = 3
+
+// An EnvironmentSection represents the ENVS section of a SoundBank file. It is
+// only found in Init.bnk, and holds the curves that map the obstruction and
+// occlusion of a sound, in percent, to changes of its properties.
+type EnvironmentSection struct {
+	Header *SectionHeader
+	// The curves driven by obstruction, one for each property of a sound.
+	Obstruction [ENVIRONMENT_CURVE_USES]EnvironmentCurve
+	// The curves driven by occlusion, one for each property of a sound.
+	Occlusion [ENVIRONMENT_CURVE_USES]EnvironmentCurve
+	// The data that follows the occlusion curves, which is kept as is.
+	Remaining []byte
+}
+
+// An EnvironmentCurve maps the obstruction or occlusion of a sound (From) to
+// the value of a property of the sound (To).
+type EnvironmentCurve struct {
+	// True if the curve is applied.
+	Enabled bool
+	// The scaling of the curve's Y axis, such as 2 for curves in decibels.
+	Scaling byte
+	Points  []GraphPoint
+}
+
+// NewEnvironmentSection creates a new EnvironmentSection, reading from r,
+// which must be seeked to the start of the ENVS section data. bkhd is the
+// header of the SoundBank, which decides the layout of the section.
+// An error is returned if this method is called on a non-ENVS header, or if the
+// layout of the section is not known.
+func (hdr *SectionHeader) NewEnvironmentSection(r io.Reader,
+	bkhd *BankHeaderSection) (*EnvironmentSection, error) {
+	if hdr.Identifier != envsHeaderId {
+		msg := fmt.Sprintf("Expected ENVS header but got: %s", hdr.Identifier)
+		return nil, errors.New(msg)
+	}
+	data := make([]byte, hdr.Length)
+	_, err := io.ReadFull(r, data)
+	if err != nil {
+		return nil, err
+	}
+	l := layoutOf(bkhd)
+	if !l.known() {
+		msg := fmt.Sprintf("The layout of the ENVS section of version %d "+
+			"SoundBanks is not known", l.version)
+		return nil, errors.New(msg)
+	}
+
+	sec := &EnvironmentSection{Header: hdr}
+	br := bytes.NewReader(data)
+	for _, curves := range []*[ENVIRONMENT_CURVE_USES]EnvironmentCurve{
+		&sec.Obstruction, &sec.Occlusion} {
+		for i := range curves {
+			curve, err := readEnvironmentCurve(br)
+			if err != nil {
+				return nil, err
+			}
+			curves[i] = curve
+		}
+	}
+
+	sec.Remaining = data[len(data)-br.Len():]
+	return sec, nil
+}
+
+// readEnvironmentCurve reads a curve that is stored as whether it is enabled,
+// its scaling, and a list of points with a 16-bit count.
+func readEnvironmentCurve(br *bytes.Reader) (EnvironmentCurve, error) {
+	var curve EnvironmentCurve
+	var count uint16
+	err := readFields(br, &curve.Enabled, &curve.Scaling, &count)
+	if err != nil {
+		return curve, err
+	}
+	if int(count)*binary.Size(GraphPoint{}) > br.Len() {
+		return curve, errUnknownLayout
+	}
+	curve.Points = make([]GraphPoint, count)
+	err = readFields(br, curve.Points)
+	return curve, err
+}
+
+// WriteTo writes the full contents of this EnvironmentSection to the Writer
+// specified by w.
+func (envs *EnvironmentSection) WriteTo(w io.Writer) (written int64, err error) {
+	envs.Header.Length = envs.Length()
+	written, err = writeFields(w, envs.Header)
+	if err != nil {
+		return
+	}
+	for _, curve := range envs.curves() {
+		n, err := writeFields(w, curve.Enabled, curve.Scaling,
+			uint16(len(curve.Points)), curve.Points)
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	n, err := writeFields(w, envs.Remaining)
+	written += n
+	return
+}
+
+// curves returns the obstruction curves of this section followed by its
+// occlusion curves, in the order that they are stored.
+func (envs *EnvironmentSection) curves() []EnvironmentCurve {
+	curves := append([]EnvironmentCurve(nil), envs.Obstruction[:]...)
+	return append(curves, envs.Occlusion[:]...)
+}
+
+// Identifier returns the four character identifier of this section.
+func (envs *EnvironmentSection) Identifier() string {
+	return string(envs.Header.Identifier[:])
+}
+
+// Length returns the length in bytes of the data of this section.
+func (envs *EnvironmentSection) Length() uint32 {
+	return uint32(envs.Size() - SECTION_HEADER_BYTES)
+}
+
+// Size returns the number of bytes that WriteTo would write.
+func (envs *EnvironmentSection) Size() int64 {
+	size := int64(SECTION_HEADER_BYTES)
+	for _, curve := range envs.curves() {
+		size += 4 + int64(binary.Size(curve.Points))
+	}
+	return size + int64(len(envs.Remaining))
+}
+
+func (envs *EnvironmentSection) String() string {
+	b := new(strings.Builder)
+	fmt.Fprintf(b, "%s: len(%d)\n", envs.Header.Identifier, envs.Header.Length)
+	names := []string{"volume", "low-pass", "high-pass"}
+	for i, curve := range envs.Obstruction {
+		fmt.Fprintf(b, "ENVS: obstruction %s enabled(%t) points(%d)\n", names[i],
+			curve.Enabled, len(curve.Points))
+	}
+	for i, curve := range envs.Occlusion {
+		fmt.Fprintf(b, "ENVS: occlusion %s enabled(%t) points(%d)\n", names[i],
+			curve.Enabled, len(curve.Points))
+	}
+	return b.String()
+}
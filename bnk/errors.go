@@ -0,0 +1,70 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotASoundBank is returned when a file does not begin with a BKHD section.
+var ErrNotASoundBank = errors.New("The file is not a SoundBank")
+
+// ErrCorruptDIDX is returned when the DIDX section describes wems that cannot
+// be stored in the DATA section.
+var ErrCorruptDIDX = errors.New("The DIDX section is corrupt")
+
+// ErrUnsupportedVersion is returned when a SoundBank was built by a version of
+// Wwise that this package cannot read.
+var ErrUnsupportedVersion = errors.New("The SoundBank version is not supported")
+
+// ErrWemTooLarge is returned when a wem, or the DATA section holding it, is too
+// large to be described by a SoundBank.
+var ErrWemTooLarge = errors.New("The wem is too large")
+
+// ErrDoesNotFit is returned when a wem cannot be patched in place, because it
+// is longer than the wem it replaces and the padding that follows it.
+var ErrDoesNotFit = errors.New("The wem does not fit in place")
+
+// ErrUnknownSection is returned when a SoundBank is read with the Strict
+// option, and holds a section that is not known or could not be decoded.
+var ErrUnknownSection = errors.New("The section is unknown, or could not be " +
+	"decoded")
+
+// The oldest SoundBank version that can be read. SoundBanks from earlier
+// releases of Wwise lay out their sections differently.
+const minSupportedVersion = 27
+
+// A SectionError records a failure to read a section of a SoundBank, along
+// with where that section is stored.
+type SectionError struct {
+	// The identifier of the section, such as "DIDX".
+	Section string
+	// The offset into the file where the header of the section begins.
+	Offset int64
+	Err    error
+}
+
+func (e *SectionError) Error() string {
+	return fmt.Sprintf("%s section at offset %d: %s", e.Section, e.Offset, e.Err)
+}
+
+// Unwrap returns the underlying error, so that SectionErrors can be inspected
+// with errors.Is and errors.As.
+func (e *SectionError) Unwrap() error {
+	return e.Err
+}
+
+// A ReferenceError records a reference from a HIRC object to an object or wem
+// that cannot be found.
+type ReferenceError struct {
+	// The ID of the object holding the reference.
+	ObjectId uint32
+	// What is referenced, such as "action" or "wem".
+	Kind string
+	Id   uint32
+}
+
+func (e *ReferenceError) Error() string {
+	return fmt.Sprintf("Object %d refers to missing %s %d", e.ObjectId, e.Kind,
+		e.Id)
+}
@@ -0,0 +1,261 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"encoding/binary"
+	"io"
+	"sort"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/util"
+)
+
+// The number of bytes used to describe the type, target and bus flag of an
+// action.
+const ACTION_PREFIX_BYTES = 2 + 4 + 1
+
+// The identifier for action objects.
+const actionObjectId = 0x03
+
+// The identifier for event objects.
+const eventObjectId = 0x04
+
+// The action category, stored in the high byte of an action type, of actions
+// that play their target.
+const actionPlayCategory = 0x04
+
+// An EventObject represents an event within the HIRC section. An event performs
+// a list of actions when it is posted by the game.
+type EventObject struct {
+	Descriptor *ObjectDescriptor
+	// The IDs of the actions performed by this event, in order.
+	ActionIds []uint32
+	// The layout of the SoundBank containing this event, which determines how
+	// the number of actions is stored.
+	layout layout
+}
+
+// An ActionObject represents an action within the HIRC section, such as
+// playing, stopping or pausing an object.
+type ActionObject struct {
+	Descriptor *ObjectDescriptor
+	// The type of this action. The high byte is the category of the action, and
+	// the low byte is its scope.
+	ActionType uint16
+	// The ID of the object, or bus, that this action targets.
+	TargetId uint32
+	// 1 if TargetId refers to a bus, and 0 if otherwise.
+	IsBus byte
+	// A reader to read the remaining data of this action.
+	RemainingReader io.Reader
+}
+
+// NewEventObject creates a new EventObject, reading from sr, which must be
+// seeked to the start of the object's data. bkhd is the header of the
+// SoundBank containing this object, and may be nil.
+func (desc *ObjectDescriptor) NewEventObject(sr util.ReadSeekerAt,
+	bkhd *BankHeaderSection) (*EventObject, error) {
+	l := layoutOf(bkhd)
+	count, err := l.readCount(sr)
+	if err != nil {
+		return nil, err
+	}
+	if int64(count)*4 > int64(desc.Length)-OBJECT_DESCRIPTOR_ID_BYTES {
+		return nil, errUnknownLayout
+	}
+	ids := make([]uint32, count)
+	err = binary.Read(sr, binary.LittleEndian, ids)
+	if err != nil {
+		return nil, err
+	}
+	return &EventObject{desc, ids, l}, nil
+}
+
+// WriteTo writes the full contents of this EventObject to the Writer specified
+// by w.
+func (event *EventObject) WriteTo(w io.Writer) (written int64, err error) {
+	err = binary.Write(w, binary.LittleEndian, event.Descriptor)
+	if err != nil {
+		return
+	}
+	written = OBJECT_DESCRIPTOR_BYTES
+
+	n, err := w.Write(event.layout.countBytes(uint32(len(event.ActionIds))))
+	written += int64(n)
+	if err != nil {
+		return
+	}
+
+	err = binary.Write(w, binary.LittleEndian, event.ActionIds)
+	if err != nil {
+		return
+	}
+	written += int64(len(event.ActionIds)) * 4
+	return written, nil
+}
+
+// Id returns the ID of this object.
+func (event *EventObject) Id() uint32 {
+	return event.Descriptor.ObjectId
+}
+
+// TypeId returns the identifier of the type of this object.
+func (event *EventObject) TypeId() byte {
+	return event.Descriptor.Type
+}
+
+// Size returns the number of bytes that WriteTo would write.
+func (event *EventObject) Size() int64 {
+	return event.Descriptor.objectSize()
+}
+
+// NewActionObject creates a new ActionObject, reading from sr, which must be
+// seeked to the start of the object's data.
+func (desc *ObjectDescriptor) NewActionObject(sr util.ReadSeekerAt) (*ActionObject, error) {
+	action := &ActionObject{Descriptor: desc}
+	err := binary.Read(sr, binary.LittleEndian, &action.ActionType)
+	if err != nil {
+		return nil, err
+	}
+	err = binary.Read(sr, binary.LittleEndian, &action.TargetId)
+	if err != nil {
+		return nil, err
+	}
+	err = binary.Read(sr, binary.LittleEndian, &action.IsBus)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a reader over the remaining elements in this object, then seek past
+	// it.
+	currOffset, _ := sr.Seek(0, io.SeekCurrent)
+	remaining :=
+		int64(desc.Length) - OBJECT_DESCRIPTOR_ID_BYTES - ACTION_PREFIX_BYTES
+	action.RemainingReader = util.NewResettingReader(sr, currOffset, remaining)
+	sr.Seek(remaining, io.SeekCurrent)
+	return action, nil
+}
+
+// WriteTo writes the full contents of this ActionObject to the Writer specified
+// by w.
+func (action *ActionObject) WriteTo(w io.Writer) (written int64, err error) {
+	err = binary.Write(w, binary.LittleEndian, action.Descriptor)
+	if err != nil {
+		return
+	}
+	written = OBJECT_DESCRIPTOR_BYTES
+
+	err = binary.Write(w, binary.LittleEndian, action.ActionType)
+	if err != nil {
+		return
+	}
+	err = binary.Write(w, binary.LittleEndian, action.TargetId)
+	if err != nil {
+		return
+	}
+	err = binary.Write(w, binary.LittleEndian, action.IsBus)
+	if err != nil {
+		return
+	}
+	written += ACTION_PREFIX_BYTES
+
+	n, err := util.CopyAll(w, action.RemainingReader)
+	if err != nil {
+		return written, err
+	}
+	written += n
+
+	return written, nil
+}
+
+// Id returns the ID of this object.
+func (action *ActionObject) Id() uint32 {
+	return action.Descriptor.ObjectId
+}
+
+// TypeId returns the identifier of the type of this object.
+func (action *ActionObject) TypeId() byte {
+	return action.Descriptor.Type
+}
+
+// Size returns the number of bytes that WriteTo would write.
+func (action *ActionObject) Size() int64 {
+	return action.Descriptor.objectSize()
+}
+
+// Plays returns true if this action plays its target.
+func (action *ActionObject) Plays() bool {
+	return action.ActionType>>8 == actionPlayCategory && action.IsBus == 0
+}
+
+// Events returns all events stored in this section, in the order that they
+// appear in the file.
+func (hrc *ObjectHierarchySection) Events() []*EventObject {
+	var events []*EventObject
+	for _, obj := range hrc.objects {
+		if event, ok := obj.(*EventObject); ok {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// WemsOf returns the IDs of all wems that may be played by the object with the
+// given ID. Events are followed to their actions, actions that play an object
+// are followed to their target, containers and music segments, playlists and
+// switches are followed to their children, and music tracks to their sources.
+// The IDs are returned in ascending order. Wems of objects that are not stored
+// in this section are not included.
+func (hrc *ObjectHierarchySection) WemsOf(id uint32) []uint32 {
+	visited := make(map[uint32]bool)
+	found := make(map[uint32]bool)
+	var visit func(id uint32)
+	visit = func(id uint32) {
+		if visited[id] {
+			return
+		}
+		visited[id] = true
+
+		switch obj := hrc.objectOf[id].(type) {
+		case *SfxVoiceSoundObject:
+			found[obj.WemDescriptor.WemId] = true
+		case *EventObject:
+			for _, actionId := range obj.ActionIds {
+				visit(actionId)
+			}
+		case *ActionObject:
+			if obj.Plays() {
+				visit(obj.TargetId)
+			}
+		case *ContainerObject:
+			for _, childId := range obj.ChildIds {
+				visit(childId)
+			}
+		case *MusicPlaylistObject:
+			for _, childId := range obj.ChildIds {
+				visit(childId)
+			}
+		case *MusicSwitchObject:
+			for _, childId := range obj.ChildIds {
+				visit(childId)
+			}
+		case *MusicSegmentObject:
+			for _, childId := range obj.ChildIds {
+				visit(childId)
+			}
+		case *MusicTrackObject:
+			for _, src := range obj.Sources {
+				found[src.SourceId] = true
+			}
+		}
+	}
+	visit(id)
+
+	wems := make([]uint32, 0, len(found))
+	for wemId := range found {
+		wems = append(wems, wemId)
+	}
+	sort.Slice(wems, func(i, j int) bool { return wems[i] < wems[j] })
+	return wems
+}
@@ -2,6 +2,9 @@
 package bnk
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -9,6 +12,8 @@ import (
 	"math"
 	"os"
 	"strings"
+	"sync"
+	"time"
 )
 
 import (
@@ -32,8 +37,64 @@ type File struct {
 	IndexSection      *DataIndexSection
 	DataSection       *DataSection
 	ObjectSection     *ObjectHierarchySection
+	StringIdSection   *StringIdSection
+	// The STMG section, which is only found in Init.bnk.
+	GlobalSettingsSection *GlobalSettingsSection
+	// The ENVS section, which is only found in Init.bnk.
+	EnvironmentSection *EnvironmentSection
+	PlatformSection    *PlatformSection
+	// The problems that were tolerated while reading this SoundBank, as allowed
+	// by the ReadOptions it was read with.
+	Warnings []error
+	opts     ReadOptions
+	// The number of bytes that the offsets of wems added to or grown in this
+	// SoundBank are aligned to, or 0 to align them as Wwise does.
+	alignment int64
+	// True if the padding that follows a replaced wem keeps its original bytes.
+	preservePadding bool
+	// The index of the first wem with each ID, for the wems in indexedWems. It is
+	// built by IndexOfWem, and rebuilt once the wems of this SoundBank change.
+	wemIndexes  map[uint32]int
+	indexedWems []*wwise.Wem
+	wemIndexMu  sync.Mutex
 }
 
+// ReadOptions describes how a SoundBank is read.
+type ReadOptions struct {
+	// If Lenient is true, a DIDX section describing wems that overlap, or that
+	// end past the end of the DATA section, does not prevent the SoundBank from
+	// being read. The affected wems are cut short or laid out again, and each
+	// problem is recorded in the Warnings of the File.
+	Lenient bool
+	// Duplicates describes how a DIDX section that repeats a wem ID is read.
+	Duplicates DuplicatePolicy
+	// If MemoryMap is true, OpenWithOptions maps the file into memory rather than
+	// reading it with system calls, which speeds up reading very large
+	// SoundBanks. The file must not be modified while it is open.
+	MemoryMap bool
+	// If Strict is true, a SoundBank holding a section that is not known, or
+	// that could not be decoded, is not read, rather than keeping the section as
+	// an UnknownSection. An error wrapping ErrUnknownSection is returned.
+	Strict bool
+}
+
+// A DuplicatePolicy describes how a SoundBank whose DIDX section repeats a wem
+// ID is read. Every repeat that is tolerated is recorded in the Warnings of the
+// File.
+type DuplicatePolicy int
+
+const (
+	// DuplicateError fails to read the SoundBank, returning an error that wraps
+	// ErrCorruptDIDX.
+	DuplicateError DuplicatePolicy = iota
+	// DuplicateKeepFirst keeps the first wem with each ID, and drops every
+	// repeat from the DIDX section.
+	DuplicateKeepFirst
+	// DuplicateKeepAll keeps every wem. Wems that share an ID can only be told
+	// apart by their index; WemByID and DescriptorMap refer to the first of them.
+	DuplicateKeepAll
+)
+
 // LoopValue describes the loop parameters of a given audio object.
 type LoopValue struct {
 	// True if this audio object loops; and false if otherwise.
@@ -46,84 +107,464 @@ type LoopValue struct {
 // NewFile creates a new File for access Wwise SoundBank files. The file is
 // expected to start at position 0 in the io.ReaderAt.
 func NewFile(r io.ReaderAt) (*File, error) {
-	bnk := new(File)
+	return NewFileContext(context.Background(), r)
+}
+
+// NewFileFromBytes creates a new File for access to the Wwise SoundBank stored
+// in b. The contents of b must not be modified while the File is in use.
+func NewFileFromBytes(b []byte) (*File, error) {
+	return NewFile(bytes.NewReader(b))
+}
+
+// NewFileContext is like NewFile, but stops reading and returns ctx.Err() if
+// ctx is done before every section has been read.
+func NewFileContext(ctx context.Context, r io.ReaderAt) (*File, error) {
+	return NewFileWithOptions(ctx, r, ReadOptions{})
+}
+
+// NewFileWithOptions is like NewFileContext, but reads the SoundBank as
+// described by opts.
+func NewFileWithOptions(ctx context.Context, r io.ReaderAt,
+	opts ReadOptions) (*File, error) {
+	bnk := &File{opts: opts}
 
 	sr := util.NewResettingReader(r, 0, math.MaxInt64)
 	for {
-		hdr := new(SectionHeader)
-		err := binary.Read(sr, binary.LittleEndian, hdr)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		offset, _ := sr.Seek(0, io.SeekCurrent)
+		hdr, err := readSectionHeader(sr)
 		if err != nil {
+			if offset == 0 {
+				// Only a file too short to hold a section header is known not to be
+				// a SoundBank; other errors are failures to read it.
+				if err == io.EOF || err == io.ErrUnexpectedEOF {
+					return nil, ErrNotASoundBank
+				}
+				return nil, fmt.Errorf("Could not read the bank header: %w", err)
+			}
 			if err == io.EOF {
 				break
 			}
 			return nil, err
 		}
+		if offset == 0 && hdr.Identifier != bkhdHeaderId {
+			return nil, ErrNotASoundBank
+		}
+
+		err = bnk.readSection(hdr, sr)
+		if err != nil {
+			return nil, &SectionError{string(hdr.Identifier[:]), offset, err}
+		}
+		if _, ok := bnk.lastSection().(*UnknownSection); ok && opts.Strict {
+			return nil, &SectionError{string(hdr.Identifier[:]), offset,
+				ErrUnknownSection}
+		}
+	}
+
+	// Init.bnk holds the global settings of a project rather than wems, so it
+	// is the only SoundBank that may be read without them.
+	if (bnk.DataSection == nil || len(bnk.Wems()) == 0) && !bnk.IsInit() {
+		return nil, errors.New("There are no wems stored within this file.")
+	}
+
+	return bnk, nil
+}
 
-		switch id := hdr.Identifier; id {
-		case bkhdHeaderId:
-			sec, err := hdr.NewBankHeaderSection(sr)
+// addWarnings records warnings about the section described by hdr, whose data
+// begins at offset, in the Warnings of this File.
+func (bnk *File) addWarnings(hdr *SectionHeader, offset int64,
+	warnings []error) {
+	for _, w := range warnings {
+		bnk.Warnings = append(bnk.Warnings, &SectionError{
+			string(hdr.Identifier[:]), offset - SECTION_HEADER_BYTES, w})
+	}
+}
+
+// readSection reads the section described by hdr from sr, which must be seeked
+// to the start of the section data, and adds it to this File.
+func (bnk *File) readSection(hdr *SectionHeader, sr util.ReadSeekerAt) error {
+	switch hdr.Identifier {
+	case bkhdHeaderId:
+		sec, err := hdr.NewBankHeaderSection(sr)
+		if err != nil {
+			return err
+		}
+		bnk.BankHeaderSection = sec
+		bnk.sections = append(bnk.sections, sec)
+	case didxHeaderId:
+		offset, _ := sr.Seek(0, io.SeekCurrent)
+		sec, warnings, err := hdr.newDataIndexSection(sr, bnk.opts.Duplicates)
+		if err != nil {
+			return err
+		}
+		bnk.addWarnings(hdr, offset, warnings)
+		bnk.IndexSection = sec
+		bnk.sections = append(bnk.sections, sec)
+	case dataHeaderId:
+		offset, _ := sr.Seek(0, io.SeekCurrent)
+		sec, warnings, err := hdr.newDataSection(sr, bnk.IndexSection,
+			bnk.opts.Lenient)
+		if err != nil {
+			return err
+		}
+		bnk.addWarnings(hdr, offset, warnings)
+		bnk.DataSection = sec
+		bnk.sections = append(bnk.sections, sec)
+	case hircHeaderId:
+		sec, err := hdr.NewObjectHierarchySection(sr, bnk.BankHeaderSection)
+		if err != nil {
+			return err
+		}
+		bnk.ObjectSection = sec
+		bnk.sections = append(bnk.sections, sec)
+	case stidHeaderId:
+		sec, err := hdr.NewStringIdSection(sr)
+		if err != nil {
+			return err
+		}
+		bnk.StringIdSection = sec
+		bnk.sections = append(bnk.sections, sec)
+	case stmgHeaderId:
+		offset, _ := sr.Seek(0, io.SeekCurrent)
+		sec, err := hdr.NewGlobalSettingsSection(sr, bnk.BankHeaderSection)
+		if err != nil {
+			return bnk.readUndecodedSection(hdr, sr, offset)
+		}
+		bnk.GlobalSettingsSection = sec
+		bnk.sections = append(bnk.sections, sec)
+	case envsHeaderId:
+		offset, _ := sr.Seek(0, io.SeekCurrent)
+		sec, err := hdr.NewEnvironmentSection(sr, bnk.BankHeaderSection)
+		if err != nil {
+			return bnk.readUndecodedSection(hdr, sr, offset)
+		}
+		bnk.EnvironmentSection = sec
+		bnk.sections = append(bnk.sections, sec)
+	case platHeaderId:
+		offset, _ := sr.Seek(0, io.SeekCurrent)
+		sec, err := hdr.NewPlatformSection(sr, bnk.BankHeaderSection)
+		if err != nil {
+			return bnk.readUndecodedSection(hdr, sr, offset)
+		}
+		bnk.PlatformSection = sec
+		bnk.sections = append(bnk.sections, sec)
+	default:
+		if parse := sectionParserOf(hdr.Identifier); parse != nil {
+			sec, err := bnk.readRegisteredSection(hdr, sr, parse)
 			if err != nil {
-				return nil, err
+				return err
 			}
-			bnk.BankHeaderSection = sec
 			bnk.sections = append(bnk.sections, sec)
-		case didxHeaderId:
-			sec, err := hdr.NewDataIndexSection(sr)
-			if err != nil {
-				return nil, err
-			}
+			return nil
+		}
+		sec, err := hdr.NewUnknownSection(sr)
+		if err != nil {
+			return err
+		}
+		bnk.sections = append(bnk.sections, sec)
+	}
+	return nil
+}
+
+// readUndecodedSection adds the section described by hdr, whose data begins
+// at offset in sr, to this File as an UnknownSection. The layouts of sections
+// such as those found in Init.bnk change between versions of Wwise, so a
+// section that cannot be decoded is kept as is.
+func (bnk *File) readUndecodedSection(hdr *SectionHeader, sr util.ReadSeekerAt,
+	offset int64) error {
+	sr.Seek(offset, io.SeekStart)
+	sec, err := hdr.NewUnknownSection(sr)
+	if err != nil {
+		return err
+	}
+	bnk.sections = append(bnk.sections, sec)
+	return nil
+}
+
+// WriteTo writes the full contents of this File to the Writer specified by w.
+func (bnk *File) WriteTo(w io.Writer) (written int64, err error) {
+	// Sections write many small fields, so buffer them to avoid a write to w for
+	// each.
+	bw := bufio.NewWriterSize(w, util.COPY_BUFFER_BYTES)
+	for _, s := range bnk.sections {
+		n, err := s.WriteTo(bw)
+		if err != nil {
+			return written, err
+		}
+		written += n
+	}
+	return written, bw.Flush()
+}
+
+// InsertSection inserts s into this SoundBank, so that it is the section at
+// index i of Sections. A SoundBank can hold at most one of each known section,
+// such as an STID section, but any number of UnknownSections.
+func (bnk *File) InsertSection(i int, s Section) error {
+	if i < 0 || i > len(bnk.sections) {
+		msg := fmt.Sprintf("%d is not a valid section index", i)
+		return errors.New(msg)
+	}
+	exists := false
+	switch sec := s.(type) {
+	case *BankHeaderSection:
+		exists = bnk.BankHeaderSection != nil
+		if !exists {
+			bnk.BankHeaderSection = sec
+		}
+	case *DataIndexSection:
+		exists = bnk.IndexSection != nil
+		if !exists {
 			bnk.IndexSection = sec
-			bnk.sections = append(bnk.sections, sec)
-		case dataHeaderId:
-			sec, err := hdr.NewDataSection(sr, bnk.IndexSection)
-			if err != nil {
-				return nil, err
-			}
+		}
+	case *DataSection:
+		exists = bnk.DataSection != nil
+		if !exists {
 			bnk.DataSection = sec
-			bnk.sections = append(bnk.sections, sec)
-		case hircHeaderId:
-			sec, err := hdr.NewObjectHierarchySection(sr)
-			if err != nil {
-				return nil, err
-			}
+		}
+	case *ObjectHierarchySection:
+		exists = bnk.ObjectSection != nil
+		if !exists {
 			bnk.ObjectSection = sec
-			bnk.sections = append(bnk.sections, sec)
-		default:
-			sec, err := hdr.NewUnknownSection(sr)
-			if err != nil {
-				return nil, err
-			}
-			bnk.sections = append(bnk.sections, sec)
 		}
+	case *StringIdSection:
+		exists = bnk.StringIdSection != nil
+		if !exists {
+			bnk.StringIdSection = sec
+		}
+	case *GlobalSettingsSection:
+		exists = bnk.GlobalSettingsSection != nil
+		if !exists {
+			bnk.GlobalSettingsSection = sec
+		}
+	case *EnvironmentSection:
+		exists = bnk.EnvironmentSection != nil
+		if !exists {
+			bnk.EnvironmentSection = sec
+		}
+	case *PlatformSection:
+		exists = bnk.PlatformSection != nil
+		if !exists {
+			bnk.PlatformSection = sec
+		}
+	}
+	if exists {
+		msg := fmt.Sprintf("The SoundBank already holds a %s section",
+			s.Identifier())
+		return errors.New(msg)
 	}
 
-	if bnk.DataSection == nil || len(bnk.Wems()) == 0 {
-		return nil, errors.New("There are no wems stored within this file.")
+	bnk.sections = append(bnk.sections, nil)
+	copy(bnk.sections[i+1:], bnk.sections[i:])
+	bnk.sections[i] = s
+	bnk.updateDataStart()
+	return nil
+}
+
+// RemoveSections removes every section with the given identifier from this
+// SoundBank, and returns the number of sections removed. The BKHD, DIDX and
+// DATA sections cannot be removed, as every SoundBank needs them.
+func (bnk *File) RemoveSections(identifier string) (int, error) {
+	switch identifier {
+	case string(bkhdHeaderId[:]), string(didxHeaderId[:]),
+		string(dataHeaderId[:]):
+		msg := fmt.Sprintf("The %s section cannot be removed", identifier)
+		return 0, errors.New(msg)
 	}
 
-	return bnk, nil
+	var kept []Section
+	for _, s := range bnk.sections {
+		if s.Identifier() == identifier {
+			continue
+		}
+		kept = append(kept, s)
+	}
+	removed := len(bnk.sections) - len(kept)
+	bnk.sections = kept
+	switch identifier {
+	case string(hircHeaderId[:]):
+		bnk.ObjectSection = nil
+	case string(stidHeaderId[:]):
+		bnk.StringIdSection = nil
+	case string(stmgHeaderId[:]):
+		bnk.GlobalSettingsSection = nil
+	case string(envsHeaderId[:]):
+		bnk.EnvironmentSection = nil
+	case string(platHeaderId[:]):
+		bnk.PlatformSection = nil
+	}
+	bnk.updateDataStart()
+	return removed, nil
 }
 
-// WriteTo writes the full contents of this File to the Writer specified by w.
-func (bnk *File) WriteTo(w io.Writer) (written int64, err error) {
+// ReplaceSectionData replaces the data of the first section with the given
+// identifier, such as HIRC, by data. The data is parsed as the section would be
+// when reading a SoundBank, and the section length is set from its size. The
+// DIDX and DATA sections cannot be replaced, as they must describe the wems of
+// this SoundBank.
+func (bnk *File) ReplaceSectionData(identifier string, data []byte) error {
+	switch identifier {
+	case string(didxHeaderId[:]), string(dataHeaderId[:]):
+		msg := fmt.Sprintf("The %s section cannot be replaced", identifier)
+		return errors.New(msg)
+	}
+	i := -1
+	for j, s := range bnk.sections {
+		if s.Identifier() == identifier {
+			i = j
+			break
+		}
+	}
+	if i < 0 {
+		msg := fmt.Sprintf("The SoundBank has no %s section", identifier)
+		return errors.New(msg)
+	}
+
+	raw, err := NewRawSection(identifier, data)
+	if err != nil {
+		return err
+	}
+	// Parse the section on its own, with the header of this SoundBank so that
+	// objects are read with its layout.
+	parsed := &File{BankHeaderSection: bnk.BankHeaderSection}
+	err = parsed.addSectionCopy(raw)
+	if err != nil {
+		return err
+	}
+	sec := parsed.sections[0]
+	switch sec := sec.(type) {
+	case *BankHeaderSection:
+		bnk.BankHeaderSection = sec
+	case *ObjectHierarchySection:
+		bnk.ObjectSection = sec
+	case *StringIdSection:
+		bnk.StringIdSection = sec
+	case *UnknownSection:
+		// The data was not read from the file of this SoundBank.
+		sec.Offset = -1
+	case *GlobalSettingsSection:
+		bnk.GlobalSettingsSection = sec
+	case *EnvironmentSection:
+		bnk.EnvironmentSection = sec
+	case *PlatformSection:
+		bnk.PlatformSection = sec
+	}
+	bnk.sections[i] = sec
+	bnk.updateDataStart()
+	return nil
+}
+
+// updateDataStart updates the offset where the data of the DATA section begins
+// to account for the sections preceding it.
+func (bnk *File) updateDataStart() {
+	if bnk.DataSection == nil {
+		return
+	}
+	dataStart := int64(SECTION_HEADER_BYTES)
 	for _, s := range bnk.sections {
-		n, err := s.WriteTo(w)
+		if s == bnk.DataSection {
+			break
+		}
+		dataStart += s.Size()
+	}
+	bnk.DataSection.DataStart = uint32(dataStart)
+}
+
+// Sections returns every section of this SoundBank, including those of an
+// unknown type, in the order that they are written.
+func (bnk *File) Sections() []Section {
+	sections := make([]Section, len(bnk.sections))
+	copy(sections, bnk.sections)
+	return sections
+}
+
+// Size returns the number of bytes that WriteTo would write.
+func (bnk *File) Size() int64 {
+	size := int64(0)
+	for _, s := range bnk.sections {
+		size += s.Size()
+	}
+	return size
+}
+
+// WriteToAt writes the full contents of this File to w, as WriteTo would write
+// them, and returns the number of bytes written. As the size of every section
+// is known up front, the wems of the DATA section are written out of order by
+// as many goroutines as specified by threads, which speeds up writing large
+// SoundBanks to fast storage. At least one goroutine is used.
+func (bnk *File) WriteToAt(w io.WriterAt, threads int) (written int64,
+	err error) {
+	for _, s := range bnk.sections {
+		ow := io.NewOffsetWriter(w, written)
+		data, ok := s.(*DataSection)
+		if !ok {
+			bw := bufio.NewWriterSize(ow, util.COPY_BUFFER_BYTES)
+			n, err := s.WriteTo(bw)
+			if err == nil {
+				err = bw.Flush()
+			}
+			if err != nil {
+				return written, err
+			}
+			written += n
+			continue
+		}
+
+		data.Header.Length = data.Length()
+		err = binary.Write(ow, binary.LittleEndian, data.Header)
+		if err != nil {
+			return written, err
+		}
+		written += SECTION_HEADER_BYTES
+		n, err := wwise.WriteWemsAt(w, written, data.Wems, threads)
 		if err != nil {
 			return written, err
 		}
 		written += n
 	}
-	return
+	return written, nil
+}
+
+// WriteToBytes returns the full contents of this File, as written by WriteTo.
+func (bnk *File) WriteToBytes() ([]byte, error) {
+	b := new(bytes.Buffer)
+	_, err := bnk.WriteTo(b)
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// WriteToContext is like WriteTo, but stops writing and returns ctx.Err() if
+// ctx is done before the full contents have been written.
+func (bnk *File) WriteToContext(ctx context.Context,
+	w io.Writer) (written int64, err error) {
+	return bnk.WriteTo(util.NewContextWriter(ctx, w))
 }
 
 // Open opens the File at the specified path using os.Open and prepares it for
 // use as a Wwise SoundBank file.
 func Open(path string) (*File, error) {
-	f, err := os.Open(path)
+	return OpenWithOptions(path, ReadOptions{})
+}
+
+// OpenWithOptions is like Open, but reads the SoundBank as described by opts.
+func OpenWithOptions(path string, opts ReadOptions) (*File, error) {
+	var f interface {
+		io.ReaderAt
+		io.Closer
+	}
+	var err error
+	if opts.MemoryMap {
+		f, err = util.OpenMapped(path)
+	} else {
+		f, err = os.Open(path)
+	}
 	if err != nil {
 		return nil, err
 	}
-	bnk, err := NewFile(f)
+	bnk, err := NewFileWithOptions(context.Background(), f, opts)
 	if err != nil {
 		f.Close()
 		return nil, err
@@ -151,19 +592,200 @@ func (bnk *File) Wems() []*wwise.Wem {
 	return bnk.DataSection.Wems
 }
 
+// WemByID returns the wem stored in this SoundBank with the given ID, and
+// whether such a wem exists.
+func (bnk *File) WemByID(id uint32) (*wwise.Wem, bool) {
+	i, ok := bnk.IndexOfWem(id)
+	if !ok {
+		return nil, false
+	}
+	return bnk.Wems()[i], true
+}
+
+// IndexOfWem returns the index, where zero is the first wem, of the wem stored
+// in this SoundBank with the given ID, and whether such a wem exists.
+func (bnk *File) IndexOfWem(id uint32) (int, bool) {
+	bnk.wemIndexMu.Lock()
+	defer bnk.wemIndexMu.Unlock()
+	wems := bnk.Wems()
+	// The wems may also have been changed through the DataSection, so check
+	// that the index still describes them.
+	indexed := len(wems) == len(bnk.indexedWems) &&
+		(len(wems) == 0 || &wems[0] == &bnk.indexedWems[0])
+	i, ok := bnk.wemIndexes[id]
+	if !indexed || bnk.wemIndexes == nil ||
+		(ok && wems[i].Descriptor.WemId != id) {
+		bnk.indexWems(wems)
+		i, ok = bnk.wemIndexes[id]
+	}
+	if !ok {
+		return -1, false
+	}
+	return i, true
+}
+
+// indexWems builds the index used by IndexOfWem for wems.
+func (bnk *File) indexWems(wems []*wwise.Wem) {
+	bnk.wemIndexes = make(map[uint32]int, len(wems))
+	for i := len(wems) - 1; i >= 0; i-- {
+		bnk.wemIndexes[wems[i].Descriptor.WemId] = i
+	}
+	bnk.indexedWems = wems
+}
+
+// invalidateWemIndex makes the next call to IndexOfWem rebuild its index, once
+// the wems of this SoundBank have changed.
+func (bnk *File) invalidateWemIndex() {
+	bnk.wemIndexMu.Lock()
+	bnk.wemIndexes = nil
+	bnk.wemIndexMu.Unlock()
+}
+
+// CheckReplacements returns an error if the wems of this SoundBank cannot be
+// replaced with rs. The error wraps ErrWemTooLarge if a replacement, or the
+// DATA section that would hold it, is too large to be described by a
+// SoundBank.
+func (bnk *File) CheckReplacements(rs ...*wwise.ReplacementWem) error {
+	wems := bnk.Wems()
+	dataLength := int64(0)
+	if bnk.DataSection != nil {
+		dataLength = int64(bnk.DataSection.Length())
+	}
+	for _, r := range rs {
+		if r.WemIndex < 0 || r.WemIndex >= len(wems) {
+			msg := fmt.Sprintf("There is no wem at index %d to replace", r.WemIndex)
+			return errors.New(msg)
+		}
+		if r.Length > math.MaxUint32 {
+			return fmt.Errorf("%w: replacement for wem %d is %d bytes long",
+				ErrWemTooLarge, wems[r.WemIndex].Descriptor.WemId, r.Length)
+		}
+		// Account for the worst case of the replacement needing a full alignment
+		// of padding.
+		dataLength += r.Length - int64(wems[r.WemIndex].Descriptor.Length) +
+			bnk.Alignment()
+	}
+	if dataLength > math.MaxUint32 {
+		return fmt.Errorf("%w: the DATA section would be %d bytes long",
+			ErrWemTooLarge, dataLength)
+	}
+	return nil
+}
+
+// ReplaceWems replaces the wems of this SoundBank with rs. The HIRC objects
+// that play the replaced wems are updated to match them: the in-memory size of
+// sounds and music sources, and the duration of the clips of music tracks.
 func (bnk *File) ReplaceWems(rs ...*wwise.ReplacementWem) {
-	surplus := wwise.ReplaceWems(bnk, wemAlignmentBytes, rs...)
+	defer bnk.invalidateWemIndex()
+	bnk.updateObjectsOf(rs)
+	// The length of the DATA header is recomputed from its wems when written.
+	opts := wwise.ReplaceOptions{Alignment: bnk.Alignment(),
+		PreservePadding: bnk.preservePadding}
+	wwise.ReplaceWemsWithOptions(bnk, opts, rs...)
+}
+
+// updateObjectsOf updates the HIRC objects that play the wems replaced by rs to
+// match their replacements. The durations of music track clips are only
+// updated if the duration of the replacement can be determined. Trim offsets
+// are relative to the ends of the source, so they are left as they are.
+func (bnk *File) updateObjectsOf(rs []*wwise.ReplacementWem) {
+	if bnk.ObjectSection == nil {
+		return
+	}
+	type replacement struct {
+		length uint32
+		// The duration of the replacement in milliseconds, or a negative number
+		// if it is unknown.
+		duration float64
+	}
+	replaced := make(map[uint32]replacement)
+	for _, r := range rs {
+		if r.WemIndex < 0 || r.WemIndex >= len(bnk.Wems()) {
+			continue
+		}
+		rep := replacement{uint32(r.Length), -1}
+		d, err := wwise.Duration(io.NewSectionReader(r.Wem, 0, r.Length))
+		if err == nil {
+			rep.duration = float64(d) / float64(time.Millisecond)
+		}
+		replaced[bnk.Wems()[r.WemIndex].Descriptor.WemId] = rep
+	}
+
+	for _, obj := range bnk.ObjectSection.objects {
+		switch obj := obj.(type) {
+		case *SfxVoiceSoundObject:
+			rep, ok := replaced[obj.WemDescriptor.WemId]
+			if ok && obj.Embedded() {
+				obj.WemDescriptor.WemLength = rep.length
+			}
+		case *MusicTrackObject:
+			for i := range obj.Sources {
+				src := &obj.Sources[i]
+				rep, ok := replaced[src.SourceId]
+				if ok && StreamType(src.StreamType) == StreamEmbedded {
+					src.InMemorySize = rep.length
+				}
+			}
+			for i := range obj.Playlist {
+				clip := &obj.Playlist[i]
+				rep, ok := replaced[clip.SourceId]
+				if ok && rep.duration >= 0 {
+					clip.SourceDuration = rep.duration
+				}
+			}
+		}
+	}
+}
+
+// SetPreservePadding sets whether the padding that follows a wem replaced in
+// this SoundBank keeps its original bytes, rather than being filled with
+// zeroes.
+func (bnk *File) SetPreservePadding(preserve bool) {
+	bnk.preservePadding = preserve
+}
+
+// Alignment returns the number of bytes that the offsets of wems added to or
+// grown in this SoundBank are aligned to. By default, this is the alignment
+// used by Wwise.
+func (bnk *File) Alignment() int64 {
+	if bnk.alignment == 0 {
+		return wemAlignmentBytes
+	}
+	return bnk.alignment
+}
 
-	if surplus != 0 {
-		// Update the length of the DATA header to account for the change in size.
-		bnk.DataSection.Header.Length += uint32(surplus)
+// SetAlignment sets the number of bytes that the offsets of wems added to or
+// grown in this SoundBank are aligned to. When a wem is replaced, the wem that
+// follows it is aligned, and the wems after that keep their relative offsets.
+// n must be positive.
+func (bnk *File) SetAlignment(n int64) error {
+	if n < 1 {
+		msg := fmt.Sprintf("%d is not a valid alignment; it must be positive", n)
+		return errors.New(msg)
 	}
+	bnk.alignment = n
+	return nil
 }
 
 func (bnk *File) DataStart() uint32 {
+	if bnk.DataSection == nil {
+		return 0
+	}
 	return bnk.DataSection.DataStart
 }
 
+// IsInit returns true if this SoundBank is an initialization SoundBank, such
+// as Init.bnk, which holds the state groups, switch groups and game parameters
+// of a project in an STMG section.
+func (bnk *File) IsInit() bool {
+	for _, s := range bnk.sections {
+		if s.Identifier() == string(stmgHeaderId[:]) {
+			return true
+		}
+	}
+	return false
+}
+
 // LoopOf returns the loop value of the wem stored in this SoundBank at index i.
 // Returns a default LoopValue{false, 0} if the index is invalid.
 func (bnk *File) LoopOf(i int) LoopValue {
@@ -229,7 +851,6 @@ func (bnk *File) ReplaceLoopOf(i int, loop LoopValue) {
 					append(ss.ParameterValues[:i], ss.ParameterValues[i+1:]...)
 
 				lengthDecrease := uint32(PARAMETER_TYPE_BYTES + PARAMETER_VALUE_BYTES)
-				bnk.ObjectSection.Header.Length -= lengthDecrease
 				object.Descriptor.Length -= lengthDecrease
 
 				delete(bnk.ObjectSection.loopOf, desc.WemId)
@@ -257,7 +878,6 @@ func (bnk *File) ReplaceLoopOf(i int, loop LoopValue) {
 			bnk.ObjectSection.loopOf[desc.WemId] = loop.Value
 
 			lengthIncrease := uint32(PARAMETER_TYPE_BYTES + PARAMETER_VALUE_BYTES)
-			bnk.ObjectSection.Header.Length += lengthIncrease
 			object.Descriptor.Length += lengthIncrease
 		}
 	}
@@ -270,14 +890,24 @@ func (bnk *File) String() string {
 		b.WriteString(sec.String())
 	}
 
-	tableParams := []string{"%-7", "%-15", "%-15", "%-15", "%-8", "%-12", "\n"}
+	if bnk.DataSection == nil {
+		return b.String()
+	}
+
+	tableParams := []string{"%-7", "%-15", "%-15", "%-15", "%-8", "%-12",
+		"%-17", "\n"}
 	titleFmt := strings.Join(tableParams, "s|")
-	wemFmt := strings.Join(tableParams, "d|")
+	wemFmt := strings.Join(tableParams[:6], "d|") + "d|" +
+		strings.Join(tableParams[6:], "s|")
 	title := fmt.Sprintf(titleFmt,
-		"Index", "Id", "Offset", "Length", "Padding", "Loop (0=Inf)")
+		"Index", "Id", "Offset", "Length", "Padding", "Loop (0=Inf)", "Storage")
 	fmt.Fprint(b, title)
 	fmt.Fprintln(b, strings.Repeat("-", len(title)-1))
 
+	var streamTypes map[uint32]StreamType
+	if bnk.ObjectSection != nil {
+		streamTypes = bnk.ObjectSection.StreamTypes()
+	}
 	for i, wem := range bnk.DataSection.Wems {
 		desc := wem.Descriptor
 		l := bnk.LoopOf(i)
@@ -285,9 +915,14 @@ func (bnk *File) String() string {
 		if l.Loops {
 			loop = int(l.Value)
 		}
+		// Wems that no object plays are left blank.
+		storage := ""
+		if s, ok := streamTypes[desc.WemId]; ok {
+			storage = s.String()
+		}
 
 		fmt.Fprintf(b, wemFmt, i+1, desc.WemId, desc.Offset, desc.Length,
-			wem.Padding.Size(), loop)
+			wem.Padding.Size(), loop, storage)
 	}
 
 	return b.String()
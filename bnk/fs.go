@@ -0,0 +1,157 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+// The extension given to every wem in the file system view of a SoundBank.
+const fsWemExtension = ".wem"
+
+// Open opens the named file of the file system view of this SoundBank, allowing
+// a File to be used as an fs.FS. The view is a single directory containing
+// every wem of this SoundBank, named by its ID with a .wem extension.
+func (bnk *File) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return &wemDir{entries: bnk.dirEntries()}, nil
+	}
+	wem, ok := bnk.wemOfName(name)
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	ra, ok := wem.Reader.(io.ReaderAt)
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name,
+			Err: errors.New("The wem does not support random access")}
+	}
+	r := io.NewSectionReader(ra, 0, int64(wem.Descriptor.Length))
+	return &wemFile{r, wemInfo{name, int64(wem.Descriptor.Length)}}, nil
+}
+
+// ReadDir reads the named directory of the file system view of this SoundBank.
+// The only directory is the root directory, ".".
+func (bnk *File) ReadDir(name string) ([]fs.DirEntry, error) {
+	if name != "." {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	return bnk.dirEntries(), nil
+}
+
+// wemOfName returns the wem whose file system name is name.
+func (bnk *File) wemOfName(name string) (*wwise.Wem, bool) {
+	if !strings.HasSuffix(name, fsWemExtension) {
+		return nil, false
+	}
+	base := strings.TrimSuffix(name, fsWemExtension)
+	id, err := strconv.ParseUint(base, 10, 32)
+	// Only accept the canonical form of the ID, so that every wem has exactly
+	// one name.
+	if err != nil || strconv.FormatUint(id, 10) != base {
+		return nil, false
+	}
+	return bnk.WemByID(uint32(id))
+}
+
+// dirEntries returns the entries of the root directory, sorted by name.
+func (bnk *File) dirEntries() []fs.DirEntry {
+	var entries []fs.DirEntry
+	for _, wem := range bnk.Wems() {
+		name := strconv.FormatUint(uint64(wem.Descriptor.WemId), 10) +
+			fsWemExtension
+		entries = append(entries,
+			fs.FileInfoToDirEntry(wemInfo{name, int64(wem.Descriptor.Length)}))
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries
+}
+
+// A wemFile is an open wem of the file system view of a SoundBank.
+type wemFile struct {
+	*io.SectionReader
+	info wemInfo
+}
+
+func (f *wemFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *wemFile) Close() error {
+	return nil
+}
+
+// A wemInfo describes a wem of the file system view of a SoundBank.
+type wemInfo struct {
+	name string
+	size int64
+}
+
+func (fi wemInfo) Name() string       { return fi.name }
+func (fi wemInfo) Size() int64        { return fi.size }
+func (fi wemInfo) Mode() fs.FileMode  { return 0444 }
+func (fi wemInfo) ModTime() time.Time { return time.Time{} }
+func (fi wemInfo) IsDir() bool        { return false }
+func (fi wemInfo) Sys() interface{}   { return nil }
+
+// A wemDir is the open root directory of the file system view of a SoundBank.
+type wemDir struct {
+	entries []fs.DirEntry
+	// The number of entries already returned by ReadDir.
+	offset int
+}
+
+func (d *wemDir) Stat() (fs.FileInfo, error) {
+	return dirInfo{}, nil
+}
+
+func (d *wemDir) Read(p []byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: ".",
+		Err: errors.New("The path is a directory")}
+}
+
+func (d *wemDir) Close() error {
+	return nil
+}
+
+// ReadDir returns the next n entries of this directory, following the
+// semantics of fs.ReadDirFile.
+func (d *wemDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(remaining) {
+		n = len(remaining)
+	}
+	d.offset += n
+	return remaining[:n], nil
+}
+
+// A dirInfo describes the root directory of the file system view of a
+// SoundBank.
+type dirInfo struct{}
+
+func (dirInfo) Name() string       { return "." }
+func (dirInfo) Size() int64        { return 0 }
+func (dirInfo) Mode() fs.FileMode  { return fs.ModeDir | 0555 }
+func (dirInfo) ModTime() time.Time { return time.Time{} }
+func (dirInfo) IsDir() bool        { return true }
+func (dirInfo) Sys() interface{}   { return nil }
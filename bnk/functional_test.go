@@ -3,10 +3,21 @@ package bnk
 
 // Large system tests for the bnk package.
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"math"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
 )
 
 import (
@@ -126,6 +137,2831 @@ func TestReplaceLoopOfCases(t *testing.T) {
 	}
 }
 
+func TestStringIdSectionRoundTrip(t *testing.T) {
+	org, err := ioutil.ReadFile(filepath.Join(testDir, simpleSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	names := []string{"Init", "Music"}
+	stid := new(bytes.Buffer)
+	binary.Write(stid, binary.LittleEndian, uint32(1))
+	binary.Write(stid, binary.LittleEndian, uint32(len(names)))
+	for _, name := range names {
+		binary.Write(stid, binary.LittleEndian, wwise.HashName(name))
+		stid.WriteByte(byte(len(name)))
+		stid.WriteString(name)
+	}
+	input := bytes.NewBuffer(org)
+	input.Write(stidHeaderId[:])
+	binary.Write(input, binary.LittleEndian, uint32(stid.Len()))
+	input.Write(stid.Bytes())
+
+	bnk, err := NewFile(bytes.NewReader(input.Bytes()))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if bnk.StringIdSection == nil {
+		t.Error("The STID section was not parsed")
+		t.FailNow()
+	}
+	for _, name := range names {
+		id := wwise.HashName(name)
+		if actual := bnk.StringIdSection.BankNames[id]; actual != name {
+			t.Errorf("SoundBank %d was expected to be named %s but was named %s",
+				id, name, actual)
+		}
+	}
+
+	output := new(bytes.Buffer)
+	bnk.WriteTo(output)
+	if !bytes.Equal(input.Bytes(), output.Bytes()) {
+		t.Error("The SoundBank with an STID section was not written unchanged")
+	}
+}
+
+func TestStringIdSectionKeepsRepeatedIds(t *testing.T) {
+	id := wwise.HashName("Music")
+	names := []string{"Music", "music_old", "Music"}
+	data := new(bytes.Buffer)
+	binary.Write(data, binary.LittleEndian, uint32(1))
+	binary.Write(data, binary.LittleEndian, uint32(len(names)))
+	for _, name := range names {
+		binary.Write(data, binary.LittleEndian, id)
+		data.WriteByte(byte(len(name)))
+		data.WriteString(name)
+	}
+	input := new(bytes.Buffer)
+	input.Write(stidHeaderId[:])
+	binary.Write(input, binary.LittleEndian, uint32(data.Len()))
+	input.Write(data.Bytes())
+
+	r := bytes.NewReader(input.Bytes())
+	hdr := new(SectionHeader)
+	err := binary.Read(r, binary.LittleEndian, hdr)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	stid, err := hdr.NewStringIdSection(r)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if len(stid.BankIds) != len(names) {
+		t.Errorf("Expected %d STID entries but got %d", len(names),
+			len(stid.BankIds))
+	}
+	if actual := stid.BankNames[id]; actual != "Music" {
+		t.Errorf("Expected SoundBank %d to be named Music but it was named %s",
+			id, actual)
+	}
+	if stid.Size() != int64(input.Len()) {
+		t.Errorf("Expected a size of %d but got %d", input.Len(), stid.Size())
+	}
+
+	output := new(bytes.Buffer)
+	_, err = stid.WriteTo(output)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if !bytes.Equal(input.Bytes(), output.Bytes()) {
+		t.Error("The STID section was not written back unchanged")
+	}
+}
+
+func TestWemsOfEvent(t *testing.T) {
+	bnk, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer bnk.Close()
+
+	if count := len(bnk.ObjectSection.Events()); count != 61 {
+		t.Errorf("Expected 61 events but found %d", count)
+	}
+	// This event plays a Random/Sequence container of two sounds.
+	expected := []uint32{53140439, 1073095987}
+	actual := bnk.ObjectSection.WemsOf(92222710)
+	if len(actual) != len(expected) {
+		t.Errorf("Expected wems %v but got %v", expected, actual)
+		t.FailNow()
+	}
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Errorf("Expected wems %v but got %v", expected, actual)
+		}
+	}
+}
+
+func TestMalformedFilesReturnErrors(t *testing.T) {
+	org, err := ioutil.ReadFile(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	didx := bytes.Index(org, didxHeaderId[:])
+	didxLength := int(binary.LittleEndian.Uint32(org[didx+4:]))
+	firstEntry := didx + SECTION_HEADER_BYTES
+
+	repeatedId := append([]byte(nil), org...)
+	copy(repeatedId[firstEntry+DIDX_ENTRY_BYTES:], org[firstEntry:firstEntry+4])
+
+	var missingIndex []byte
+	missingIndex = append(missingIndex, org[:didx]...)
+	missingIndex = append(missingIndex,
+		org[didx+SECTION_HEADER_BYTES+didxLength:]...)
+
+	oldVersion := append([]byte(nil), org...)
+	binary.LittleEndian.PutUint32(oldVersion[SECTION_HEADER_BYTES:], 1)
+
+	cases := []struct {
+		name     string
+		input    []byte
+		expected error
+	}{
+		{"repeated wem ID", repeatedId, ErrCorruptDIDX},
+		{"DATA section without a DIDX", missingIndex, nil},
+		{"version that is too old", oldVersion, ErrUnsupportedVersion},
+		{"missing BKHD section", org[didx:], ErrNotASoundBank},
+		{"no data", nil, ErrNotASoundBank},
+	}
+	for _, c := range cases {
+		_, err := NewFile(bytes.NewReader(c.input))
+		if err == nil {
+			t.Errorf("Expected an error for a SoundBank with a %s", c.name)
+			continue
+		}
+		if c.expected != nil && !errors.Is(err, c.expected) {
+			t.Errorf("Expected the error for a SoundBank with a %s to be %q but "+
+				"got %q", c.name, c.expected, err)
+		}
+	}
+
+	_, err = NewFile(bytes.NewReader(repeatedId))
+	var secErr *SectionError
+	if !errors.As(err, &secErr) || secErr.Section != "DIDX" ||
+		secErr.Offset != int64(didx) {
+		t.Errorf("Expected the error to describe the DIDX section at offset %d "+
+			"but got %q", didx, err)
+	}
+
+	// A failure to read the file does not mean that it is not a SoundBank.
+	readErr := errors.New("read failed")
+	_, err = NewFile(errReaderAt{readErr})
+	if !errors.Is(err, readErr) || errors.Is(err, ErrNotASoundBank) {
+		t.Errorf("Expected the read error to be returned but got %q", err)
+	}
+
+	hdr := &SectionHeader{bkhdHeaderId, 0}
+	if _, err := hdr.NewDataIndexSection(bytes.NewReader(nil)); err == nil {
+		t.Error("Expected an error when reading a BKHD header as a DIDX section")
+	}
+}
+
+// An errReaderAt is a ReaderAt whose reads all fail with err.
+type errReaderAt struct {
+	err error
+}
+
+func (r errReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	return 0, r.err
+}
+
+func TestCheckReplacementsRejectsLargeWems(t *testing.T) {
+	bnk, err := Open(filepath.Join(testDir, simpleSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer bnk.Close()
+
+	small := &wwise.ReplacementWem{util.NewConstantReader(100), 0, 100}
+	if err := bnk.CheckReplacements(small); err != nil {
+		t.Errorf("Expected a small replacement to be accepted but got %q", err)
+	}
+	large := &wwise.ReplacementWem{util.NewConstantReader(0), 0, math.MaxUint32 + 1}
+	if err := bnk.CheckReplacements(large); !errors.Is(err, ErrWemTooLarge) {
+		t.Errorf("Expected %q but got %q", ErrWemTooLarge, err)
+	}
+}
+
+func TestCancelledContextStopsReadAndWrite(t *testing.T) {
+	org, err := ioutil.ReadFile(filepath.Join(testDir, simpleSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = NewFileContext(ctx, bytes.NewReader(org))
+	if err != context.Canceled {
+		t.Errorf("Expected reading to be cancelled but got %v", err)
+	}
+
+	bnk, err := NewFile(bytes.NewReader(org))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	output := new(bytes.Buffer)
+	_, err = bnk.WriteToContext(ctx, output)
+	if err != context.Canceled {
+		t.Errorf("Expected writing to be cancelled but got %v", err)
+	}
+	if output.Len() != 0 {
+		t.Errorf("Expected nothing to be written but %d bytes were written",
+			output.Len())
+	}
+}
+
+func TestFileSystemView(t *testing.T) {
+	bnk, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer bnk.Close()
+
+	var names []string
+	for _, wem := range bnk.Wems() {
+		names = append(names, fmt.Sprintf("%d.wem", wem.Descriptor.WemId))
+	}
+	err = fstest.TestFS(bnk, names...)
+	if err != nil {
+		t.Error(err)
+	}
+
+	wem := bnk.Wems()[0]
+	data, err := fs.ReadFile(bnk, names[0])
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	expected := make([]byte, wem.Descriptor.Length)
+	wem.Reader.(io.ReaderAt).ReadAt(expected, 0)
+	if !bytes.Equal(data, expected) {
+		t.Errorf("The contents of %s did not match its wem", names[0])
+	}
+}
+
+func TestBytesRoundTrip(t *testing.T) {
+	org, err := ioutil.ReadFile(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	bnk, err := NewFileFromBytes(org)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	output, err := bnk.WriteToBytes()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if !bytes.Equal(org, output) {
+		t.Error("The SoundBank was not written unchanged")
+	}
+}
+
+func TestWemByID(t *testing.T) {
+	bnk, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer bnk.Close()
+
+	for i, expected := range bnk.Wems() {
+		id := expected.Descriptor.WemId
+		if wem, ok := bnk.WemByID(id); !ok || wem != expected {
+			t.Errorf("Expected wem %d to be found", id)
+		}
+		if actual, ok := bnk.IndexOfWem(id); !ok || actual != i {
+			t.Errorf("Expected wem %d to be at index %d but got %d", id, i, actual)
+		}
+	}
+	if _, ok := bnk.WemByID(0); ok {
+		t.Error("Expected no wem to have the ID 0")
+	}
+
+	// Adding a wem with the lowest ID moves every other wem up by one.
+	last := bnk.Wems()[len(bnk.Wems())-1].Descriptor.WemId
+	err = bnk.AddWem(WemSource{1, util.NewConstantReader(100), 100})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if i, ok := bnk.IndexOfWem(1); !ok || i != 0 {
+		t.Errorf("Expected the added wem to be at index 0 but got %d", i)
+	}
+	if i, ok := bnk.IndexOfWem(last); !ok || i != len(bnk.Wems())-1 {
+		t.Errorf("Expected wem %d to be at index %d but got %d", last,
+			len(bnk.Wems())-1, i)
+	}
+}
+
+func TestPartiallyReadWemIsWrittenUnchanged(t *testing.T) {
+	org, err := ioutil.ReadFile(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	bnk, err := NewFileFromBytes(org)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	// Leave a wem and an object reader part way through their data.
+	bnk.Wems()[0].Read(make([]byte, 10))
+	for _, obj := range bnk.ObjectSection.Objects() {
+		if unknown, ok := obj.(*UnknownObject); ok {
+			unknown.Reader.Read(make([]byte, 1))
+			break
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		output, err := bnk.WriteToBytes()
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		if !bytes.Equal(org, output) {
+			t.Errorf("Write %d of the SoundBank was not unchanged", i+1)
+		}
+	}
+}
+
+func TestCloneIsIndependent(t *testing.T) {
+	org, err := ioutil.ReadFile(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	bnk, err := NewFileFromBytes(org)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	clone, err := bnk.Clone()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	output, err := clone.WriteToBytes()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if !bytes.Equal(org, output) {
+		t.Error("The clone was not written unchanged")
+	}
+
+	clone.ReplaceWems(&wwise.ReplacementWem{util.NewConstantReader(100), 0, 100})
+	clone.ReplaceLoopOf(1, LoopValue{true, 5})
+	output, err = bnk.WriteToBytes()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if !bytes.Equal(org, output) {
+		t.Error("Changing the clone changed the original SoundBank")
+	}
+	if clone.LoopOf(1) == bnk.LoopOf(1) {
+		t.Error("The loop of the clone was not changed")
+	}
+}
+
+func TestSizeMatchesWrittenLength(t *testing.T) {
+	bnk, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer bnk.Close()
+
+	assertSize := func(when string) {
+		output, err := bnk.WriteToBytes()
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		if bnk.Size() != int64(len(output)) {
+			t.Errorf("%s, expected a size of %d but got %d", when, len(output),
+				bnk.Size())
+		}
+	}
+	assertSize("Before any changes")
+	bnk.ReplaceWems(&wwise.ReplacementWem{util.NewConstantReader(1001), 3, 1001})
+	assertSize("After replacing a wem")
+	bnk.ReplaceLoopOf(1, LoopValue{true, 5})
+	assertSize("After changing a loop")
+}
+
+func TestSectionsInFileOrder(t *testing.T) {
+	org, err := ioutil.ReadFile(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	bnk, err := NewFileFromBytes(org)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	offset := 0
+	for _, s := range bnk.Sections() {
+		id := string(org[offset : offset+4])
+		length := binary.LittleEndian.Uint32(org[offset+4:])
+		if s.Identifier() != id || s.Length() != length {
+			t.Errorf("Expected section %s of length %d at offset %d but got %s of "+
+				"length %d", id, length, offset, s.Identifier(), s.Length())
+		}
+		offset += SECTION_HEADER_BYTES + int(length)
+	}
+	if offset != len(org) {
+		t.Errorf("The sections covered %d of %d bytes", offset, len(org))
+	}
+}
+
+func TestCreateFromWems(t *testing.T) {
+	contents := map[uint32][]byte{
+		500: bytes.Repeat([]byte{'a'}, 1001),
+		20:  bytes.Repeat([]byte{'b'}, 37),
+	}
+	var wems []WemSource
+	for id, data := range contents {
+		wems = append(wems, WemSource{id, bytes.NewReader(data), int64(len(data))})
+	}
+	desc := BankDescriptor{132, wwise.HashName("Created")}
+	created, err := Create(desc, wems...)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	output, err := created.WriteToBytes()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	bnk, err := NewFileFromBytes(output)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if bnk.BankHeaderSection.Descriptor != desc {
+		t.Errorf("Expected a bank descriptor of %v but got %v", desc,
+			bnk.BankHeaderSection.Descriptor)
+	}
+	expectedIds := []uint32{20, 500}
+	for i, wem := range bnk.Wems() {
+		id := wem.Descriptor.WemId
+		if id != expectedIds[i] {
+			t.Errorf("Expected wem %d to have ID %d but got %d", i, expectedIds[i], id)
+		}
+		if wem.Descriptor.Offset%wemAlignmentBytes != 0 {
+			t.Errorf("Wem %d is not aligned at offset %d", id, wem.Descriptor.Offset)
+		}
+		data := new(bytes.Buffer)
+		wem.WriteTo(data)
+		if !bytes.Equal(data.Bytes(), contents[id]) {
+			t.Errorf("The contents of wem %d were not stored unchanged", id)
+		}
+	}
+
+	_, err = Create(desc, wems[0], wems[0])
+	if err == nil {
+		t.Error("Expected an error when creating a SoundBank with a repeated wem")
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	data := bytes.Repeat([]byte{'a'}, 100)
+	bnk, err := NewBuilder().SetVersion(120).SetBankID(7).
+		AddWem(3, bytes.NewReader(data), int64(len(data))).Build()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	expected := BankDescriptor{120, 7}
+	if bnk.BankHeaderSection.Descriptor != expected {
+		t.Errorf("Expected a bank descriptor of %v but got %v", expected,
+			bnk.BankHeaderSection.Descriptor)
+	}
+	if wem, ok := bnk.WemByID(3); !ok || wem.Descriptor.Length != 100 {
+		t.Error("Expected the SoundBank to hold wem 3 of 100 bytes")
+	}
+
+	if _, err := NewBuilder().Build(); err == nil {
+		t.Error("Expected an error when building a SoundBank without wems")
+	}
+}
+
+func TestMergeReportsCollisions(t *testing.T) {
+	simple, err := Open(filepath.Join(testDir, simpleSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer simple.Close()
+	complex, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer complex.Close()
+
+	merged, collisions, err := Merge([]*File{complex, simple, complex},
+		MergeOptions{Objects: false})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	expectedWems := len(complex.Wems()) + len(simple.Wems())
+	if len(merged.Wems()) != expectedWems {
+		t.Errorf("Expected %d wems but got %d", expectedWems, len(merged.Wems()))
+	}
+	if len(collisions) != len(complex.Wems()) {
+		t.Errorf("Expected %d collisions but got %d", len(complex.Wems()),
+			len(collisions))
+	}
+	for _, c := range collisions {
+		if c.Kind != WemCollision || len(c.Banks) != 2 || c.Banks[0] != 0 ||
+			c.Banks[1] != 2 {
+			t.Errorf("Unexpected collision: %s", c)
+		}
+	}
+
+	output, err := merged.WriteToBytes()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	reread, err := NewFileFromBytes(output)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	for _, wem := range simple.Wems() {
+		if _, ok := reread.WemByID(wem.Descriptor.WemId); !ok {
+			t.Errorf("Expected wem %d to be merged", wem.Descriptor.WemId)
+		}
+	}
+
+	merged, collisions, err = Merge([]*File{complex, complex},
+		MergeOptions{Objects: true})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	objects := len(complex.ObjectSection.Objects())
+	if len(merged.ObjectSection.Objects()) != objects {
+		t.Errorf("Expected %d objects but got %d", objects,
+			len(merged.ObjectSection.Objects()))
+	}
+	if expected := len(complex.Wems()) + objects; len(collisions) != expected {
+		t.Errorf("Expected %d collisions but got %d", expected, len(collisions))
+	}
+	if _, _, err := Merge([]*File{complex, simple},
+		MergeOptions{Objects: true}); err == nil {
+		t.Error("Expected an error when merging objects of different versions")
+	}
+}
+
+func TestSplitBySize(t *testing.T) {
+	bnk, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer bnk.Close()
+
+	const budget = 500000
+	parts, err := bnk.SplitBySize(budget)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if len(parts) < 2 {
+		t.Errorf("Expected the SoundBank to be split but got %d part(s)",
+			len(parts))
+	}
+	count := 0
+	for i, part := range parts {
+		output, err := part.WriteToBytes()
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		reread, err := NewFileFromBytes(output)
+		if err != nil {
+			t.Errorf("Part %d could not be read back: %s", i, err)
+			continue
+		}
+		if len(reread.Wems()) > 1 && part.Size() > budget {
+			t.Errorf("Part %d is %d bytes long, over the budget of %d", i,
+				part.Size(), budget)
+		}
+		count += len(reread.Wems())
+	}
+	if count != len(bnk.Wems()) {
+		t.Errorf("Expected the parts to hold %d wems but they held %d",
+			len(bnk.Wems()), count)
+	}
+}
+
+func TestSplitBySizeWithPadding(t *testing.T) {
+	// The wems are stored out of order of their ID, and a SoundBank lays them out
+	// in order, which needs more padding than the stored order does.
+	data := bytes.Repeat([]byte{1}, 100)
+	bnk, err := NewBuilder().SetAlignment(64).
+		AddWem(1, bytes.NewReader(data), 100).
+		AddWem(2, bytes.NewReader(data), 1).
+		AddWem(3, bytes.NewReader(data), 100).
+		AddWem(4, bytes.NewReader(data), 1).Build()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	wems := bnk.DataSection.Wems
+	bnk.DataSection.Wems = []*wwise.Wem{wems[0], wems[2], wems[1], wems[3]}
+
+	fixed := bnk.BankHeaderSection.Size() + 2*SECTION_HEADER_BYTES
+	// Laid out by ID, the first three wems take 292 bytes rather than 257.
+	for _, budget := range []int64{fixed + 2*DIDX_ENTRY_BYTES + 228,
+		fixed + 3*DIDX_ENTRY_BYTES + 260, fixed + 4*DIDX_ENTRY_BYTES + 321} {
+		parts, err := bnk.SplitBySize(budget)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		for i, part := range parts {
+			if len(part.Wems()) > 1 && part.Size() > budget {
+				t.Errorf("Part %d is %d bytes long, over the budget of %d", i,
+					part.Size(), budget)
+			}
+		}
+	}
+}
+
+func TestSplitByIds(t *testing.T) {
+	bnk, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer bnk.Close()
+
+	ids := bnk.IndexSection.WemIds
+	groups := [][]uint32{{ids[0], ids[2]}, {ids[1]}}
+	parts, err := bnk.SplitByIds(groups)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	for i, group := range groups {
+		if len(parts[i].Wems()) != len(group) {
+			t.Errorf("Expected part %d to hold %d wems but it held %d", i,
+				len(group), len(parts[i].Wems()))
+		}
+		for _, id := range group {
+			if _, ok := parts[i].WemByID(id); !ok {
+				t.Errorf("Expected part %d to hold wem %d", i, id)
+			}
+		}
+	}
+
+	if _, err := bnk.SplitByIds([][]uint32{{0}}); err == nil {
+		t.Error("Expected an error when splitting by a missing wem ID")
+	}
+}
+
+func TestAddWem(t *testing.T) {
+	bnk, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer bnk.Close()
+
+	contents := make(map[uint32][]byte)
+	for _, wem := range bnk.Wems() {
+		data := new(bytes.Buffer)
+		wem.WriteTo(data)
+		contents[wem.Descriptor.WemId] = data.Bytes()
+	}
+	// An ID between those of existing wems.
+	const id = 459158967
+	contents[id] = bytes.Repeat([]byte{'a'}, 1001)
+	err = bnk.AddWem(WemSource{id, bytes.NewReader(contents[id]), 1001})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	output, err := bnk.WriteToBytes()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	reread, err := NewFileFromBytes(output)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if len(reread.Wems()) != len(contents) {
+		t.Errorf("Expected %d wems but got %d", len(contents), len(reread.Wems()))
+	}
+	for _, wem := range reread.Wems() {
+		data := new(bytes.Buffer)
+		wem.WriteTo(data)
+		if !bytes.Equal(data.Bytes(), contents[wem.Descriptor.WemId]) {
+			t.Errorf("The contents of wem %d changed", wem.Descriptor.WemId)
+		}
+	}
+	if reread.DataStart() != bnk.DataStart() {
+		t.Errorf("Expected the DATA section to start at %d but it started at %d",
+			bnk.DataStart(), reread.DataStart())
+	}
+
+	if err := bnk.AddWem(WemSource{id, bytes.NewReader(nil), 0}); err == nil {
+		t.Error("Expected an error when adding a wem that is already held")
+	}
+}
+
+func TestInsertSection(t *testing.T) {
+	bnk, err := Open(filepath.Join(testDir, simpleSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer bnk.Close()
+
+	custom, err := NewRawSection("CUST", []byte("custom data"))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	err = bnk.InsertSection(1, custom)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	names := NewBankNameSection("Init", "Music")
+	err = bnk.InsertSection(len(bnk.Sections()), names)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if err := bnk.InsertSection(0, NewBankNameSection()); err == nil {
+		t.Error("Expected an error when inserting a second STID section")
+	}
+	if _, err := NewRawSection("TOOLONG", nil); err == nil {
+		t.Error("Expected an error for an identifier that is not four characters")
+	}
+
+	output, err := bnk.WriteToBytes()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	reread, err := NewFileFromBytes(output)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	sections := reread.Sections()
+	if sections[1].Identifier() != "CUST" ||
+		sections[1].Length() != uint32(len("custom data")) {
+		t.Errorf("Expected the custom section at index 1 but got %s",
+			sections[1].Identifier())
+	}
+	if reread.StringIdSection == nil ||
+		reread.StringIdSection.BankNames[wwise.HashName("Music")] != "Music" {
+		t.Error("Expected the STID section to name the Music SoundBank")
+	}
+	if reread.DataStart() != bnk.DataStart() {
+		t.Errorf("Expected the DATA section to start at %d but it started at %d",
+			bnk.DataStart(), reread.DataStart())
+	}
+}
+
+func TestRemoveSections(t *testing.T) {
+	bnk, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer bnk.Close()
+
+	n, err := bnk.RemoveSections("HIRC")
+	if err != nil || n != 1 {
+		t.Errorf("Expected one HIRC section to be removed but got %d, %v", n, err)
+	}
+	if bnk.ObjectSection != nil {
+		t.Error("Expected the ObjectSection to be cleared")
+	}
+	if _, err := bnk.RemoveSections("DATA"); err == nil {
+		t.Error("Expected an error when removing the DATA section")
+	}
+
+	output, err := bnk.WriteToBytes()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	reread, err := NewFileFromBytes(output)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if reread.ObjectSection != nil {
+		t.Error("Expected the written SoundBank to have no HIRC section")
+	}
+	if len(reread.Wems()) != len(bnk.Wems()) {
+		t.Errorf("Expected %d wems but got %d", len(bnk.Wems()),
+			len(reread.Wems()))
+	}
+}
+
+func TestReplaceSectionData(t *testing.T) {
+	bnk, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer bnk.Close()
+
+	// Replace the HIRC section with a copy of itself that has no objects.
+	empty := make([]byte, 4)
+	err = bnk.ReplaceSectionData("HIRC", empty)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if len(bnk.ObjectSection.objects) != 0 {
+		t.Errorf("Expected no objects but got %d", len(bnk.ObjectSection.objects))
+	}
+	if err := bnk.ReplaceSectionData("DATA", nil); err == nil {
+		t.Error("Expected an error when replacing the DATA section")
+	}
+	if err := bnk.ReplaceSectionData("STID", nil); err == nil {
+		t.Error("Expected an error when replacing a missing section")
+	}
+
+	reread := rereadFile(t, bnk)
+	if reread.ObjectSection == nil ||
+		reread.ObjectSection.Header.Length != uint32(len(empty)) {
+		t.Error("Expected the written HIRC section to hold only its object count")
+	}
+	if len(reread.Wems()) != len(bnk.Wems()) {
+		t.Errorf("Expected %d wems but got %d", len(bnk.Wems()),
+			len(reread.Wems()))
+	}
+}
+
+func TestWriteRecomputesSectionLengths(t *testing.T) {
+	path := filepath.Join(testDir, complexSoundBank)
+	org, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	bnk, err := NewFileFromBytes(org)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	// Stale lengths must not make it into the written SoundBank.
+	bnk.BankHeaderSection.Header.Length = 0
+	bnk.IndexSection.Header.Length = 0
+	bnk.DataSection.Header.Length = 1
+	bnk.ObjectSection.Header.Length = 2
+	output, err := bnk.WriteToBytes()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if !bytes.Equal(output, org) {
+		t.Error("Expected the written SoundBank to equal the original")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	org, err := ioutil.ReadFile(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if problems := verifyBytes(org); problems != nil {
+		t.Errorf("Expected no problems but got %v", problems)
+	}
+
+	bnk, err := NewFileFromBytes(org)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	// Grow the first wem so that it overlaps with the wem that follows it.
+	first := bnk.IndexSection.WemIds[0]
+	// The length of a wem follows its ID and offset in the DIDX entry.
+	entry := int(bnk.BankHeaderSection.Size()) + SECTION_HEADER_BYTES + 8
+	corrupt := append([]byte(nil), org...)
+	binary.LittleEndian.PutUint32(corrupt[entry:],
+		bnk.IndexSection.DescriptorMap[first].Length+wemAlignmentBytes)
+	problems := Verify(bytes.NewReader(corrupt), int64(len(corrupt)), 0)
+	if len(problems) != 1 || !errors.Is(problems[0], ErrCorruptDIDX) {
+		t.Errorf("Expected a single corrupt DIDX problem but got %v", problems)
+	}
+	var serr *SectionError
+	if len(problems) > 0 && (!errors.As(problems[0], &serr) ||
+		serr.Section != "DATA") {
+		t.Errorf("Expected the problem to be in the DATA section but got %v",
+			problems[0])
+	}
+
+	// Cut off the end of the HIRC section.
+	truncated := org[:len(org)-10]
+	problems = Verify(bytes.NewReader(truncated), int64(len(truncated)), 0)
+	if len(problems) == 0 {
+		t.Error("Expected problems with a truncated SoundBank")
+	}
+}
+
+func TestRepairRebuildsIndex(t *testing.T) {
+	org, err := ioutil.ReadFile(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	bnk, err := NewFileFromBytes(org)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	// Corrupt the length of the first wem, and lose the ID of the second.
+	first := int(bnk.BankHeaderSection.Size()) + SECTION_HEADER_BYTES
+	second := first + DIDX_ENTRY_BYTES
+	corrupt := append([]byte(nil), org...)
+	binary.LittleEndian.PutUint32(corrupt[first+8:], math.MaxUint32)
+	binary.LittleEndian.PutUint32(corrupt[second+4:], 1)
+	if _, err := NewFileFromBytes(corrupt); err == nil {
+		t.Error("Expected the corrupt SoundBank to be unreadable")
+	}
+
+	repaired, wems, err := Repair(bytes.NewReader(corrupt), int64(len(corrupt)))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if len(wems) != len(bnk.Wems()) {
+		t.Errorf("Expected %d recovered wems but got %d", len(bnk.Wems()),
+			len(wems))
+		t.FailNow()
+	}
+	for i, wem := range wems {
+		desc := bnk.Wems()[i].Descriptor
+		if wem.Offset != int64(desc.Offset) || wem.Length != int64(desc.Length) {
+			t.Errorf("Expected wem %d at offset %d with length %d but got %d, %d",
+				i, desc.Offset, desc.Length, wem.Offset, wem.Length)
+		}
+		if wem.IdRecovered != (i != 1) {
+			t.Errorf("Expected wem %d to have IdRecovered %t", i, i != 1)
+		}
+	}
+	if repaired.ObjectSection == nil {
+		t.Error("Expected the repaired SoundBank to keep its HIRC section")
+	}
+
+	output, err := repaired.WriteToBytes()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if problems := verifyBytes(output); problems != nil {
+		t.Errorf("Expected the repaired SoundBank to be consistent but got %v",
+			problems)
+	}
+	wem, ok := repaired.WemByID(bnk.Wems()[0].Descriptor.WemId)
+	if !ok {
+		t.Error("Expected the repaired SoundBank to hold the first wem")
+		t.FailNow()
+	}
+	got := new(bytes.Buffer)
+	want := new(bytes.Buffer)
+	wem.WriteTo(got)
+	bnk.Wems()[0].WriteTo(want)
+	if !bytes.Equal(got.Bytes(), want.Bytes()) {
+		t.Error("Expected the repaired wem to equal the original")
+	}
+}
+
+func TestRepairCorruptIndexLength(t *testing.T) {
+	org, err := ioutil.ReadFile(filepath.Join(testDir, simpleSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	bnk, err := NewFileFromBytes(org)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	didx := int(bnk.BankHeaderSection.Size())
+	didxLength := bnk.IndexSection.Header.Length
+
+	// A length past the end of the file, and one that ends inside the DATA
+	// section.
+	for _, length := range []uint32{0xFFFFFF00, didxLength + 4} {
+		corrupt := append([]byte(nil), org...)
+		binary.LittleEndian.PutUint32(corrupt[didx+4:], length)
+
+		repaired, wems, err := Repair(bytes.NewReader(corrupt),
+			int64(len(corrupt)))
+		if err != nil {
+			t.Errorf("Could not repair a DIDX length of %d: %s", length, err)
+			continue
+		}
+		if len(wems) != len(bnk.Wems()) {
+			t.Errorf("Expected %d recovered wems but got %d", len(bnk.Wems()),
+				len(wems))
+			continue
+		}
+		for i, wem := range wems {
+			desc := bnk.Wems()[i].Descriptor
+			if wem.Id != desc.WemId || !wem.IdRecovered {
+				t.Errorf("Expected wem %d to keep ID %d but got %v", i, desc.WemId,
+					wem)
+			}
+		}
+		if (repaired.ObjectSection == nil) != (bnk.ObjectSection == nil) {
+			t.Error("Expected the repaired SoundBank to keep its HIRC section")
+		}
+		output, err := repaired.WriteToBytes()
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if !bytes.Equal(output, org) {
+			t.Errorf("Expected repairing a DIDX length of %d to restore the "+
+				"original SoundBank", length)
+		}
+	}
+}
+
+func TestLenientReadToleratesCorruptIndex(t *testing.T) {
+	org, err := ioutil.ReadFile(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	bnk, err := NewFileFromBytes(org)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	// Grow the first wem so that it overlaps with the wem that follows it.
+	first := bnk.IndexSection.WemIds[0]
+	entry := int(bnk.BankHeaderSection.Size()) + SECTION_HEADER_BYTES + 8
+	corrupt := append([]byte(nil), org...)
+	binary.LittleEndian.PutUint32(corrupt[entry:],
+		bnk.IndexSection.DescriptorMap[first].Length+wemAlignmentBytes)
+
+	_, err = NewFileFromBytes(corrupt)
+	if !errors.Is(err, ErrCorruptDIDX) {
+		t.Errorf("Expected a corrupt DIDX error but got %v", err)
+	}
+	lenient, err := NewFileWithOptions(context.Background(),
+		bytes.NewReader(corrupt), ReadOptions{Lenient: true})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if len(lenient.Warnings) != 1 ||
+		!errors.Is(lenient.Warnings[0], ErrCorruptDIDX) {
+		t.Errorf("Expected a single corrupt DIDX warning but got %v",
+			lenient.Warnings)
+	}
+	if len(lenient.Wems()) != len(bnk.Wems()) {
+		t.Errorf("Expected %d wems but got %d", len(bnk.Wems()),
+			len(lenient.Wems()))
+	}
+
+	output, err := lenient.WriteToBytes()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if problems := verifyBytes(output); problems != nil {
+		t.Errorf("Expected the written SoundBank to be consistent but got %v",
+			problems)
+	}
+}
+
+func TestDuplicateWemIds(t *testing.T) {
+	org, err := ioutil.ReadFile(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	bnk, err := NewFileFromBytes(org)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	// Give the second wem the ID of the first.
+	first := bnk.IndexSection.WemIds[0]
+	second := int(bnk.BankHeaderSection.Size()) + SECTION_HEADER_BYTES +
+		DIDX_ENTRY_BYTES
+	duplicated := append([]byte(nil), org...)
+	binary.LittleEndian.PutUint32(duplicated[second:], first)
+	read := func(policy DuplicatePolicy) (*File, error) {
+		return NewFileWithOptions(context.Background(),
+			bytes.NewReader(duplicated), ReadOptions{Duplicates: policy})
+	}
+
+	if _, err := read(DuplicateError); !errors.Is(err, ErrCorruptDIDX) {
+		t.Errorf("Expected a corrupt DIDX error but got %v", err)
+	}
+
+	kept, err := read(DuplicateKeepFirst)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if len(kept.Wems()) != len(bnk.Wems())-1 || len(kept.Warnings) != 1 {
+		t.Errorf("Expected %d wems and a warning but got %d wems and %v",
+			len(bnk.Wems())-1, len(kept.Wems()), kept.Warnings)
+	}
+	if _, err := NewFile(bytes.NewReader(writeToBytes(t, kept))); err != nil {
+		t.Errorf("Expected the deduplicated SoundBank to be readable but got %v",
+			err)
+	}
+
+	all, err := read(DuplicateKeepAll)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if len(all.Wems()) != len(bnk.Wems()) || len(all.Warnings) != 1 {
+		t.Errorf("Expected %d wems and a warning but got %d wems and %v",
+			len(bnk.Wems()), len(all.Wems()), all.Warnings)
+	}
+	if i, ok := all.IndexOfWem(first); !ok || i != 0 {
+		t.Errorf("Expected wem %d to be found at index 0 but got %d", first, i)
+	}
+	if !bytes.Equal(writeToBytes(t, all), duplicated) {
+		t.Error("Expected the SoundBank to be written unchanged")
+	}
+}
+
+func writeToBytes(t *testing.T, bnk *File) []byte {
+	b, err := bnk.WriteToBytes()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	return b
+}
+
+func TestEmptyWems(t *testing.T) {
+	data := bytes.Repeat([]byte{1}, 100)
+	b, err := NewBuilder().SetBankID(1).AddWem(1, bytes.NewReader(data), 100).
+		AddWem(2, bytes.NewReader(nil), 0).
+		AddWem(3, bytes.NewReader(data), 100).Build()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	output := writeToBytes(t, b)
+	// The offset of an empty wem does not matter, and may point anywhere.
+	entry := int(b.BankHeaderSection.Size()) + SECTION_HEADER_BYTES +
+		DIDX_ENTRY_BYTES + 4
+	binary.LittleEndian.PutUint32(output[entry:], 0)
+
+	reread, err := NewFileFromBytes(output)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	wem, ok := reread.WemByID(2)
+	if !ok {
+		t.Error("Expected the SoundBank to hold the empty wem")
+		t.FailNow()
+	}
+	contents, err := ioutil.ReadAll(wem)
+	if err != nil || len(contents) != 0 {
+		t.Errorf("Expected the empty wem to have no contents but got %d bytes, %v",
+			len(contents), err)
+	}
+	if problems := verifyBytes(output); problems != nil {
+		t.Errorf("Expected no problems but got %v", problems)
+	}
+	if !bytes.Equal(writeToBytes(t, reread), output) {
+		t.Error("Expected the SoundBank to be written unchanged")
+	}
+
+	// Give the empty wem data, which must be stored where it is written.
+	reread.ReplaceWems(&wwise.ReplacementWem{util.NewConstantReader(20), 1, 20})
+	replaced := writeToBytes(t, reread)
+	if problems := verifyBytes(replaced); problems != nil {
+		t.Errorf("Expected no problems after replacing the empty wem but got %v",
+			problems)
+	}
+	if _, err := NewFileFromBytes(replaced); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAlignment(t *testing.T) {
+	bnk, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer bnk.Close()
+
+	if err := bnk.SetAlignment(0); err == nil {
+		t.Error("Expected an error for an alignment of 0")
+	}
+	const alignment = 2048
+	err = bnk.SetAlignment(alignment)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	bnk.ReplaceWems(&wwise.ReplacementWem{util.NewConstantReader(100), 0, 100})
+	reread := rereadFile(t, bnk)
+	if offset := reread.Wems()[1].Descriptor.Offset; offset%alignment != 0 {
+		t.Errorf("Expected the second wem to be aligned to %d bytes but its "+
+			"offset is %d", alignment, offset)
+	}
+
+	data := bytes.Repeat([]byte{1}, 100)
+	built, err := NewBuilder().SetAlignment(64).
+		AddWem(1, bytes.NewReader(data), 100).
+		AddWem(2, bytes.NewReader(data), 100).Build()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if offset := built.Wems()[1].Descriptor.Offset; offset != 128 {
+		t.Errorf("Expected the second wem to be at offset 128 but got %d", offset)
+	}
+
+	output := new(bytes.Buffer)
+	_, err = built.WriteTo(output)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	r := bytes.NewReader(output.Bytes())
+	if problems := Verify(r, r.Size(), 64); problems != nil {
+		t.Errorf("Expected no problems with an alignment of 64 but got %v",
+			problems)
+	}
+	// The padding needed for 64 bytes is more than is needed for 16.
+	if problems := Verify(r, r.Size(), 16); len(problems) != 1 {
+		t.Errorf("Expected a single problem with an alignment of 16 but got %v",
+			problems)
+	}
+}
+
+func TestPreservePadding(t *testing.T) {
+	org, err := ioutil.ReadFile(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	bnk, err := NewFileFromBytes(org)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	// Find a wem followed by padding, and fill the padding with non-zero bytes.
+	index := -1
+	for i, wem := range bnk.Wems() {
+		if wem.Padding.Size() > 0 {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		t.Error("Expected a wem to be followed by padding")
+		t.FailNow()
+	}
+	desc := bnk.Wems()[index].Descriptor
+	start := int64(bnk.DataStart()) + int64(desc.Offset)
+	end := start + int64(desc.Length)
+	padded := append([]byte(nil), org...)
+	for i := end; i < end+bnk.Wems()[index].Padding.Size(); i++ {
+		padded[i] = 0xAA
+	}
+	// The replacement has the same length, so only the wem itself changes.
+	expected := append([]byte(nil), padded...)
+	copy(expected[start:end], bytes.Repeat([]byte{'A'}, int(desc.Length)))
+
+	for _, preserve := range []bool{true, false} {
+		b, err := NewFileFromBytes(padded)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		b.SetPreservePadding(preserve)
+		b.ReplaceWems(&wwise.ReplacementWem{
+			util.NewConstantReader(int64(desc.Length)), index, int64(desc.Length)})
+		if bytes.Equal(writeToBytes(t, b), expected) != preserve {
+			t.Errorf("Expected the padding to be preserved to be %t", preserve)
+		}
+	}
+}
+
+func TestMemoryMappedFileIsEqual(t *testing.T) {
+	path := filepath.Join(testDir, complexSoundBank)
+	bnk, err := OpenWithOptions(path, ReadOptions{MemoryMap: true})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer bnk.Close()
+	org, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if !bytes.Equal(writeToBytes(t, bnk), org) {
+		t.Error("Expected a memory mapped SoundBank to be written unchanged")
+	}
+}
+
+func TestListWemsMatchesFile(t *testing.T) {
+	f, err := os.Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer f.Close()
+	descs, err := ListWems(f)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	bnk, err := NewFile(f)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if len(descs) != len(bnk.Wems()) {
+		t.Errorf("Expected %d wems to be listed, but got %d", len(bnk.Wems()),
+			len(descs))
+		t.FailNow()
+	}
+	for i, wem := range bnk.Wems() {
+		if *descs[i] != *wem.Descriptor {
+			t.Errorf("Wem %d was listed as %v, but is %v", i, *descs[i],
+				*wem.Descriptor)
+		}
+	}
+}
+
+func TestPatchWemsInPlace(t *testing.T) {
+	org, err := ioutil.ReadFile(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	f, err := ioutil.TempFile("", "patch-*.bnk")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	_, err = f.Write(org)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	bnk, err := NewFile(f)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	wems := bnk.Wems()
+	slot := int64(wems[0].Descriptor.Length) + wems[0].Padding.Size()
+	tooLarge := &wwise.ReplacementWem{util.NewConstantReader(slot + 1), 0,
+		slot + 1}
+	if err := bnk.PatchWems(f, tooLarge); !errors.Is(err, ErrDoesNotFit) {
+		t.Errorf("Expected a replacement that does not fit to be refused, "+
+			"but got: %v", err)
+	}
+
+	length := int64(wems[0].Descriptor.Length) / 2
+	err = bnk.PatchWems(f, &wwise.ReplacementWem{util.NewConstantReader(length),
+		0, length})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	patched, err := NewFile(f)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	for i, wem := range patched.Wems() {
+		desc, orgDesc := *wem.Descriptor, *wems[i].Descriptor
+		if i == 0 {
+			orgDesc.Length = uint32(length)
+		}
+		if desc != orgDesc {
+			t.Errorf("Expected wem %d to be described by %v, but got %v", i,
+				orgDesc, desc)
+		}
+	}
+	data, err := ioutil.ReadAll(patched.Wems()[0])
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if !bytes.Equal(data, bytes.Repeat([]byte{'A'}, int(length))) {
+		t.Error("Expected the patched wem to hold the replacement")
+	}
+}
+
+func TestPatchWemsUpdatesObjects(t *testing.T) {
+	org, err := ioutil.ReadFile(filepath.Join(testDir, simpleSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	f, err := ioutil.TempFile("", "patch-*.bnk")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	_, err = f.Write(org)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	bnk, err := NewFile(f)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	id := bnk.Wems()[0].Descriptor.WemId
+	if obj := bnk.ObjectSection.wemToObject[id]; obj == nil || !obj.Embedded() {
+		t.Error("Expected the wem to be played by an embedded sound")
+		t.FailNow()
+	}
+
+	length := int64(bnk.Wems()[0].Descriptor.Length) / 2
+	err = bnk.PatchWems(f, &wwise.ReplacementWem{util.NewConstantReader(length),
+		0, length})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	patched, err := NewFile(f)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	obj := patched.ObjectSection.wemToObject[id]
+	if obj.WemDescriptor.WemLength != uint32(length) {
+		t.Errorf("Expected the sound to describe a length of %d but got %d",
+			length, obj.WemDescriptor.WemLength)
+	}
+
+	// The HIRC section matches the one written by ReplaceWems.
+	replaced, err := NewFileFromBytes(org)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	replaced.ReplaceWems(&wwise.ReplacementWem{util.NewConstantReader(length), 0,
+		length})
+	want, got := new(bytes.Buffer), new(bytes.Buffer)
+	replaced.ObjectSection.WriteTo(want)
+	patched.ObjectSection.WriteTo(got)
+	if !bytes.Equal(got.Bytes(), want.Bytes()) {
+		t.Error("Expected the patched HIRC section to match a replaced one")
+	}
+}
+
+func TestWalkVisitsEverySectionAndWem(t *testing.T) {
+	f, err := os.Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer f.Close()
+	bnk, err := NewFile(f)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	sections := 0
+	var wems [][]byte
+	err = Walk(bufio.NewReader(io.NewSectionReader(f, 0, math.MaxInt64)),
+		func(hdr *SectionHeader, wem *wwise.Wem) error {
+			if wem == nil {
+				sections++
+				return nil
+			}
+			b, err := ioutil.ReadAll(wem)
+			wems = append(wems, b)
+			return err
+		})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if sections != len(bnk.Sections()) {
+		t.Errorf("Expected %d sections to be visited, but got %d",
+			len(bnk.Sections()), sections)
+	}
+	if len(wems) != len(bnk.Wems()) {
+		t.Errorf("Expected %d wems to be visited, but got %d", len(bnk.Wems()),
+			len(wems))
+		t.FailNow()
+	}
+	for i, wem := range bnk.Wems() {
+		b, err := ioutil.ReadAll(wem)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		if !bytes.Equal(b, wems[i]) {
+			t.Errorf("Wem %d was not visited with its contents", i)
+		}
+	}
+}
+
+func TestWriteToAtMatchesWriteTo(t *testing.T) {
+	bnk, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer bnk.Close()
+	bnk.ReplaceWems(&wwise.ReplacementWem{util.NewConstantReader(1000), 3, 1000})
+	expected := writeToBytes(t, bnk)
+
+	for _, threads := range []int{0, 1, 4} {
+		f, err := ioutil.TempFile("", "write-at-*.bnk")
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		defer os.Remove(f.Name())
+		defer f.Close()
+		n, err := bnk.WriteToAt(f, threads)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		if n != int64(len(expected)) {
+			t.Errorf("Expected %d bytes to be written with %d threads, but got %d",
+				len(expected), threads, n)
+		}
+		actual, err := ioutil.ReadFile(f.Name())
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		if !bytes.Equal(actual, expected) {
+			t.Errorf("Expected WriteToAt with %d threads to match WriteTo", threads)
+		}
+	}
+}
+
+func TestPatchRoundTrip(t *testing.T) {
+	original, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer original.Close()
+	modified, err := original.Clone()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	modified.ReplaceWems(&wwise.ReplacementWem{util.NewConstantReader(1000), 3,
+		1000})
+	err = modified.AddWem(WemSource{1, util.NewConstantReader(500), 500})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	expected := writeToBytes(t, modified)
+
+	p, err := CreatePatch(original, modified)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if len(p.Wems) != 2 {
+		t.Errorf("Expected the patch to hold 2 wems, but it holds %d",
+			len(p.Wems))
+	}
+	b := new(bytes.Buffer)
+	_, err = p.WriteTo(b)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	reread, err := ReadPatch(b)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	err = original.ApplyPatch(reread)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if !bytes.Equal(writeToBytes(t, original), expected) {
+		t.Error("Expected applying the patch to reproduce the modified SoundBank")
+	}
+}
+
+func TestApplyPatchLeavesSoundBankOnError(t *testing.T) {
+	bnk, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer bnk.Close()
+	expected := writeToBytes(t, bnk)
+
+	// The replacement and the first addition are valid, but the second addition
+	// repeats the ID of the first.
+	p := &Patch{BankId: bnk.BankHeaderSection.Descriptor.BankId,
+		Wems: []PatchWem{{bnk.Wems()[0].Descriptor.WemId, make([]byte, 1000)},
+			{1, make([]byte, 500)}, {1, make([]byte, 200)}}}
+	err = bnk.ApplyPatch(p)
+	if err == nil {
+		t.Error("Expected an error for a patch holding a wem twice")
+	}
+	if !bytes.Equal(writeToBytes(t, bnk), expected) {
+		t.Error("Expected the SoundBank to be unchanged by a failed patch")
+	}
+}
+
+func TestMusicObjectsRoundTrip(t *testing.T) {
+	bnk, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer bnk.Close()
+
+	structure := decodedStructure(t, bnk)
+	node := func(b *bytes.Buffer, childId uint32) {
+		writeFields(b, byte(0))
+		b.Write(structure)
+		writeFields(b, uint32(1), childId, MeterInfo{1000, 0, 120, 4, 4}, byte(1),
+			uint32(0))
+	}
+
+	track := new(bytes.Buffer)
+	writeFields(track, byte(0), uint32(1), MusicSource{0x40001, 0, 77, 0, 0},
+		uint32(1), TrackSource{0, 77, 0, 10, -10, 2000}, uint32(1), uint32(0))
+	track.Write(structure)
+	writeFields(track, byte(0), uint32(0))
+
+	segment := new(bytes.Buffer)
+	node(segment, 300)
+	writeFields(segment, float64(2000), uint32(1), uint32(5), float64(0), uint32(5),
+		[]byte("Entry"))
+
+	playlist := new(bytes.Buffer)
+	node(playlist, 200)
+	writeFields(playlist, uint32(1), uint32(1), uint32(200), uint32(1), uint32(200),
+		make([]byte, 21+24), byte(1), make([]byte, 30), uint32(1),
+		PlaylistItem{SegmentId: 200, ItemId: 1, Loop: 1, Weight: 50000})
+
+	// A switch of segment 200, followed by its decision tree.
+	sw := new(bytes.Buffer)
+	node(sw, 200)
+	writeFields(sw, uint32(0), byte(1), uint32(1), uint32(7), byte(0), uint32(12),
+		byte(0), uint32(0), uint32(200), uint16(0), uint16(100))
+
+	cases := []struct {
+		typeId byte
+		id     uint32
+		data   []byte
+	}{
+		{musicTrackId, 300, track.Bytes()},
+		{musicSegmentId, 200, segment.Bytes()},
+		{musicPlaylistId, 100, playlist.Bytes()},
+		{musicSwitchId, 101, sw.Bytes()},
+	}
+	objects := make(map[uint32]Object)
+	for _, c := range cases {
+		objects[c.id] = decodeObject(t, bnk, c.typeId, c.id, c.data)
+	}
+
+	if trk, ok := objects[300].(*MusicTrackObject); ok {
+		if len(trk.Sources) != 1 || trk.Sources[0].SourceId != 77 {
+			t.Errorf("Expected a single source 77 but got %v", trk.Sources)
+		}
+		if len(trk.Playlist) != 1 || trk.Playlist[0].SourceDuration != 2000 {
+			t.Errorf("Expected a 2000ms clip but got %v", trk.Playlist)
+		}
+	}
+	if seg, ok := objects[200].(*MusicSegmentObject); ok {
+		if seg.Duration != 2000 || seg.Meter.Tempo != 120 {
+			t.Errorf("Expected a 2000ms segment at 120bpm but got %vms at %vbpm",
+				seg.Duration, seg.Meter.Tempo)
+		}
+		if len(seg.Markers) != 1 || seg.Markers[0].Name != "Entry" {
+			t.Errorf("Expected an Entry marker but got %v", seg.Markers)
+		}
+	}
+	if pl, ok := objects[100].(*MusicPlaylistObject); ok {
+		if len(pl.Items) != 1 || pl.Items[0].SegmentId != 200 {
+			t.Errorf("Expected a playlist of segment 200 but got %v", pl.Items)
+		}
+	}
+	if sw, ok := objects[101].(*MusicSwitchObject); ok {
+		if len(sw.ChildIds) != 1 || sw.ChildIds[0] != 200 {
+			t.Errorf("Expected a switch of segment 200 but got %v", sw.ChildIds)
+		}
+	}
+
+	// An event that plays the switch plays the source of its track.
+	event := new(bytes.Buffer)
+	event.Write(layoutOf(bnk.BankHeaderSection).countBytes(1))
+	writeFields(event, uint32(400))
+	action := new(bytes.Buffer)
+	writeFields(action, uint16(actionPlayCategory<<8|0x03), uint32(101), byte(0),
+		make([]byte, 6))
+	objects[400] = decodeObject(t, bnk, actionObjectId, 400, action.Bytes())
+	objects[500] = decodeObject(t, bnk, eventObjectId, 500, event.Bytes())
+
+	b := new(File)
+	hdr := new(bytes.Buffer)
+	_, err = bnk.BankHeaderSection.WriteTo(hdr)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	err = b.addSectionBytes(hdr.Bytes())
+	if err == nil {
+		err = b.addObjects([]Object{objects[500], objects[400], objects[101],
+			objects[200], objects[300]})
+	}
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if wems := b.ObjectSection.WemsOf(500); len(wems) != 1 || wems[0] != 77 {
+		t.Errorf("Expected the event to play wem 77 but got %v", wems)
+	}
+}
+
+func TestSwitchAndBlendContainersRoundTrip(t *testing.T) {
+	bnk, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer bnk.Close()
+	structure := decodedStructure(t, bnk)
+
+	sw := new(bytes.Buffer)
+	sw.Write(structure)
+	writeFields(sw, byte(0), uint32(10), uint32(1), byte(0),
+		uint32(2), uint32(301), uint32(302),
+		uint32(2), uint32(1), uint32(1), uint32(301), uint32(2), uint32(1),
+		uint32(302), uint32(2), SwitchParam{301, 0, 0, 0, 0},
+		SwitchParam{302, 2, 1, 500, 250})
+
+	blend := new(bytes.Buffer)
+	blend.Write(structure)
+	writeFields(blend, uint32(1), uint32(301), uint32(1), uint32(7),
+		uint16(0), uint32(20), byte(0), uint32(1), uint32(301), uint32(2),
+		GraphPoint{0, 1, 4}, GraphPoint{100, 0, 4}, byte(0))
+
+	obj := decodeObject(t, bnk, switchContainerId, 100, sw.Bytes())
+	if ctr, ok := obj.(*ContainerObject); ok {
+		if children := ctr.ChildrenOf(2); len(children) != 1 ||
+			children[0] != 302 {
+			t.Errorf("Expected switch 2 to play [302] but got %v", children)
+		}
+		if len(ctr.SwitchParams) != 2 || ctr.SwitchParams[1].FadeOutTime != 500 {
+			t.Errorf("Expected a 500ms fade out but got %v", ctr.SwitchParams)
+		}
+	}
+	obj = decodeObject(t, bnk, layerContainerId, 200, blend.Bytes())
+	if ctr, ok := obj.(*ContainerObject); ok {
+		if len(ctr.Layers) != 1 || ctr.Layers[0].CrossfadeId != 20 ||
+			len(ctr.Layers[0].Associations) != 1 ||
+			len(ctr.Layers[0].Associations[0].Curve) != 2 {
+			t.Errorf("Expected a layer crossfading 301 over RTPC 20 but got %v",
+				ctr.Layers)
+		}
+	}
+}
+
+// decodedStructure returns the bytes of a decoded SoundStructure of a sound in
+// bnk, which objects that share its layout can be built from.
+func decodedStructure(t *testing.T, bnk *File) []byte {
+	for _, obj := range bnk.ObjectSection.Objects() {
+		if sfx, ok := obj.(*SfxVoiceSoundObject); ok && sfx.Structure.decoded {
+			b := new(bytes.Buffer)
+			sfx.Structure.WriteTo(b)
+			return b.Bytes()
+		}
+	}
+	t.Error("Expected the SoundBank to contain a decoded sound structure")
+	t.FailNow()
+	return nil
+}
+
+// decodeObject decodes the object with the given type, id and data as if it
+// were stored in bnk, and checks that it is written back unchanged.
+func TestReplaceWemOfMusicTrack(t *testing.T) {
+	bnk, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer bnk.Close()
+
+	wem := bnk.Wems()[0].Descriptor
+	track := new(bytes.Buffer)
+	writeFields(track, byte(0), uint32(1),
+		MusicSource{0x40001, byte(StreamEmbedded), wem.WemId, wem.Length, 0},
+		uint32(1), TrackSource{0, wem.WemId, 0, 10, -10, 2000}, uint32(1),
+		uint32(0))
+	track.Write(decodedStructure(t, bnk))
+	writeFields(track, byte(0), uint32(0))
+	trk, ok := decodeObject(t, bnk, musicTrackId, 300,
+		track.Bytes()).(*MusicTrackObject)
+	if !ok {
+		t.FailNow()
+	}
+	bnk.ObjectSection.objects = append(bnk.ObjectSection.objects, trk)
+
+	// A second of 16 bit stereo PCM at 1000 samples per second.
+	data := new(bytes.Buffer)
+	data.WriteString("RIFF")
+	writeFields(data, uint32(4+8+16+8+4000))
+	data.WriteString("WAVEfmt ")
+	writeFields(data, uint32(16), uint16(1), uint16(2), uint32(1000),
+		uint32(4000), uint16(4), uint16(16))
+	data.WriteString("data")
+	writeFields(data, uint32(4000), make([]byte, 4000))
+	bnk.ReplaceWems(&wwise.ReplacementWem{bytes.NewReader(data.Bytes()), 0,
+		int64(data.Len())})
+
+	if size := trk.Sources[0].InMemorySize; size != uint32(data.Len()) {
+		t.Errorf("Expected the source to be %d bytes but it is %d bytes",
+			data.Len(), size)
+	}
+	clip := trk.Playlist[0]
+	if clip.SourceDuration != 1000 {
+		t.Errorf("Expected the clip to last 1000ms but it lasts %vms",
+			clip.SourceDuration)
+	}
+	if clip.BeginTrimOffset != 10 || clip.EndTrimOffset != -10 {
+		t.Errorf("Expected the trim offsets to be kept but got %v and %v",
+			clip.BeginTrimOffset, clip.EndTrimOffset)
+	}
+}
+
+func decodeObject(t *testing.T, bnk *File, typeId byte, id uint32,
+	data []byte) Object {
+	desc := &ObjectDescriptor{typeId, uint32(len(data)) + 4, id}
+	obj, err := newObject(desc, bytes.NewReader(data), bnk.BankHeaderSection)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if _, ok := obj.(*UnknownObject); ok {
+		t.Errorf("Expected object of type %d to be decoded", typeId)
+		return obj
+	}
+
+	b := new(bytes.Buffer)
+	n, err := obj.WriteTo(b)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if n != int64(b.Len()) ||
+		!bytes.Equal(b.Bytes()[OBJECT_DESCRIPTOR_BYTES:], data) {
+		t.Errorf("Object of type %d was not written back unchanged", typeId)
+	}
+	return obj
+}
+
+func TestRTPCsRoundTrip(t *testing.T) {
+	bnk, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer bnk.Close()
+
+	var target uint32
+	for _, obj := range bnk.ObjectSection.Objects() {
+		ss, _ := structureOf(obj)
+		if ss == nil || !ss.decoded {
+			continue
+		}
+		rtpcs, err := ss.RTPCs()
+		if err != nil {
+			t.Errorf("Object %d: %v", obj.Id(), err)
+			continue
+		}
+		bs, err := encodeRTPCs(rtpcs)
+		if err != nil || !bytes.Equal(bs, ss.RTPC) {
+			t.Errorf("The RTPCs of object %d were not encoded unchanged", obj.Id())
+		}
+		if target == 0 {
+			target = obj.Id()
+		}
+	}
+	if target == 0 {
+		t.Error("Expected complex.bnk to contain an object with RTPCs")
+		t.FailNow()
+	}
+
+	volume := RTPC{Id: 1234, ParameterId: 0, CurveId: 5678, Scaling: 2,
+		Points: []GraphPoint{{0, -96, 4}, {100, 0, 4}}}
+	err = bnk.ObjectSection.ReplaceRTPCsOf(target, []RTPC{volume})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	rtpcs, err := rereadFile(t, bnk).ObjectSection.RTPCsOf(target)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if len(rtpcs) != 1 || rtpcs[0].Id != 1234 || len(rtpcs[0].Points) != 2 ||
+		rtpcs[0].Points[0].To != -96 {
+		t.Errorf("Expected RTPCs %v but got %v", []RTPC{volume}, rtpcs)
+	}
+}
+
+func TestAttenuations(t *testing.T) {
+	bnk, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer bnk.Close()
+
+	atts := bnk.ObjectSection.Attenuations()
+	if len(atts) != 8 {
+		t.Errorf("Expected 8 attenuations but found %d", len(atts))
+		t.FailNow()
+	}
+	att := atts[0]
+	if att.Cone == nil || att.Cone.InsideDegrees != 245 {
+		t.Errorf("Expected a cone with an inside angle of 245 but got %v",
+			att.Cone)
+	}
+	if max := att.MaxDistance(); max != 8000 {
+		t.Errorf("Expected a max distance of 8000 but got %v", max)
+	}
+
+	// Double the audible range of the dry volume curve.
+	dry := att.Curve(0)
+	for i := range dry.Points {
+		dry.Points[i].From *= 2
+	}
+	reread := rereadFile(t, bnk).ObjectSection.Object(att.Id())
+	changed, ok := reread.(*AttenuationObject)
+	if !ok {
+		t.Errorf("Expected object %d to be an attenuation", att.Id())
+		t.FailNow()
+	}
+	if max := changed.MaxDistance(); max != 16000 {
+		t.Errorf("Expected a max distance of 16000 but got %v", max)
+	}
+}
+
+func TestBusObjectRoundTrip(t *testing.T) {
+	bnk, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer bnk.Close()
+
+	data := new(bytes.Buffer)
+	writeFields(data, uint32(500), byte(2), byte(parameterVolumeType),
+		byte(parameterBusVolumeType), float32(-3), float32(-6),
+		[BUS_SETTINGS_BYTES]byte{}, int32(0), float32(-96), uint32(1),
+		Duck{600, -12, 500, 250, 4, 0}, byte(1), byte(1),
+		BusEffect{0, 700, 1, 0}, uint32(0), byte(0), []byte{0, 0, 0, 0})
+
+	obj := decodeObject(t, bnk, busId, 400, data.Bytes())
+	bus, ok := obj.(*BusObject)
+	if !ok {
+		t.FailNow()
+	}
+	if bus.ParentId != 500 || bus.Volume() != -3 || bus.BusVolume() != -6 {
+		t.Errorf("Expected a bus under 500 at -3dB and -6dB but got %d at %vdB "+
+			"and %vdB", bus.ParentId, bus.Volume(), bus.BusVolume())
+	}
+	if len(bus.Ducks) != 1 || bus.Ducks[0].BusId != 600 {
+		t.Errorf("Expected a duck of bus 600 but got %v", bus.Ducks)
+	}
+	if len(bus.Effects) != 1 || bus.Effects[0].EffectId != 700 {
+		t.Errorf("Expected effect 700 but got %v", bus.Effects)
+	}
+}
+
+func TestEffects(t *testing.T) {
+	bnk, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer bnk.Close()
+
+	effects := bnk.ObjectSection.Effects()
+	if len(effects) != 1 {
+		t.Errorf("Expected 1 effect but found %d", len(effects))
+		t.FailNow()
+	}
+	fx := effects[0]
+	if !fx.IsShareSet() || fx.PluginName() != "Wwise Meter" {
+		t.Errorf("Expected a Wwise Meter ShareSet but got plugin %#x",
+			fx.PluginId)
+	}
+	params, err := fx.Parameters()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if params[2].Name != "Min" || params[2].Value != float32(-48) {
+		t.Errorf("Expected a minimum of -48 but got %v", params[2])
+	}
+
+	fx.PluginId = 0
+	if _, err := fx.Parameters(); err == nil {
+		t.Error("Expected the parameters of an unknown plugin to be rejected")
+	}
+}
+
+func TestCheckReferences(t *testing.T) {
+	bnk, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer bnk.Close()
+
+	if problems := bnk.CheckReferences(nil); len(problems) != 0 {
+		t.Errorf("Expected no dangling references but got %v", problems)
+	}
+
+	var action *ActionObject
+	var sound *SfxVoiceSoundObject
+	for _, obj := range bnk.ObjectSection.Objects() {
+		switch obj := obj.(type) {
+		case *ActionObject:
+			if action == nil && obj.Plays() {
+				action = obj
+			}
+		case *SfxVoiceSoundObject:
+			if sound == nil && obj.Embedded() {
+				sound = obj
+			}
+		}
+	}
+	action.TargetId = 1
+	sound.WemDescriptor.WemId = 2
+
+	problems := bnk.CheckReferences(nil)
+	if len(problems) != 2 {
+		t.Errorf("Expected 2 dangling references but got %v", problems)
+		t.FailNow()
+	}
+	expected := map[uint32]*ReferenceError{
+		action.Id(): {action.Id(), "target", 1},
+		sound.Id():  {sound.Id(), "wem", 2},
+	}
+	for _, p := range problems {
+		var re *ReferenceError
+		if !errors.As(p, &re) || expected[re.ObjectId] == nil ||
+			*re != *expected[re.ObjectId] {
+			t.Errorf("Unexpected problem %v", p)
+		}
+	}
+}
+
+func TestReplaceWemsUpdatesObjects(t *testing.T) {
+	bnk, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer bnk.Close()
+
+	var sound *SfxVoiceSoundObject
+	for _, obj := range bnk.ObjectSection.Objects() {
+		if s, ok := obj.(*SfxVoiceSoundObject); ok && s.Embedded() {
+			sound = s
+			break
+		}
+	}
+	i, ok := bnk.IndexOfWem(sound.WemDescriptor.WemId)
+	if !ok {
+		t.Errorf("Expected wem %d to be stored", sound.WemDescriptor.WemId)
+		t.FailNow()
+	}
+	wem := bytes.Repeat([]byte{1}, 1000)
+	bnk.ReplaceWems(&wwise.ReplacementWem{bytes.NewReader(wem), i, 1000})
+
+	reread := rereadFile(t, bnk).ObjectSection.Object(sound.Id())
+	if length := reread.(*SfxVoiceSoundObject).WemDescriptor.WemLength; length !=
+		1000 {
+		t.Errorf("Expected the sound to describe 1000 bytes but got %d", length)
+	}
+}
+
+func TestSetProperty(t *testing.T) {
+	bnk, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer bnk.Close()
+
+	var sound *SfxVoiceSoundObject
+	for _, obj := range bnk.ObjectSection.Objects() {
+		if s, ok := obj.(*SfxVoiceSoundObject); ok {
+			sound = s
+			break
+		}
+	}
+	hrc := bnk.ObjectSection
+	for _, err := range []error{
+		hrc.SetProperty(sound.Id(), PropertyVolume, -3),
+		hrc.SetProperty(sound.Id(), PropertyPitch, 1200),
+		hrc.SetProperty(sound.Id(), PropertyLoop, 4),
+	} {
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+	}
+	if err := hrc.SetProperty(sound.Id(), PropertyLoop, 1.5); err == nil {
+		t.Error("Expected a fractional loop count to be rejected")
+	}
+
+	reread := rereadFile(t, bnk)
+	expected := map[Property]float64{PropertyVolume: -3, PropertyPitch: 1200,
+		PropertyLoop: 4}
+	for p, value := range expected {
+		actual, ok := reread.ObjectSection.Property(sound.Id(), p)
+		if !ok || actual != value {
+			t.Errorf("Expected %s to be %v but got %v", p, value, actual)
+		}
+	}
+	i, _ := reread.IndexOfWem(sound.WemDescriptor.WemId)
+	if loop := reread.LoopOf(i); !loop.Loops || loop.Value != 4 {
+		t.Errorf("Expected the wem to loop 4 times but got %v", loop)
+	}
+}
+
+func TestGlobalSettingsSection(t *testing.T) {
+	bnk, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer bnk.Close()
+
+	b := new(bytes.Buffer)
+	writeFields(b, float32(-80), uint16(256),
+		// One state group with a single transition.
+		uint32(1), uint32(11), uint32(500), uint32(1),
+		StateTransition{12, 13, 250},
+		// One switch group driven by a two point curve.
+		uint32(1), uint32(21), uint32(22), byte(0), uint32(2),
+		GraphPoint{0, 0, 4}, GraphPoint{100, 1, 4},
+		// One game parameter, followed by no acoustic textures.
+		uint32(1), GameParameter{31, 50, 0, 0, 0, 0}, uint32(0))
+	data := b.Bytes()
+	raw, err := NewRawSection("STMG", data)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	err = bnk.InsertSection(1, raw)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	err = bnk.ReplaceSectionData("STMG", data)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	stmg := rereadFile(t, bnk).GlobalSettingsSection
+	if stmg == nil {
+		t.Error("Expected the STMG section to be decoded")
+		t.FailNow()
+	}
+	if stmg.VolumeThreshold != -80 || stmg.MaxVoices != 256 {
+		t.Errorf("Expected a volume threshold of -80 and 256 voices but got %g "+
+			"and %d", stmg.VolumeThreshold, stmg.MaxVoices)
+	}
+	if len(stmg.StateGroups) != 1 ||
+		stmg.StateGroups[0].DefaultTransitionTime != 500 ||
+		len(stmg.StateGroups[0].Transitions) != 1 ||
+		stmg.StateGroups[0].Transitions[0].Time != 250 {
+		t.Errorf("Unexpected state groups: %v", stmg.StateGroups)
+	}
+	if len(stmg.SwitchGroups) != 1 || stmg.SwitchGroups[0].RTPCId != 22 ||
+		len(stmg.SwitchGroups[0].Points) != 2 {
+		t.Errorf("Unexpected switch groups: %v", stmg.SwitchGroups)
+	}
+	if len(stmg.GameParameters) != 1 || stmg.GameParameters[0].Value != 50 {
+		t.Errorf("Unexpected game parameters: %v", stmg.GameParameters)
+	}
+	if len(stmg.Remaining) != 4 {
+		t.Errorf("Expected 4 remaining bytes but got %d", len(stmg.Remaining))
+	}
+	written := new(bytes.Buffer)
+	stmg.WriteTo(written)
+	if !bytes.Equal(written.Bytes()[SECTION_HEADER_BYTES:], data) {
+		t.Error("Expected the STMG section to be written unchanged")
+	}
+}
+
+func TestEnvironmentSection(t *testing.T) {
+	bnk, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer bnk.Close()
+
+	b := new(bytes.Buffer)
+	for i := 0; i < 2*ENVIRONMENT_CURVE_USES; i++ {
+		// Only the obstruction volume curve is enabled and has points.
+		if i == 0 {
+			writeFields(b, true, byte(2), uint16(2), GraphPoint{0, 0, 4},
+				GraphPoint{100, -20, 4})
+			continue
+		}
+		writeFields(b, false, byte(0), uint16(0))
+	}
+	data := b.Bytes()
+	raw, err := NewRawSection("ENVS", data)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	err = bnk.InsertSection(1, raw)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	err = bnk.ReplaceSectionData("ENVS", data)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	envs := rereadFile(t, bnk).EnvironmentSection
+	if envs == nil {
+		t.Error("Expected the ENVS section to be decoded")
+		t.FailNow()
+	}
+	volume := envs.Obstruction[0]
+	if !volume.Enabled || volume.Scaling != 2 || len(volume.Points) != 2 ||
+		volume.Points[1].To != -20 {
+		t.Errorf("Unexpected obstruction volume curve: %v", volume)
+	}
+	if envs.Occlusion[0].Enabled || len(envs.Occlusion[0].Points) != 0 {
+		t.Errorf("Unexpected occlusion volume curve: %v", envs.Occlusion[0])
+	}
+	written := new(bytes.Buffer)
+	envs.WriteTo(written)
+	if !bytes.Equal(written.Bytes()[SECTION_HEADER_BYTES:], data) {
+		t.Error("Expected the ENVS section to be written unchanged")
+	}
+}
+
+func TestPlatformSection(t *testing.T) {
+	bnk, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer bnk.Close()
+
+	b := new(bytes.Buffer)
+	writeFields(b, uint32(7), []byte("Windows"))
+	data := b.Bytes()
+	raw, err := NewRawSection("PLAT", data)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	err = bnk.InsertSection(1, raw)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	err = bnk.ReplaceSectionData("PLAT", data)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	plat := rereadFile(t, bnk).PlatformSection
+	if plat == nil {
+		t.Error("Expected the PLAT section to be decoded")
+		t.FailNow()
+	}
+	if plat.Platform != "Windows" {
+		t.Errorf("Expected the Windows platform but got %s", plat.Platform)
+	}
+	if !strings.Contains(bnk.String(), "platform(Windows)") {
+		t.Error("Expected the platform to be described by the SoundBank")
+	}
+	written := new(bytes.Buffer)
+	plat.WriteTo(written)
+	if !bytes.Equal(written.Bytes()[SECTION_HEADER_BYTES:], data) {
+		t.Error("Expected the PLAT section to be written unchanged")
+	}
+}
+
+func TestOpenInitSoundBank(t *testing.T) {
+	org, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer org.Close()
+
+	// An Init.bnk holds no DIDX or DATA section.
+	b := new(bytes.Buffer)
+	org.BankHeaderSection.WriteTo(b)
+	stmg := new(bytes.Buffer)
+	writeFields(stmg, float32(-80), uint16(256), uint32(0), uint32(0),
+		uint32(1), GameParameter{31, 50, 0, 0, 0, 0})
+	writeFields(b, SectionHeader{stmgHeaderId, uint32(stmg.Len())},
+		stmg.Bytes())
+
+	bnk, err := NewFileFromBytes(b.Bytes())
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if !bnk.IsInit() || bnk.GlobalSettingsSection == nil {
+		t.Error("Expected the SoundBank to be read as an Init.bnk")
+		t.FailNow()
+	}
+	if len(bnk.Wems()) != 0 {
+		t.Errorf("Expected no wems but got %d", len(bnk.Wems()))
+	}
+	if !strings.Contains(bnk.String(), "game_parameters(1)") {
+		t.Error("Expected the game parameters to be described by the SoundBank")
+	}
+	if org.IsInit() {
+		t.Error("Expected a SoundBank with wems not to be an Init.bnk")
+	}
+
+	// A SoundBank with neither wems nor global settings is still rejected.
+	b.Reset()
+	org.BankHeaderSection.WriteTo(b)
+	if _, err := NewFileFromBytes(b.Bytes()); err == nil {
+		t.Error("Expected an error when reading a SoundBank without wems")
+	}
+}
+
+func TestBankSettings(t *testing.T) {
+	complex, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer complex.Close()
+	settings := complex.BankHeaderSection.Settings
+	if settings == nil || settings.ProjectId != 1114 ||
+		settings.FeedbackInBank == 0 {
+		t.Errorf("Expected project 1114 with feedback but got %+v", settings)
+	}
+
+	simple, err := Open(filepath.Join(testDir, simpleSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer simple.Close()
+	settings = simple.BankHeaderSection.Settings
+	if settings == nil || settings.LanguageId != wwise.HashName("SFX") {
+		t.Errorf("Expected the SFX language but got %+v", settings)
+	}
+
+	// Changed settings are written back in place.
+	settings.ProjectId = 42
+	reread := rereadFile(t, simple)
+	if reread.BankHeaderSection.Settings.ProjectId != 42 {
+		t.Errorf("Expected project 42 but got %d",
+			reread.BankHeaderSection.Settings.ProjectId)
+	}
+	if reread.BankHeaderSection.Size() != simple.BankHeaderSection.Size() {
+		t.Error("Expected the BKHD section to keep its size")
+	}
+}
+
+func TestSetLanguage(t *testing.T) {
+	complex, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer complex.Close()
+	simple, err := Open(filepath.Join(testDir, simpleSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer simple.Close()
+
+	cases := []struct {
+		hdr      *BankHeaderSection
+		language string
+		id       uint32
+	}{
+		{complex.BankHeaderSection, "English(US)", 12},
+		{complex.BankHeaderSection, "SFX", 0},
+		{complex.BankHeaderSection, "7", 7},
+		{simple.BankHeaderSection, "English(US)", wwise.HashName("English(US)")},
+		{simple.BankHeaderSection, "SFX", wwise.HashName("SFX")},
+	}
+	for _, c := range cases {
+		err = c.hdr.SetLanguage(c.language)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if c.hdr.Settings.LanguageId != c.id {
+			t.Errorf("Expected %s to be recorded as %d in a version %d SoundBank "+
+				"but it was recorded as %d", c.language, c.id,
+				c.hdr.Descriptor.Version, c.hdr.Settings.LanguageId)
+		}
+		if name, _ := c.hdr.Language(); c.id != 7 && name != c.language {
+			t.Errorf("Expected the language %s but got %s", c.language, name)
+		}
+	}
+
+	if err := complex.BankHeaderSection.SetLanguage("Klingon"); err == nil {
+		t.Error("Expected an error for a non-standard language in a SoundBank " +
+			"that numbers its languages")
+	}
+}
+
+func TestSetVersion(t *testing.T) {
+	complex, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer complex.Close()
+	simple, err := Open(filepath.Join(testDir, simpleSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer simple.Close()
+
+	for _, hdr := range []*BankHeaderSection{complex.BankHeaderSection,
+		simple.BankHeaderSection} {
+		version := hdr.Descriptor.Version
+		settings := *hdr.Settings
+		other := uint32(lastFeedbackVersion + 1)
+		if version > lastFeedbackVersion {
+			other = lastFeedbackVersion
+		}
+		if err := hdr.SetVersion(other); err == nil {
+			t.Errorf("Expected an error changing the version from %d to %d",
+				version, other)
+		}
+		if hdr.Descriptor.Version != version || *hdr.Settings != settings {
+			t.Error("Expected a rejected version change to leave the BKHD " +
+				"section unchanged")
+		}
+	}
+
+	// Crossing the version at which languages are hashed converts the language.
+	hdr := complex.BankHeaderSection
+	err = hdr.SetLanguage("English(US)")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	err = hdr.SetVersion(lastNumericLanguageVersion + 1)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if hdr.Settings.LanguageId != wwise.HashName("English(US)") {
+		t.Errorf("Expected the hashed English(US) language but got %d",
+			hdr.Settings.LanguageId)
+	}
+	reread := rereadFile(t, complex)
+	if name, _ := reread.BankHeaderSection.Language(); name != "English(US)" {
+		t.Errorf("Expected the English(US) language but got %s", name)
+	}
+	if reread.BankHeaderSection.Settings.FeedbackInBank !=
+		hdr.Settings.FeedbackInBank {
+		t.Error("Expected the feedback setting to be kept")
+	}
+}
+
+func TestUnknownSectionOffsets(t *testing.T) {
+	org, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer org.Close()
+	raw, err := NewRawSection("ABCD", []byte{1, 2, 3, 4})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if raw.Offset != -1 {
+		t.Errorf("Expected a created section to have no offset but got %d",
+			raw.Offset)
+	}
+	err = org.InsertSection(1, raw)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	bnk := rereadFile(t, org)
+	unknown, ok := bnk.Sections()[1].(*UnknownSection)
+	if !ok {
+		t.Error("Expected the inserted section to be read as unknown")
+		t.FailNow()
+	}
+	offset := bnk.BankHeaderSection.Size()
+	if unknown.Offset != offset {
+		t.Errorf("Expected the section to be at offset %d but got %d", offset,
+			unknown.Offset)
+	}
+	want := fmt.Sprintf("ABCD: len(4) unknown section at offset(%d)", offset)
+	if !strings.Contains(bnk.String(), want) {
+		t.Errorf("Expected the SoundBank to describe %q", want)
+	}
+
+	clone, err := bnk.Clone()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if clone.Sections()[1].(*UnknownSection).Offset != offset {
+		t.Error("Expected a cloned section to keep its offset")
+	}
+}
+
+func TestStrictRejectsUnknownSections(t *testing.T) {
+	path := filepath.Join(testDir, complexSoundBank)
+	org, err := OpenWithOptions(path, ReadOptions{Strict: true})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer org.Close()
+	raw, err := NewRawSection("ABCD", []byte{1, 2, 3, 4})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	err = org.InsertSection(1, raw)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	// Sections added after reading are still kept.
+	if _, err := org.Clone(); err != nil {
+		t.Error(err)
+	}
+
+	b := new(bytes.Buffer)
+	_, err = org.WriteTo(b)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	_, err = NewFileWithOptions(context.Background(), bytes.NewReader(b.Bytes()),
+		ReadOptions{Strict: true})
+	var sectionErr *SectionError
+	if !errors.Is(err, ErrUnknownSection) || !errors.As(err, &sectionErr) {
+		t.Errorf("Expected an unknown section error but got %v", err)
+		t.FailNow()
+	}
+	if sectionErr.Section != "ABCD" ||
+		sectionErr.Offset != org.BankHeaderSection.Size() {
+		t.Errorf("Expected the error to describe the ABCD section but got %v",
+			sectionErr)
+	}
+}
+
+// A countSection is a custom section holding a single count, used to test
+// registered section parsers.
+type countSection struct {
+	Header *SectionHeader
+	Count  uint32
+}
+
+func (c *countSection) WriteTo(w io.Writer) (int64, error) {
+	c.Header.Length = c.Length()
+	return writeFields(w, c.Header, c.Count)
+}
+
+func (c *countSection) String() string     { return "CNTS\n" }
+func (c *countSection) Size() int64        { return SECTION_HEADER_BYTES + 4 }
+func (c *countSection) Identifier() string { return "CNTS" }
+func (c *countSection) Length() uint32     { return 4 }
+
+func TestRegisterSectionParser(t *testing.T) {
+	parse := func(hdr *SectionHeader, r io.Reader,
+		bkhd *BankHeaderSection) (Section, error) {
+		if bkhd == nil {
+			return nil, errors.New("Expected the header of the SoundBank")
+		}
+		sec := &countSection{Header: hdr}
+		return sec, readFields(r, &sec.Count)
+	}
+	err := RegisterSectionParser("CNTS", parse)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer RegisterSectionParser("CNTS", nil)
+	if err := RegisterSectionParser("HIRC", parse); err == nil {
+		t.Error("Expected an error when registering a built-in section")
+	}
+
+	org, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer org.Close()
+	raw, err := NewRawSection("CNTS", []byte{7, 0, 0, 0})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	err = org.InsertSection(1, raw)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	b := new(bytes.Buffer)
+	_, err = org.WriteTo(b)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	// Registered sections are known, so they are read in strict mode.
+	bnk, err := NewFileWithOptions(context.Background(),
+		bytes.NewReader(b.Bytes()), ReadOptions{Strict: true})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	sec, ok := bnk.Sections()[1].(*countSection)
+	if !ok || sec.Count != 7 {
+		t.Errorf("Expected a count section of 7 but got %v", bnk.Sections()[1])
+	}
+	written := new(bytes.Buffer)
+	_, err = bnk.WriteTo(written)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if !bytes.Equal(written.Bytes(), b.Bytes()) {
+		t.Error("Expected the SoundBank to be written unchanged")
+	}
+}
+
+func TestStreamTypes(t *testing.T) {
+	bnk, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer bnk.Close()
+
+	sources := bnk.ObjectSection.Sources()
+	if len(sources) == 0 {
+		t.Error("Expected the SoundBank to play some media")
+		t.FailNow()
+	}
+	for _, src := range sources {
+		if src.StreamType != StreamEmbedded {
+			t.Errorf("Expected media %d to be embedded but it is %s", src.MediaId,
+				src.StreamType)
+		}
+	}
+
+	// Mark the first sound as streamed.
+	var sound *SfxVoiceSoundObject
+	for _, obj := range bnk.ObjectSection.objects {
+		if s, ok := obj.(*SfxVoiceSoundObject); ok {
+			sound = s
+			break
+		}
+	}
+	sound.Unknown[4] = byte(StreamStreamed)
+	types := rereadFile(t, bnk).ObjectSection.StreamTypes()
+	if types[sound.WemDescriptor.WemId] != StreamStreamed {
+		t.Errorf("Expected media %d to be streamed but it is %s",
+			sound.WemDescriptor.WemId, types[sound.WemDescriptor.WemId])
+	}
+	if StreamType(7).String() != "unknown(7)" {
+		t.Errorf("Unexpected name of an unknown stream type: %s", StreamType(7))
+	}
+}
+
+func TestReplaceWemEndingAligned(t *testing.T) {
+	bnk, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer bnk.Close()
+
+	first := bnk.Wems()[0].Descriptor
+	length := int64(100 * wemAlignmentBytes)
+	// The replacement ends on an aligned offset, so it needs no padding.
+	length -= int64(first.Offset) % wemAlignmentBytes
+	bnk.ReplaceWems(&wwise.ReplacementWem{util.NewConstantReader(length), 0,
+		length})
+	reread := rereadFile(t, bnk)
+	expected := first.Offset + uint32(length)
+	if offset := reread.Wems()[1].Descriptor.Offset; offset != expected {
+		t.Errorf("Expected the second wem to be at offset %d but got %d",
+			expected, offset)
+	}
+}
+
+// verifyBytes returns the problems found by Verify with the SoundBank stored in
+// b, which is aligned as Wwise aligns it.
+func verifyBytes(b []byte) []error {
+	return Verify(bytes.NewReader(b), int64(len(b)), 0)
+}
+
+func TestObjectSizes(t *testing.T) {
+	bnk, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer bnk.Close()
+
+	for _, obj := range bnk.ObjectSection.Objects() {
+		n, err := obj.WriteTo(ioutil.Discard)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		if obj.Size() != n {
+			t.Errorf("Object %d wrote %d bytes, but its size is %d", obj.Id(), n,
+				obj.Size())
+		}
+	}
+}
+
 func rereadFile(t *testing.T, org *File) *File {
 	orgBytes := new(bytes.Buffer)
 	_, err := org.WriteTo(orgBytes)
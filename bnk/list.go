@@ -0,0 +1,48 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"errors"
+	"io"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+// ListWems returns the descriptor of every wem of the SoundBank read from r, in
+// the order they are stored. Only the section headers and the DIDX section are
+// read, so this is much faster than NewFile for listing the wems of a large
+// SoundBank. Repeated wem IDs are kept, and the wems are not checked against
+// the DATA section.
+func ListWems(r io.ReaderAt) ([]*wwise.WemDescriptor, error) {
+	offset := int64(0)
+	for {
+		hr := io.NewSectionReader(r, offset, SECTION_HEADER_BYTES)
+		hdr, err := readSectionHeader(hr)
+		if err != nil {
+			if offset == 0 {
+				return nil, ErrNotASoundBank
+			}
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		if offset == 0 && hdr.Identifier != bkhdHeaderId {
+			return nil, ErrNotASoundBank
+		}
+
+		if hdr.Identifier == didxHeaderId {
+			sr := io.NewSectionReader(r, offset+SECTION_HEADER_BYTES,
+				int64(hdr.Length))
+			idx, _, err := hdr.newDataIndexSection(sr, DuplicateKeepAll)
+			if err != nil {
+				return nil, &SectionError{string(hdr.Identifier[:]), offset, err}
+			}
+			return idx.Descriptors, nil
+		}
+		offset += SECTION_HEADER_BYTES + int64(hdr.Length)
+	}
+	return nil, errors.New("There are no wems stored within this file.")
+}
@@ -0,0 +1,156 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// The kinds of IDs that may collide when merging SoundBanks.
+const (
+	WemCollision    = "wem"
+	ObjectCollision = "HIRC object"
+)
+
+// MergeOptions control how SoundBanks are merged.
+type MergeOptions struct {
+	// True if the HIRC objects of the SoundBanks should be merged, as well as
+	// their wems. Objects can only be merged between SoundBanks of the same
+	// version.
+	Objects bool
+}
+
+// A Collision describes an ID that is held by more than one of the SoundBanks
+// being merged. Only the first SoundBank's wem or object is kept.
+type Collision struct {
+	// The kind of the ID, which is either WemCollision or ObjectCollision.
+	Kind string
+	Id   uint32
+	// The indexes, into the merged SoundBanks, of every SoundBank holding the
+	// ID.
+	Banks []int
+}
+
+func (c Collision) String() string {
+	return fmt.Sprintf("%s %d is held by SoundBanks %v", c.Kind, c.Id, c.Banks)
+}
+
+// Merge creates a new File holding the wems of every SoundBank in banks, and
+// their HIRC objects if opts.Objects is true. The new SoundBank has the bank
+// header of the first SoundBank. IDs held by more than one SoundBank are kept
+// from the first SoundBank holding them, and are returned as Collisions. The
+// new File reads wems from the same sources as banks, so banks must not be
+// closed while it is in use.
+func Merge(banks []*File, opts MergeOptions) (*File, []Collision, error) {
+	if len(banks) == 0 {
+		return nil, nil, errors.New("At least one SoundBank must be merged")
+	}
+	first := banks[0].BankHeaderSection
+	if first == nil {
+		return nil, nil, ErrNotASoundBank
+	}
+	if opts.Objects {
+		for i, b := range banks {
+			if b.BankHeaderSection == nil ||
+				b.BankHeaderSection.Descriptor.Version != first.Descriptor.Version {
+				msg := fmt.Sprintf("The objects of SoundBank %d cannot be merged, as "+
+					"its version differs from that of the first SoundBank", i)
+				return nil, nil, errors.New(msg)
+			}
+		}
+	}
+
+	merged := new(File)
+	err := merged.addSectionCopy(first)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var collisions []*Collision
+	// Tracks the collision of each colliding ID, for every kind of ID.
+	collisionOf := map[string]map[uint32]*Collision{
+		WemCollision:    make(map[uint32]*Collision),
+		ObjectCollision: make(map[uint32]*Collision),
+	}
+	// Tracks the SoundBank holding each kept ID, for every kind of ID.
+	ownerOf := map[string]map[uint32]int{
+		WemCollision:    make(map[uint32]int),
+		ObjectCollision: make(map[uint32]int),
+	}
+	keep := func(kind string, id uint32, bank int) bool {
+		owner, ok := ownerOf[kind][id]
+		if !ok {
+			ownerOf[kind][id] = bank
+			return true
+		}
+		c, ok := collisionOf[kind][id]
+		if !ok {
+			c = &Collision{kind, id, []int{owner}}
+			collisionOf[kind][id] = c
+			collisions = append(collisions, c)
+		}
+		c.Banks = append(c.Banks, bank)
+		return false
+	}
+
+	var wems []WemSource
+	var objects []Object
+	for i, b := range banks {
+		for _, wem := range b.Wems() {
+			id := wem.Descriptor.WemId
+			if !keep(WemCollision, id, i) {
+				continue
+			}
+			src, err := sourceOf(wem)
+			if err != nil {
+				return nil, nil, err
+			}
+			wems = append(wems, src)
+		}
+		if opts.Objects && b.ObjectSection != nil {
+			for _, obj := range b.ObjectSection.Objects() {
+				if keep(ObjectCollision, obj.Id(), i) {
+					objects = append(objects, obj)
+				}
+			}
+		}
+	}
+
+	err = merged.addWems(wems)
+	if err != nil {
+		return nil, nil, err
+	}
+	if opts.Objects && len(objects) > 0 {
+		err = merged.addObjects(objects)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	result := make([]Collision, len(collisions))
+	for i, c := range collisions {
+		result[i] = *c
+	}
+	return merged, result, nil
+}
+
+// addObjects adds a HIRC section holding copies of the given objects after the
+// existing sections of this File.
+func (bnk *File) addObjects(objects []Object) error {
+	data := new(bytes.Buffer)
+	binary.Write(data, binary.LittleEndian, uint32(len(objects)))
+	for _, obj := range objects {
+		_, err := obj.WriteTo(data)
+		if err != nil {
+			return err
+		}
+	}
+
+	b := new(bytes.Buffer)
+	binary.Write(b, binary.LittleEndian,
+		&SectionHeader{hircHeaderId, uint32(data.Len())})
+	b.Write(data.Bytes())
+	return bnk.addSectionBytes(b.Bytes())
+}
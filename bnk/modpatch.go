@@ -0,0 +1,228 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+// The identifier that begins every patch file.
+var patchId = [4]byte{'B', 'P', 'C', 'H'}
+
+// The version of the patch file format written by Patch.WriteTo.
+const patchVersion = 1
+
+// The file extension of patch files.
+const PatchExtension = ".bnkpatch"
+
+// A Patch describes the wems that differ between a modified SoundBank and the
+// original it was made from, so that a modification can be distributed without
+// distributing the full SoundBank. A Patch is stored in a .bnkpatch file, which
+// holds the identifier "BPCH", the format version, the ID of the SoundBank and
+// then the ID, length and contents of each wem, all in little endian order.
+type Patch struct {
+	// The ID of the SoundBank that this patch applies to.
+	BankId uint32
+	// The wems of the patch, in the order they are applied.
+	Wems []PatchWem
+}
+
+// A PatchWem is a wem held by a Patch. It replaces the wem with the same ID, or
+// is added if there is no such wem.
+type PatchWem struct {
+	Id   uint32
+	Data []byte
+}
+
+// CreatePatch returns a Patch that turns original into modified. The patch
+// holds every wem of modified that is not held by original, or whose contents
+// differ from the wem of original with the same ID. An error is returned if the
+// SoundBanks have different IDs.
+func CreatePatch(original, modified *File) (*Patch, error) {
+	bankId := original.BankHeaderSection.Descriptor.BankId
+	if modifiedId := modified.BankHeaderSection.Descriptor.BankId; modifiedId !=
+		bankId {
+		msg := fmt.Sprintf("The original SoundBank has ID %d, but the modified "+
+			"SoundBank has ID %d", bankId, modifiedId)
+		return nil, errors.New(msg)
+	}
+	orgWems := make(map[uint32]*wwise.Wem)
+	for _, wem := range original.Wems() {
+		orgWems[wem.Descriptor.WemId] = wem
+	}
+	p := &Patch{BankId: bankId}
+	for _, wem := range modified.Wems() {
+		data, err := wemBytes(wem)
+		if err != nil {
+			return nil, err
+		}
+		if org, ok := orgWems[wem.Descriptor.WemId]; ok {
+			orgData, err := wemBytes(org)
+			if err != nil {
+				return nil, err
+			}
+			if bytes.Equal(data, orgData) {
+				continue
+			}
+		}
+		p.Wems = append(p.Wems, PatchWem{wem.Descriptor.WemId, data})
+	}
+	return p, nil
+}
+
+// wemBytes returns the full contents of wem.
+func wemBytes(wem *wwise.Wem) ([]byte, error) {
+	b := new(bytes.Buffer)
+	_, err := wem.WriteTo(b)
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// ReadPatch reads a Patch, as written by Patch.WriteTo, from r.
+func ReadPatch(r io.Reader) (*Patch, error) {
+	var hdr struct {
+		Identifier [4]byte
+		Version    uint32
+		BankId     uint32
+		WemCount   uint32
+	}
+	err := binary.Read(r, binary.LittleEndian, &hdr)
+	if err != nil {
+		return nil, err
+	}
+	if hdr.Identifier != patchId {
+		return nil, errors.New("The file is not a SoundBank patch")
+	}
+	if hdr.Version != patchVersion {
+		msg := fmt.Sprintf("Version %d of the patch format is not supported",
+			hdr.Version)
+		return nil, errors.New(msg)
+	}
+
+	p := &Patch{BankId: hdr.BankId}
+	for i := uint32(0); i < hdr.WemCount; i++ {
+		var desc [2]uint32
+		err := binary.Read(r, binary.LittleEndian, &desc)
+		if err != nil {
+			return nil, err
+		}
+		// Read through a buffer, so that a corrupt length does not allocate more
+		// than the patch holds.
+		data := new(bytes.Buffer)
+		_, err = io.CopyN(data, r, int64(desc[1]))
+		if err != nil {
+			msg := fmt.Sprintf("Wem %d of the patch is cut off: %s", desc[0], err)
+			return nil, errors.New(msg)
+		}
+		p.Wems = append(p.Wems, PatchWem{desc[0], data.Bytes()})
+	}
+	return p, nil
+}
+
+// WriteTo writes this Patch to w.
+func (p *Patch) WriteTo(w io.Writer) (written int64, err error) {
+	hdr := []interface{}{patchId, uint32(patchVersion), p.BankId,
+		uint32(len(p.Wems))}
+	for _, field := range hdr {
+		err = binary.Write(w, binary.LittleEndian, field)
+		if err != nil {
+			return
+		}
+		written += int64(binary.Size(field))
+	}
+	for _, wem := range p.Wems {
+		err = binary.Write(w, binary.LittleEndian,
+			[2]uint32{wem.Id, uint32(len(wem.Data))})
+		if err != nil {
+			return
+		}
+		written += 8
+		n, err := w.Write(wem.Data)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// ApplyPatch replaces the wems of this SoundBank with the wems of p that share
+// their ID, and adds the wems of p that this SoundBank does not hold. An error
+// is returned, before anything is changed, if p was made for a SoundBank with a
+// different ID, holds a wem more than once, or its wems are too large to be
+// stored.
+func (bnk *File) ApplyPatch(p *Patch) error {
+	bankId := bnk.BankHeaderSection.Descriptor.BankId
+	if p.BankId != bankId {
+		msg := fmt.Sprintf("The patch is for the SoundBank with ID %d, but this "+
+			"SoundBank has ID %d", p.BankId, bankId)
+		return errors.New(msg)
+	}
+	seen := make(map[uint32]bool)
+	var replacements []*wwise.ReplacementWem
+	var additions []WemSource
+	for _, wem := range p.Wems {
+		if seen[wem.Id] {
+			msg := fmt.Sprintf("The patch holds wem %d more than once", wem.Id)
+			return errors.New(msg)
+		}
+		seen[wem.Id] = true
+		r := bytes.NewReader(wem.Data)
+		if i, ok := bnk.IndexOfWem(wem.Id); ok {
+			replacements = append(replacements,
+				&wwise.ReplacementWem{r, i, int64(len(wem.Data))})
+		} else {
+			additions = append(additions, WemSource{wem.Id, r, int64(len(wem.Data))})
+		}
+	}
+	err := bnk.CheckReplacements(replacements...)
+	if err != nil {
+		return err
+	}
+	if len(additions) == 0 {
+		if len(replacements) > 0 {
+			bnk.ReplaceWems(replacements...)
+		}
+		return nil
+	}
+
+	// Adding wems lays out every wem again, so lay them out once with the
+	// replacements in place. This checks the added wems before the SoundBank is
+	// changed.
+	if bnk.IndexSection == nil || bnk.DataSection == nil {
+		return errors.New("The SoundBank has no DIDX and DATA sections to add " +
+			"wems to")
+	}
+	replaced := make(map[int]*wwise.ReplacementWem)
+	for _, r := range replacements {
+		replaced[r.WemIndex] = r
+	}
+	wems := additions
+	for i, wem := range bnk.Wems() {
+		if r, ok := replaced[i]; ok {
+			wems = append(wems, WemSource{wem.Descriptor.WemId, r.Wem, r.Length})
+			continue
+		}
+		src, err := sourceOf(wem)
+		if err != nil {
+			return err
+		}
+		wems = append(wems, src)
+	}
+	idx, data, err := newWemSections(wems, 0, bnk.Alignment())
+	if err != nil {
+		return err
+	}
+	bnk.updateObjectsOf(replacements)
+	bnk.setWemSections(idx, data)
+	return nil
+}
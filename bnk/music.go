@@ -0,0 +1,711 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/util"
+)
+
+// The identifier for Music Segment objects.
+const musicSegmentId = 0x0A
+
+// The identifier for Music Track objects.
+const musicTrackId = 0x0B
+
+// The identifier for Music Switch container objects.
+const musicSwitchId = 0x0C
+
+// The identifier for Music Playlist (Random/Sequence) container objects.
+const musicPlaylistId = 0x0D
+
+// The number of bytes used to describe the source of a music track.
+const MUSIC_SOURCE_BYTES = 14
+
+// The number of bytes used to describe a clip of a music track's playlist.
+const TRACK_SOURCE_BYTES = 40
+
+// The number of bytes used to describe a single item of a music playlist.
+const PLAYLIST_ITEM_BYTES = 30
+
+// A MusicSource describes audio used by a Music Track. Embedded and prefetched
+// sources are stored as wems with the ID SourceId.
+type MusicSource struct {
+	// The ID of the plugin that decodes this source, which determines its codec.
+	PluginId uint32
+	// Whether the source is embedded in a SoundBank, streamed, or both.
+	StreamType byte
+	SourceId   uint32
+	// The number of bytes of the source that are stored in memory.
+	InMemorySize uint32
+	SourceBits   byte
+}
+
+// A TrackSource is a clip of a Music Track's playlist, which places one of the
+// track's sources on its timeline. All times are in milliseconds.
+type TrackSource struct {
+	TrackId         uint32
+	SourceId        uint32
+	PlayAt          float64
+	BeginTrimOffset float64
+	EndTrimOffset   float64
+	SourceDuration  float64
+}
+
+// A GraphPoint is a point of a curve, such as a clip automation or RTPC curve.
+type GraphPoint struct {
+	From float32
+	To   float32
+	// The shape of the curve between this point and the next.
+	Interpolation uint32
+}
+
+// A ClipAutomation is a curve that changes a property, such as the volume, of
+// a clip of a Music Track over time.
+type ClipAutomation struct {
+	// The index of the clip in the track's playlist.
+	ClipIndex uint32
+	Type      uint32
+	Points    []GraphPoint
+}
+
+// A MeterInfo describes the tempo and time signature of a music object.
+type MeterInfo struct {
+	// The period and offset of the grid, in milliseconds.
+	GridPeriod float64
+	GridOffset float64
+	// The tempo, in beats per minute.
+	Tempo       float32
+	BeatsPerBar byte
+	BeatValue   byte
+}
+
+// A Stinger is a segment played over a music object when a trigger is posted.
+type Stinger struct {
+	TriggerId        uint32
+	SegmentId        uint32
+	SyncPlayAt       uint32
+	CueFilterHash    uint32
+	DontRepeatTime   int32
+	SegmentLookAhead uint32
+}
+
+// A MusicNode holds the properties common to Music Segments, Playlists and
+// Switches.
+type MusicNode struct {
+	Flags     byte
+	Structure *SoundStructure
+	ChildIds  []uint32
+	Meter     MeterInfo
+	// 1 if Meter overrides the meter of the parent object, and 0 if otherwise.
+	MeterFlag byte
+	Stingers  []Stinger
+}
+
+// A MusicMarker is a named position within a Music Segment.
+type MusicMarker struct {
+	Id uint32
+	// The position of the marker, in milliseconds.
+	Position float64
+	Name     string
+}
+
+// A PlaylistItem is a node of the playlist tree of a Music Playlist. Groups of
+// items have a SegmentId of 0, and are followed by their ChildCount children.
+type PlaylistItem struct {
+	SegmentId  uint32
+	ItemId     uint32
+	ChildCount uint32
+	// Whether the children of a group are played in sequence or at random.
+	Type uint32
+	// The number of times the item is played, where 0 plays it infinitely.
+	Loop             int16
+	LoopMin          int16
+	LoopMax          int16
+	Weight           uint32
+	AvoidRepeatCount uint16
+	UsingWeight      byte
+	Shuffle          byte
+}
+
+// A MusicTrackObject represents a Music Track within the HIRC section, which
+// places its sources on a timeline.
+type MusicTrackObject struct {
+	Descriptor *ObjectDescriptor
+	Flags      byte
+	Sources    []MusicSource
+	Playlist   []TrackSource
+	// The number of sub-tracks of the playlist. Only stored if the playlist is
+	// not empty.
+	SubTrackCount   uint32
+	ClipAutomations []ClipAutomation
+	Structure       *SoundStructure
+	// A reader to read the remaining data of this object, which holds the type
+	// of the track and its look-ahead time.
+	RemainingReader io.Reader
+}
+
+// A MusicSegmentObject represents a Music Segment within the HIRC section,
+// which plays its child tracks together.
+type MusicSegmentObject struct {
+	Descriptor *ObjectDescriptor
+	MusicNode
+	// The duration of the segment, in milliseconds.
+	Duration float64
+	Markers  []MusicMarker
+	// A reader to read the remaining data of this object.
+	RemainingReader io.Reader
+}
+
+// A MusicPlaylistObject represents a Music Playlist container within the HIRC
+// section, which plays its child segments in the order given by its playlist.
+type MusicPlaylistObject struct {
+	Descriptor *ObjectDescriptor
+	MusicNode
+	// The undecoded transition rules between the children of the playlist,
+	// including their count.
+	TransitionRules []byte
+	// The playlist tree, in depth first order.
+	Items []PlaylistItem
+	// A reader to read the remaining data of this object.
+	RemainingReader io.Reader
+}
+
+// A MusicSwitchObject represents a Music Switch container within the HIRC
+// section, which plays one of its children depending on the state of its
+// switch or state groups.
+type MusicSwitchObject struct {
+	Descriptor *ObjectDescriptor
+	MusicNode
+	// The undecoded transition rules between the children of the switch,
+	// including their count.
+	TransitionRules []byte
+	// A reader to read the remaining data of this object, which holds the
+	// decision tree that chooses a child.
+	RemainingReader io.Reader
+}
+
+// NewMusicTrackObject creates a new MusicTrackObject, reading from sr, which
+// must be seeked to the start of the object's data. bkhd is the header of the
+// SoundBank containing this object, and may be nil.
+func (desc *ObjectDescriptor) NewMusicTrackObject(sr util.ReadSeekerAt,
+	bkhd *BankHeaderSection) (*MusicTrackObject, error) {
+	l := layoutOf(bkhd)
+	if !l.known() {
+		return nil, errUnknownLayout
+	}
+	startOffset, _ := sr.Seek(0, io.SeekCurrent)
+	dataLength := int64(desc.Length) - OBJECT_DESCRIPTOR_ID_BYTES
+
+	track := &MusicTrackObject{Descriptor: desc}
+	err := readFields(sr, &track.Flags)
+	if err != nil {
+		return nil, err
+	}
+	count, err := readListCount(sr, MUSIC_SOURCE_BYTES, dataLength)
+	if err != nil {
+		return nil, err
+	}
+	track.Sources = make([]MusicSource, count)
+	err = readFields(sr, track.Sources)
+	if err != nil {
+		return nil, err
+	}
+	count, err = readListCount(sr, TRACK_SOURCE_BYTES, dataLength)
+	if err != nil {
+		return nil, err
+	}
+	track.Playlist = make([]TrackSource, count)
+	err = readFields(sr, track.Playlist)
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		err = readFields(sr, &track.SubTrackCount)
+		if err != nil {
+			return nil, err
+		}
+	}
+	track.ClipAutomations, err = readClipAutomations(sr, dataLength)
+	if err != nil {
+		return nil, err
+	}
+	track.Structure, err = readSoundStructure(sr, l)
+	if err != nil {
+		return nil, err
+	}
+	if !track.Structure.decoded {
+		return nil, errUnknownLayout
+	}
+
+	track.RemainingReader, err = remainingReader(sr, startOffset, dataLength)
+	if err != nil {
+		return nil, err
+	}
+	return track, nil
+}
+
+// readListCount reads the number of items of a list, each of which is at least
+// itemBytes long, and returns an error if the list would be longer than
+// maxBytes.
+func readListCount(r io.Reader, itemBytes, maxBytes int64) (uint32, error) {
+	var count uint32
+	err := binary.Read(r, binary.LittleEndian, &count)
+	if err != nil {
+		return 0, err
+	}
+	if int64(count)*itemBytes > maxBytes {
+		return 0, errUnknownLayout
+	}
+	return count, nil
+}
+
+// remainingReader returns a reader over the rest of an object whose data
+// begins at startOffset and is dataLength bytes long, and seeks sr past it.
+func remainingReader(sr util.ReadSeekerAt, startOffset,
+	dataLength int64) (io.Reader, error) {
+	currOffset, _ := sr.Seek(0, io.SeekCurrent)
+	remaining := dataLength - (currOffset - startOffset)
+	if remaining < 0 {
+		return nil, errUnknownLayout
+	}
+	r := util.NewResettingReader(sr, currOffset, remaining)
+	sr.Seek(remaining, io.SeekCurrent)
+	return r, nil
+}
+
+func readClipAutomations(r io.Reader, maxBytes int64) ([]ClipAutomation,
+	error) {
+	count, err := readListCount(r, 12, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	clips := make([]ClipAutomation, count)
+	for i := range clips {
+		c := &clips[i]
+		err = readFields(r, &c.ClipIndex, &c.Type)
+		if err != nil {
+			return nil, err
+		}
+		points, err := readListCount(r, RTPC_POINT_BYTES, maxBytes)
+		if err != nil {
+			return nil, err
+		}
+		c.Points = make([]GraphPoint, points)
+		err = readFields(r, c.Points)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return clips, nil
+}
+
+func writeClipAutomations(w io.Writer, clips []ClipAutomation) (written int64,
+	err error) {
+	written, err = writeFields(w, uint32(len(clips)))
+	if err != nil {
+		return
+	}
+	for _, c := range clips {
+		n, err := writeFields(w, c.ClipIndex, c.Type, uint32(len(c.Points)),
+			c.Points)
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// WriteTo writes the full contents of this MusicTrackObject to the Writer
+// specified by w.
+func (track *MusicTrackObject) WriteTo(w io.Writer) (written int64, err error) {
+	fields := []interface{}{track.Descriptor, track.Flags,
+		uint32(len(track.Sources)), track.Sources, uint32(len(track.Playlist)),
+		track.Playlist}
+	if len(track.Playlist) > 0 {
+		fields = append(fields, track.SubTrackCount)
+	}
+	written, err = writeFields(w, fields...)
+	if err != nil {
+		return
+	}
+
+	n, err := writeClipAutomations(w, track.ClipAutomations)
+	written += n
+	if err != nil {
+		return
+	}
+	n, err = track.Structure.WriteTo(w)
+	written += n
+	if err != nil {
+		return
+	}
+	n, err = util.CopyAll(w, track.RemainingReader)
+	written += n
+	return written, err
+}
+
+// Id returns the ID of this object.
+func (track *MusicTrackObject) Id() uint32 {
+	return track.Descriptor.ObjectId
+}
+
+// TypeId returns the identifier of the type of this object.
+func (track *MusicTrackObject) TypeId() byte {
+	return track.Descriptor.Type
+}
+
+// Size returns the number of bytes that WriteTo would write.
+func (track *MusicTrackObject) Size() int64 {
+	return track.Descriptor.objectSize()
+}
+
+// readMusicNode reads the properties common to music objects from sr, which
+// must be seeked to the start of the object's data.
+func readMusicNode(sr util.ReadSeekerAt, l layout,
+	maxBytes int64) (MusicNode, error) {
+	var node MusicNode
+	err := readFields(sr, &node.Flags)
+	if err != nil {
+		return node, err
+	}
+	node.Structure, err = readSoundStructure(sr, l)
+	if err != nil {
+		return node, err
+	}
+	if !node.Structure.decoded {
+		return node, errUnknownLayout
+	}
+	count, err := readListCount(sr, 4, maxBytes)
+	if err != nil {
+		return node, err
+	}
+	node.ChildIds = make([]uint32, count)
+	err = readFields(sr, node.ChildIds, &node.Meter, &node.MeterFlag)
+	if err != nil {
+		return node, err
+	}
+	count, err = readListCount(sr, int64(binary.Size(Stinger{})), maxBytes)
+	if err != nil {
+		return node, err
+	}
+	node.Stingers = make([]Stinger, count)
+	err = readFields(sr, node.Stingers)
+	return node, err
+}
+
+// writeTo writes the properties common to music objects to w.
+func (node *MusicNode) writeTo(w io.Writer) (written int64, err error) {
+	written, err = writeFields(w, node.Flags)
+	if err != nil {
+		return
+	}
+	n, err := node.Structure.WriteTo(w)
+	written += n
+	if err != nil {
+		return
+	}
+	n, err = writeFields(w, uint32(len(node.ChildIds)), node.ChildIds,
+		node.Meter, node.MeterFlag, uint32(len(node.Stingers)), node.Stingers)
+	written += n
+	return written, err
+}
+
+// NewMusicSegmentObject creates a new MusicSegmentObject, reading from sr,
+// which must be seeked to the start of the object's data. bkhd is the header of
+// the SoundBank containing this object, and may be nil.
+func (desc *ObjectDescriptor) NewMusicSegmentObject(sr util.ReadSeekerAt,
+	bkhd *BankHeaderSection) (*MusicSegmentObject, error) {
+	l := layoutOf(bkhd)
+	if !l.known() {
+		return nil, errUnknownLayout
+	}
+	startOffset, _ := sr.Seek(0, io.SeekCurrent)
+	dataLength := int64(desc.Length) - OBJECT_DESCRIPTOR_ID_BYTES
+
+	node, err := readMusicNode(sr, l, dataLength)
+	if err != nil {
+		return nil, err
+	}
+	seg := &MusicSegmentObject{Descriptor: desc, MusicNode: node}
+	err = readFields(sr, &seg.Duration)
+	if err != nil {
+		return nil, err
+	}
+	count, err := readListCount(sr, 4+8+4, dataLength)
+	if err != nil {
+		return nil, err
+	}
+	seg.Markers = make([]MusicMarker, count)
+	for i := range seg.Markers {
+		m := &seg.Markers[i]
+		var nameLength uint32
+		err = readFields(sr, &m.Id, &m.Position, &nameLength)
+		if err != nil {
+			return nil, err
+		}
+		if int64(nameLength) > dataLength {
+			return nil, errUnknownLayout
+		}
+		name := make([]byte, nameLength)
+		_, err = io.ReadFull(sr, name)
+		if err != nil {
+			return nil, err
+		}
+		m.Name = string(name)
+	}
+
+	seg.RemainingReader, err = remainingReader(sr, startOffset, dataLength)
+	if err != nil {
+		return nil, err
+	}
+	return seg, nil
+}
+
+// WriteTo writes the full contents of this MusicSegmentObject to the Writer
+// specified by w.
+func (seg *MusicSegmentObject) WriteTo(w io.Writer) (written int64, err error) {
+	written, err = writeFields(w, seg.Descriptor)
+	if err != nil {
+		return
+	}
+	n, err := seg.MusicNode.writeTo(w)
+	written += n
+	if err != nil {
+		return
+	}
+	n, err = writeFields(w, seg.Duration, uint32(len(seg.Markers)))
+	written += n
+	if err != nil {
+		return
+	}
+	for _, m := range seg.Markers {
+		n, err = writeFields(w, m.Id, m.Position, uint32(len(m.Name)),
+			[]byte(m.Name))
+		written += n
+		if err != nil {
+			return
+		}
+	}
+	n, err = util.CopyAll(w, seg.RemainingReader)
+	written += n
+	return written, err
+}
+
+// Id returns the ID of this object.
+func (seg *MusicSegmentObject) Id() uint32 {
+	return seg.Descriptor.ObjectId
+}
+
+// TypeId returns the identifier of the type of this object.
+func (seg *MusicSegmentObject) TypeId() byte {
+	return seg.Descriptor.Type
+}
+
+// Size returns the number of bytes that WriteTo would write.
+func (seg *MusicSegmentObject) Size() int64 {
+	return seg.Descriptor.objectSize()
+}
+
+// NewMusicPlaylistObject creates a new MusicPlaylistObject, reading from sr,
+// which must be seeked to the start of the object's data. bkhd is the header of
+// the SoundBank containing this object, and may be nil.
+func (desc *ObjectDescriptor) NewMusicPlaylistObject(sr util.ReadSeekerAt,
+	bkhd *BankHeaderSection) (*MusicPlaylistObject, error) {
+	l := layoutOf(bkhd)
+	if !l.known() {
+		return nil, errUnknownLayout
+	}
+	startOffset, _ := sr.Seek(0, io.SeekCurrent)
+	dataLength := int64(desc.Length) - OBJECT_DESCRIPTOR_ID_BYTES
+
+	node, err := readMusicNode(sr, l, dataLength)
+	if err != nil {
+		return nil, err
+	}
+	playlist := &MusicPlaylistObject{Descriptor: desc, MusicNode: node}
+	// Capture the raw bytes of the transition rules as they are read.
+	rules := new(bytes.Buffer)
+	err = readTransitionRules(io.TeeReader(sr, rules), l, dataLength)
+	if err != nil {
+		return nil, err
+	}
+	playlist.TransitionRules = rules.Bytes()
+	count, err := readListCount(sr, PLAYLIST_ITEM_BYTES, dataLength)
+	if err != nil {
+		return nil, err
+	}
+	playlist.Items = make([]PlaylistItem, count)
+	err = readFields(sr, playlist.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	playlist.RemainingReader, err = remainingReader(sr, startOffset, dataLength)
+	if err != nil {
+		return nil, err
+	}
+	return playlist, nil
+}
+
+// readTransitionRules reads the transition rules of a music container from r.
+func readTransitionRules(r io.Reader, l layout, maxBytes int64) error {
+	count, err := readListCount(r, 4+4, maxBytes)
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < count; i++ {
+		// The lists of source and destination object IDs.
+		for j := 0; j < 2; j++ {
+			ids, err := readListCount(r, 4, maxBytes)
+			if err != nil {
+				return err
+			}
+			err = skip(r, int64(ids)*4)
+			if err != nil {
+				return err
+			}
+		}
+		// The source rule: the transition time, fade curve, fade offset, sync
+		// type, cue filter and whether to play the post-exit.
+		// The destination rule: the transition time, fade curve, fade offset,
+		// cue filter, jump target, entry type, whether to play the pre-entry and
+		// whether to match the source cue name.
+		n := int64(4+4+4+4+4+1) + (4 + 4 + 4 + 4 + 4 + 2 + 1 + 1)
+		if l.version > 132 {
+			// The type of the jump target.
+			n += 2
+		}
+		err = skip(r, n)
+		if err != nil {
+			return err
+		}
+		var hasTransitionObject byte
+		err = readFields(r, &hasTransitionObject)
+		if err != nil {
+			return err
+		}
+		if hasTransitionObject != 0 {
+			// The segment ID, the fade in and fade out parameters, and whether to
+			// play its pre-entry and post-exit.
+			err = skip(r, 4+12+12+1+1)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WriteTo writes the full contents of this MusicPlaylistObject to the Writer
+// specified by w.
+func (playlist *MusicPlaylistObject) WriteTo(w io.Writer) (written int64,
+	err error) {
+	written, err = writeFields(w, playlist.Descriptor)
+	if err != nil {
+		return
+	}
+	n, err := playlist.MusicNode.writeTo(w)
+	written += n
+	if err != nil {
+		return
+	}
+	n, err = writeFields(w, playlist.TransitionRules,
+		uint32(len(playlist.Items)), playlist.Items)
+	written += n
+	if err != nil {
+		return
+	}
+	n, err = util.CopyAll(w, playlist.RemainingReader)
+	written += n
+	return written, err
+}
+
+// Id returns the ID of this object.
+func (playlist *MusicPlaylistObject) Id() uint32 {
+	return playlist.Descriptor.ObjectId
+}
+
+// TypeId returns the identifier of the type of this object.
+func (playlist *MusicPlaylistObject) TypeId() byte {
+	return playlist.Descriptor.Type
+}
+
+// Size returns the number of bytes that WriteTo would write.
+func (playlist *MusicPlaylistObject) Size() int64 {
+	return playlist.Descriptor.objectSize()
+}
+
+// NewMusicSwitchObject creates a new MusicSwitchObject, reading from sr, which
+// must be seeked to the start of the object's data. bkhd is the header of the
+// SoundBank containing this object, and may be nil.
+func (desc *ObjectDescriptor) NewMusicSwitchObject(sr util.ReadSeekerAt,
+	bkhd *BankHeaderSection) (*MusicSwitchObject, error) {
+	l := layoutOf(bkhd)
+	if !l.known() {
+		return nil, errUnknownLayout
+	}
+	startOffset, _ := sr.Seek(0, io.SeekCurrent)
+	dataLength := int64(desc.Length) - OBJECT_DESCRIPTOR_ID_BYTES
+
+	node, err := readMusicNode(sr, l, dataLength)
+	if err != nil {
+		return nil, err
+	}
+	sw := &MusicSwitchObject{Descriptor: desc, MusicNode: node}
+	// Capture the raw bytes of the transition rules as they are read.
+	rules := new(bytes.Buffer)
+	err = readTransitionRules(io.TeeReader(sr, rules), l, dataLength)
+	if err != nil {
+		return nil, err
+	}
+	sw.TransitionRules = rules.Bytes()
+
+	sw.RemainingReader, err = remainingReader(sr, startOffset, dataLength)
+	if err != nil {
+		return nil, err
+	}
+	return sw, nil
+}
+
+// WriteTo writes the full contents of this MusicSwitchObject to the Writer
+// specified by w.
+func (sw *MusicSwitchObject) WriteTo(w io.Writer) (written int64, err error) {
+	written, err = writeFields(w, sw.Descriptor)
+	if err != nil {
+		return
+	}
+	n, err := sw.MusicNode.writeTo(w)
+	written += n
+	if err != nil {
+		return
+	}
+	n, err = writeFields(w, sw.TransitionRules)
+	written += n
+	if err != nil {
+		return
+	}
+	n, err = util.CopyAll(w, sw.RemainingReader)
+	written += n
+	return written, err
+}
+
+// Id returns the ID of this object.
+func (sw *MusicSwitchObject) Id() uint32 {
+	return sw.Descriptor.ObjectId
+}
+
+// TypeId returns the identifier of the type of this object.
+func (sw *MusicSwitchObject) TypeId() byte {
+	return sw.Descriptor.Type
+}
+
+// Size returns the number of bytes that WriteTo would write.
+func (sw *MusicSwitchObject) Size() int64 {
+	return sw.Descriptor.objectSize()
+}
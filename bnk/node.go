@@ -0,0 +1,427 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+)
+
+// The oldest and newest SoundBank versions for which the full layout of a
+// SoundStructure is known.
+const (
+	minKnownLayoutVersion = 118
+	maxKnownLayoutVersion = 135
+)
+
+// The number of bytes used to describe the advanced settings of a
+// SoundStructure.
+const ADVANCED_SETTINGS_BYTES = 6
+
+// The number of bytes used to describe a single point of an RTPC curve.
+const RTPC_POINT_BYTES = 12
+
+// The number of bytes used to describe a ranged parameter value.
+const RANGED_VALUE_BYTES = 8
+
+var errUnknownLayout = errors.New("The layout of this object is unknown for " +
+	"this SoundBank version")
+
+// A layout describes the properties of a SoundBank that determine how its HIRC
+// objects are laid out.
+type layout struct {
+	version uint32
+	// True if objects in this SoundBank carry feedback (motion) information.
+	feedback bool
+}
+
+// layoutOf returns the layout of HIRC objects in the SoundBank with the bank
+// header bkhd, which may be nil if the SoundBank has no header.
+func layoutOf(bkhd *BankHeaderSection) layout {
+	if bkhd == nil {
+		return layout{}
+	}
+	return layout{bkhd.Descriptor.Version, bkhd.feedbackInBank()}
+}
+
+// known returns true if the full layout of a SoundStructure is known for this
+// SoundBank's version.
+func (l layout) known() bool {
+	return l.version >= minKnownLayoutVersion && l.version <= maxKnownLayoutVersion
+}
+
+// readVarUint reads a variable length integer from r. Each byte stores 7 bits
+// of the value, most significant bits first, and has its high bit set if
+// another byte follows.
+func readVarUint(r io.Reader) (uint32, error) {
+	var value uint32
+	for i := 0; i < 5; i++ {
+		var b byte
+		err := binary.Read(r, binary.LittleEndian, &b)
+		if err != nil {
+			return 0, err
+		}
+		value = value<<7 | uint32(b&0x7F)
+		if b&0x80 == 0 {
+			return value, nil
+		}
+	}
+	return 0, errors.New("A variable length integer is longer than 5 bytes")
+}
+
+// varUintBytes returns the variable length encoding of value.
+func varUintBytes(value uint32) []byte {
+	bs := []byte{byte(value & 0x7F)}
+	for value >>= 7; value > 0; value >>= 7 {
+		bs = append([]byte{byte(value&0x7F) | 0x80}, bs...)
+	}
+	return bs
+}
+
+// readCount reads the number of items in a list of an object, which is stored
+// as a uint32 in older SoundBank versions and as a variable length integer in
+// newer ones.
+func (l layout) readCount(r io.Reader) (uint32, error) {
+	if l.version <= 122 {
+		var count uint32
+		err := binary.Read(r, binary.LittleEndian, &count)
+		return count, err
+	}
+	return readVarUint(r)
+}
+
+// countBytes returns the encoding of count as read by readCount.
+func (l layout) countBytes(count uint32) []byte {
+	if l.version <= 122 {
+		var bs [4]byte
+		binary.LittleEndian.PutUint32(bs[:], count)
+		return bs[:]
+	}
+	return varUintBytes(count)
+}
+
+// skip reads and discards n bytes from r.
+func skip(r io.Reader, n int64) error {
+	_, err := io.CopyN(ioutil.Discard, r, n)
+	return err
+}
+
+// readFields reads each of values, which must be pointers to fixed size data,
+// from r in little endian order.
+func readFields(r io.Reader, values ...interface{}) error {
+	for _, v := range values {
+		err := binary.Read(r, binary.LittleEndian, v)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFields writes each of values, which must be fixed size data, to w in
+// little endian order, and returns the number of bytes written.
+func writeFields(w io.Writer, values ...interface{}) (written int64, err error) {
+	for _, v := range values {
+		err = binary.Write(w, binary.LittleEndian, v)
+		if err != nil {
+			return
+		}
+		written += int64(binary.Size(v))
+	}
+	return written, nil
+}
+
+// readStructureTail reads the portion of a SoundStructure that follows its
+// parameters from r, which must be seeked to the start of that portion.
+func (ss *SoundStructure) readStructureTail(r io.Reader, l layout) error {
+	if !l.known() {
+		return errUnknownLayout
+	}
+
+	err := binary.Read(r, binary.LittleEndian, &ss.RangedParameterCount)
+	if err != nil {
+		return err
+	}
+	ss.RangedParameterTypes = make([]byte, ss.RangedParameterCount)
+	err = binary.Read(r, binary.LittleEndian, ss.RangedParameterTypes)
+	if err != nil {
+		return err
+	}
+	ss.RangedParameterValues = make([][RANGED_VALUE_BYTES]byte,
+		ss.RangedParameterCount)
+	err = binary.Read(r, binary.LittleEndian, ss.RangedParameterValues)
+	if err != nil {
+		return err
+	}
+
+	readers := []struct {
+		dst  *[]byte
+		read func(io.Reader, layout) error
+	}{
+		{&ss.Positioning, readPositioning},
+		{&ss.Auxiliary, readAuxiliary},
+		{&ss.AdvancedSettings, readAdvancedSettings},
+		{&ss.States, readStates},
+		{&ss.RTPC, readRTPC},
+		{&ss.Feedback, readFeedback},
+	}
+	for _, rd := range readers {
+		// Capture the raw bytes of each portion as it is read.
+		buf := new(bytes.Buffer)
+		err := rd.read(io.TeeReader(r, buf), l)
+		if err != nil {
+			return err
+		}
+		*rd.dst = buf.Bytes()
+	}
+	ss.decoded = true
+	return nil
+}
+
+// clearStructureTail discards any decoded portion of a SoundStructure that
+// follows its parameters.
+func (ss *SoundStructure) clearStructureTail() {
+	ss.RangedParameterCount = 0
+	ss.RangedParameterTypes = nil
+	ss.RangedParameterValues = nil
+	ss.Positioning = nil
+	ss.Auxiliary = nil
+	ss.AdvancedSettings = nil
+	ss.States = nil
+	ss.RTPC = nil
+	ss.Feedback = nil
+	ss.decoded = false
+}
+
+// writeStructureTail writes the portion of a SoundStructure that follows its
+// parameters to w. It has no effect if the portion was never decoded.
+func (ss *SoundStructure) writeStructureTail(w io.Writer) (written int64, err error) {
+	if !ss.decoded {
+		return 0, nil
+	}
+
+	err = binary.Write(w, binary.LittleEndian, ss.RangedParameterCount)
+	if err != nil {
+		return
+	}
+	written = 1
+	err = binary.Write(w, binary.LittleEndian, ss.RangedParameterTypes)
+	if err != nil {
+		return
+	}
+	written += int64(ss.RangedParameterCount)
+	err = binary.Write(w, binary.LittleEndian, ss.RangedParameterValues)
+	if err != nil {
+		return
+	}
+	written += int64(ss.RangedParameterCount) * RANGED_VALUE_BYTES
+
+	for _, bs := range [][]byte{ss.Positioning, ss.Auxiliary,
+		ss.AdvancedSettings, ss.States, ss.RTPC, ss.Feedback} {
+		n, err := w.Write(bs)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+func readPositioning(r io.Reader, l layout) error {
+	var bits byte
+	err := binary.Read(r, binary.LittleEndian, &bits)
+	if err != nil {
+		return err
+	}
+	overridesParent := bits&0x01 != 0
+
+	var has3d bool
+	if l.version <= 128 {
+		has3d = overridesParent && bits&0x08 != 0
+	} else {
+		// The listener relative routing bit.
+		has3d = overridesParent && bits&0x02 != 0
+	}
+	if !has3d {
+		return nil
+	}
+
+	var bits3d byte
+	err = binary.Read(r, binary.LittleEndian, &bits3d)
+	if err != nil {
+		return err
+	}
+	var hasAutomation bool
+	if l.version <= 128 {
+		// The attenuation ID.
+		err = skip(r, 4)
+		if err != nil {
+			return err
+		}
+		hasAutomation = bits3d&0x03 == 0x02
+	} else {
+		hasAutomation = (bits>>5)&0x03 != 0
+	}
+	if !hasAutomation {
+		return nil
+	}
+
+	// The path mode and transition time.
+	err = skip(r, 1+4)
+	if err != nil {
+		return err
+	}
+	var vertexCount uint32
+	err = binary.Read(r, binary.LittleEndian, &vertexCount)
+	if err != nil {
+		return err
+	}
+	// Each vertex is made up of a 3D coordinate and a duration.
+	err = skip(r, int64(vertexCount)*16)
+	if err != nil {
+		return err
+	}
+	var itemCount uint32
+	err = binary.Read(r, binary.LittleEndian, &itemCount)
+	if err != nil {
+		return err
+	}
+	// Each playlist item has a vertex offset and count, and a 3D range.
+	return skip(r, int64(itemCount)*(8+12))
+}
+
+func readAuxiliary(r io.Reader, l layout) error {
+	var bits byte
+	err := binary.Read(r, binary.LittleEndian, &bits)
+	if err != nil {
+		return err
+	}
+	if bits&0x08 != 0 {
+		// The IDs of the four user auxiliary sends.
+		err = skip(r, 4*4)
+		if err != nil {
+			return err
+		}
+	}
+	if l.version > 134 {
+		// The ID of the reflections auxiliary bus.
+		return skip(r, 4)
+	}
+	return nil
+}
+
+func readAdvancedSettings(r io.Reader, l layout) error {
+	return skip(r, ADVANCED_SETTINGS_BYTES)
+}
+
+func readStates(r io.Reader, l layout) error {
+	if l.version <= 122 {
+		var groupCount uint32
+		err := binary.Read(r, binary.LittleEndian, &groupCount)
+		if err != nil {
+			return err
+		}
+		for i := uint32(0); i < groupCount; i++ {
+			// The state group ID and sync type.
+			err = skip(r, 4+1)
+			if err != nil {
+				return err
+			}
+			var stateCount uint16
+			err = binary.Read(r, binary.LittleEndian, &stateCount)
+			if err != nil {
+				return err
+			}
+			// Each state has an ID and an instance ID.
+			err = skip(r, int64(stateCount)*8)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	propCount, err := readVarUint(r)
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < propCount; i++ {
+		_, err = readVarUint(r)
+		if err != nil {
+			return err
+		}
+		// The accumulation type, and whether the property is in decibels.
+		n := int64(1)
+		if l.version > 126 {
+			n++
+		}
+		err = skip(r, n)
+		if err != nil {
+			return err
+		}
+	}
+	groupCount, err := readVarUint(r)
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < groupCount; i++ {
+		// The state group ID and sync type.
+		err = skip(r, 4+1)
+		if err != nil {
+			return err
+		}
+		stateCount, err := readVarUint(r)
+		if err != nil {
+			return err
+		}
+		err = skip(r, int64(stateCount)*8)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readRTPC(r io.Reader, l layout) error {
+	var count uint16
+	err := binary.Read(r, binary.LittleEndian, &count)
+	if err != nil {
+		return err
+	}
+	for i := uint16(0); i < count; i++ {
+		// The RTPC ID, type and accumulation type.
+		err = skip(r, 4+1+1)
+		if err != nil {
+			return err
+		}
+		_, err = readVarUint(r)
+		if err != nil {
+			return err
+		}
+		// The curve ID and scaling type.
+		err = skip(r, 4+1)
+		if err != nil {
+			return err
+		}
+		var pointCount uint16
+		err = binary.Read(r, binary.LittleEndian, &pointCount)
+		if err != nil {
+			return err
+		}
+		err = skip(r, int64(pointCount)*RTPC_POINT_BYTES)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readFeedback(r io.Reader, l layout) error {
+	if l.version <= 126 && l.feedback {
+		// The ID of the feedback bus.
+		return skip(r, 4)
+	}
+	return nil
+}
@@ -29,12 +29,16 @@ const parameterLoopType = 0x3A
 // The identifier for SFX or Voice sound objects.
 const soundObjectId = 0x02
 
-// The wem is embedded in this sound file.
-const streamSettingEmbedded = 0x00
-
 // Object represents a single object within the HIRC section.
 type Object interface {
 	io.WriterTo
+	// Id returns the ID of this object.
+	Id() uint32
+	// TypeId returns the identifier of the type of this object.
+	TypeId() byte
+	// Size returns the number of bytes that WriteTo would write, as described by
+	// the descriptor of this object.
+	Size() int64
 }
 
 // A ObjectDescriptor describes a single object within a HIRC section.
@@ -45,6 +49,12 @@ type ObjectDescriptor struct {
 	ObjectId uint32
 }
 
+// objectSize returns the number of bytes taken up by the object described by
+// this descriptor, including the descriptor itself.
+func (desc *ObjectDescriptor) objectSize() int64 {
+	return OBJECT_DESCRIPTOR_BYTES - OBJECT_DESCRIPTOR_ID_BYTES + int64(desc.Length)
+}
+
 // An SfxVoiceSoundObject represents a Voice/SFX Sound object within the HIRC
 // section.
 type SfxVoiceSoundObject struct {
@@ -90,6 +100,22 @@ type SoundStructure struct {
 	// A convinience field to determine the number of times this sound loops, wher
 	// 0 means the sound will loop infinite times.
 	loopCount uint32
+
+	// The following fields are only decoded if the layout of this structure is
+	// known for the version of its SoundBank. Otherwise, their data is read by
+	// RemainingReader.
+	RangedParameterCount  byte
+	RangedParameterTypes  []byte
+	RangedParameterValues [][RANGED_VALUE_BYTES]byte
+	Positioning           []byte
+	Auxiliary             []byte
+	AdvancedSettings      []byte
+	States                []byte
+	RTPC                  []byte
+	Feedback              []byte
+	// True if the fields above were decoded.
+	decoded bool
+
 	// A reader to read the remaining data of this structure.
 	RemainingReader io.Reader
 }
@@ -110,8 +136,10 @@ type Effect struct {
 }
 
 // NewSfxVoiceSoundObject creates a new SfxVoiceSoundObject, reading from sr,
-// which must be seeked to the start of the object's data.
-func (desc *ObjectDescriptor) NewSfxVoiceSoundObject(sr util.ReadSeekerAt) (*SfxVoiceSoundObject, error) {
+// which must be seeked to the start of the object's data. bkhd is the header of
+// the SoundBank containing this object, and may be nil.
+func (desc *ObjectDescriptor) NewSfxVoiceSoundObject(sr util.ReadSeekerAt,
+	bkhd *BankHeaderSection) (*SfxVoiceSoundObject, error) {
 	// Get the offset into the file where the data portion of this object begins.
 	startOffset, _ := sr.Seek(0, io.SeekCurrent)
 	// The descriptor length includes the Object ID, which has already been
@@ -138,7 +166,7 @@ func (desc *ObjectDescriptor) NewSfxVoiceSoundObject(sr util.ReadSeekerAt) (*Sfx
 	ssOffset, _ := sr.Seek(0, io.SeekCurrent)
 	remaining := dataLength - (ssOffset - startOffset)
 
-	ss, err := NewSoundStructure(sr, remaining)
+	ss, err := NewSoundStructure(sr, remaining, bkhd)
 	if err != nil {
 		return nil, err
 	}
@@ -182,6 +210,21 @@ func (sound *SfxVoiceSoundObject) WriteTo(w io.Writer) (written int64, err error
 	return written, nil
 }
 
+// Id returns the ID of this object.
+func (sound *SfxVoiceSoundObject) Id() uint32 {
+	return sound.Descriptor.ObjectId
+}
+
+// TypeId returns the identifier of the type of this object.
+func (sound *SfxVoiceSoundObject) TypeId() byte {
+	return sound.Descriptor.Type
+}
+
+// Size returns the number of bytes that WriteTo would write.
+func (sound *SfxVoiceSoundObject) Size() int64 {
+	return sound.Descriptor.objectSize()
+}
+
 // NewUnknownObject creates a new UnknownObject, reading from sr, which must
 // be seeked to the start of the unknown object's data.
 func (desc *ObjectDescriptor) NewUnknownObject(sr util.ReadSeekerAt) (*UnknownObject, error) {
@@ -204,7 +247,7 @@ func (unknown *UnknownObject) WriteTo(w io.Writer) (written int64, err error) {
 	}
 	written = int64(OBJECT_DESCRIPTOR_BYTES)
 
-	n, err := io.Copy(w, unknown.Reader)
+	n, err := util.CopyAll(w, unknown.Reader)
 	if err != nil {
 		return written, err
 	}
@@ -213,11 +256,59 @@ func (unknown *UnknownObject) WriteTo(w io.Writer) (written int64, err error) {
 	return written, nil
 }
 
-// NewSoundStructure creates a new SoundStructure, reading from sr, which must be
-// seeked to the start of the structure's data.
-func NewSoundStructure(sr util.ReadSeekerAt, length int64) (*SoundStructure, error) {
+// Id returns the ID of this object.
+func (unknown *UnknownObject) Id() uint32 {
+	return unknown.Descriptor.ObjectId
+}
+
+// TypeId returns the identifier of the type of this object.
+func (unknown *UnknownObject) TypeId() byte {
+	return unknown.Descriptor.Type
+}
+
+// Size returns the number of bytes that WriteTo would write.
+func (unknown *UnknownObject) Size() int64 {
+	return unknown.Descriptor.objectSize()
+}
+
+// NewSoundStructure creates a new SoundStructure of length bytes, reading from
+// sr, which must be seeked to the start of the structure's data. bkhd is the
+// header of the SoundBank containing this structure, and may be nil.
+func NewSoundStructure(sr util.ReadSeekerAt, length int64,
+	bkhd *BankHeaderSection) (*SoundStructure, error) {
 	// Get the offset into the file where the structure begins.
 	startOffset, _ := sr.Seek(0, io.SeekCurrent)
+	ss, err := readSoundStructure(sr, layoutOf(bkhd))
+	if err != nil {
+		return nil, err
+	}
+
+	currOffset, _ := sr.Seek(0, io.SeekCurrent)
+	if ss.decoded && currOffset-startOffset != length {
+		// The decoded structure does not have the expected length, so the layout
+		// is not what it was expected to be. Leave everything following the
+		// parameters undecoded instead.
+		sr.Seek(startOffset, io.SeekStart)
+		ss, err = readSoundStructure(sr, layout{})
+		if err != nil {
+			return nil, err
+		}
+		currOffset, _ = sr.Seek(0, io.SeekCurrent)
+	}
+
+	// Create a reader over the remaining elements in this object, then seek past
+	// it.
+	remaining := length - (currOffset - startOffset)
+	ss.RemainingReader = util.NewResettingReader(sr, currOffset, remaining)
+	sr.Seek(remaining, io.SeekCurrent)
+	return ss, nil
+}
+
+// readSoundStructure reads a SoundStructure from sr, which must be seeked to
+// the start of the structure's data. If the full layout of the structure is not
+// known for l, only the portion up to the end of its parameters is decoded, and
+// sr is left seeked to that point.
+func readSoundStructure(sr util.ReadSeekerAt, l layout) (*SoundStructure, error) {
 	var override byte
 	err := binary.Read(sr, binary.LittleEndian, &override)
 	if err != nil {
@@ -270,14 +361,19 @@ func NewSoundStructure(sr util.ReadSeekerAt, length int64) (*SoundStructure, err
 		values = append(values, v)
 	}
 
-	// Create a reader over the remaining elements in this object, then seek past
-	// it.
-	currOffset, _ := sr.Seek(0, io.SeekCurrent)
-	remaining := length - (currOffset - startOffset)
-	r := util.NewResettingReader(sr, currOffset, remaining)
-	sr.Seek(remaining, io.SeekCurrent)
-	return &SoundStructure{override, ctr, unknown, count, types, values,
-		loops, loopCount, r}, nil
+	ss := &SoundStructure{OverrideParentEffects: override, EffectContainer: ctr,
+		Unknown: unknown, ParameterCount: count, ParameterTypes: types,
+		ParameterValues: values, loops: loops, loopCount: loopCount,
+		RemainingReader: util.NewResettingReader(sr, 0, 0)}
+
+	tailOffset, _ := sr.Seek(0, io.SeekCurrent)
+	err = ss.readStructureTail(sr, l)
+	if err != nil {
+		// Leave the tail of this structure undecoded.
+		sr.Seek(tailOffset, io.SeekStart)
+		ss.clearStructureTail()
+	}
+	return ss, nil
 }
 
 func (ss *SoundStructure) WriteTo(w io.Writer) (written int64, err error) {
@@ -317,7 +413,13 @@ func (ss *SoundStructure) WriteTo(w io.Writer) (written int64, err error) {
 	}
 	written += int64(ss.ParameterCount) * PARAMETER_VALUE_BYTES
 
-	n, err = io.Copy(w, ss.RemainingReader)
+	n, err = ss.writeStructureTail(w)
+	if err != nil {
+		return written, err
+	}
+	written += n
+
+	n, err = util.CopyAll(w, ss.RemainingReader)
 	if err != nil {
 		return written, err
 	}
@@ -0,0 +1,105 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/util"
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+// PatchWems replaces wems of this SoundBank in place, by writing only the
+// affected DIDX entries and wem data to w, which must hold the file this
+// SoundBank was read from. Every replacement must fit within the wem it
+// replaces and the padding that follows it, so that no other wem moves; the
+// rest of that space is filled with zeroes, and is reported as unused by Verify
+// if it is longer than the padding needed to align the next wem. The HIRC
+// section is rewritten in place with the lengths and durations of the
+// replacements, as ReplaceWems records them; its fields have fixed sizes, so
+// it keeps its size. An error
+// wrapping ErrDoesNotFit is returned, before anything is written, if a
+// replacement does not fit. After PatchWems returns, this File no longer
+// describes w, and should be read again before it is used.
+func (bnk *File) PatchWems(w io.WriterAt, rs ...*wwise.ReplacementWem) error {
+	if bnk.IndexSection == nil || bnk.DataSection == nil {
+		return errors.New("There are no wems stored within this file.")
+	}
+	if len(bnk.Warnings) > 0 {
+		return errors.New("The SoundBank was read with problems, so its wems " +
+			"may not be where its DIDX section describes them")
+	}
+	wems := bnk.Wems()
+	for _, r := range rs {
+		if r.WemIndex < 0 || r.WemIndex >= len(wems) {
+			msg := fmt.Sprintf("There is no wem at index %d to replace", r.WemIndex)
+			return errors.New(msg)
+		}
+		wem := wems[r.WemIndex]
+		slot := int64(wem.Descriptor.Length) + wem.Padding.Size()
+		if r.Length > slot {
+			return fmt.Errorf("%w: replacement for wem %d is %d bytes long, but "+
+				"there are only %d bytes available", ErrDoesNotFit,
+				wem.Descriptor.WemId, r.Length, slot)
+		}
+	}
+
+	// Find the entries of the DIDX section, which are in the same order as wems,
+	// and the HIRC section.
+	entries, hircStart := int64(0), int64(-1)
+	offset := int64(0)
+	for _, s := range bnk.sections {
+		switch {
+		case s == Section(bnk.IndexSection):
+			entries = offset + SECTION_HEADER_BYTES
+		case bnk.ObjectSection != nil && s == Section(bnk.ObjectSection):
+			hircStart = offset
+		}
+		offset += s.Size()
+	}
+	if hircStart >= 0 {
+		size := bnk.ObjectSection.Size()
+		bnk.updateObjectsOf(rs)
+		if bnk.ObjectSection.Size() != size {
+			return errors.New("The HIRC section would change size, so it cannot " +
+				"be patched in place")
+		}
+	}
+
+	for _, r := range rs {
+		wem := wems[r.WemIndex]
+		start := int64(bnk.DataStart()) + int64(wem.Descriptor.Offset)
+		slot := int64(wem.Descriptor.Length) + wem.Padding.Size()
+		ow := io.NewOffsetWriter(w, start)
+		_, err := io.Copy(ow, io.NewSectionReader(r.Wem, 0, r.Length))
+		if err != nil {
+			return err
+		}
+		zeroes := io.NewSectionReader(&util.InfiniteReaderAt{0}, 0, slot-r.Length)
+		_, err = io.Copy(ow, zeroes)
+		if err != nil {
+			return err
+		}
+
+		// The length is the last field of an entry.
+		var length [4]byte
+		binary.LittleEndian.PutUint32(length[:], uint32(r.Length))
+		_, err = w.WriteAt(length[:],
+			entries+int64(r.WemIndex)*DIDX_ENTRY_BYTES+8)
+		if err != nil {
+			return err
+		}
+	}
+
+	if hircStart >= 0 {
+		_, err := bnk.ObjectSection.WriteTo(io.NewOffsetWriter(w, hircStart))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var platHeaderId = [4]byte{'P', 'L', 'A', 'T'}
+
+// The last SoundBank version whose platform name is preceded by its length,
+// rather than followed by a zero byte.
+const lastSizedPlatformVersion = 136
+
+// A PlatformSection represents the PLAT section of a SoundBank file, which
+// names the custom platform, such as "Windows", that the SoundBank was
+// generated for.
+type PlatformSection struct {
+	Header   *SectionHeader
+	Platform string
+	// True if the name is followed by a zero byte, rather than preceded by its
+	// length.
+	terminated bool
+	// The data that follows the name, which is kept as is.
+	Remaining []byte
+}
+
+// NewPlatformSection creates a new PlatformSection, reading from r, which must
+// be seeked to the start of the PLAT section data. bkhd is the header of the
+// SoundBank, which decides how the platform name is stored.
+// An error is returned if this method is called on a non-PLAT header.
+func (hdr *SectionHeader) NewPlatformSection(r io.Reader,
+	bkhd *BankHeaderSection) (*PlatformSection, error) {
+	if hdr.Identifier != platHeaderId {
+		msg := fmt.Sprintf("Expected PLAT header but got: %s", hdr.Identifier)
+		return nil, errors.New(msg)
+	}
+	data := make([]byte, hdr.Length)
+	_, err := io.ReadFull(r, data)
+	if err != nil {
+		return nil, err
+	}
+
+	sec := &PlatformSection{Header: hdr}
+	sec.terminated = layoutOf(bkhd).version > lastSizedPlatformVersion
+	if sec.terminated {
+		end := bytes.IndexByte(data, 0)
+		if end < 0 {
+			return nil, errors.New("The platform name is not terminated")
+		}
+		sec.Platform = string(data[:end])
+		sec.Remaining = data[end+1:]
+		return sec, nil
+	}
+
+	br := bytes.NewReader(data)
+	var size uint32
+	err = readFields(br, &size)
+	if err != nil {
+		return nil, err
+	}
+	if int64(size) > int64(br.Len()) {
+		return nil, errors.New("The platform name is longer than the section")
+	}
+	name := make([]byte, size)
+	br.Read(name)
+	sec.Platform = string(name)
+	sec.Remaining = data[len(data)-br.Len():]
+	return sec, nil
+}
+
+// WriteTo writes the full contents of this PlatformSection to the Writer
+// specified by w.
+func (plat *PlatformSection) WriteTo(w io.Writer) (written int64, err error) {
+	plat.Header.Length = plat.Length()
+	if plat.terminated {
+		return writeFields(w, plat.Header, []byte(plat.Platform), byte(0),
+			plat.Remaining)
+	}
+	return writeFields(w, plat.Header, uint32(len(plat.Platform)),
+		[]byte(plat.Platform), plat.Remaining)
+}
+
+// Identifier returns the four character identifier of this section.
+func (plat *PlatformSection) Identifier() string {
+	return string(plat.Header.Identifier[:])
+}
+
+// Length returns the length in bytes of the data of this section.
+func (plat *PlatformSection) Length() uint32 {
+	return uint32(plat.Size() - SECTION_HEADER_BYTES)
+}
+
+// Size returns the number of bytes that WriteTo would write.
+func (plat *PlatformSection) Size() int64 {
+	size := int64(SECTION_HEADER_BYTES + len(plat.Platform) + len(plat.Remaining))
+	if plat.terminated {
+		return size + 1
+	}
+	return size + 4
+}
+
+func (plat *PlatformSection) String() string {
+	return fmt.Sprintf("%s: len(%d) platform(%s)\n", plat.Header.Identifier,
+		plat.Header.Length, plat.Platform)
+}
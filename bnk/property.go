@@ -0,0 +1,150 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// A Property names a common property of a HIRC object, which is stored as one
+// of its parameters.
+type Property string
+
+const (
+	// The volume of the object, in decibels.
+	PropertyVolume Property = "volume"
+	// The pitch of the object, in cents.
+	PropertyPitch Property = "pitch"
+	// The make-up gain of the object, in decibels.
+	PropertyMakeUpGain Property = "makeup-gain"
+	// The number of times the object loops, where 0 loops it infinitely.
+	PropertyLoop Property = "loop"
+)
+
+// The parameter type that stores each property.
+var propertyTypes = map[Property]byte{
+	PropertyVolume:     parameterVolumeType,
+	PropertyPitch:      0x02,
+	PropertyMakeUpGain: 0x06,
+	PropertyLoop:       parameterLoopType,
+}
+
+// Properties returns the properties that can be set with SetProperty.
+func Properties() []Property {
+	return []Property{PropertyVolume, PropertyPitch, PropertyMakeUpGain,
+		PropertyLoop}
+}
+
+// SetProperty sets the property p of the object with the given ID to value,
+// adding the property to the object if it is not already set. The value of
+// PropertyLoop must be a non-negative integer. Sounds, containers, music
+// objects and buses have properties.
+func (hrc *ObjectHierarchySection) SetProperty(id uint32, p Property,
+	value float64) error {
+	paramType, ok := propertyTypes[p]
+	if !ok {
+		return fmt.Errorf("%s is not a known property", p)
+	}
+	var bits [4]byte
+	if p == PropertyLoop {
+		if value < 0 || value > math.MaxUint32 || value != math.Trunc(value) {
+			return fmt.Errorf("%v is not a valid loop count", value)
+		}
+		binary.LittleEndian.PutUint32(bits[:], uint32(value))
+	} else {
+		binary.LittleEndian.PutUint32(bits[:], math.Float32bits(float32(value)))
+	}
+
+	obj := hrc.objectOf[id]
+	if bus, ok := obj.(*BusObject); ok {
+		if !canAddParameter(bus.ParameterTypes, paramType) {
+			return fmt.Errorf("Object %d cannot have more than %d parameters", id,
+				math.MaxUint8)
+		}
+		grew := setParameter(&bus.ParameterTypes, &bus.ParameterValues, paramType,
+			bits)
+		if grew {
+			bus.Descriptor.Length += PARAMETER_TYPE_BYTES + PARAMETER_VALUE_BYTES
+		}
+		return nil
+	}
+	ss, desc := structureOf(obj)
+	if ss == nil {
+		return fmt.Errorf("Object %d does not have properties", id)
+	}
+	if !canAddParameter(ss.ParameterTypes, paramType) {
+		return fmt.Errorf("Object %d cannot have more than %d parameters", id,
+			math.MaxUint8)
+	}
+	if setParameter(&ss.ParameterTypes, &ss.ParameterValues, paramType, bits) {
+		ss.ParameterCount++
+		desc.Length += PARAMETER_TYPE_BYTES + PARAMETER_VALUE_BYTES
+	}
+
+	if p == PropertyLoop {
+		ss.loops, ss.loopCount = true, uint32(value)
+		if sound, ok := obj.(*SfxVoiceSoundObject); ok {
+			hrc.loopOf[sound.WemDescriptor.WemId] = ss.loopCount
+		}
+	}
+	return nil
+}
+
+// Property returns the value of the property p of the object with the given ID,
+// and true if the object sets it.
+func (hrc *ObjectHierarchySection) Property(id uint32, p Property) (float64,
+	bool) {
+	paramType, ok := propertyTypes[p]
+	if !ok {
+		return 0, false
+	}
+	var types []byte
+	var values [][4]byte
+	obj := hrc.objectOf[id]
+	if bus, ok := obj.(*BusObject); ok {
+		types, values = bus.ParameterTypes, bus.ParameterValues
+	} else if ss, _ := structureOf(obj); ss != nil {
+		types, values = ss.ParameterTypes, ss.ParameterValues
+	}
+	for i, t := range types {
+		if t != paramType {
+			continue
+		}
+		bits := binary.LittleEndian.Uint32(values[i][:])
+		if p == PropertyLoop {
+			return float64(bits), true
+		}
+		return float64(math.Float32frombits(bits)), true
+	}
+	return 0, false
+}
+
+// canAddParameter returns true if the parameter of the given type can be set
+// on an object with the parameters types, whose count is stored in a byte.
+func canAddParameter(types []byte, paramType byte) bool {
+	if len(types) < math.MaxUint8 {
+		return true
+	}
+	for _, t := range types {
+		if t == paramType {
+			return true
+		}
+	}
+	return false
+}
+
+// setParameter sets the parameter of the given type to value, appending it to
+// types and values if it is not present. Returns true if it was appended.
+func setParameter(types *[]byte, values *[][4]byte, paramType byte,
+	value [4]byte) bool {
+	for i, t := range *types {
+		if t == paramType {
+			(*values)[i] = value
+			return false
+		}
+	}
+	*types = append(*types, paramType)
+	*values = append(*values, value)
+	return true
+}
@@ -0,0 +1,77 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+// Embedded returns true if the wem played by this sound is embedded in its
+// SoundBank, rather than streamed.
+func (sound *SfxVoiceSoundObject) Embedded() bool {
+	return sound.StreamType() == StreamEmbedded
+}
+
+// CheckReferences returns a *ReferenceError for every reference from a HIRC
+// object of this SoundBank that cannot be found: actions of events, targets of
+// actions and children of containers that are not stored in the HIRC section,
+// and embedded wems that are not stored in the DIDX section. Streamed wems are
+// only checked if streamed is not nil, in which case they must be stored in the
+// DIDX section or be keys of streamed. The errors are returned in the order
+// that their objects appear in the file.
+func (bnk *File) CheckReferences(streamed map[uint32]bool) []error {
+	hrc := bnk.ObjectSection
+	if hrc == nil {
+		return nil
+	}
+	stored := make(map[uint32]bool)
+	for _, wem := range bnk.Wems() {
+		stored[wem.Descriptor.WemId] = true
+	}
+
+	var problems []error
+	object := func(obj Object, kind string, id uint32) {
+		if hrc.objectOf[id] == nil {
+			problems = append(problems, &ReferenceError{obj.Id(), kind, id})
+		}
+	}
+	wem := func(obj Object, id uint32, embedded bool) {
+		if stored[id] || !embedded && (streamed == nil || streamed[id]) {
+			return
+		}
+		kind := "wem"
+		if !embedded {
+			kind = "streamed wem"
+		}
+		problems = append(problems, &ReferenceError{obj.Id(), kind, id})
+	}
+	children := func(obj Object, ids []uint32) {
+		for _, id := range ids {
+			object(obj, "child", id)
+		}
+	}
+
+	for _, obj := range hrc.objects {
+		switch obj := obj.(type) {
+		case *EventObject:
+			for _, id := range obj.ActionIds {
+				object(obj, "action", id)
+			}
+		case *ActionObject:
+			if obj.IsBus == 0 && obj.TargetId != 0 {
+				object(obj, "target", obj.TargetId)
+			}
+		case *SfxVoiceSoundObject:
+			wem(obj, obj.WemDescriptor.WemId, obj.Embedded())
+		case *ContainerObject:
+			children(obj, obj.ChildIds)
+		case *MusicPlaylistObject:
+			children(obj, obj.ChildIds)
+		case *MusicSwitchObject:
+			children(obj, obj.ChildIds)
+		case *MusicSegmentObject:
+			children(obj, obj.ChildIds)
+		case *MusicTrackObject:
+			for _, src := range obj.Sources {
+				wem(obj, src.SourceId,
+					StreamType(src.StreamType) == StreamEmbedded)
+			}
+		}
+	}
+	return problems
+}
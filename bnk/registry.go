@@ -0,0 +1,92 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/util"
+)
+
+// A SectionParser decodes a section that is not known to this package, such
+// as a custom section written by a game. hdr describes the section, r reads
+// its data, and bkhd is the header of the SoundBank being read, or nil if it
+// has none. The returned Section is written in place of the original section
+// when the SoundBank is written.
+type SectionParser func(hdr *SectionHeader, r io.Reader,
+	bkhd *BankHeaderSection) (Section, error)
+
+var sectionParsersMu sync.RWMutex
+
+// The parsers registered for each section identifier.
+var sectionParsers = make(map[[4]byte]SectionParser)
+
+// The identifiers of the sections that this package decodes itself.
+var builtinSections = [][4]byte{bkhdHeaderId, didxHeaderId, dataHeaderId,
+	hircHeaderId, stidHeaderId, stmgHeaderId, envsHeaderId, platHeaderId}
+
+// RegisterSectionParser registers parse to decode every section with the given
+// four character identifier that is read after this call. Registering a parser
+// for an identifier replaces the parser registered before it, and registering
+// nil removes it. The sections decoded by this package itself cannot be
+// registered.
+func RegisterSectionParser(identifier string, parse SectionParser) error {
+	if len(identifier) != 4 {
+		msg := fmt.Sprintf("The section identifier \"%s\" is not four characters "+
+			"long", identifier)
+		return errors.New(msg)
+	}
+	var id [4]byte
+	copy(id[:], identifier)
+	for _, builtin := range builtinSections {
+		if id == builtin {
+			msg := fmt.Sprintf("The %s section is decoded by this package, and "+
+				"cannot be registered", identifier)
+			return errors.New(msg)
+		}
+	}
+
+	sectionParsersMu.Lock()
+	defer sectionParsersMu.Unlock()
+	if parse == nil {
+		delete(sectionParsers, id)
+		return nil
+	}
+	sectionParsers[id] = parse
+	return nil
+}
+
+// sectionParserOf returns the parser registered for sections with the given
+// identifier, or nil if there is none.
+func sectionParserOf(id [4]byte) SectionParser {
+	sectionParsersMu.RLock()
+	defer sectionParsersMu.RUnlock()
+	return sectionParsers[id]
+}
+
+// readRegisteredSection decodes the section described by hdr with parse,
+// reading from sr, which must be seeked to the start of the section data. The
+// parser is given only the data of the section, and sr is seeked past it.
+func (bnk *File) readRegisteredSection(hdr *SectionHeader,
+	sr util.ReadSeekerAt, parse SectionParser) (Section, error) {
+	data := make([]byte, hdr.Length)
+	_, err := io.ReadFull(sr, data)
+	if err != nil {
+		return nil, err
+	}
+	sec, err := parse(hdr, bytes.NewReader(data), bnk.BankHeaderSection)
+	if err != nil {
+		return nil, err
+	}
+	if sec == nil {
+		msg := fmt.Sprintf("The parser of the %s section returned no section",
+			hdr.Identifier)
+		return nil, errors.New(msg)
+	}
+	return sec, nil
+}
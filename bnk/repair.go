@@ -0,0 +1,320 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The identifiers that begin every wem, in little and big endian byte order.
+var riffId = [4]byte{'R', 'I', 'F', 'F'}
+var rifxId = [4]byte{'R', 'I', 'F', 'X'}
+
+// The number of bytes of the identifier and length that begin every wem.
+const RIFF_HEADER_BYTES = 8
+
+// The identifiers of the sections that Repair looks for when the length of a
+// section cannot be trusted.
+var knownSectionIds = [][4]byte{bkhdHeaderId, didxHeaderId, dataHeaderId,
+	hircHeaderId, stidHeaderId, stmgHeaderId, envsHeaderId, platHeaderId}
+
+// A RecoveredWem describes a wem found in the DATA section by Repair.
+type RecoveredWem struct {
+	Id uint32
+	// The offset into the data of the DATA section where this wem begins.
+	Offset int64
+	// The length in bytes of this wem. This is shorter than the length in its
+	// RIFF header if the wem is cut off by the end of the DATA section.
+	Length int64
+	// True if the ID of this wem was taken from the DIDX section, rather than
+	// assigned by Repair.
+	IdRecovered bool
+}
+
+func (w RecoveredWem) String() string {
+	source := "assigned"
+	if w.IdRecovered {
+		source = "from DIDX"
+	}
+	return fmt.Sprintf("wem %d (%s) at offset %d, %d bytes", w.Id, source,
+		w.Offset, w.Length)
+}
+
+// A rawSection is a section located in a SoundBank, that has not been parsed.
+type rawSection struct {
+	hdr    *SectionHeader
+	offset int64
+	length int64
+}
+
+// Repair reads the SoundBank stored in the first size bytes of r, whose DIDX
+// section may be corrupt or missing, and rebuilds its DIDX section from the
+// wems found in its DATA section. Wems are found by scanning the DATA section
+// for RIFF headers. A wem keeps the ID given by the DIDX section if it has an
+// entry at the offset of the wem; otherwise it is assigned the lowest ID that
+// is not already in use.
+// The length of a section is not trusted if it runs past the end of r, or if
+// no section begins where it ends; the section is then taken to end where the
+// next known section, such as the DATA section, is found, or at the end of r.
+// The repaired SoundBank holds the BKHD section of r, the rebuilt DIDX and DATA
+// sections and then the remaining sections of r, in the order they appear.
+// The wems of the repaired SoundBank are read from r, which must remain open
+// until it has been written.
+func Repair(r io.ReaderAt, size int64) (*File, []RecoveredWem, error) {
+	var sections []rawSection
+	offset := int64(0)
+	for offset+SECTION_HEADER_BYTES <= size {
+		hr := io.NewSectionReader(r, offset, SECTION_HEADER_BYTES)
+		hdr, err := readSectionHeader(hr)
+		if err != nil {
+			return nil, nil, err
+		}
+		length := int64(hdr.Length)
+		end := offset + SECTION_HEADER_BYTES + length
+		if end > size || (end < size && !identifierAt(r, end, size)) {
+			// A length that fits is kept if no known section follows, as the
+			// SoundBank may just be padded.
+			next := nextSection(r, offset+SECTION_HEADER_BYTES, size)
+			if next < size || end > size {
+				length = next - offset - SECTION_HEADER_BYTES
+			}
+		}
+		sections = append(sections, rawSection{hdr, offset, length})
+		offset += SECTION_HEADER_BYTES + length
+	}
+	if len(sections) == 0 || sections[0].hdr.Identifier != bkhdHeaderId {
+		return nil, nil, ErrNotASoundBank
+	}
+
+	bnk := new(File)
+	err := bnk.addRawSection(r, sections[0])
+	if err != nil {
+		return nil, nil, &SectionError{"BKHD", 0, err}
+	}
+	idOf := make(map[int64]uint32)
+	var data *rawSection
+	for i, s := range sections {
+		switch s.hdr.Identifier {
+		case didxHeaderId:
+			idOf = recoverIds(io.NewSectionReader(r,
+				s.offset+SECTION_HEADER_BYTES, s.length), s.length)
+		case dataHeaderId:
+			data = &sections[i]
+		}
+	}
+	if data == nil {
+		return nil, nil, errors.New("The SoundBank has no DATA section to " +
+			"recover wems from")
+	}
+
+	dataStart := data.offset + SECTION_HEADER_BYTES
+	wems, err := scanWems(io.NewSectionReader(r, dataStart, data.length),
+		data.length)
+	if err != nil {
+		return nil, nil, &SectionError{"DATA", data.offset, err}
+	}
+	if len(wems) == 0 {
+		return nil, nil, errors.New("No wems were found in the DATA section")
+	}
+	assignIds(wems, idOf)
+
+	var sources []WemSource
+	for _, wem := range wems {
+		sources = append(sources, WemSource{wem.Id,
+			io.NewSectionReader(r, dataStart+wem.Offset, wem.Length), wem.Length})
+	}
+	err = bnk.addWems(sources)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, s := range sections[1:] {
+		switch s.hdr.Identifier {
+		case bkhdHeaderId, didxHeaderId, dataHeaderId:
+			continue
+		}
+		err := bnk.addRawSection(r, s)
+		if err != nil {
+			return nil, nil, &SectionError{string(s.hdr.Identifier[:]), s.offset,
+				err}
+		}
+	}
+	return bnk, wems, nil
+}
+
+// identifierAt returns true if a section identifier, which Wwise makes of four
+// upper case letters or digits, is found at offset in the first size bytes of
+// r.
+func identifierAt(r io.ReaderAt, offset, size int64) bool {
+	var id [4]byte
+	if offset+int64(len(id)) > size {
+		return false
+	}
+	_, err := r.ReadAt(id[:], offset)
+	if err != nil {
+		return false
+	}
+	for _, b := range id {
+		if (b < 'A' || b > 'Z') && (b < '0' || b > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// knownSectionAt returns true if a section header with one of the
+// knownSectionIds, whose section fits in the first size bytes of r, is found at
+// offset.
+func knownSectionAt(r io.ReaderAt, offset, size int64) bool {
+	if offset+SECTION_HEADER_BYTES > size {
+		return false
+	}
+	hdr, err := readSectionHeader(io.NewSectionReader(r, offset,
+		SECTION_HEADER_BYTES))
+	if err != nil {
+		return false
+	}
+	if offset+SECTION_HEADER_BYTES+int64(hdr.Length) > size {
+		return false
+	}
+	for _, id := range knownSectionIds {
+		if hdr.Identifier == id {
+			return true
+		}
+	}
+	return false
+}
+
+// nextSection returns the offset of the first section header with one of the
+// knownSectionIds found at or after offset in the first size bytes of r, or
+// size if there is none.
+func nextSection(r io.ReaderAt, offset, size int64) int64 {
+	br := bufio.NewReader(io.NewSectionReader(r, offset, size-offset))
+	var window [4]byte
+	for i := offset; i < size; i++ {
+		b, err := br.ReadByte()
+		if err != nil {
+			break
+		}
+		copy(window[:], window[1:])
+		window[3] = b
+		start := i - 3
+		if start < offset {
+			continue
+		}
+		for _, id := range knownSectionIds {
+			if window == id && knownSectionAt(r, start, size) {
+				return start
+			}
+		}
+	}
+	return size
+}
+
+// scanWems returns the wems found in the first length bytes of the data of a
+// DATA section, read from r, without assigning them IDs. A wem is found
+// wherever a RIFF header begins at an offset aligned as Wwise aligns wems.
+func scanWems(r io.ReaderAt, length int64) ([]RecoveredWem, error) {
+	var wems []RecoveredWem
+	offset := int64(0)
+	for offset+RIFF_HEADER_BYTES <= length {
+		var hdr [RIFF_HEADER_BYTES]byte
+		_, err := r.ReadAt(hdr[:], offset)
+		if err != nil {
+			return nil, err
+		}
+		var id [4]byte
+		copy(id[:], hdr[:4])
+		var riffLength int64
+		switch id {
+		case riffId:
+			riffLength = int64(binary.LittleEndian.Uint32(hdr[4:]))
+		case rifxId:
+			riffLength = int64(binary.BigEndian.Uint32(hdr[4:]))
+		default:
+			offset += wemAlignmentBytes
+			continue
+		}
+
+		wemLength := RIFF_HEADER_BYTES + riffLength
+		if offset+wemLength > length {
+			wemLength = length - offset
+		}
+		wems = append(wems, RecoveredWem{0, offset, wemLength, false})
+		end := offset + wemLength
+		offset = (end + wemAlignmentBytes - 1) / wemAlignmentBytes *
+			wemAlignmentBytes
+	}
+	return wems, nil
+}
+
+// recoverIds reads what it can of the wem descriptors of a DIDX section of the
+// given length from r, and returns the ID of the wem at each offset. An ID that
+// is repeated is only kept for its first offset.
+func recoverIds(r io.Reader, length int64) map[int64]uint32 {
+	idOf := make(map[int64]uint32)
+	seen := make(map[uint32]bool)
+	for i := int64(0); i < length/DIDX_ENTRY_BYTES; i++ {
+		var desc [3]uint32
+		err := binary.Read(r, binary.LittleEndian, &desc)
+		if err != nil {
+			break
+		}
+		id, offset := desc[0], int64(desc[1])
+		if _, ok := idOf[offset]; ok || seen[id] {
+			continue
+		}
+		seen[id] = true
+		idOf[offset] = id
+	}
+	return idOf
+}
+
+// assignIds sets the ID of every wem in wems, using the ID recorded in idOf for
+// its offset if there is one, and otherwise the lowest ID not in use.
+func assignIds(wems []RecoveredWem, idOf map[int64]uint32) {
+	used := make(map[uint32]bool)
+	for i := range wems {
+		if id, ok := idOf[wems[i].Offset]; ok {
+			wems[i].Id, wems[i].IdRecovered = id, true
+			used[id] = true
+		}
+	}
+	next := uint32(1)
+	for i := range wems {
+		if wems[i].IdRecovered {
+			continue
+		}
+		for used[next] {
+			next++
+		}
+		wems[i].Id = next
+		used[next] = true
+	}
+}
+
+// addRawSection reads the section s from r, and adds it to the end of this
+// File. A section that cannot be parsed is added as an UnknownSection, so that
+// its data is kept.
+func (bnk *File) addRawSection(r io.ReaderAt, s rawSection) error {
+	b := make([]byte, SECTION_HEADER_BYTES+s.length)
+	_, err := r.ReadAt(b, s.offset)
+	if err != nil {
+		return err
+	}
+	binary.LittleEndian.PutUint32(b[4:], uint32(s.length))
+	err = bnk.addSectionBytes(b)
+	if err == nil || s.hdr.Identifier == bkhdHeaderId {
+		return err
+	}
+	raw, err := NewRawSection(string(s.hdr.Identifier[:]),
+		b[SECTION_HEADER_BYTES:])
+	if err != nil {
+		return err
+	}
+	bnk.sections = append(bnk.sections, raw)
+	return nil
+}
@@ -0,0 +1,146 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// An RTPC (Real-Time Parameter Control) drives a property of an object, such
+// as its volume or pitch, from the value of a game parameter.
+type RTPC struct {
+	// The ID of the game parameter that drives the property.
+	Id uint32
+	// The type of the game parameter, such as a game parameter or a MIDI value.
+	Type byte
+	// How the value of the curve is combined with the value of the property.
+	Accumulation byte
+	// The ID of the property that is driven, such as volume or pitch.
+	ParameterId uint32
+	CurveId     uint32
+	// The scaling of the curve's Y axis, such as 2 for curves in decibels.
+	Scaling byte
+	// The points of the curve, mapping values of the game parameter (From) to
+	// values of the property (To).
+	Points []GraphPoint
+}
+
+// RTPCs returns the RTPCs of this SoundStructure. It is an error to call this
+// method on a structure whose layout is unknown for its SoundBank's version.
+func (ss *SoundStructure) RTPCs() ([]RTPC, error) {
+	if !ss.decoded {
+		return nil, errUnknownLayout
+	}
+	return decodeRTPCs(ss.RTPC)
+}
+
+// decodeRTPCs decodes a list of RTPCs as read by readRTPC.
+func decodeRTPCs(bs []byte) ([]RTPC, error) {
+	r := bytes.NewReader(bs)
+	var count uint16
+	err := binary.Read(r, binary.LittleEndian, &count)
+	if err != nil {
+		return nil, err
+	}
+	rtpcs := make([]RTPC, count)
+	for i := range rtpcs {
+		rtpc := &rtpcs[i]
+		err = readFields(r, &rtpc.Id, &rtpc.Type, &rtpc.Accumulation)
+		if err != nil {
+			return nil, err
+		}
+		rtpc.ParameterId, err = readVarUint(r)
+		if err != nil {
+			return nil, err
+		}
+		var pointCount uint16
+		err = readFields(r, &rtpc.CurveId, &rtpc.Scaling, &pointCount)
+		if err != nil {
+			return nil, err
+		}
+		if int(pointCount)*RTPC_POINT_BYTES > r.Len() {
+			return nil, io.ErrUnexpectedEOF
+		}
+		rtpc.Points = make([]GraphPoint, pointCount)
+		err = readFields(r, rtpc.Points)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if r.Len() != 0 {
+		msg := fmt.Sprintf("%d bytes remain after the last RTPC", r.Len())
+		return nil, errors.New(msg)
+	}
+	return rtpcs, nil
+}
+
+// encodeRTPCs returns the encoding of rtpcs as read by readRTPC.
+func encodeRTPCs(rtpcs []RTPC) ([]byte, error) {
+	if len(rtpcs) > 0xFFFF {
+		return nil, errors.New("An object cannot have more than 65535 RTPCs")
+	}
+	b := new(bytes.Buffer)
+	writeFields(b, uint16(len(rtpcs)))
+	for i, rtpc := range rtpcs {
+		if len(rtpc.Points) > 0xFFFF {
+			msg := fmt.Sprintf("RTPC %d has more than 65535 points", i)
+			return nil, errors.New(msg)
+		}
+		writeFields(b, rtpc.Id, rtpc.Type, rtpc.Accumulation,
+			varUintBytes(rtpc.ParameterId), rtpc.CurveId, rtpc.Scaling,
+			uint16(len(rtpc.Points)), rtpc.Points)
+	}
+	return b.Bytes(), nil
+}
+
+// structureOf returns the SoundStructure and descriptor of obj, or nil if obj
+// does not have a structure.
+func structureOf(obj Object) (*SoundStructure, *ObjectDescriptor) {
+	switch obj := obj.(type) {
+	case *SfxVoiceSoundObject:
+		return obj.Structure, obj.Descriptor
+	case *ContainerObject:
+		return obj.Structure, obj.Descriptor
+	case *MusicTrackObject:
+		return obj.Structure, obj.Descriptor
+	case *MusicSegmentObject:
+		return obj.Structure, obj.Descriptor
+	case *MusicPlaylistObject:
+		return obj.Structure, obj.Descriptor
+	case *MusicSwitchObject:
+		return obj.Structure, obj.Descriptor
+	}
+	return nil, nil
+}
+
+// RTPCsOf returns the RTPCs of the object with the given ID.
+func (hrc *ObjectHierarchySection) RTPCsOf(id uint32) ([]RTPC, error) {
+	ss, _ := structureOf(hrc.objectOf[id])
+	if ss == nil {
+		return nil, fmt.Errorf("Object %d does not have RTPCs", id)
+	}
+	return ss.RTPCs()
+}
+
+// ReplaceRTPCsOf replaces the RTPCs of the object with the given ID with
+// rtpcs, updating the length of the object.
+func (hrc *ObjectHierarchySection) ReplaceRTPCsOf(id uint32,
+	rtpcs []RTPC) error {
+	ss, desc := structureOf(hrc.objectOf[id])
+	if ss == nil {
+		return fmt.Errorf("Object %d does not have RTPCs", id)
+	}
+	if !ss.decoded {
+		return errUnknownLayout
+	}
+	bs, err := encodeRTPCs(rtpcs)
+	if err != nil {
+		return err
+	}
+	desc.Length = uint32(int64(desc.Length) + int64(len(bs)-len(ss.RTPC)))
+	ss.RTPC = bs
+	return nil
+}
@@ -2,9 +2,14 @@
 package bnk
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
+	"math"
+	"strconv"
 	"strings"
 )
 
@@ -20,6 +25,13 @@ const SECTION_HEADER_BYTES = 8
 // excluding its own header.
 const BKHD_SECTION_BYTES = 8
 
+// The number of bytes used to describe the BankSettings of a BKHD section.
+const BKHD_SETTINGS_BYTES = 12
+
+// The newest SoundBank version that records whether its objects carry feedback
+// information, rather than its alignment.
+const lastFeedbackVersion = 126
+
 // The number of bytes used to describe a single data index
 // entry (a WemDescriptor) within the DIDX section.
 const DIDX_ENTRY_BYTES = 12
@@ -39,10 +51,24 @@ var dataHeaderId = [4]byte{'D', 'A', 'T', 'A'}
 // The identifier for the start of the HIRC section.
 var hircHeaderId = [4]byte{'H', 'I', 'R', 'C'}
 
+// The identifier for the start of the STID (String ID) section.
+var stidHeaderId = [4]byte{'S', 'T', 'I', 'D'}
+
+// The type of the strings of an STID section that names SoundBanks.
+const stidBankNameType = 1
+
 // Section represents a single section of a Wwise SoundBank.
 type Section interface {
 	io.WriterTo
 	fmt.Stringer
+	// Size returns the number of bytes that WriteTo would write.
+	Size() int64
+	// Identifier returns the four character identifier of this section, such as
+	// "DIDX".
+	Identifier() string
+	// Length returns the length in bytes of the data of this section, as stored
+	// in its header.
+	Length() uint32
 }
 
 // A SectionHeader represents a single Wwise SoundBank header.
@@ -51,10 +77,35 @@ type SectionHeader struct {
 	Length     uint32
 }
 
+// The most wems that are allocated for up front when a DIDX section is read.
+const maxPreallocatedWems = 1 << 16
+
+// The number of wem descriptors that are allocated at once when a DIDX section
+// is read.
+const descriptorBlockSize = 256
+
+// readSectionHeader reads a SectionHeader from r. It decodes the header by
+// hand, as it is read for every section.
+func readSectionHeader(r io.Reader) (*SectionHeader, error) {
+	var b [SECTION_HEADER_BYTES]byte
+	_, err := io.ReadFull(r, b[:])
+	if err != nil {
+		return nil, err
+	}
+	hdr := new(SectionHeader)
+	copy(hdr.Identifier[:], b[:4])
+	hdr.Length = binary.LittleEndian.Uint32(b[4:])
+	return hdr, nil
+}
+
 // A BankHeaderSection represents the BKHD section of a SoundBank file.
 type BankHeaderSection struct {
-	Header          *SectionHeader
-	Descriptor      BankDescriptor
+	Header     *SectionHeader
+	Descriptor BankDescriptor
+	// The settings that follow the descriptor, or nil if the section is too
+	// short to hold them.
+	Settings *BankSettings
+	// A reader to read the data that follows the settings.
 	RemainingReader io.Reader
 }
 
@@ -64,6 +115,25 @@ type BankDescriptor struct {
 	BankId  uint32
 }
 
+// A BankSettings describes the language, project and loading settings of a
+// SoundBank. Which fields are stored depends on the SoundBank version.
+type BankSettings struct {
+	// The ID of the language of the wems of this SoundBank. Versions up to 122
+	// number languages, and later versions use the hash of the language name.
+	LanguageId uint32
+	// Non-zero if the objects of this SoundBank carry feedback information. Only
+	// stored by versions up to 126.
+	FeedbackInBank uint32
+	// The alignment, in bytes, of the SoundBank once loaded. Only stored by
+	// versions after 126.
+	Alignment uint16
+	// Non-zero if the SoundBank is loaded into device memory. Only stored by
+	// versions after 126.
+	DeviceAllocated uint16
+	// The ID of the Wwise project that generated this SoundBank.
+	ProjectId uint32
+}
+
 // A DataIndexSection represents the DIDX section of a SoundBank file.
 type DataIndexSection struct {
 	Header *SectionHeader
@@ -71,8 +141,11 @@ type DataIndexSection struct {
 	WemCount int
 	// A list of all wem IDs, in order of their offset into the file.
 	WemIds []uint32
-	// A mapping from wem ID to its descriptor.
+	// A mapping from wem ID to its descriptor. If an ID is repeated, it maps to
+	// the descriptor of the first wem with that ID.
 	DescriptorMap map[uint32]*wwise.WemDescriptor
+	// The descriptor of every wem, in the same order as WemIds.
+	Descriptors []*wwise.WemDescriptor
 }
 
 // A DataIndexSection represents the DATA section of a SoundBank file.
@@ -96,6 +169,23 @@ type ObjectHierarchySection struct {
 	// infinity.
 	loopOf      map[uint32]uint32
 	wemToObject map[uint32]*SfxVoiceSoundObject
+	objectOf    map[uint32]Object
+}
+
+// A StringIdSection represents the STID section of a SoundBank file, which maps
+// the IDs of SoundBanks to their names.
+type StringIdSection struct {
+	Header *SectionHeader
+	// The type of the strings in this section. SoundBank names are the only
+	// known type.
+	Type uint32
+	// A list of all SoundBank IDs, in the order they are stored in the section.
+	// An ID may be stored more than once.
+	BankIds []uint32
+	// The name stored with each entry of BankIds, in the same order.
+	Names []string
+	// A mapping from SoundBank ID to the first name stored for it.
+	BankNames map[uint32]string
 }
 
 // An UnknownSection represents an unknown section in a SoundBank file.
@@ -103,14 +193,56 @@ type UnknownSection struct {
 	Header *SectionHeader
 	// A reader to read the data of this section.
 	Reader io.Reader
+	// The offset of the header of this section within the file it was read
+	// from, or -1 if it was not read from a file.
+	Offset int64
+}
+
+// NewRawSection creates a new UnknownSection with the given four character
+// identifier, holding data. The section is written with data unchanged, and
+// with a header describing its length.
+func NewRawSection(identifier string, data []byte) (*UnknownSection, error) {
+	if len(identifier) != 4 {
+		msg := fmt.Sprintf("The section identifier \"%s\" is not four characters "+
+			"long", identifier)
+		return nil, errors.New(msg)
+	}
+	if int64(len(data)) > math.MaxUint32 {
+		msg := fmt.Sprintf("The section data is %d bytes long, which is too long "+
+			"to be described by a section header", len(data))
+		return nil, errors.New(msg)
+	}
+	hdr := &SectionHeader{Length: uint32(len(data))}
+	copy(hdr.Identifier[:], identifier)
+	r := util.NewResettingReader(bytes.NewReader(data), 0, int64(len(data)))
+	return &UnknownSection{hdr, r, -1}, nil
+}
+
+// NewBankNameSection creates a new StringIdSection naming the SoundBanks with
+// the given names. Each SoundBank is identified by the hash of its name.
+func NewBankNameSection(names ...string) *StringIdSection {
+	sec := &StringIdSection{&SectionHeader{stidHeaderId, 0}, stidBankNameType,
+		nil, nil, make(map[uint32]string)}
+	for _, name := range names {
+		id := wwise.HashName(name)
+		if _, ok := sec.BankNames[id]; ok {
+			continue
+		}
+		sec.BankIds = append(sec.BankIds, id)
+		sec.Names = append(sec.Names, name)
+		sec.BankNames[id] = name
+	}
+	sec.Header.Length = uint32(sec.Size() - SECTION_HEADER_BYTES)
+	return sec
 }
 
 // NewBankHeaderSection creates a new BankHeaderSection, reading from sr, which
 // must be seeked to the start of the BKHD section data.
-// It is an error to call this method on a non-BKHD header.
+// An error is returned if this method is called on a non-BKHD header.
 func (hdr *SectionHeader) NewBankHeaderSection(sr util.ReadSeekerAt) (*BankHeaderSection, error) {
 	if hdr.Identifier != bkhdHeaderId {
-		panic(fmt.Sprintf("Expected BKHD header but got: %s", hdr.Identifier))
+		msg := fmt.Sprintf("Expected BKHD header but got: %s", hdr.Identifier)
+		return nil, errors.New(msg)
 	}
 	sec := new(BankHeaderSection)
 	sec.Header = hdr
@@ -120,9 +252,39 @@ func (hdr *SectionHeader) NewBankHeaderSection(sr util.ReadSeekerAt) (*BankHeade
 		return nil, err
 	}
 	sec.Descriptor = desc
+	if desc.Version < minSupportedVersion {
+		return nil, fmt.Errorf("%w: version %d", ErrUnsupportedVersion,
+			desc.Version)
+	}
+	if hdr.Length < BKHD_SECTION_BYTES {
+		msg := fmt.Sprintf("The BKHD section is %d bytes long, but must be at "+
+			"least %d bytes long", hdr.Length, BKHD_SECTION_BYTES)
+		return nil, errors.New(msg)
+	}
+	remaining := int64(hdr.Length - BKHD_SECTION_BYTES)
+	if remaining >= BKHD_SETTINGS_BYTES {
+		sec.Settings = new(BankSettings)
+		err = readFields(sr, &sec.Settings.LanguageId)
+		if err != nil {
+			return nil, err
+		}
+		if desc.Version > lastFeedbackVersion {
+			err = readFields(sr, &sec.Settings.Alignment,
+				&sec.Settings.DeviceAllocated)
+		} else {
+			err = readFields(sr, &sec.Settings.FeedbackInBank)
+		}
+		if err != nil {
+			return nil, err
+		}
+		err = readFields(sr, &sec.Settings.ProjectId)
+		if err != nil {
+			return nil, err
+		}
+		remaining -= BKHD_SETTINGS_BYTES
+	}
 	// Get the offset into the file where the known portion of the BKHD ends.
 	knownOffset, _ := sr.Seek(0, io.SeekCurrent)
-	remaining := int64(hdr.Length - BKHD_SECTION_BYTES)
 	sec.RemainingReader = util.NewResettingReader(sr, knownOffset, remaining)
 	sr.Seek(remaining, io.SeekCurrent)
 
@@ -132,6 +294,7 @@ func (hdr *SectionHeader) NewBankHeaderSection(sr util.ReadSeekerAt) (*BankHeade
 // WriteTo writes the full contents of this BankHeaderSection to the Writer
 // specified by w.
 func (hdr *BankHeaderSection) WriteTo(w io.Writer) (written int64, err error) {
+	hdr.Header.Length = hdr.Length()
 	err = binary.Write(w, binary.LittleEndian, hdr.Header)
 	if err != nil {
 		return
@@ -142,7 +305,20 @@ func (hdr *BankHeaderSection) WriteTo(w io.Writer) (written int64, err error) {
 		return
 	}
 	written += int64(BKHD_SECTION_BYTES)
-	n, err := io.Copy(w, hdr.RemainingReader)
+	if s := hdr.Settings; s != nil {
+		var n int64
+		if hdr.Descriptor.Version > lastFeedbackVersion {
+			n, err = writeFields(w, s.LanguageId, s.Alignment, s.DeviceAllocated,
+				s.ProjectId)
+		} else {
+			n, err = writeFields(w, s.LanguageId, s.FeedbackInBank, s.ProjectId)
+		}
+		written += n
+		if err != nil {
+			return
+		}
+	}
+	n, err := util.CopyAll(w, hdr.RemainingReader)
 	if err != nil {
 		return
 	}
@@ -150,64 +326,247 @@ func (hdr *BankHeaderSection) WriteTo(w io.Writer) (written int64, err error) {
 	return written, nil
 }
 
+// feedbackInBank returns true if the HIRC objects of this SoundBank carry
+// feedback information, which is only recorded by older SoundBank versions.
+func (hdr *BankHeaderSection) feedbackInBank() bool {
+	if hdr.Descriptor.Version > lastFeedbackVersion || hdr.Settings == nil {
+		return false
+	}
+	return hdr.Settings.FeedbackInBank != 0
+}
+
+// Language returns the name of the standard language of this SoundBank, such
+// as SFX or English(US). The second result is false if the SoundBank does not
+// record its language, or if the language is not a standard one.
+func (hdr *BankHeaderSection) Language() (string, bool) {
+	if hdr.Settings == nil {
+		return "", false
+	}
+	numeric := hdr.Descriptor.Version <= lastNumericLanguageVersion
+	return wwise.LanguageName(hdr.Settings.LanguageId, numeric)
+}
+
+// SetVersion changes the version of this SoundBank to version. If the new
+// version identifies languages differently, the language ID is converted.
+// An error is returned if the settings of the SoundBank would have to change
+// layout, as the settings of versions up to 126 and of later versions do not
+// describe the same things, or if the language is not a standard one and so
+// cannot be converted.
+func (hdr *BankHeaderSection) SetVersion(version uint32) error {
+	old := hdr.Descriptor.Version
+	s := hdr.Settings
+	if s == nil {
+		hdr.Descriptor.Version = version
+		return nil
+	}
+	if (old > lastFeedbackVersion) != (version > lastFeedbackVersion) {
+		msg := fmt.Sprintf("Cannot change the version from %d to %d, as the "+
+			"settings of versions up to %d have a different layout to those of "+
+			"later versions", old, version, lastFeedbackVersion)
+		return errors.New(msg)
+	}
+	numeric := version <= lastNumericLanguageVersion
+	if (old <= lastNumericLanguageVersion) != numeric {
+		name, ok := hdr.Language()
+		if !ok {
+			msg := fmt.Sprintf("Cannot change the version from %d to %d, as "+
+				"language %d is not a standard language and cannot be converted",
+				old, version, s.LanguageId)
+			return errors.New(msg)
+		}
+		s.LanguageId, _ = wwise.LanguageId(name, numeric)
+	}
+	hdr.Descriptor.Version = version
+	return nil
+}
+
+// SetLanguage records the language with the given name, or with the given
+// numeric ID, as the language of this SoundBank. A name is encoded as this
+// version of SoundBank identifies languages, as Language decodes them.
+// An error is returned if the SoundBank does not record its language, or if it
+// identifies languages by index and name is not a standard language.
+func (hdr *BankHeaderSection) SetLanguage(name string) error {
+	if hdr.Settings == nil {
+		return errors.New("The BKHD section is too short to hold a language ID")
+	}
+	if id, err := strconv.ParseUint(name, 10, 32); err == nil {
+		hdr.Settings.LanguageId = uint32(id)
+		return nil
+	}
+	numeric := hdr.Descriptor.Version <= lastNumericLanguageVersion
+	id, ok := wwise.LanguageId(name, numeric)
+	if !ok {
+		msg := fmt.Sprintf("%s is not a standard language, which version %d "+
+			"SoundBanks require", name, hdr.Descriptor.Version)
+		return errors.New(msg)
+	}
+	hdr.Settings.LanguageId = id
+	return nil
+}
+
+// Identifier returns the four character identifier of this section.
+func (hdr *BankHeaderSection) Identifier() string {
+	return string(hdr.Header.Identifier[:])
+}
+
+// Length returns the length in bytes of the data of this section.
+func (hdr *BankHeaderSection) Length() uint32 {
+	return uint32(hdr.Size() - SECTION_HEADER_BYTES)
+}
+
+// Size returns the number of bytes that WriteTo would write.
+func (hdr *BankHeaderSection) Size() int64 {
+	remaining, ok := util.SizeOf(hdr.RemainingReader)
+	if !ok {
+		return SECTION_HEADER_BYTES + int64(hdr.Header.Length)
+	}
+	size := SECTION_HEADER_BYTES + BKHD_SECTION_BYTES + remaining
+	if hdr.Settings != nil {
+		size += BKHD_SETTINGS_BYTES
+	}
+	return size
+}
+
 func (hdr *BankHeaderSection) String() string {
-	return fmt.Sprintf("%s: len(%d) version(%d) id(%d)\n",
-		hdr.Header.Identifier, hdr.Header.Length, hdr.Descriptor.Version,
-		hdr.Descriptor.BankId)
+	b := new(strings.Builder)
+	fmt.Fprintf(b, "%s: len(%d) version(%d) id(%d)", hdr.Header.Identifier,
+		hdr.Header.Length, hdr.Descriptor.Version, hdr.Descriptor.BankId)
+	if s := hdr.Settings; s != nil {
+		language, ok := hdr.Language()
+		if !ok {
+			language = strconv.FormatUint(uint64(s.LanguageId), 10)
+		}
+		fmt.Fprintf(b, " language(%s) project(%d)", language, s.ProjectId)
+		if hdr.Descriptor.Version > lastFeedbackVersion {
+			fmt.Fprintf(b, " alignment(%d) device_allocated(%d)", s.Alignment,
+				s.DeviceAllocated)
+		}
+	}
+	b.WriteString("\n")
+	return b.String()
 }
 
 // NewDataIndexSection creates a new DataIndexSection, reading from r, which must
 // be seeked to the start of the DIDX section data.
-// It is an error to call this method on a non-DIDX header.
+// An error is returned if this method is called on a non-DIDX header, or if a
+// wem ID is repeated.
 func (hdr *SectionHeader) NewDataIndexSection(r io.Reader) (*DataIndexSection, error) {
+	sec, _, err := hdr.newDataIndexSection(r, DuplicateError)
+	return sec, err
+}
+
+// newDataIndexSection is like NewDataIndexSection, but handles repeated wem IDs
+// as described by duplicates. The repeats that were tolerated are returned as
+// warnings.
+func (hdr *SectionHeader) newDataIndexSection(r io.Reader,
+	duplicates DuplicatePolicy) (*DataIndexSection, []error, error) {
 	if hdr.Identifier != didxHeaderId {
-		panic(fmt.Sprintf("Expected DIDX header but got: %s", hdr.Identifier))
+		msg := fmt.Sprintf("Expected DIDX header but got: %s", hdr.Identifier)
+		return nil, nil, errors.New(msg)
+	}
+	if hdr.Length%DIDX_ENTRY_BYTES != 0 {
+		return nil, nil, fmt.Errorf("%w: its length %d is not a multiple of %d",
+			ErrCorruptDIDX, hdr.Length, DIDX_ENTRY_BYTES)
 	}
 	wemCount := int(hdr.Length / DIDX_ENTRY_BYTES)
-	sec := DataIndexSection{hdr, wemCount, make([]uint32, 0),
-		make(map[uint32]*wwise.WemDescriptor)}
+	// The length of a corrupt section cannot be trusted, so only allocate up
+	// front for as many wems as a large SoundBank holds.
+	capacity := wemCount
+	if capacity > maxPreallocatedWems {
+		capacity = maxPreallocatedWems
+	}
+	sec := DataIndexSection{hdr, wemCount, make([]uint32, 0, capacity),
+		make(map[uint32]*wwise.WemDescriptor, capacity),
+		make([]*wwise.WemDescriptor, 0, capacity)}
+	// A DIDX section may describe many thousands of wems, so its entries are
+	// read through a buffer, decoded by hand and allocated in blocks.
+	br := bufio.NewReader(io.LimitReader(r, int64(hdr.Length)))
+	var block []wwise.WemDescriptor
+	var warnings []error
 	for i := 0; i < wemCount; i++ {
-		var desc wwise.WemDescriptor
-		err := binary.Read(r, binary.LittleEndian, &desc)
+		var entry [DIDX_ENTRY_BYTES]byte
+		_, err := io.ReadFull(br, entry[:])
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
+		if len(block) == 0 {
+			size := wemCount - i
+			if size > descriptorBlockSize {
+				size = descriptorBlockSize
+			}
+			block = make([]wwise.WemDescriptor, size)
+		}
+		desc := &block[0]
+		block = block[1:]
+		desc.WemId = binary.LittleEndian.Uint32(entry[:])
+		desc.Offset = binary.LittleEndian.Uint32(entry[4:])
+		desc.Length = binary.LittleEndian.Uint32(entry[8:])
 
 		if _, ok := sec.DescriptorMap[desc.WemId]; ok {
-			panic(fmt.Sprintf(
-				"%d is an illegal repeated wem ID in the DIDX", desc.WemId))
+			err := fmt.Errorf("%w: %d is an illegal repeated wem ID",
+				ErrCorruptDIDX, desc.WemId)
+			switch duplicates {
+			case DuplicateKeepFirst:
+				warnings = append(warnings,
+					fmt.Errorf("%w; the wem at index %d was dropped", err, i))
+				continue
+			case DuplicateKeepAll:
+				warnings = append(warnings,
+					fmt.Errorf("%w; the wem at index %d was kept", err, i))
+			default:
+				return nil, nil, err
+			}
+		} else {
+			sec.DescriptorMap[desc.WemId] = desc
 		}
 		sec.WemIds = append(sec.WemIds, desc.WemId)
-		sec.DescriptorMap[desc.WemId] = &desc
+		sec.Descriptors = append(sec.Descriptors, desc)
 	}
+	sec.WemCount = len(sec.WemIds)
 
-	return &sec, nil
+	return &sec, warnings, nil
 }
 
 // WriteTo writes the full contents of this DataIndexSection to the Writer
 // specified by w.
 func (idx *DataIndexSection) WriteTo(w io.Writer) (written int64, err error) {
+	idx.Header.Length = idx.Length()
 	err = binary.Write(w, binary.LittleEndian, idx.Header)
 	if err != nil {
 		return
 	}
 	written = int64(SECTION_HEADER_BYTES)
 
-	for _, id := range idx.WemIds {
-		desc := idx.DescriptorMap[id]
-		err = binary.Write(w, binary.LittleEndian, desc)
-		if err != nil {
-			return
-		}
-		written += int64(DIDX_ENTRY_BYTES)
+	b := make([]byte, len(idx.Descriptors)*DIDX_ENTRY_BYTES)
+	for i, desc := range idx.Descriptors {
+		entry := b[i*DIDX_ENTRY_BYTES:]
+		binary.LittleEndian.PutUint32(entry, desc.WemId)
+		binary.LittleEndian.PutUint32(entry[4:], desc.Offset)
+		binary.LittleEndian.PutUint32(entry[8:], desc.Length)
 	}
-	return written, nil
+	n, err := w.Write(b)
+	return written + int64(n), err
+}
+
+// Identifier returns the four character identifier of this section.
+func (idx *DataIndexSection) Identifier() string {
+	return string(idx.Header.Identifier[:])
+}
+
+// Length returns the length in bytes of the data of this section.
+func (idx *DataIndexSection) Length() uint32 {
+	return uint32(idx.Size() - SECTION_HEADER_BYTES)
+}
+
+// Size returns the number of bytes that WriteTo would write.
+func (idx *DataIndexSection) Size() int64 {
+	return SECTION_HEADER_BYTES + int64(len(idx.Descriptors))*DIDX_ENTRY_BYTES
 }
 
 func (idx *DataIndexSection) String() string {
 	b := new(strings.Builder)
 	total := uint32(0)
-	for _, desc := range idx.DescriptorMap {
+	for _, desc := range idx.Descriptors {
 		total += desc.Length
 	}
 	fmt.Fprintf(b, "%s: len(%d) wem_count(%d)\n", idx.Header.Identifier,
@@ -219,17 +578,67 @@ func (idx *DataIndexSection) String() string {
 // NewDataSection creates a new DataSection, reading from sr, which must be
 // seeked to the start of the DATA section data. idx specifies how each wem
 // should be indexed from, given the current sr offset.
-// It is an error to call this method on a non-DATA header.
+// An error is returned if this method is called on a non-DATA header, or if idx
+// describes wems that cannot be stored in this section.
 func (hdr *SectionHeader) NewDataSection(sr util.ReadSeekerAt,
 	idx *DataIndexSection) (*DataSection, error) {
+	sec, _, err := hdr.newDataSection(sr, idx, false)
+	return sec, err
+}
+
+// newDataSection is like NewDataSection. If lenient is true, wems that end past
+// the end of this section are cut short and wems that overlap are laid out
+// again, and the problems are returned as warnings rather than as an error.
+func (hdr *SectionHeader) newDataSection(sr util.ReadSeekerAt,
+	idx *DataIndexSection, lenient bool) (*DataSection, []error, error) {
 	if hdr.Identifier != dataHeaderId {
-		panic(fmt.Sprintf("Expected DATA header but got: %s", hdr.Identifier))
+		msg := fmt.Sprintf("Expected DATA header but got: %s", hdr.Identifier)
+		return nil, nil, errors.New(msg)
+	}
+	if idx == nil {
+		return nil, nil,
+			errors.New("The DATA section is not preceded by a DIDX section")
 	}
 	dataOffset, _ := sr.Seek(0, io.SeekCurrent)
 
-	sec := DataSection{hdr, uint32(dataOffset), make([]*wwise.Wem, 0)}
+	var warnings []error
+	relayout := false
+	sec := DataSection{hdr, uint32(dataOffset),
+		make([]*wwise.Wem, 0, len(idx.WemIds))}
+	// The offset of the first wem that holds data after each wem, or the end of
+	// the data section if there is none.
+	nextOffsets := make([]int64, len(idx.WemIds))
+	next := dataOffset + int64(hdr.Length)
+	for i := len(idx.Descriptors) - 1; i >= 0; i-- {
+		nextOffsets[i] = next
+		if idx.Descriptors[i].Length > 0 {
+			next = dataOffset + int64(idx.Descriptors[i].Offset)
+		}
+	}
 	for i, id := range idx.WemIds {
-		desc := idx.DescriptorMap[id]
+		desc := idx.Descriptors[i]
+		if desc.Length == 0 {
+			// An empty wem holds no data, so its offset does not matter.
+			empty := util.NewResettingReader(sr, dataOffset, 0)
+			sec.Wems = append(sec.Wems, &wwise.Wem{empty, desc, empty})
+			continue
+		}
+		if int64(desc.Offset)+int64(desc.Length) > int64(hdr.Length) {
+			err := fmt.Errorf("%w: wem %d ends at offset %d, past the end of "+
+				"the DATA section at offset %d", ErrCorruptDIDX, id,
+				int64(desc.Offset)+int64(desc.Length), hdr.Length)
+			if !lenient {
+				return nil, nil, err
+			}
+			// Keep what remains of the wem within the section.
+			available := int64(hdr.Length) - int64(desc.Offset)
+			if available < 0 {
+				available, desc.Offset = 0, hdr.Length
+			}
+			desc.Length = uint32(available)
+			warnings = append(warnings,
+				fmt.Errorf("%w; it was cut short to %d bytes", err, available))
+		}
 		wemStartOffset := dataOffset + int64(desc.Offset)
 		wemReader := util.NewResettingReader(sr, wemStartOffset, int64(desc.Length))
 
@@ -237,18 +646,20 @@ func (hdr *SectionHeader) NewDataSection(sr util.ReadSeekerAt,
 
 		if i <= len(idx.WemIds)-1 {
 			wemEndOffset := wemStartOffset + int64(desc.Length)
-			var nextOffset int64
-			if i == len(idx.WemIds)-1 {
-				// This is the last wem, check how many bytes remain until the end of
-				// the data section.
-				nextOffset = dataOffset + int64(hdr.Length)
-			} else {
-				// This is not the last wem, check how many bytes remain until the next
-				// wem.
-				nextDesc := idx.DescriptorMap[idx.WemIds[i+1]]
-				nextOffset = dataOffset + int64(nextDesc.Offset)
+			// If this is the last wem that holds data, check how many bytes remain
+			// until the end of the data section. Otherwise, check how many bytes
+			// remain until the next wem that holds data.
+			remaining := nextOffsets[i] - wemEndOffset
+			if remaining < 0 {
+				err := fmt.Errorf("%w: wem %d overlaps with the wem that "+
+					"follows it", ErrCorruptDIDX, id)
+				if !lenient {
+					return nil, nil, err
+				}
+				warnings = append(warnings,
+					fmt.Errorf("%w; the wems were laid out again", err))
+				remaining, relayout = 0, true
 			}
-			remaining := nextOffset - wemEndOffset
 			// Pass a Reader over the remaining section if we have remaining bytes to
 			// read, or an empty Reader if remaining is 0 (no bytes will be read).
 			padding = util.NewResettingReader(sr, wemEndOffset, remaining)
@@ -257,26 +668,48 @@ func (hdr *SectionHeader) NewDataSection(sr util.ReadSeekerAt,
 		wem := wwise.Wem{wemReader, desc, padding}
 		sec.Wems = append(sec.Wems, &wem)
 	}
+	if relayout {
+		sec.relayout()
+	}
 
 	sr.Seek(int64(hdr.Length), io.SeekCurrent)
-	return &sec, nil
+	return &sec, warnings, nil
+}
+
+// relayout stores the wems of this section one after another, in the order of
+// the DIDX section, aligned as Wwise aligns them.
+func (data *DataSection) relayout() {
+	offset := int64(0)
+	for i, wem := range data.Wems {
+		wem.Descriptor.Offset = uint32(offset)
+		end := offset + int64(wem.Descriptor.Length)
+		padding := int64(0)
+		if i < len(data.Wems)-1 {
+			padding = (wemAlignmentBytes - end%wemAlignmentBytes) %
+				wemAlignmentBytes
+		}
+		wem.Padding = util.NewResettingReader(&util.InfiniteReaderAt{0}, 0,
+			padding)
+		offset = end + padding
+	}
 }
 
 // WriteTo writes the full contents of this DataSection to the Writer specified
 // by w.
 func (data *DataSection) WriteTo(w io.Writer) (written int64, err error) {
+	data.Header.Length = data.Length()
 	err = binary.Write(w, binary.LittleEndian, data.Header)
 	if err != nil {
 		return
 	}
 	written = int64(SECTION_HEADER_BYTES)
 	for _, wem := range data.Wems {
-		n, err := io.Copy(w, wem)
+		n, err := wem.WriteTo(w)
 		if err != nil {
 			return written, err
 		}
 		written += int64(n)
-		n, err = io.Copy(w, wem.Padding)
+		n, err = util.CopyAll(w, wem.Padding)
 		if err != nil {
 			return written, err
 		}
@@ -286,21 +719,44 @@ func (data *DataSection) WriteTo(w io.Writer) (written int64, err error) {
 	return written, nil
 }
 
+// Identifier returns the four character identifier of this section.
+func (data *DataSection) Identifier() string {
+	return string(data.Header.Identifier[:])
+}
+
+// Length returns the length in bytes of the data of this section.
+func (data *DataSection) Length() uint32 {
+	return uint32(data.Size() - SECTION_HEADER_BYTES)
+}
+
+// Size returns the number of bytes that WriteTo would write.
+func (data *DataSection) Size() int64 {
+	size := int64(SECTION_HEADER_BYTES)
+	for _, wem := range data.Wems {
+		size += int64(wem.Descriptor.Length) + wem.Padding.Size()
+	}
+	return size
+}
+
 func (data *DataSection) String() string {
 	return fmt.Sprintf("%s: len(%d)\n", data.Header.Identifier, data.Header.Length)
 }
 
 // NewObjectHierarchySection creates a new ObjectHierarchySection, reading from
-// sr, which must be seeked to the start of the HIRC section data.
-// It is an error to call this method on a non-HIRC header.
-func (hdr *SectionHeader) NewObjectHierarchySection(sr util.ReadSeekerAt) (*ObjectHierarchySection, error) {
+// sr, which must be seeked to the start of the HIRC section data. bkhd is the
+// header of the SoundBank containing this section, and may be nil.
+// An error is returned if this method is called on a non-HIRC header.
+func (hdr *SectionHeader) NewObjectHierarchySection(sr util.ReadSeekerAt,
+	bkhd *BankHeaderSection) (*ObjectHierarchySection, error) {
 	if hdr.Identifier != hircHeaderId {
-		panic(fmt.Sprintf("Expected HIRC header but got: %s", hdr.Identifier))
+		msg := fmt.Sprintf("Expected HIRC header but got: %s", hdr.Identifier)
+		return nil, errors.New(msg)
 	}
 	sec := new(ObjectHierarchySection)
 	sec.Header = hdr
 	sec.loopOf = make(map[uint32]uint32)
 	sec.wemToObject = make(map[uint32]*SfxVoiceSoundObject)
+	sec.objectOf = make(map[uint32]Object)
 
 	var count uint32
 	err := binary.Read(sr, binary.LittleEndian, &count)
@@ -315,33 +771,81 @@ func (hdr *SectionHeader) NewObjectHierarchySection(sr util.ReadSeekerAt) (*Obje
 		if err != nil {
 			return nil, err
 		}
-		switch id := desc.Type; id {
-		case soundObjectId:
-			obj, err := desc.NewSfxVoiceSoundObject(sr)
-			if err != nil {
-				return nil, err
-			}
-
-			sec.wemToObject[obj.WemDescriptor.WemId] = obj
-			if obj.Structure.loops {
-				sec.loopOf[obj.WemDescriptor.WemId] = obj.Structure.loopCount
-			}
-			sec.objects = append(sec.objects, obj)
-		default:
-			obj, err := desc.NewUnknownObject(sr)
-			if err != nil {
-				return nil, err
+		obj, err := newObject(desc, sr, bkhd)
+		if err != nil {
+			return nil, err
+		}
+		if sound, ok := obj.(*SfxVoiceSoundObject); ok {
+			sec.wemToObject[sound.WemDescriptor.WemId] = sound
+			if sound.Structure.loops {
+				sec.loopOf[sound.WemDescriptor.WemId] = sound.Structure.loopCount
 			}
-			sec.objects = append(sec.objects, obj)
 		}
+		sec.objectOf[obj.Id()] = obj
+		sec.objects = append(sec.objects, obj)
 	}
 
 	return sec, nil
 }
 
+// newObject creates the Object described by desc, reading from sr, which must
+// be seeked to the start of the object's data. Objects whose data cannot be
+// fully decoded are created as UnknownObjects, so that they are written back
+// unchanged.
+func newObject(desc *ObjectDescriptor, sr util.ReadSeekerAt,
+	bkhd *BankHeaderSection) (Object, error) {
+	dataOffset, _ := sr.Seek(0, io.SeekCurrent)
+	dataLength := int64(desc.Length) - OBJECT_DESCRIPTOR_ID_BYTES
+
+	var obj Object
+	var err error
+	switch desc.Type {
+	case soundObjectId:
+		obj, err = desc.NewSfxVoiceSoundObject(sr, bkhd)
+	case actionObjectId:
+		obj, err = desc.NewActionObject(sr)
+	case eventObjectId:
+		obj, err = desc.NewEventObject(sr, bkhd)
+	case randomSequenceContainerId, switchContainerId, actorMixerId,
+		layerContainerId:
+		obj, err = desc.NewContainerObject(sr, bkhd)
+	case musicTrackId:
+		obj, err = desc.NewMusicTrackObject(sr, bkhd)
+	case musicSegmentId:
+		obj, err = desc.NewMusicSegmentObject(sr, bkhd)
+	case musicSwitchId:
+		obj, err = desc.NewMusicSwitchObject(sr, bkhd)
+	case musicPlaylistId:
+		obj, err = desc.NewMusicPlaylistObject(sr, bkhd)
+	case attenuationId:
+		obj, err = desc.NewAttenuationObject(sr, bkhd)
+	case busId, auxBusId:
+		obj, err = desc.NewBusObject(sr, bkhd)
+	case effectShareSetId, effectCustomId:
+		obj, err = desc.NewEffectObject(sr, bkhd)
+	default:
+		return desc.NewUnknownObject(sr)
+	}
+
+	currOffset, _ := sr.Seek(0, io.SeekCurrent)
+	if err != nil || currOffset-dataOffset != dataLength {
+		sr.Seek(dataOffset, io.SeekStart)
+		return desc.NewUnknownObject(sr)
+	}
+	return obj, nil
+}
+
+// Object returns the object with the given ID, or nil if this section does not
+// contain such an object.
+func (hrc *ObjectHierarchySection) Object(id uint32) Object {
+	return hrc.objectOf[id]
+}
+
 // WriteTo writes the full contents of this ObjectHierarchySection to the Writer
 // specified by w.
 func (hrc *ObjectHierarchySection) WriteTo(w io.Writer) (written int64, err error) {
+	hrc.ObjectCount = uint32(len(hrc.objects))
+	hrc.Header.Length = hrc.Length()
 	err = binary.Write(w, binary.LittleEndian, hrc.Header)
 	if err != nil {
 		return
@@ -364,6 +868,31 @@ func (hrc *ObjectHierarchySection) WriteTo(w io.Writer) (written int64, err erro
 	return written, nil
 }
 
+// Objects returns all objects stored in this section, in the order that they
+// appear in the file.
+func (hrc *ObjectHierarchySection) Objects() []Object {
+	return hrc.objects
+}
+
+// Identifier returns the four character identifier of this section.
+func (hrc *ObjectHierarchySection) Identifier() string {
+	return string(hrc.Header.Identifier[:])
+}
+
+// Length returns the length in bytes of the data of this section.
+func (hrc *ObjectHierarchySection) Length() uint32 {
+	return uint32(hrc.Size() - SECTION_HEADER_BYTES)
+}
+
+// Size returns the number of bytes that WriteTo would write.
+func (hrc *ObjectHierarchySection) Size() int64 {
+	size := int64(SECTION_HEADER_BYTES + OBJECT_COUNT_BYTES)
+	for _, obj := range hrc.objects {
+		size += obj.Size()
+	}
+	return size
+}
+
 func (hrc *ObjectHierarchySection) String() string {
 	b := new(strings.Builder)
 
@@ -372,6 +901,119 @@ func (hrc *ObjectHierarchySection) String() string {
 	return b.String()
 }
 
+// NewStringIdSection creates a new StringIdSection, reading from r, which must
+// be seeked to the start of the STID section data.
+// An error is returned if this method is called on a non-STID header.
+func (hdr *SectionHeader) NewStringIdSection(r io.Reader) (*StringIdSection, error) {
+	if hdr.Identifier != stidHeaderId {
+		msg := fmt.Sprintf("Expected STID header but got: %s", hdr.Identifier)
+		return nil, errors.New(msg)
+	}
+	sec := &StringIdSection{hdr, 0, nil, nil, make(map[uint32]string)}
+	err := binary.Read(r, binary.LittleEndian, &sec.Type)
+	if err != nil {
+		return nil, err
+	}
+	var count uint32
+	err = binary.Read(r, binary.LittleEndian, &count)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := uint32(0); i < count; i++ {
+		var id uint32
+		err = binary.Read(r, binary.LittleEndian, &id)
+		if err != nil {
+			return nil, err
+		}
+		var size byte
+		err = binary.Read(r, binary.LittleEndian, &size)
+		if err != nil {
+			return nil, err
+		}
+		name := make([]byte, size)
+		_, err = io.ReadFull(r, name)
+		if err != nil {
+			return nil, err
+		}
+		sec.BankIds = append(sec.BankIds, id)
+		sec.Names = append(sec.Names, string(name))
+		if _, ok := sec.BankNames[id]; !ok {
+			sec.BankNames[id] = string(name)
+		}
+	}
+
+	return sec, nil
+}
+
+// WriteTo writes the full contents of this StringIdSection to the Writer
+// specified by w.
+func (stid *StringIdSection) WriteTo(w io.Writer) (written int64, err error) {
+	stid.Header.Length = stid.Length()
+	err = binary.Write(w, binary.LittleEndian, stid.Header)
+	if err != nil {
+		return
+	}
+	written = int64(SECTION_HEADER_BYTES)
+
+	err = binary.Write(w, binary.LittleEndian, stid.Type)
+	if err != nil {
+		return
+	}
+	err = binary.Write(w, binary.LittleEndian, uint32(len(stid.BankIds)))
+	if err != nil {
+		return
+	}
+	written += 8
+
+	for i, id := range stid.BankIds {
+		name := stid.Names[i]
+		err = binary.Write(w, binary.LittleEndian, id)
+		if err != nil {
+			return
+		}
+		err = binary.Write(w, binary.LittleEndian, byte(len(name)))
+		if err != nil {
+			return
+		}
+		n, err := io.WriteString(w, name)
+		if err != nil {
+			return written, err
+		}
+		written += 5 + int64(n)
+	}
+	return written, nil
+}
+
+// Identifier returns the four character identifier of this section.
+func (stid *StringIdSection) Identifier() string {
+	return string(stid.Header.Identifier[:])
+}
+
+// Length returns the length in bytes of the data of this section.
+func (stid *StringIdSection) Length() uint32 {
+	return uint32(stid.Size() - SECTION_HEADER_BYTES)
+}
+
+// Size returns the number of bytes that WriteTo would write.
+func (stid *StringIdSection) Size() int64 {
+	size := int64(SECTION_HEADER_BYTES + 8)
+	for _, name := range stid.Names {
+		size += 5 + int64(len(name))
+	}
+	return size
+}
+
+func (stid *StringIdSection) String() string {
+	b := new(strings.Builder)
+	fmt.Fprintf(b, "%s: len(%d) string_count(%d)\n", stid.Header.Identifier,
+		stid.Header.Length, len(stid.BankIds))
+	for i, id := range stid.BankIds {
+		fmt.Fprintf(b, "STID: %d = %s\n", id, stid.Names[i])
+	}
+	return b.String()
+}
+
 // NewUnknownSection creates a new UnknownSection, reading from sr, which
 // must be seeked to the start of the unknown section data.
 func (hdr *SectionHeader) NewUnknownSection(sr util.ReadSeekerAt) (*UnknownSection, error) {
@@ -379,19 +1021,20 @@ func (hdr *SectionHeader) NewUnknownSection(sr util.ReadSeekerAt) (*UnknownSecti
 	dataOffset, _ := sr.Seek(0, io.SeekCurrent)
 	r := util.NewResettingReader(sr, dataOffset, int64(hdr.Length))
 	sr.Seek(int64(hdr.Length), io.SeekCurrent)
-	return &UnknownSection{hdr, r}, nil
+	return &UnknownSection{hdr, r, dataOffset - SECTION_HEADER_BYTES}, nil
 }
 
 // WriteTo writes the full contents of this UnknownSection to the Writer
 // specified by w.
 func (unknown *UnknownSection) WriteTo(w io.Writer) (written int64, err error) {
+	unknown.Header.Length = unknown.Length()
 	err = binary.Write(w, binary.LittleEndian, unknown.Header)
 	if err != nil {
 		return
 	}
 	written = int64(SECTION_HEADER_BYTES)
 
-	n, err := io.Copy(w, unknown.Reader)
+	n, err := util.CopyAll(w, unknown.Reader)
 	if err != nil {
 		return written, err
 	}
@@ -400,7 +1043,30 @@ func (unknown *UnknownSection) WriteTo(w io.Writer) (written int64, err error) {
 	return written, nil
 }
 
+// Identifier returns the four character identifier of this section.
+func (unknown *UnknownSection) Identifier() string {
+	return string(unknown.Header.Identifier[:])
+}
+
+// Length returns the length in bytes of the data of this section.
+func (unknown *UnknownSection) Length() uint32 {
+	return uint32(unknown.Size() - SECTION_HEADER_BYTES)
+}
+
+// Size returns the number of bytes that WriteTo would write.
+func (unknown *UnknownSection) Size() int64 {
+	size, ok := util.SizeOf(unknown.Reader)
+	if !ok {
+		return SECTION_HEADER_BYTES + int64(unknown.Header.Length)
+	}
+	return SECTION_HEADER_BYTES + size
+}
+
 func (unknown *UnknownSection) String() string {
-	return fmt.Sprintf("%s: len(%d)\n", unknown.Header.Identifier,
-		unknown.Header.Length)
+	if unknown.Offset < 0 {
+		return fmt.Sprintf("%s: len(%d) unknown section\n",
+			unknown.Header.Identifier, unknown.Header.Length)
+	}
+	return fmt.Sprintf("%s: len(%d) unknown section at offset(%d)\n",
+		unknown.Header.Identifier, unknown.Header.Length, unknown.Offset)
 }
@@ -0,0 +1,134 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+// SplitByIds creates a new File for every group of wem IDs in groups, holding
+// the wems of this SoundBank with those IDs. Each new SoundBank has a copy of
+// the bank header of this SoundBank, and a DIDX and a DATA section; other
+// sections, including the HIRC section, are not copied. The new Files read
+// wems from the same source as this File, so this File must not be closed
+// while they are in use.
+func (bnk *File) SplitByIds(groups [][]uint32) ([]*File, error) {
+	var parts [][]*wwise.Wem
+	for i, group := range groups {
+		var part []*wwise.Wem
+		for _, id := range group {
+			wem, ok := bnk.WemByID(id)
+			if !ok {
+				msg := fmt.Sprintf("Group %d holds wem %d, which is not stored in "+
+					"this SoundBank", i, id)
+				return nil, errors.New(msg)
+			}
+			part = append(part, wem)
+		}
+		parts = append(parts, part)
+	}
+	return bnk.split(parts)
+}
+
+// SplitBySize creates new Files that each hold consecutive wems of this
+// SoundBank, such that each new SoundBank takes up at most budget bytes when
+// written. A wem too large to fit in a SoundBank with other wems is placed in a
+// SoundBank of its own. The new SoundBanks are made up as described by
+// SplitByIds, and align their wems as this SoundBank does.
+func (bnk *File) SplitBySize(budget int64) ([]*File, error) {
+	if budget <= 0 {
+		return nil, errors.New("The size budget must be positive")
+	}
+	if bnk.BankHeaderSection == nil {
+		return nil, ErrNotASoundBank
+	}
+	// The size of a new SoundBank without the wems it holds.
+	fixed := bnk.BankHeaderSection.Size() + 2*SECTION_HEADER_BYTES
+	alignment := bnk.Alignment()
+	var parts [][]*wwise.Wem
+	var part []*wwise.Wem
+	// The length of the DATA section of part, and the highest ID it holds.
+	length, maxId := int64(0), uint32(0)
+	for _, wem := range bnk.Wems() {
+		id := wem.Descriptor.WemId
+		grown := append(part, wem)
+		grownLength := int64(wem.Descriptor.Length)
+		switch {
+		case len(part) > 0 && id > maxId:
+			// The wem is laid out last, so the others keep their padding.
+			grownLength += length + (alignment-length%alignment)%alignment
+		case len(part) > 0:
+			grownLength = wemsLength(grown, alignment)
+		}
+		size := fixed + int64(len(grown))*DIDX_ENTRY_BYTES + grownLength
+		if len(part) > 0 && size > budget {
+			parts = append(parts, part)
+			grown, grownLength = []*wwise.Wem{wem}, int64(wem.Descriptor.Length)
+			maxId = id
+		}
+		if id > maxId {
+			maxId = id
+		}
+		part, length = grown, grownLength
+	}
+	if len(part) > 0 {
+		parts = append(parts, part)
+	}
+	return bnk.split(parts)
+}
+
+// wemsLength returns the length of a DATA section holding wems, which are laid
+// out in ascending order of their ID, as newWemSections lays them out, and
+// padded to alignment bytes.
+func wemsLength(wems []*wwise.Wem, alignment int64) int64 {
+	lengths := make(map[uint32]int64)
+	var ids []uint32
+	for _, wem := range wems {
+		ids = append(ids, wem.Descriptor.WemId)
+		lengths[wem.Descriptor.WemId] = int64(wem.Descriptor.Length)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	length := int64(0)
+	for i, id := range ids {
+		if i > 0 {
+			length += (alignment - length%alignment) % alignment
+		}
+		length += lengths[id]
+	}
+	return length
+}
+
+// split creates a new File for every list of wems in parts.
+func (bnk *File) split(parts [][]*wwise.Wem) ([]*File, error) {
+	if bnk.BankHeaderSection == nil {
+		return nil, ErrNotASoundBank
+	}
+	var files []*File
+	for _, part := range parts {
+		var wems []WemSource
+		for _, wem := range part {
+			src, err := sourceOf(wem)
+			if err != nil {
+				return nil, err
+			}
+			wems = append(wems, src)
+		}
+
+		f := &File{alignment: bnk.alignment}
+		err := f.addSectionCopy(bnk.BankHeaderSection)
+		if err != nil {
+			return nil, err
+		}
+		err = f.addWems(wems)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
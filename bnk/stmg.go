@@ -0,0 +1,248 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var stmgHeaderId = [4]byte{'S', 'T', 'M', 'G'}
+
+// A GlobalSettingsSection represents the STMG section of a SoundBank file. It
+// is only found in Init.bnk, and holds the project wide settings of the state
+// manager: state groups, switch groups driven by game parameters, and the
+// game parameters themselves.
+type GlobalSettingsSection struct {
+	Header *SectionHeader
+	// The volume, in decibels, under which voices are made virtual.
+	VolumeThreshold float32
+	// The default limit on the number of voices that may play at once.
+	MaxVoices      uint16
+	StateGroups    []StateGroup
+	SwitchGroups   []GameSwitchGroup
+	GameParameters []GameParameter
+	// The data that follows the game parameters, such as acoustic textures,
+	// which is kept as is.
+	Remaining []byte
+}
+
+// A StateGroup describes how long a change between two states of a group
+// takes.
+type StateGroup struct {
+	Id uint32
+	// The time, in milliseconds, of a change between states that is not
+	// described by a transition.
+	DefaultTransitionTime uint32
+	Transitions           []StateTransition
+}
+
+// A StateTransition is the time, in milliseconds, that a change from one
+// state to another takes.
+type StateTransition struct {
+	From uint32
+	To   uint32
+	Time uint32
+}
+
+// A GameSwitchGroup is a switch group whose switch is chosen from the value of
+// a game parameter.
+type GameSwitchGroup struct {
+	Id uint32
+	// The ID of the game parameter that drives the switch.
+	RTPCId   uint32
+	RTPCType byte
+	// The curve mapping the game parameter value to a switch.
+	Points []GraphPoint
+}
+
+// A GameParameter describes the default value of a game parameter, and how
+// changes to its value are smoothed.
+type GameParameter struct {
+	Id    uint32
+	Value float32
+	// The kind of smoothing applied when the value changes.
+	RampType uint32
+	RampUp   float32
+	RampDown float32
+	// The built-in parameter, such as the listener distance, that this game
+	// parameter is bound to, or 0 if it is not bound.
+	BuiltIn byte
+}
+
+// NewGlobalSettingsSection creates a new GlobalSettingsSection, reading from
+// r, which must be seeked to the start of the STMG section data. bkhd is the
+// header of the SoundBank, which decides the layout of the section.
+// An error is returned if this method is called on a non-STMG header, or if the
+// layout of the section is not known.
+func (hdr *SectionHeader) NewGlobalSettingsSection(r io.Reader,
+	bkhd *BankHeaderSection) (*GlobalSettingsSection, error) {
+	if hdr.Identifier != stmgHeaderId {
+		msg := fmt.Sprintf("Expected STMG header but got: %s", hdr.Identifier)
+		return nil, errors.New(msg)
+	}
+	data := make([]byte, hdr.Length)
+	_, err := io.ReadFull(r, data)
+	if err != nil {
+		return nil, err
+	}
+	l := layoutOf(bkhd)
+	if !l.known() {
+		msg := fmt.Sprintf("The layout of the STMG section of version %d "+
+			"SoundBanks is not known", l.version)
+		return nil, errors.New(msg)
+	}
+
+	sec := &GlobalSettingsSection{Header: hdr}
+	br := bytes.NewReader(data)
+	err = readFields(br, &sec.VolumeThreshold, &sec.MaxVoices)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := readListCount(br, 12, int64(br.Len()))
+	if err != nil {
+		return nil, err
+	}
+	for i := uint32(0); i < count; i++ {
+		var group StateGroup
+		err = readFields(br, &group.Id, &group.DefaultTransitionTime)
+		if err != nil {
+			return nil, err
+		}
+		transitions, err := readListCount(br, 12, int64(br.Len()))
+		if err != nil {
+			return nil, err
+		}
+		group.Transitions = make([]StateTransition, transitions)
+		err = readFields(br, group.Transitions)
+		if err != nil {
+			return nil, err
+		}
+		sec.StateGroups = append(sec.StateGroups, group)
+	}
+
+	count, err = readListCount(br, 13, int64(br.Len()))
+	if err != nil {
+		return nil, err
+	}
+	for i := uint32(0); i < count; i++ {
+		var group GameSwitchGroup
+		err = readFields(br, &group.Id, &group.RTPCId, &group.RTPCType)
+		if err != nil {
+			return nil, err
+		}
+		points, err := readListCount(br, 12, int64(br.Len()))
+		if err != nil {
+			return nil, err
+		}
+		group.Points = make([]GraphPoint, points)
+		err = readFields(br, group.Points)
+		if err != nil {
+			return nil, err
+		}
+		sec.SwitchGroups = append(sec.SwitchGroups, group)
+	}
+
+	count, err = readListCount(br, int64(binary.Size(GameParameter{})),
+		int64(br.Len()))
+	if err != nil {
+		return nil, err
+	}
+	sec.GameParameters = make([]GameParameter, count)
+	err = readFields(br, sec.GameParameters)
+	if err != nil {
+		return nil, err
+	}
+
+	sec.Remaining = data[len(data)-br.Len():]
+	return sec, nil
+}
+
+// WriteTo writes the full contents of this GlobalSettingsSection to the Writer
+// specified by w.
+func (stmg *GlobalSettingsSection) WriteTo(w io.Writer) (written int64, err error) {
+	stmg.Header.Length = stmg.Length()
+	n, err := writeFields(w, stmg.Header, stmg.VolumeThreshold, stmg.MaxVoices,
+		uint32(len(stmg.StateGroups)))
+	written += n
+	if err != nil {
+		return
+	}
+	for _, group := range stmg.StateGroups {
+		n, err = writeFields(w, group.Id, group.DefaultTransitionTime,
+			uint32(len(group.Transitions)), group.Transitions)
+		written += n
+		if err != nil {
+			return
+		}
+	}
+
+	n, err = writeFields(w, uint32(len(stmg.SwitchGroups)))
+	written += n
+	if err != nil {
+		return
+	}
+	for _, group := range stmg.SwitchGroups {
+		n, err = writeFields(w, group.Id, group.RTPCId, group.RTPCType,
+			uint32(len(group.Points)), group.Points)
+		written += n
+		if err != nil {
+			return
+		}
+	}
+
+	n, err = writeFields(w, uint32(len(stmg.GameParameters)),
+		stmg.GameParameters, stmg.Remaining)
+	written += n
+	return
+}
+
+// Identifier returns the four character identifier of this section.
+func (stmg *GlobalSettingsSection) Identifier() string {
+	return string(stmg.Header.Identifier[:])
+}
+
+// Length returns the length in bytes of the data of this section.
+func (stmg *GlobalSettingsSection) Length() uint32 {
+	return uint32(stmg.Size() - SECTION_HEADER_BYTES)
+}
+
+// Size returns the number of bytes that WriteTo would write.
+func (stmg *GlobalSettingsSection) Size() int64 {
+	size := int64(SECTION_HEADER_BYTES + 6 + 12)
+	for _, group := range stmg.StateGroups {
+		size += 12 + int64(binary.Size(group.Transitions))
+	}
+	for _, group := range stmg.SwitchGroups {
+		size += 13 + int64(binary.Size(group.Points))
+	}
+	size += int64(binary.Size(stmg.GameParameters))
+	return size + int64(len(stmg.Remaining))
+}
+
+func (stmg *GlobalSettingsSection) String() string {
+	b := new(strings.Builder)
+	fmt.Fprintf(b, "%s: len(%d) volume_threshold(%g) max_voices(%d) "+
+		"state_groups(%d) switch_groups(%d) game_parameters(%d)\n",
+		stmg.Header.Identifier, stmg.Header.Length, stmg.VolumeThreshold,
+		stmg.MaxVoices, len(stmg.StateGroups), len(stmg.SwitchGroups),
+		len(stmg.GameParameters))
+	for _, group := range stmg.StateGroups {
+		fmt.Fprintf(b, "STMG: state group %d default_transition(%dms) "+
+			"transitions(%d)\n", group.Id, group.DefaultTransitionTime,
+			len(group.Transitions))
+	}
+	for _, group := range stmg.SwitchGroups {
+		fmt.Fprintf(b, "STMG: switch group %d rtpc(%d) points(%d)\n", group.Id,
+			group.RTPCId, len(group.Points))
+	}
+	for _, param := range stmg.GameParameters {
+		fmt.Fprintf(b, "STMG: game parameter %d default(%g)\n", param.Id,
+			param.Value)
+	}
+	return b.String()
+}
@@ -0,0 +1,86 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"fmt"
+)
+
+// A StreamType describes where the media of a sound or music source is stored.
+type StreamType byte
+
+const (
+	// StreamEmbedded media is stored in full in the DATA section of the
+	// SoundBank.
+	StreamEmbedded StreamType = iota
+	// StreamPrefetch media is streamed from a File Package or loose file, and
+	// its beginning is also stored in the DATA section of the SoundBank, so that
+	// it can start playing without waiting for the stream.
+	StreamPrefetch
+	// StreamStreamed media is only stored outside of the SoundBank, in a File
+	// Package or loose file.
+	StreamStreamed
+)
+
+var streamTypeNames = map[StreamType]string{
+	StreamEmbedded: "embedded",
+	StreamPrefetch: "prefetch+streamed",
+	StreamStreamed: "streamed",
+}
+
+func (s StreamType) String() string {
+	if name, ok := streamTypeNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", byte(s))
+}
+
+// InBank returns true if some of the media is stored in the SoundBank.
+func (s StreamType) InBank() bool {
+	return s == StreamEmbedded || s == StreamPrefetch
+}
+
+// A MediaSource is a reference from a HIRC object to the media it plays.
+type MediaSource struct {
+	// The ID of the sound or music track that plays the media.
+	ObjectId uint32
+	// The ID of the media, which is the ID of its wem.
+	MediaId    uint32
+	StreamType StreamType
+}
+
+// StreamType returns where the wem played by this sound is stored.
+func (sound *SfxVoiceSoundObject) StreamType() StreamType {
+	return StreamType(sound.Unknown[4])
+}
+
+// Sources returns every reference from a sound or music track of this section
+// to the media it plays, in the order that the objects are stored.
+func (hrc *ObjectHierarchySection) Sources() []MediaSource {
+	var sources []MediaSource
+	for _, obj := range hrc.objects {
+		switch obj := obj.(type) {
+		case *SfxVoiceSoundObject:
+			sources = append(sources, MediaSource{obj.Id(),
+				obj.WemDescriptor.WemId, obj.StreamType()})
+		case *MusicTrackObject:
+			for _, src := range obj.Sources {
+				sources = append(sources, MediaSource{obj.Id(), src.SourceId,
+					StreamType(src.StreamType)})
+			}
+		}
+	}
+	return sources
+}
+
+// StreamTypes returns a mapping from the ID of every media played by an object
+// of this section to where it is stored. If objects disagree about a media, the
+// first of them is used.
+func (hrc *ObjectHierarchySection) StreamTypes() map[uint32]StreamType {
+	types := make(map[uint32]StreamType)
+	for _, src := range hrc.Sources() {
+		if _, ok := types[src.MediaId]; !ok {
+			types[src.MediaId] = src.StreamType
+		}
+	}
+	return types
+}
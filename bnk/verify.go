@@ -0,0 +1,251 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+// Verify checks the internal consistency of the SoundBank stored in the first
+// size bytes of r, without requiring it to be readable by NewFile. It checks
+// that every section fits within the file, that the length of every section
+// matches the data it holds, and that the wems described by the DIDX section
+// fit within the DATA section without gaps or overlaps. Wems may be separated
+// by the padding needed to align them to alignment bytes, or as Wwise aligns
+// them if alignment is 0. Every problem found is returned; problems within a
+// section are returned as a *SectionError. A nil slice is returned if the
+// SoundBank is consistent.
+func Verify(r io.ReaderAt, size int64, alignment int64) []error {
+	if alignment == 0 {
+		alignment = wemAlignmentBytes
+	}
+	var problems []error
+	var descs []wwise.WemDescriptor
+	var idxOffset, dataOffset, dataLength int64 = -1, -1, 0
+
+	offset := int64(0)
+	for offset < size {
+		if size-offset < SECTION_HEADER_BYTES {
+			msg := fmt.Sprintf("%d bytes at offset %d are too short to hold a "+
+				"section header", size-offset, offset)
+			problems = append(problems, errors.New(msg))
+			break
+		}
+		hr := io.NewSectionReader(r, offset, SECTION_HEADER_BYTES)
+		hdr, err := readSectionHeader(hr)
+		if err != nil {
+			problems = append(problems, err)
+			break
+		}
+		if offset == 0 && hdr.Identifier != bkhdHeaderId {
+			problems = append(problems, ErrNotASoundBank)
+		}
+
+		var sectionProblems []error
+		length := int64(hdr.Length)
+		if offset+SECTION_HEADER_BYTES+length > size {
+			msg := fmt.Sprintf("Its length %d extends %d bytes past the end of the "+
+				"file", length, offset+SECTION_HEADER_BYTES+length-size)
+			sectionProblems = append(sectionProblems, errors.New(msg))
+			length = size - offset - SECTION_HEADER_BYTES
+		}
+		sr := io.NewSectionReader(r, offset+SECTION_HEADER_BYTES, length)
+
+		switch hdr.Identifier {
+		case bkhdHeaderId:
+			if length < BKHD_SECTION_BYTES {
+				msg := fmt.Sprintf("It is %d bytes long, but must be at least %d "+
+					"bytes long", length, BKHD_SECTION_BYTES)
+				sectionProblems = append(sectionProblems, errors.New(msg))
+			}
+		case didxHeaderId:
+			var errs []error
+			descs, errs = verifyIndex(sr, length)
+			sectionProblems = append(sectionProblems, errs...)
+			idxOffset = offset
+		case dataHeaderId:
+			dataOffset, dataLength = offset, length
+		case hircHeaderId:
+			sectionProblems = append(sectionProblems, verifyObjects(sr, length)...)
+		case stidHeaderId:
+			sectionProblems = append(sectionProblems, verifyStringIds(sr, length)...)
+		}
+		for _, err := range sectionProblems {
+			problems = append(problems,
+				&SectionError{string(hdr.Identifier[:]), offset, err})
+		}
+		offset += SECTION_HEADER_BYTES + length
+	}
+
+	if idxOffset >= 0 && dataOffset < 0 && len(descs) > 0 {
+		msg := fmt.Sprintf("It describes %d wems, but there is no DATA section",
+			len(descs))
+		problems = append(problems,
+			&SectionError{string(didxHeaderId[:]), idxOffset, errors.New(msg)})
+	}
+	if dataOffset >= 0 {
+		for _, err := range verifyWems(descs, dataLength, alignment) {
+			problems = append(problems,
+				&SectionError{string(dataHeaderId[:]), dataOffset, err})
+		}
+	}
+	return problems
+}
+
+// verifyIndex reads the wem descriptors of a DIDX section of the given length
+// from r, and returns them along with any problems with the section.
+func verifyIndex(r io.Reader, length int64) ([]wwise.WemDescriptor, []error) {
+	var problems []error
+	if length%DIDX_ENTRY_BYTES != 0 {
+		problems = append(problems, fmt.Errorf("%w: its length %d is not a "+
+			"multiple of %d", ErrCorruptDIDX, length, DIDX_ENTRY_BYTES))
+	}
+	var descs []wwise.WemDescriptor
+	seen := make(map[uint32]bool)
+	for i := int64(0); i < length/DIDX_ENTRY_BYTES; i++ {
+		var desc wwise.WemDescriptor
+		err := binary.Read(r, binary.LittleEndian, &desc)
+		if err != nil {
+			return descs, append(problems, err)
+		}
+		if seen[desc.WemId] {
+			problems = append(problems, fmt.Errorf("%w: %d is an illegal repeated "+
+				"wem ID", ErrCorruptDIDX, desc.WemId))
+			continue
+		}
+		seen[desc.WemId] = true
+		descs = append(descs, desc)
+	}
+	return descs, problems
+}
+
+// verifyWems returns the problems with storing the wems described by descs in
+// a DATA section of the given length. Wems may be separated by no more padding
+// than is needed to align the wem that follows to alignment bytes.
+func verifyWems(descs []wwise.WemDescriptor, length int64,
+	alignment int64) []error {
+	var problems []error
+	sorted := make([]wwise.WemDescriptor, len(descs))
+	copy(sorted, descs)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Offset < sorted[j].Offset
+	})
+
+	end, last := int64(0), uint32(0)
+	for _, desc := range sorted {
+		start := int64(desc.Offset)
+		if desc.Length == 0 {
+			// An empty wem holds no data, so it cannot overlap with another wem.
+			continue
+		}
+		aligned := (end + alignment - 1) / alignment * alignment
+		switch {
+		case start < end:
+			problems = append(problems, fmt.Errorf("%w: wem %d overlaps with wem "+
+				"%d by %d bytes", ErrCorruptDIDX, last, desc.WemId, end-start))
+		case start > aligned:
+			msg := fmt.Sprintf("There are %d unused bytes before wem %d",
+				start-end, desc.WemId)
+			problems = append(problems, errors.New(msg))
+		}
+		if start+int64(desc.Length) > length {
+			problems = append(problems, fmt.Errorf("%w: wem %d ends at offset %d, "+
+				"past the end of the DATA section at offset %d", ErrCorruptDIDX,
+				desc.WemId, start+int64(desc.Length), length))
+		}
+		if start+int64(desc.Length) > end {
+			end, last = start+int64(desc.Length), desc.WemId
+		}
+	}
+	aligned := (end + alignment - 1) / alignment * alignment
+	if length > aligned {
+		msg := fmt.Sprintf("There are %d unused bytes after the last wem",
+			length-end)
+		problems = append(problems, errors.New(msg))
+	}
+	return problems
+}
+
+// verifyObjects returns the problems with the objects of a HIRC section of the
+// given length, read from r.
+func verifyObjects(r io.Reader, length int64) []error {
+	var count uint32
+	err := binary.Read(r, binary.LittleEndian, &count)
+	if err != nil {
+		return []error{err}
+	}
+	read := int64(OBJECT_COUNT_BYTES)
+	for i := uint32(0); i < count; i++ {
+		var objType byte
+		var objLength uint32
+		err := binary.Read(r, binary.LittleEndian, &objType)
+		if err == nil {
+			err = binary.Read(r, binary.LittleEndian, &objLength)
+		}
+		if err != nil {
+			msg := fmt.Sprintf("Object %d of %d is cut off by the end of the "+
+				"section", i+1, count)
+			return []error{errors.New(msg)}
+		}
+		read += OBJECT_DESCRIPTOR_BYTES - OBJECT_DESCRIPTOR_ID_BYTES +
+			int64(objLength)
+		if read > length {
+			msg := fmt.Sprintf("Object %d of %d ends %d bytes past the end of the "+
+				"section", i+1, count, read-length)
+			return []error{errors.New(msg)}
+		}
+		_, err = io.CopyN(ioutil.Discard, r, int64(objLength))
+		if err != nil {
+			return []error{err}
+		}
+	}
+	if read != length {
+		msg := fmt.Sprintf("Its length is %d, but its %d objects take %d bytes",
+			length, count, read)
+		return []error{errors.New(msg)}
+	}
+	return nil
+}
+
+// verifyStringIds returns the problems with the entries of a STID section of
+// the given length, read from r.
+func verifyStringIds(r io.Reader, length int64) []error {
+	var fields [2]uint32
+	err := binary.Read(r, binary.LittleEndian, &fields)
+	if err != nil {
+		return []error{err}
+	}
+	count := fields[1]
+	read := int64(8)
+	for i := uint32(0); i < count; i++ {
+		var id uint32
+		var size byte
+		err := binary.Read(r, binary.LittleEndian, &id)
+		if err == nil {
+			err = binary.Read(r, binary.LittleEndian, &size)
+		}
+		if err == nil {
+			_, err = io.CopyN(ioutil.Discard, r, int64(size))
+		}
+		if err != nil {
+			msg := fmt.Sprintf("Entry %d of %d is cut off by the end of the "+
+				"section", i+1, count)
+			return []error{errors.New(msg)}
+		}
+		read += 5 + int64(size)
+	}
+	if read != length {
+		msg := fmt.Sprintf("Its length is %d, but its %d entries take %d bytes",
+			length, count, read)
+		return []error{errors.New(msg)}
+	}
+	return nil
+}
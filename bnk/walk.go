@@ -0,0 +1,121 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+// SkipSection may be returned by a WalkFunc to skip the wems of the section it
+// was called for, or the remaining wems if it was called for a wem. It is not
+// returned as an error by Walk.
+var SkipSection = errors.New("skip this section")
+
+// A WalkFunc is called by Walk for every section of a SoundBank, with wem set
+// to nil, and then for every wem of the DATA section, with hdr set to the
+// header of the DATA section. The Reader of wem only holds the wem until the
+// WalkFunc returns, and its Padding is nil. If a WalkFunc returns an error,
+// Walk stops and returns that error, unless it is SkipSection.
+type WalkFunc func(hdr *SectionHeader, wem *wwise.Wem) error
+
+// Walk reads the SoundBank from r in a single pass, calling fn for each of its
+// sections and wems in the order they are stored. Unlike NewFile, no part of
+// the SoundBank is retained once fn has returned, so Walk processes SoundBanks
+// of any size in constant memory. The DIDX section must come before the DATA
+// section, and its wems must not overlap, as r cannot be read backwards.
+func Walk(r io.Reader, fn WalkFunc) error {
+	var descs []*wwise.WemDescriptor
+	offset := int64(0)
+	for {
+		hdr, err := readSectionHeader(r)
+		if err != nil {
+			if offset == 0 {
+				return ErrNotASoundBank
+			}
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if offset == 0 && hdr.Identifier != bkhdHeaderId {
+			return ErrNotASoundBank
+		}
+
+		sr := io.LimitReader(r, int64(hdr.Length))
+		err = fn(hdr, nil)
+		switch {
+		case err == SkipSection:
+		case err != nil:
+			return err
+		case hdr.Identifier == didxHeaderId:
+			var idx *DataIndexSection
+			idx, _, err = hdr.newDataIndexSection(sr, DuplicateKeepAll)
+			if err == nil {
+				descs = idx.Descriptors
+			}
+		case hdr.Identifier == dataHeaderId:
+			err = walkWems(hdr, sr, descs, fn)
+		}
+		if err != nil {
+			return &SectionError{string(hdr.Identifier[:]), offset, err}
+		}
+		// Skip whatever remains of the section.
+		_, err = io.Copy(ioutil.Discard, sr)
+		if err != nil {
+			return err
+		}
+		offset += SECTION_HEADER_BYTES + int64(hdr.Length)
+	}
+}
+
+// walkWems calls fn for each wem described by descs, reading the data of the
+// DATA section described by hdr from r.
+func walkWems(hdr *SectionHeader, r io.Reader,
+	descs []*wwise.WemDescriptor, fn WalkFunc) error {
+	sorted := make([]*wwise.WemDescriptor, len(descs))
+	copy(sorted, descs)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Offset < sorted[j].Offset
+	})
+
+	read := int64(0)
+	for _, desc := range sorted {
+		if desc.Length > 0 {
+			if int64(desc.Offset) < read {
+				return fmt.Errorf("%w: wem %d overlaps with the wem before it",
+					ErrCorruptDIDX, desc.WemId)
+			}
+			_, err := io.CopyN(ioutil.Discard, r, int64(desc.Offset)-read)
+			if err != nil {
+				return err
+			}
+			read = int64(desc.Offset)
+		}
+		lr := &io.LimitedReader{R: r, N: int64(desc.Length)}
+		err := fn(hdr, &wwise.Wem{Reader: lr, Descriptor: desc})
+		if err == SkipSection {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// Skip whatever the WalkFunc did not read of the wem.
+		_, err = io.Copy(ioutil.Discard, lr)
+		if err != nil {
+			return err
+		}
+		if lr.N > 0 {
+			return fmt.Errorf("%w: wem %d ends past the end of the DATA section",
+				ErrCorruptDIDX, desc.WemId)
+		}
+		read += int64(desc.Length)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+var shouldListAttenuations bool
+
+func init() {
+	const (
+		usage = "list every attenuation ShareSet within the .bnk specified by " +
+			"filepath, along with the points of its distance curves."
+		flagName = "attenuations"
+	)
+	flag.BoolVar(&shouldListAttenuations, flagName, false, usage)
+	registerMode(&mode{name: flagName, selected: &shouldListAttenuations,
+		needsFile: true, run: listAttenuations})
+}
+
+// The names of the properties that an attenuation may drive, in the order of
+// bnk.AttenuationObject.CurveToUse.
+var attenuationProperties = []string{"Dry volume", "Game aux volume",
+	"User aux volume", "Low pass", "High pass", "Spread", "Focus"}
+
+// listAttenuations prints every attenuation of the input SoundBank and its
+// curves.
+func listAttenuations(isSoundBank bool) {
+	if !isSoundBank {
+		fatal(exitUsage, "attenuations only supports SoundBank files")
+	}
+
+	b, err := openSoundBank(filePath)
+	if err != nil {
+		fatalln(exitParse, "Could not parse .bnk file:", err)
+	}
+	defer b.Close()
+	if b.ObjectSection == nil {
+		fatal(exitValidation, "The SoundBank does not contain a HIRC section")
+	}
+
+	atts := b.ObjectSection.Attenuations()
+	for _, att := range atts {
+		fmt.Printf("Attenuation %d: max distance %g\n", att.Id(),
+			att.MaxDistance())
+		if att.Cone != nil {
+			fmt.Printf("  Cone: inside %g°, outside %g°, outside volume %gdB\n",
+				att.Cone.InsideDegrees, att.Cone.OutsideDegrees,
+				att.Cone.OutsideVolume)
+		}
+		for i, name := range attenuationProperties {
+			c := att.Curve(i)
+			if c == nil {
+				continue
+			}
+			fmt.Printf("  %s:", name)
+			for _, p := range c.Points {
+				fmt.Printf(" (%g, %g)", p.From, p.To)
+			}
+			fmt.Println()
+		}
+	}
+	fmt.Printf("Listed %d attenuation(s)\n", len(atts))
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/util"
+)
+
+// A batchResult is the result of running a mode on a single file of a batch.
+type batchResult struct {
+	summary string
+	err     error
+	// True if the file was processed. Files are not processed after a failure,
+	// unless the keep-going flag is set.
+	done bool
+}
+
+// batchInputs returns the .bnk and .pck files named by path, which may be a
+// directory, which is searched recursively, or a glob pattern. isBatch is false
+// if path names a single file.
+func batchInputs(path string) (paths []string, isBatch bool) {
+	var candidates []string
+	if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+		err := filepath.Walk(path, func(p string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !fi.IsDir() {
+				candidates = append(candidates, p)
+			}
+			return nil
+		})
+		if err != nil {
+			fatalf(exitIO, "Could not open input directory, \"%s\": %s\n", path, err)
+		}
+	} else if strings.ContainsAny(path, "*?[") {
+		var err error
+		candidates, err = filepath.Glob(path)
+		if err != nil {
+			fatalf(exitUsage, "\"%s\" is not a valid glob pattern: %s\n", path, err)
+		}
+	} else {
+		return nil, false
+	}
+
+	for _, c := range candidates {
+		fileType, _ := util.GetFileType(c)
+		if fileType == util.SoundBankFileType ||
+			fileType == util.FilePackageFileType {
+			paths = append(paths, c)
+		}
+	}
+	return paths, true
+}
+
+// batchOutputName returns the name of the output of the file at path within a
+// batch. Files found in a directory are named after their path relative to it,
+// so that files of the same name in different directories do not collide.
+func batchOutputName(path string) string {
+	name := filepath.Base(path)
+	if fi, err := os.Stat(filePath); err == nil && fi.IsDir() {
+		if rel, err := filepath.Rel(filePath, path); err == nil {
+			name = rel
+		}
+	}
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// runBatch runs the mode m on every file of paths, using as many goroutines as
+// specified by threads, and then prints a summary of the result for each file.
+// Unless the keep-going flag is set, no further files are processed once m
+// fails for a file. Exits with a non-zero status if m failed for any file.
+func runBatch(m *mode, paths []string) {
+	if m.batch == nil {
+		fatalf(exitUsage, "%s does not support more than one input file\n", m.name)
+	}
+	if len(paths) == 0 {
+		fatalf(exitUsage, "There are no .bnk or .pck files in %s\n", filePath)
+	}
+	if m.needsOutput {
+		err := createDirIfEmpty(output)
+		if err != nil {
+			fatalln(exitIO, "Could not create output directory:", err)
+		}
+	}
+
+	results := make([]batchResult, len(paths))
+	jobs := make(chan int)
+	var failures int32
+	var wg sync.WaitGroup
+	for t := 0; t < threads && t < len(paths); t++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fileType, _ := util.GetFileType(paths[i])
+				summary, err := m.batch(paths[i], fileType == util.SoundBankFileType)
+				results[i] = batchResult{summary, err, true}
+				if err != nil {
+					atomic.AddInt32(&failures, 1)
+				}
+			}
+		}()
+	}
+	for i := range paths {
+		if !keepGoing && atomic.LoadInt32(&failures) > 0 {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	failed, skipped := 0, 0
+	for i, r := range results {
+		if !r.done {
+			skipped++
+			continue
+		}
+		if r.err != nil {
+			fmt.Printf("%s: failed: %s\n", paths[i], r.err)
+			opReport.addWarning("%s: %s", paths[i], r.err)
+			failed++
+			continue
+		}
+		fmt.Printf("%s: %s\n", paths[i], r.summary)
+	}
+	fmt.Printf("Processed %d file(s), %d failed\n", len(paths)-skipped, failed)
+	if skipped > 0 {
+		fmt.Printf("Stopped before processing %d file(s); use -keep-going to "+
+			"process every file\n", skipped)
+	}
+	if failed > 0 {
+		exit(exitPartial, fmt.Sprintf("%d of %d file(s) failed", failed,
+			len(paths)))
+	}
+}
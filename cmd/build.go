@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/util"
+)
+
+var shouldBuild bool
+var projectPath string
+
+func init() {
+	const (
+		usage = "rebuild every .bnk and .pck listed in the given project file, " +
+			"replacing their wems as replace does. The project file is a JSON " +
+			"object of the form {\"banks\": [{\"filepath\": ..., \"target\": ..., " +
+			"\"output\": ...}]}, where each entry holds the values of the flags of " +
+			"the same name for one file. Relative paths are relative to the " +
+			"directory of the project file."
+		flagName = "build"
+	)
+	flag.Var(modeValue{&projectPath, &shouldBuild, true}, flagName, usage)
+	registerMode(&mode{name: flagName, selected: &shouldBuild, run: build})
+}
+
+// A project lists the files that a mod modifies, and the replacements made to
+// each of them.
+type project struct {
+	Banks []projectBank `json:"banks"`
+}
+
+// A projectBank is a file modified by a project.
+type projectBank struct {
+	FilePath string `json:"filepath"`
+	Target   string `json:"target"`
+	Output   string `json:"output"`
+}
+
+// readProject reads the project file at path, and resolves the paths it holds
+// against the directory of the project file.
+func readProject(path string) (*project, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var p project
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	err = dec.Decode(&p)
+	if err != nil {
+		return nil, err
+	}
+	if len(p.Banks) == 0 {
+		return nil, errors.New("The project does not list any banks")
+	}
+	dir := filepath.Dir(path)
+	for i := range p.Banks {
+		b := &p.Banks[i]
+		if b.FilePath == "" || b.Target == "" || b.Output == "" {
+			msg := fmt.Sprintf("Bank %d of the project must specify filepath, "+
+				"target and output", i+1)
+			return nil, errors.New(msg)
+		}
+		for _, bp := range []*string{&b.FilePath, &b.Target, &b.Output} {
+			if !filepath.IsAbs(*bp) {
+				*bp = filepath.Join(dir, *bp)
+			}
+		}
+	}
+	return &p, nil
+}
+
+// build replaces the wems of every file listed in the project file. Unless the
+// keep-going flag is set, it stops at the first file that cannot be rebuilt.
+func build(bool) {
+	p, err := readProject(projectPath)
+	if err != nil {
+		fatalln(exitParse, "Could not read project file:", err)
+	}
+	var failed []string
+	for i, b := range p.Banks {
+		fmt.Printf("Building %s (%d of %d)\n", b.Output, i+1, len(p.Banks))
+		err := buildBank(b)
+		if err == nil {
+			continue
+		}
+		if !keepGoing {
+			fatalErr(err)
+		}
+		log.Printf("Could not build %s: %s\n", b.Output, err)
+		failed = append(failed, b.Output)
+	}
+	if len(failed) > 0 {
+		fatalf(exitPartial, "Built %d of %d file(s); failed to build: %s\n",
+			len(p.Banks)-len(failed), len(p.Banks), strings.Join(failed, ", "))
+	}
+	fmt.Printf("Successfully built %d file(s)\n", len(p.Banks))
+}
+
+// buildBank rebuilds a single file of a project, exactly as if replace had been
+// used with its flags.
+func buildBank(b projectBank) error {
+	filePath, targetPath, output = b.FilePath, b.Target, b.Output
+	fileType, ext := util.GetFileType(filePath)
+	if fileType != util.SoundBankFileType &&
+		fileType != util.FilePackageFileType {
+		return exitErrorf(exitUsage, "%s, is not a supported input file type",
+			ext)
+	}
+	err := os.MkdirAll(filepath.Dir(output), os.ModePerm)
+	if err != nil {
+		return exitErrorf(exitIO, "Could not create output directory: %s", err)
+	}
+	return replaceFile(fileType == util.SoundBankFileType)
+}
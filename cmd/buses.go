@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+)
+
+var shouldListBuses bool
+
+func init() {
+	const (
+		usage = "print the tree of audio and auxiliary buses within the .bnk " +
+			"specified by filepath, along with their volumes and effects. Buses " +
+			"whose parent is stored in another SoundBank are printed at the root."
+		flagName = "buses"
+	)
+	flag.BoolVar(&shouldListBuses, flagName, false, usage)
+	registerMode(&mode{name: flagName, selected: &shouldListBuses,
+		needsFile: true, run: listBuses})
+}
+
+// listBuses prints the bus tree of the input SoundBank.
+func listBuses(isSoundBank bool) {
+	if !isSoundBank {
+		fatal(exitUsage, "buses only supports SoundBank files")
+	}
+
+	b, err := openSoundBank(filePath)
+	if err != nil {
+		fatalln(exitParse, "Could not parse .bnk file:", err)
+	}
+	defer b.Close()
+	if b.ObjectSection == nil {
+		fatal(exitValidation, "The SoundBank does not contain a HIRC section")
+	}
+
+	buses := b.ObjectSection.Buses()
+	stored := make(map[uint32]bool)
+	for _, bus := range buses {
+		stored[bus.Id()] = true
+	}
+	children := make(map[uint32][]*bnk.BusObject)
+	var roots []*bnk.BusObject
+	for _, bus := range buses {
+		if stored[bus.ParentId] {
+			children[bus.ParentId] = append(children[bus.ParentId], bus)
+		} else {
+			roots = append(roots, bus)
+		}
+	}
+
+	var print func(bus *bnk.BusObject, depth int)
+	print = func(bus *bnk.BusObject, depth int) {
+		indent := strings.Repeat("  ", depth)
+		kind := "Bus"
+		if bus.IsAuxiliary() {
+			kind = "Aux bus"
+		}
+		fmt.Printf("%s%s %d: volume %gdB, bus volume %gdB", indent, kind,
+			bus.Id(), bus.Volume(), bus.BusVolume())
+		if depth == 0 && bus.ParentId != 0 {
+			fmt.Printf(", parent %d", bus.ParentId)
+		}
+		fmt.Println()
+		for _, fx := range bus.Effects {
+			kind := "custom effect"
+			if fx.IsShareSet != 0 {
+				kind = "ShareSet"
+			}
+			bypassed := ""
+			if bus.Bypass&(1<<fx.Index) != 0 {
+				bypassed = ", bypassed"
+			}
+			fmt.Printf("%s  Effect slot %d: %d (%s%s)\n", indent, fx.Index,
+				fx.EffectId, kind, bypassed)
+		}
+		for _, child := range children[bus.Id()] {
+			print(child, depth+1)
+		}
+	}
+	for _, bus := range roots {
+		print(bus, 0)
+	}
+	fmt.Printf("Listed %d bus(es)\n", len(buses))
+}
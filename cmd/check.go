@@ -0,0 +1,101 @@
+package main
+
+import (
+	"crypto/sha256"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+var shouldCheck bool
+
+func init() {
+	const (
+		usage = "compare the wems within the directory specified by target " +
+			"against the wems they would replace in the .bnk or .pck specified by " +
+			"filepath, and report which replacements are already present. Nothing " +
+			"is written."
+		flagName = "check"
+	)
+	flag.BoolVar(&shouldCheck, flagName, false, usage)
+	registerMode(&mode{name: flagName, selected: &shouldCheck,
+		needsFile: true, run: func(isSoundBank bool) {
+			verifyReplaceFlags()
+			check(isSoundBank)
+		}})
+}
+
+// check reports, for every replacement wem in the target directory, whether
+// the input file already holds a wem with the same contents at its index.
+func check(isSoundBank bool) {
+	var ctn wwise.Container
+	var err error
+	if isSoundBank {
+		ctn, err = openSoundBank(filePath)
+	} else { // Input is file package
+		ctn, err = openFilePackage(filePath)
+	}
+	if err != nil {
+		fatalln(exitParse, "Could not parse .bnk or .pck file:", err)
+	}
+	defer ctn.Close()
+
+	targetFileInfos, err := ioutil.ReadDir(targetPath)
+	if err != nil {
+		fatalf(exitIO,
+			"Could not open target directory, \"%s\": %s\n", targetPath, err)
+	}
+	targets, err := processTargetFiles(ctn, targetFileInfos)
+	if err != nil {
+		fatalErr(err)
+	}
+	defer closeTargets(targets)
+
+	tableParams := []string{"%-7", "%-15", "%-8", "\n"}
+	titleFmt := strings.Join(tableParams, "s|")
+	title := fmt.Sprintf(titleFmt, "Index", "Id", "Applied")
+	fmt.Print(title)
+	fmt.Println(strings.Repeat("-", len(title)-1))
+	applied := 0
+	wems := ctn.Wems()
+	for _, t := range targets {
+		wem := wems[t.WemIndex]
+		present, err := sameContents(wem, t)
+		if err != nil {
+			fatalf(exitIO, "Could not compare wem %d: %s", t.WemIndex+1, err)
+		}
+		status := "no"
+		if present {
+			status = "yes"
+			applied++
+		}
+		fmt.Printf(titleFmt, fmt.Sprint(t.WemIndex+1),
+			fmt.Sprint(wem.Descriptor.WemId), status)
+	}
+	fmt.Printf("%d of %d replacement(s) already applied\n", applied,
+		len(targets))
+}
+
+// sameContents returns true if the wem and its replacement have the same
+// SHA-256 hash.
+func sameContents(wem *wwise.Wem, r *wwise.ReplacementWem) (bool, error) {
+	if int64(wem.Descriptor.Length) != r.Length {
+		return false, nil
+	}
+	h := sha256.New()
+	if _, err := wem.WriteTo(h); err != nil {
+		return false, err
+	}
+	want := h.Sum(nil)
+	h.Reset()
+	if _, err := io.Copy(h, io.NewSectionReader(r.Wem, 0, r.Length)); err != nil {
+		return false, err
+	}
+	return string(h.Sum(nil)) == string(want), nil
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"sort"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+var shouldCompare bool
+var comparePath string
+
+func init() {
+	const (
+		usage = "compare the .bnk specified by filepath with the given .bnk, such " +
+			"as the same SoundBank after a game patch, and print the differences " +
+			"between their BKHD sections, the sizes of their sections, and the " +
+			"wems that were added, removed or changed, as found by their IDs and " +
+			"the SHA-256 checksums of their data."
+		flagName = "compare"
+	)
+	flag.Var(modeValue{&comparePath, &shouldCompare, true}, flagName, usage)
+	registerMode(&mode{name: flagName, selected: &shouldCompare,
+		needsFile: true, run: compare})
+}
+
+// compare prints the differences between the input SoundBank and the SoundBank
+// given to compare.
+func compare(isSoundBank bool) {
+	if !isSoundBank {
+		fatal(exitUsage, "compare only supports SoundBank files")
+	}
+	before, err := openSoundBank(filePath)
+	if err != nil {
+		fatalln(exitParse, "Could not parse .bnk file:", err)
+	}
+	defer before.Close()
+	after, err := openSoundBank(comparePath)
+	if err != nil {
+		fatalln(exitParse, "Could not parse compared .bnk file:", err)
+	}
+	defer after.Close()
+
+	differences := compareHeaders(before, after)
+	differences += compareSections(before, after)
+	differences += compareWems(before, after)
+	if differences == 0 {
+		fmt.Println("The SoundBanks do not differ")
+	}
+}
+
+// compareHeaders prints the fields of the BKHD sections of before and after
+// that differ, and returns the number of them.
+func compareHeaders(before, after *bnk.File) int {
+	oldFields, newFields := headerFields(before), headerFields(after)
+	differences := 0
+	for _, name := range []string{"Version", "Bank ID", "Language",
+		"Feedback in bank", "Alignment", "Device allocated", "Project ID"} {
+		if oldFields[name] != newFields[name] {
+			fmt.Printf("%s: %s -> %s\n", name, oldFields[name], newFields[name])
+			differences++
+		}
+	}
+	return differences
+}
+
+// headerFields returns the fields of the BKHD section of b, by their names.
+func headerFields(b *bnk.File) map[string]string {
+	fields := make(map[string]string)
+	hdr := b.BankHeaderSection
+	if hdr == nil {
+		return fields
+	}
+	fields["Version"] = fmt.Sprint(hdr.Descriptor.Version)
+	fields["Bank ID"] = fmt.Sprint(hdr.Descriptor.BankId)
+	if s := hdr.Settings; s != nil {
+		fields["Language"] = fmt.Sprint(s.LanguageId)
+		if name, ok := hdr.Language(); ok {
+			fields["Language"] = name
+		}
+		fields["Feedback in bank"] = fmt.Sprint(s.FeedbackInBank)
+		fields["Alignment"] = fmt.Sprint(s.Alignment)
+		fields["Device allocated"] = fmt.Sprint(s.DeviceAllocated)
+		fields["Project ID"] = fmt.Sprint(s.ProjectId)
+	}
+	return fields
+}
+
+// compareSections prints the sections of before and after whose sizes differ,
+// and returns the number of them. Sections with the same identifier are counted
+// together.
+func compareSections(before, after *bnk.File) int {
+	oldSizes, newSizes := sectionSizes(before), sectionSizes(after)
+	var ids []string
+	for id := range oldSizes {
+		ids = append(ids, id)
+	}
+	for id := range newSizes {
+		if _, ok := oldSizes[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	differences := 0
+	for _, id := range ids {
+		oldSize, inOld := oldSizes[id]
+		newSize, inNew := newSizes[id]
+		switch {
+		case !inOld:
+			fmt.Printf("Section %s: added, %d bytes\n", id, newSize)
+		case !inNew:
+			fmt.Printf("Section %s: removed, %d bytes\n", id, oldSize)
+		case oldSize != newSize:
+			fmt.Printf("Section %s: %d -> %d bytes (%+d)\n", id, oldSize, newSize,
+				newSize-oldSize)
+		default:
+			continue
+		}
+		differences++
+	}
+	return differences
+}
+
+// sectionSizes returns the total size of the sections of b with each
+// identifier.
+func sectionSizes(b *bnk.File) map[string]int64 {
+	sizes := make(map[string]int64)
+	for _, s := range b.Sections() {
+		sizes[s.Identifier()] += s.Size()
+	}
+	return sizes
+}
+
+// compareWems prints the wems that were added to, removed from or changed
+// between before and after, and returns the number of them.
+func compareWems(before, after *bnk.File) int {
+	oldSums, newSums := wemChecksums(before), wemChecksums(after)
+	var ids []uint32
+	for id := range oldSums {
+		ids = append(ids, id)
+	}
+	for id := range newSums {
+		if _, ok := oldSums[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	added, removed, changed := 0, 0, 0
+	for _, id := range ids {
+		oldSum, inOld := oldSums[id]
+		newSum, inNew := newSums[id]
+		switch {
+		case !inOld:
+			fmt.Printf("Wem %d: added, %d bytes, %s\n", id, newSum.length,
+				newSum.sum)
+			added++
+		case !inNew:
+			fmt.Printf("Wem %d: removed, %d bytes, %s\n", id, oldSum.length,
+				oldSum.sum)
+			removed++
+		case oldSum != newSum:
+			fmt.Printf("Wem %d: changed, %d -> %d bytes, %s -> %s\n", id,
+				oldSum.length, newSum.length, oldSum.sum, newSum.sum)
+			changed++
+		}
+	}
+	if added+removed+changed > 0 {
+		fmt.Printf("%d wem(s) added, %d removed and %d changed\n", added, removed,
+			changed)
+	}
+	return added + removed + changed
+}
+
+// A wemChecksum identifies the contents of a wem.
+type wemChecksum struct {
+	length int64
+	sum    string
+}
+
+// wemChecksums returns the length and SHA-256 checksum of every wem of b, by
+// its ID. If b holds a wem more than once, its first copy is used.
+func wemChecksums(b *bnk.File) map[uint32]wemChecksum {
+	sums := make(map[uint32]wemChecksum)
+	for _, wem := range b.Wems() {
+		id := wem.Descriptor.WemId
+		if _, ok := sums[id]; ok {
+			continue
+		}
+		sums[id] = wemChecksum{int64(wem.Descriptor.Length), wemSum(wem)}
+	}
+	return sums
+}
+
+// wemSum returns the hex encoded SHA-256 checksum of the data of wem.
+func wemSum(wem *wwise.Wem) string {
+	h := sha256.New()
+	_, err := wem.WriteTo(h)
+	if err != nil {
+		fatalf(exitIO, "Could not read wem %d: %s\n", wem.Descriptor.WemId, err)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var shouldComplete bool
+var completionShell string
+
+// The shells that completion scripts can be generated for.
+var completionShells = []string{"bash", "fish", "powershell", "zsh"}
+
+func init() {
+	const (
+		usage = "print a script that completes the flags of this tool, and the " +
+			"files and values they take, for the given shell. One of bash, zsh, " +
+			"fish or powershell."
+		flagName = "completion"
+	)
+	flag.Var(modeValue{&completionShell, &shouldComplete, false}, flagName,
+		usage)
+	registerMode(&mode{name: flagName, selected: &shouldComplete,
+		run: completion})
+}
+
+// completion prints the completion script for the shell given to completion.
+func completion(bool) {
+	name := strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe")
+	switch completionShell {
+	case "bash":
+		bashCompletion(name)
+	case "zsh":
+		zshCompletion(name)
+	case "fish":
+		fishCompletion(name)
+	case "powershell":
+		powershellCompletion(name)
+	default:
+		fatalf(exitUsage, "\"%s\" is not a supported shell\n", completionShell)
+	}
+}
+
+// A completedFlag describes how a single flag is completed.
+type completedFlag struct {
+	name string
+	// The first sentence of the flag's usage.
+	desc string
+	// True if the flag takes a value.
+	hasValue bool
+	// The values the flag may take, if they are known.
+	choices []string
+	// True if the flag takes a file path.
+	isPath bool
+}
+
+// completedFlags returns how every flag is completed, sorted by name.
+func completedFlags() []completedFlag {
+	choices := map[string][]string{
+		"codec-naming": {codecNamingExtension, codecNamingSuffix},
+		"on-conflict":  {conflictOverwrite, conflictSkip, conflictError},
+	}
+	for p := range duplicatePolicies {
+		choices["duplicate-ids"] = append(choices["duplicate-ids"], p)
+	}
+	for l := range layouts {
+		choices["layout"] = append(choices["layout"], l)
+	}
+	choices["completion"] = completionShells
+
+	var flags []completedFlag
+	flag.VisitAll(func(f *flag.Flag) {
+		desc := f.Usage
+		if i := strings.Index(desc, ". "); i >= 0 {
+			desc = desc[:i]
+		}
+		desc = strings.TrimSuffix(desc, ".")
+		hasValue := true
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			hasValue = false
+		}
+		isPath := pathFlags[f.Name]
+		if v, ok := f.Value.(modeValue); ok {
+			isPath = v.isPath
+		}
+		c := choices[f.Name]
+		sort.Strings(c)
+		flags = append(flags, completedFlag{f.Name, desc, hasValue, c, isPath})
+	})
+	return flags
+}
+
+func bashCompletion(name string) {
+	var all, paths []string
+	fmt.Printf("_%s() {\n", name)
+	fmt.Println("\tlocal cur=\"${COMP_WORDS[COMP_CWORD]}\"")
+	fmt.Println("\tlocal prev=\"${COMP_WORDS[COMP_CWORD-1]}\"")
+	fmt.Println("\tcase \"$prev\" in")
+	for _, f := range completedFlags() {
+		all = append(all, "-"+f.name)
+		switch {
+		case f.choices != nil:
+			fmt.Printf("\t-%s) COMPREPLY=($(compgen -W \"%s\" -- \"$cur\")); "+
+				"return;;\n", f.name, strings.Join(f.choices, " "))
+		case f.isPath:
+			paths = append(paths, "-"+f.name)
+		case f.hasValue:
+			fmt.Printf("\t-%s) return;;\n", f.name)
+		}
+	}
+	fmt.Printf("\t%s) COMPREPLY=($(compgen -f -- \"$cur\")); return;;\n",
+		strings.Join(paths, "|"))
+	fmt.Println("\tesac")
+	fmt.Printf("\tCOMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n",
+		strings.Join(all, " "))
+	fmt.Println("}")
+	fmt.Printf("complete -o filenames -F _%s %s\n", name, name)
+}
+
+func zshCompletion(name string) {
+	escape := strings.NewReplacer("[", "\\[", "]", "\\]", ":", "\\:", "'",
+		"'\\''")
+	fmt.Printf("#compdef %s\n\n", name)
+	fmt.Printf("_arguments \\\n")
+	for _, f := range completedFlags() {
+		spec := fmt.Sprintf("-%s[%s]", f.name, escape.Replace(f.desc))
+		switch {
+		case f.choices != nil:
+			spec += fmt.Sprintf(":%s:(%s)", f.name, strings.Join(f.choices, " "))
+		case f.isPath:
+			spec += fmt.Sprintf(":%s:_files", f.name)
+		case f.hasValue:
+			spec += fmt.Sprintf(":%s: ", f.name)
+		}
+		fmt.Printf("\t'%s' \\\n", spec)
+	}
+	fmt.Println("\t'*:file:_files'")
+}
+
+func fishCompletion(name string) {
+	escape := strings.NewReplacer("\\", "\\\\", "'", "\\'")
+	for _, f := range completedFlags() {
+		opts := ""
+		switch {
+		case f.choices != nil:
+			opts = fmt.Sprintf(" -x -a '%s'", strings.Join(f.choices, " "))
+		case f.isPath:
+			opts = " -r -F"
+		case f.hasValue:
+			opts = " -x"
+		}
+		fmt.Printf("complete -c %s -o %s%s -d '%s'\n", name, f.name, opts,
+			escape.Replace(f.desc))
+	}
+}
+
+func powershellCompletion(name string) {
+	escape := strings.NewReplacer("'", "''")
+	fmt.Printf("Register-ArgumentCompleter -Native -CommandName %s "+
+		"-ScriptBlock {\n", name)
+	fmt.Println("\tparam($wordToComplete, $commandAst, $cursorPosition)")
+	fmt.Println("\t$flags = @{")
+	var choices []completedFlag
+	for _, f := range completedFlags() {
+		fmt.Printf("\t\t'-%s' = '%s'\n", f.name, escape.Replace(f.desc))
+		if f.choices != nil {
+			choices = append(choices, f)
+		}
+	}
+	fmt.Println("\t}")
+	fmt.Println("\t$choices = @{")
+	for _, f := range choices {
+		fmt.Printf("\t\t'-%s' = @('%s')\n", f.name,
+			strings.Join(f.choices, "', '"))
+	}
+	fmt.Println("\t}")
+	fmt.Println("\t$elements = $commandAst.CommandElements")
+	fmt.Println("\t$prev = if ($wordToComplete) { $elements[-2] } " +
+		"else { $elements[-1] }")
+	fmt.Println("\tif ($choices.ContainsKey(\"$prev\")) {")
+	fmt.Println("\t\t$choices[\"$prev\"] | Where-Object { $_ -like " +
+		"\"$wordToComplete*\" } | ForEach-Object {")
+	fmt.Println("\t\t\t[System.Management.Automation.CompletionResult]::new(" +
+		"$_, $_, 'ParameterValue', $_)")
+	fmt.Println("\t\t}")
+	fmt.Println("\t\treturn")
+	fmt.Println("\t}")
+	fmt.Println("\tif ($wordToComplete -like '-*') {")
+	fmt.Println("\t\t$flags.Keys | Where-Object { $_ -like " +
+		"\"$wordToComplete*\" } | Sort-Object | ForEach-Object {")
+	fmt.Println("\t\t\t[System.Management.Automation.CompletionResult]::new(" +
+		"$_, $_, 'ParameterName', $flags[$_])")
+	fmt.Println("\t\t}")
+	fmt.Println("\t}")
+	fmt.Println("}")
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// The name of the configuration files that hold default flag values.
+const configName = "wwiseutil.json"
+
+// configPaths returns the paths of the configuration files that are read, in
+// the order they are applied: the file of the user's configuration directory,
+// and then the file of the working directory, so that the latter takes
+// precedence.
+func configPaths() []string {
+	var paths []string
+	if dir, err := os.UserConfigDir(); err == nil {
+		paths = append(paths, filepath.Join(dir, "wwiseutil", configName))
+	}
+	return append(paths, configName)
+}
+
+// loadConfig sets the flags named in every configuration file that exists to
+// the values the file gives them. A configuration file holds a JSON object
+// whose keys are flag names, such as {"output": "out", "align": 16}. Flags
+// given on the command line are parsed afterwards, and so take precedence.
+func loadConfig() error {
+	for _, path := range configPaths() {
+		data, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		var values map[string]interface{}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		// Keep numbers as they are written, rather than as float64.
+		dec.UseNumber()
+		err = dec.Decode(&values)
+		if err != nil {
+			return fmt.Errorf("%s is not a JSON object: %s", path, err)
+		}
+		for name, value := range values {
+			if flag.Lookup(name) == nil {
+				msg := fmt.Sprintf("%s: %s is not a known flag", path, name)
+				return errors.New(msg)
+			}
+			err := flag.Set(name, fmt.Sprint(value))
+			if err != nil {
+				return fmt.Errorf("%s: invalid value for %s: %s", path, name, err)
+			}
+		}
+		for _, m := range modes {
+			if *m.selected {
+				msg := fmt.Sprintf("%s: %s selects a mode, and cannot be given a "+
+					"default", path, m.name)
+				return errors.New(msg)
+			}
+		}
+	}
+	return nil
+}
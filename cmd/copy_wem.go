@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+var shouldCopyWem bool
+var copyWemId string
+var intoPath string
+
+func init() {
+	const (
+		usage = "copy the wem with the given ID from the .bnk specified by " +
+			"filepath into the .bnk specified by into, writing the result to the " +
+			"file specified by output. If the destination already holds a wem " +
+			"with the same ID, it is replaced; otherwise, the wem is added."
+		flagName = "copy-wem"
+	)
+	flag.Var(modeValue{&copyWemId, &shouldCopyWem, false}, flagName, usage)
+	registerMode(&mode{name: flagName, selected: &shouldCopyWem,
+		needsFile: true, needsOutput: true, run: copyWem})
+}
+
+func init() {
+	const (
+		usage = "When copy-wem is used, the path to the .bnk to copy the wem " +
+			"into."
+		flagName = "into"
+	)
+	pathVar(&intoPath, flagName, "", usage)
+}
+
+// copyWem copies a wem of the input SoundBank into the destination SoundBank.
+func copyWem(isSoundBank bool) {
+	if !isSoundBank {
+		fatal(exitUsage, "copy-wem only supports SoundBank files")
+	}
+	if intoPath == "" {
+		flag.Usage()
+		fatal(exitUsage, "into cannot be empty")
+	}
+	id, err := strconv.ParseUint(copyWemId, 10, 32)
+	if err != nil {
+		fatalf(exitUsage, "\"%s\" is not a valid wem ID\n", copyWemId)
+	}
+
+	src, err := openSoundBank(filePath)
+	if err != nil {
+		fatalln(exitParse, "Could not parse source .bnk file:", err)
+	}
+	defer src.Close()
+	dst, err := openSoundBank(intoPath)
+	if err != nil {
+		fatalln(exitParse, "Could not parse destination .bnk file:", err)
+	}
+	defer dst.Close()
+
+	wem, ok := src.WemByID(uint32(id))
+	if !ok {
+		fatalf(exitValidation, "The source SoundBank does not hold wem %d\n", id)
+	}
+	r, ok := wem.Reader.(io.ReaderAt)
+	if !ok {
+		fatalf(exitValidation,
+			"Wem %d of the source SoundBank cannot be read\n", id)
+	}
+	length := int64(wem.Descriptor.Length)
+	if i, ok := dst.IndexOfWem(uint32(id)); ok {
+		replacement := &wwise.ReplacementWem{r, i, length}
+		err = dst.CheckReplacements(replacement)
+		if err == nil {
+			dst.ReplaceWems(replacement)
+		}
+		fmt.Printf("Replacing wem %d of the destination SoundBank\n", id)
+	} else {
+		err = dst.AddWem(bnk.WemSource{uint32(id), r, length})
+		fmt.Printf("Adding wem %d to the destination SoundBank\n", id)
+	}
+	if err != nil {
+		fatalln(exitCode(err), "Could not copy wem:", err)
+	}
+	opReport.addReplaced(1)
+
+	total, err := writeOutput(output, dst)
+	if err != nil {
+		fatalln(exitIO, "Could not write output to file: ", err)
+	}
+	fmt.Println("Successfully copied! Output file written to:", output)
+	fmt.Printf("Wrote %d bytes in total\n", total)
+}
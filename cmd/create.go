@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+var shouldCreate bool
+var bankId string
+var bankVersion uint
+
+func init() {
+	const (
+		usage = "create a new .bnk from the .wem files in the directory " +
+			"specified by target, writing it to the file specified by output. " +
+			"Each wem file's name must be the ID of the wem. The SoundBank is " +
+			"described by bank-id and bank-version."
+		flagName = "create"
+	)
+	flag.BoolVar(&shouldCreate, flagName, false, usage)
+	registerMode(&mode{name: flagName, selected: &shouldCreate,
+		needsOutput: true, run: create})
+}
+
+func init() {
+	const (
+		usage = "The ID of the SoundBank, given either as a number or as the " +
+			"name of the SoundBank, which is hashed to find its ID. When replace " +
+			"is used, the ID that the output .bnk is given."
+		flagName = "bank-id"
+	)
+	flag.StringVar(&bankId, flagName, "", usage)
+}
+
+func init() {
+	const (
+		usage = "The version of the SoundBank, which must match the version " +
+			"expected by the game that loads it. When replace is used, the " +
+			"version that the output .bnk is given; its objects are not " +
+			"converted to the layout of that version, and it may not cross " +
+			"version 126, after which the BKHD settings change layout."
+		flagName = "bank-version"
+	)
+	flag.UintVar(&bankVersion, flagName, bnk.DefaultVersion, usage)
+}
+
+// parseId returns the ID described by s, which is either a number or a name
+// whose hash is the ID.
+func parseId(s string) uint32 {
+	if id, err := strconv.ParseUint(s, 10, 32); err == nil {
+		return uint32(id)
+	}
+	return wwise.HashName(s)
+}
+
+// create writes a new SoundBank holding the wems of the target directory.
+func create(bool) {
+	if targetPath == "" {
+		flag.Usage()
+		fatal(exitUsage, "target cannot be empty")
+	}
+	if bankId == "" {
+		flag.Usage()
+		fatal(exitUsage, "bank-id cannot be empty")
+	}
+
+	fis, err := ioutil.ReadDir(targetPath)
+	if err != nil {
+		fatalf(exitIO,
+			"Could not open target directory, \"%s\": %s\n", targetPath, err)
+	}
+	builder := bnk.NewBuilder().SetVersion(uint32(bankVersion)).
+		SetBankID(parseId(bankId)).SetAlignment(alignment)
+	count := 0
+	for _, fi := range fis {
+		name := fi.Name()
+		ext := filepath.Ext(name)
+		if ext != wemExtension {
+			log.Printf("Ignoring %s: It does not have a .wem file extension", name)
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(name, ext), 10, 32)
+		if err != nil {
+			log.Printf("Ignoring %s: Its name is not a valid wem ID", name)
+			continue
+		}
+		f, err := os.Open(filepath.Join(targetPath, name))
+		if err != nil {
+			fatalf(exitIO, "Could not open wem file \"%s\": %s\n", name, err)
+		}
+		defer f.Close()
+		err = wwise.ValidateWem(f, fi.Size())
+		if err != nil {
+			fatalf(exitValidation, "Could not use %s: %s", name, err)
+		}
+		builder.AddWem(uint32(id), f, fi.Size())
+		count++
+	}
+
+	b, err := builder.Build()
+	if err != nil {
+		fatalln(exitCode(err), "Could not create SoundBank:", err)
+	}
+
+	total, err := writeOutput(output, b)
+	if err != nil {
+		fatalln(exitIO, "Could not write output to file: ", err)
+	}
+	fmt.Printf("Successfully created a SoundBank of %d wem(s) at %s\n",
+		count, output)
+	fmt.Printf("Wrote %d bytes in total\n", total)
+}
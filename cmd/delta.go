@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/delta"
+)
+
+var shouldDiff bool
+var diffModifiedPath string
+var shouldApplyDiff bool
+var deltaPath string
+
+func init() {
+	const (
+		usage = "write a compact binary delta that turns the file specified by " +
+			"filepath into the given modified file to the file specified by " +
+			"output. The delta only holds the bytes of the modified file that are " +
+			"not found in the original, so it can be shared where the original " +
+			"audio cannot."
+		flagName = "diff"
+	)
+	flag.Var(modeValue{&diffModifiedPath, &shouldDiff, true}, flagName,
+		usage)
+	registerMode(&mode{name: flagName, selected: &shouldDiff,
+		needsFile: true, needsOutput: true, run: diff})
+}
+
+func init() {
+	const (
+		usage = "apply the given binary delta, created by diff, to the file " +
+			"specified by filepath, and write the result to the file specified " +
+			"by output."
+		flagName = "apply-diff"
+	)
+	flag.Var(modeValue{&deltaPath, &shouldApplyDiff, true}, flagName, usage)
+	registerMode(&mode{name: flagName, selected: &shouldApplyDiff,
+		needsFile: true, needsOutput: true, repacks: true,
+		run: applyDiff})
+}
+
+// diff writes a delta between the input file and the modified file.
+func diff(isSoundBank bool) {
+	original, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		fatalln(exitIO, "Could not read original file:", err)
+	}
+	modified, err := ioutil.ReadFile(diffModifiedPath)
+	if err != nil {
+		fatalln(exitIO, "Could not read modified file:", err)
+	}
+
+	_, err = writeOutput(output, writerToFunc(func(w io.Writer) (int64, error) {
+		return 0, delta.Diff(w, original, modified)
+	}))
+	if err != nil {
+		fatalln(exitIO, "Could not write output to file: ", err)
+	}
+	fmt.Println("Successfully created a delta! Output file written to:", output)
+}
+
+// applyDiff applies a delta to the input file.
+func applyDiff(isSoundBank bool) {
+	original, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		fatalln(exitIO, "Could not read original file:", err)
+	}
+	d, err := os.Open(deltaPath)
+	if err != nil {
+		fatalln(exitIO, "Could not open delta file:", err)
+	}
+	defer d.Close()
+
+	_, err = writeOutput(output, writerToFunc(func(w io.Writer) (int64, error) {
+		return 0, delta.Apply(w, original, d)
+	}))
+	if err != nil {
+		fatalln(exitValidation, "Could not apply delta:", err)
+	}
+	fmt.Println("Successfully applied the delta! Output file written to:", output)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+)
+
+// The extension given to dumped section data.
+const sectionDumpExtension = ".bin"
+
+var shouldDumpSections bool
+
+func init() {
+	const (
+		usage = "write the data of every section of the .bnk specified by " +
+			"filepath, without its header, to a separate file in the directory " +
+			"specified by output. Each file is named by the position and " +
+			"identifier of its section, e.g. 04_HIRC.bin."
+		flagName = "dump-sections"
+	)
+	flag.BoolVar(&shouldDumpSections, flagName, false, usage)
+	registerMode(&mode{name: flagName, selected: &shouldDumpSections,
+		needsFile: true, needsOutput: true, run: dumpSections})
+}
+
+// A prefixSkipper is a Writer that discards the first skip bytes written to it,
+// and writes the rest to w.
+type prefixSkipper struct {
+	w    io.Writer
+	skip int
+}
+
+func (ps *prefixSkipper) Write(p []byte) (int, error) {
+	n := len(p)
+	if ps.skip > 0 {
+		if ps.skip >= len(p) {
+			ps.skip -= len(p)
+			return n, nil
+		}
+		p = p[ps.skip:]
+		ps.skip = 0
+	}
+	_, err := ps.w.Write(p)
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// dumpSections writes the data of every section of the input SoundBank to the
+// output directory.
+func dumpSections(isSoundBank bool) {
+	if !isSoundBank {
+		fatal(exitUsage, "dump-sections only supports SoundBank files")
+	}
+
+	b, err := openSoundBank(filePath)
+	if err != nil {
+		fatalln(exitParse, "Could not parse .bnk file:", err)
+	}
+	defer b.Close()
+
+	err = createDirIfEmpty(output)
+	if err != nil {
+		fatalln(exitIO, "Could not create output directory:", err)
+	}
+	sections := b.Sections()
+	for i, s := range sections {
+		filename := fmt.Sprintf("%02d_%s%s", i+1, s.Identifier(),
+			sectionDumpExtension)
+		f, err := createFile(filepath.Join(output, filename))
+		if err == errSkipped {
+			fmt.Printf("%-12s skipped, as it already exists\n", filename)
+			continue
+		}
+		if err != nil {
+			fatalf(exitIO, "Could not create section file \"%s\": %s", filename, err)
+		}
+		_, err = s.WriteTo(&prefixSkipper{f, bnk.SECTION_HEADER_BYTES})
+		f.Close()
+		if err != nil {
+			fatalf(exitIO, "Could not write section file \"%s\": %s", filename, err)
+		}
+		fmt.Printf("%-12s %d bytes\n", filename, s.Length())
+	}
+	fmt.Printf("Successfully wrote %d section(s) to %s\n", len(sections), output)
+}
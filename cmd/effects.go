@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+var shouldListEffects bool
+
+func init() {
+	const (
+		usage = "list every effect ShareSet and custom effect within the .bnk " +
+			"specified by filepath, along with its plugin and the parameters of " +
+			"built-in effects."
+		flagName = "effects"
+	)
+	flag.BoolVar(&shouldListEffects, flagName, false, usage)
+	registerMode(&mode{name: flagName, selected: &shouldListEffects,
+		needsFile: true, run: listEffects})
+}
+
+// listEffects prints every effect of the input SoundBank and its parameters.
+func listEffects(isSoundBank bool) {
+	if !isSoundBank {
+		fatal(exitUsage, "effects only supports SoundBank files")
+	}
+
+	b, err := openSoundBank(filePath)
+	if err != nil {
+		fatalln(exitParse, "Could not parse .bnk file:", err)
+	}
+	defer b.Close()
+	if b.ObjectSection == nil {
+		fatal(exitValidation, "The SoundBank does not contain a HIRC section")
+	}
+
+	effects := b.ObjectSection.Effects()
+	for _, fx := range effects {
+		kind := "Custom effect"
+		if fx.IsShareSet() {
+			kind = "Effect ShareSet"
+		}
+		name := fx.PluginName()
+		if name == "" {
+			name = "unknown plugin"
+		}
+		fmt.Printf("%s %d: %s (%#08x), %d byte(s) of parameters\n", kind,
+			fx.Id(), name, fx.PluginId, len(fx.Params))
+		params, err := fx.Parameters()
+		if err != nil {
+			if fx.PluginName() != "" {
+				fmt.Printf("  %v\n", err)
+			}
+			continue
+		}
+		for _, p := range params {
+			fmt.Printf("  %-16s %v\n", p.Name, p.Value)
+		}
+	}
+	fmt.Printf("Listed %d effect(s)\n", len(effects))
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/util"
+)
+
+var shouldListEvents bool
+
+func init() {
+	const (
+		usage = "list every event within the .bnk specified by filepath, along " +
+			"with the IDs of the wems that it may play. Wems stored in the " +
+			"SoundBank are also listed by the name they are given when unpacked."
+		flagName = "events"
+	)
+	flag.BoolVar(&shouldListEvents, flagName, false, usage)
+	registerMode(&mode{name: flagName, selected: &shouldListEvents,
+		needsFile: true, run: listEvents})
+}
+
+// listEvents prints every event of the input SoundBank and the wems that it may
+// play.
+func listEvents(isSoundBank bool) {
+	if !isSoundBank {
+		fatal(exitUsage, "events only supports SoundBank files")
+	}
+
+	b, err := openSoundBank(filePath)
+	if err != nil {
+		fatalln(exitParse, "Could not parse .bnk file:", err)
+	}
+	defer b.Close()
+	if b.ObjectSection == nil {
+		fatal(exitValidation, "The SoundBank does not contain a HIRC section")
+	}
+
+	events := b.ObjectSection.Events()
+	for _, event := range events {
+		wems := b.ObjectSection.WemsOf(event.Id())
+		fmt.Printf("Event %d: %d wem(s)\n", event.Id(), len(wems))
+		for _, wemId := range wems {
+			if i, ok := b.IndexOfWem(wemId); ok {
+				fmt.Printf("  %-10d %s\n", wemId,
+					util.CanonicalWemName(i, len(b.Wems())))
+			} else {
+				fmt.Printf("  %-10d (streamed or in another SoundBank)\n", wemId)
+			}
+		}
+	}
+	fmt.Printf("Listed %d event(s)\n", len(events))
+}
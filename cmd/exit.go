@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+	"github.com/hpxro7/wwiseutil/pck"
+)
+
+// The exit statuses of this tool, so that scripts that run it can tell why it
+// failed.
+const (
+	// Any failure not described by a more specific status.
+	exitFailure = 1
+	// The flags or arguments are invalid. This is also the status used by the
+	// flag package when the flags cannot be parsed.
+	exitUsage = 2
+	// An input file is not a valid .bnk, .pck or other file of the format it is
+	// expected to have.
+	exitParse = 3
+	// A file could not be opened, read, created or written.
+	exitIO = 4
+	// An input was read, but it is inconsistent, does not match what it was
+	// checked against, or cannot be used.
+	exitValidation = 5
+	// A wem is too large to be stored where it was to be written.
+	exitOverflow = 6
+	// One or more of the files of a batch or project failed.
+	exitPartial = 7
+)
+
+// An exitError is an error that causes this tool to exit with a specific
+// status.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// exitErrorf returns an error formatted as fmt.Errorf does, that causes this
+// tool to exit with the status code.
+func exitErrorf(code int, format string, v ...interface{}) error {
+	return &exitError{code, fmt.Errorf(format, v...)}
+}
+
+// exitCode returns the status that this tool exits with because of err.
+func exitCode(err error) int {
+	var e *exitError
+	switch {
+	case errors.Is(err, bnk.ErrWemTooLarge) || errors.Is(err, bnk.ErrDoesNotFit) ||
+		errors.Is(err, pck.ErrDoesNotFit) || errors.Is(err, pck.ErrFileTooLarge):
+		return exitOverflow
+	case errors.As(err, &e):
+		return e.code
+	}
+	return exitFailure
+}
+
+// exit writes the summary of the operation, and exits with the status code.
+// msg describes the failure, if there was one.
+func exit(code int, msg string) {
+	opReport.finish(code, msg)
+	os.Exit(code)
+}
+
+// fatal prints its arguments as log.Print does, and exits with the status code.
+func fatal(code int, v ...interface{}) {
+	log.Print(v...)
+	exit(code, fmt.Sprint(v...))
+}
+
+// fatalf prints its arguments as log.Printf does, and exits with the status
+// code.
+func fatalf(code int, format string, v ...interface{}) {
+	log.Printf(format, v...)
+	exit(code, strings.TrimSuffix(fmt.Sprintf(format, v...), "\n"))
+}
+
+// fatalln prints its arguments as log.Println does, and exits with the status
+// code.
+func fatalln(code int, v ...interface{}) {
+	log.Println(v...)
+	exit(code, strings.TrimSuffix(fmt.Sprintln(v...), "\n"))
+}
+
+// fatalErr prints err, and exits with the status described by exitCode.
+func fatalErr(err error) {
+	log.Println(err)
+	exit(exitCode(err), err.Error())
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strconv"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+var shouldExtractEvent bool
+var extractEventName string
+
+func init() {
+	const (
+		usage = "unpack only the wems that may be played by the given event of " +
+			"the .bnk specified by filepath into the directory specified by " +
+			"output. The event may be given by its name or its ID. Unpacked wems " +
+			"are named as they would be by unpack."
+		flagName = "extract-event"
+	)
+	flag.Var(modeValue{&extractEventName, &shouldExtractEvent, false},
+		flagName, usage)
+	registerMode(&mode{name: flagName, selected: &shouldExtractEvent,
+		needsFile: true, needsOutput: true, run: extractEvent})
+}
+
+// findEvent returns the event of hrc identified by s, which is either the ID of
+// the event or its name.
+func findEvent(hrc *bnk.ObjectHierarchySection, s string) (*bnk.EventObject, bool) {
+	if id, err := strconv.ParseUint(s, 10, 32); err == nil {
+		if event, ok := hrc.Object(uint32(id)).(*bnk.EventObject); ok {
+			return event, true
+		}
+	}
+	event, ok := hrc.Object(wwise.HashName(s)).(*bnk.EventObject)
+	return event, ok
+}
+
+// extractEvent unpacks the wems of the input SoundBank that may be played by
+// the selected event.
+func extractEvent(isSoundBank bool) {
+	if !isSoundBank {
+		fatal(exitUsage, "extract-event only supports SoundBank files")
+	}
+
+	b, err := openSoundBank(filePath)
+	if err != nil {
+		fatalln(exitParse, "Could not parse .bnk file:", err)
+	}
+	defer b.Close()
+	if b.ObjectSection == nil {
+		fatal(exitValidation, "The SoundBank does not contain a HIRC section")
+	}
+
+	event, ok := findEvent(b.ObjectSection, extractEventName)
+	if !ok {
+		fatalf(exitValidation, "The SoundBank does not contain the event \"%s\"\n",
+			extractEventName)
+	}
+
+	err = createDirIfEmpty(output)
+	if err != nil {
+		fatalln(exitIO, "Could not create output directory:", err)
+	}
+	wems := b.Wems()
+	var indices []int
+	for _, wemId := range b.ObjectSection.WemsOf(event.Id()) {
+		i, ok := b.IndexOfWem(wemId)
+		if !ok {
+			log.Printf("Skipping wem %d: It is not stored in this SoundBank\n", wemId)
+			continue
+		}
+		indices = append(indices, i)
+	}
+	pending, err := pendingWems(output, wems, nil, indices)
+	if err != nil {
+		fatalErr(err)
+	}
+	total, err := writeUnpackedWems(output, wems, nil, pending)
+	if err != nil {
+		fatalErr(err)
+	}
+	count := len(pending)
+	fmt.Printf("Successfully wrote %d wem(s) of event %d to %s\n", count,
+		event.Id(), output)
+	if skipped := len(indices) - count; skipped > 0 {
+		fmt.Printf("Skipped %d wem(s) that were already unpacked\n", skipped)
+	}
+	fmt.Printf("Wrote %d bytes in total\n", total)
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+	"github.com/hpxro7/wwiseutil/util"
+)
+
+var shouldGraph bool
+
+func init() {
+	const (
+		usage = "write a Graphviz DOT file of the relationships between the " +
+			"events, actions, sounds, containers and wems within the .bnk " +
+			"specified by filepath to the file specified by output. Render it " +
+			"with, for example, dot -Tsvg."
+		flagName = "graph"
+	)
+	flag.BoolVar(&shouldGraph, flagName, false, usage)
+	registerMode(&mode{name: flagName, selected: &shouldGraph,
+		needsFile: true, needsOutput: true, run: graph})
+}
+
+// graph writes the event graph of the input SoundBank as a DOT file.
+func graph(isSoundBank bool) {
+	if !isSoundBank {
+		fatal(exitUsage, "graph only supports SoundBank files")
+	}
+
+	b, err := openSoundBank(filePath)
+	if err != nil {
+		fatalln(exitParse, "Could not parse .bnk file:", err)
+	}
+	defer b.Close()
+	if b.ObjectSection == nil {
+		fatal(exitValidation, "The SoundBank does not contain a HIRC section")
+	}
+
+	g := &eventGraph{b: b, visited: make(map[uint32]bool),
+		wems: make(map[uint32]bool)}
+	_, err = writeOutput(output, writerToFunc(func(w io.Writer) (int64, error) {
+		g.w = bufio.NewWriter(w)
+		return 0, g.write()
+	}))
+	if err != nil {
+		fatalln(exitIO, "Could not write output to file:", err)
+	}
+	fmt.Printf("Successfully graphed %d event(s)! Output file written to: %s\n",
+		len(b.ObjectSection.Events()), output)
+}
+
+// An eventGraph writes the objects reachable from the events of a SoundBank as
+// a DOT graph.
+type eventGraph struct {
+	b       *bnk.File
+	w       *bufio.Writer
+	visited map[uint32]bool
+	wems    map[uint32]bool
+}
+
+func (g *eventGraph) write() error {
+	fmt.Fprintln(g.w, "digraph events {")
+	fmt.Fprintln(g.w, "  rankdir=LR;")
+	fmt.Fprintln(g.w, "  node [fontname=\"sans-serif\"];")
+	for _, event := range g.b.ObjectSection.Events() {
+		g.visit(event.Id())
+	}
+	fmt.Fprintln(g.w, "}")
+	return g.w.Flush()
+}
+
+// visit writes the node of the object with the given ID, the nodes of the
+// objects it refers to and the edges between them.
+func (g *eventGraph) visit(id uint32) {
+	if g.visited[id] {
+		return
+	}
+	g.visited[id] = true
+
+	obj := g.b.ObjectSection.Object(id)
+	switch obj := obj.(type) {
+	case *bnk.EventObject:
+		g.node(id, "Event", "box", "lightblue")
+		for _, actionId := range obj.ActionIds {
+			g.edge(id, actionId, "")
+			g.visit(actionId)
+		}
+	case *bnk.ActionObject:
+		label := "Action"
+		if obj.Plays() {
+			label = "Play"
+		}
+		g.node(id, label, "ellipse", "lightyellow")
+		if obj.IsBus == 0 {
+			g.edge(id, obj.TargetId, "")
+			g.visit(obj.TargetId)
+		}
+	case *bnk.SfxVoiceSoundObject:
+		g.node(id, "Sound", "ellipse", "white")
+		g.wem(id, obj.WemDescriptor.WemId)
+	case *bnk.ContainerObject:
+		g.node(id, "Container", "hexagon", "lightgrey")
+		labels := make(map[uint32]string)
+		for _, sg := range obj.SwitchGroups {
+			for _, childId := range sg.NodeIds {
+				labels[childId] = fmt.Sprintf("switch %d", sg.SwitchId)
+			}
+		}
+		for _, childId := range obj.ChildIds {
+			g.edge(id, childId, labels[childId])
+			g.visit(childId)
+		}
+	case *bnk.MusicPlaylistObject:
+		g.node(id, "Music playlist", "hexagon", "lightgrey")
+		g.children(id, obj.ChildIds)
+	case *bnk.MusicSwitchObject:
+		g.node(id, "Music switch", "hexagon", "lightgrey")
+		g.children(id, obj.ChildIds)
+	case *bnk.MusicSegmentObject:
+		g.node(id, "Music segment", "hexagon", "lightgrey")
+		g.children(id, obj.ChildIds)
+	case *bnk.MusicTrackObject:
+		g.node(id, "Music track", "ellipse", "white")
+		for _, src := range obj.Sources {
+			g.wem(id, src.SourceId)
+		}
+	case nil:
+		g.node(id, "Not in SoundBank", "ellipse", "white")
+	default:
+		g.node(id, "Object", "ellipse", "white")
+	}
+}
+
+func (g *eventGraph) children(id uint32, childIds []uint32) {
+	for _, childId := range childIds {
+		g.edge(id, childId, "")
+		g.visit(childId)
+	}
+}
+
+func (g *eventGraph) node(id uint32, kind, shape, color string) {
+	fmt.Fprintf(g.w, "  o%d [label=\"%s\\n%d\", shape=%s, style=filled, "+
+		"fillcolor=%s];\n", id, kind, id, shape, color)
+}
+
+func (g *eventGraph) edge(from, to uint32, label string) {
+	if label == "" {
+		fmt.Fprintf(g.w, "  o%d -> o%d;\n", from, to)
+		return
+	}
+	fmt.Fprintf(g.w, "  o%d -> o%d [label=\"%s\"];\n", from, to, label)
+}
+
+// wem writes an edge from the object with the given ID to the wem it plays,
+// writing the node of the wem the first time it is seen.
+func (g *eventGraph) wem(from, wemId uint32) {
+	if !g.wems[wemId] {
+		g.wems[wemId] = true
+		label := "streamed or in another SoundBank"
+		if i, ok := g.b.IndexOfWem(wemId); ok {
+			label = util.CanonicalWemName(i, len(g.b.Wems()))
+		}
+		fmt.Fprintf(g.w, "  w%d [label=\"Wem %d\\n%s\", shape=note, "+
+			"style=filled, fillcolor=palegreen];\n", wemId, wemId, label)
+	}
+	fmt.Fprintf(g.w, "  o%d -> w%d;\n", from, wemId)
+}
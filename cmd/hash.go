@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+var shouldHash bool
+
+func init() {
+	const (
+		usage = "print the Wwise ID of each name given as an argument. Wwise " +
+			"derives the IDs of events, SoundBanks, busses and other named objects " +
+			"from their names."
+		flagName = "hash"
+	)
+	flag.BoolVar(&shouldHash, flagName, false, usage)
+	registerMode(&mode{name: flagName, selected: &shouldHash, run: hash})
+}
+
+// hash prints the Wwise ID of every name given as a command line argument.
+func hash(bool) {
+	names := flag.Args()
+	if len(names) == 0 {
+		flag.Usage()
+		fatal(exitUsage,
+			"hash requires at least one name to be given as an argument")
+	}
+	for _, name := range names {
+		fmt.Printf("%-10d %s\n", wwise.HashName(name), name)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+)
+
+var shouldHircSet bool
+var hircSetId string
+var hircProperties stringList
+
+func init() {
+	const (
+		usage = "set properties of the HIRC object with the given ID within the " +
+			".bnk specified by filepath, writing the updated SoundBank to the " +
+			"file specified by output. The properties are given by set."
+		flagName = "hirc-set"
+	)
+	flag.Var(modeValue{&hircSetId, &shouldHircSet, false}, flagName, usage)
+	registerMode(&mode{name: flagName, selected: &shouldHircSet,
+		needsFile: true, needsOutput: true, repacks: true, run: hircSet})
+}
+
+func init() {
+	var names []string
+	for _, p := range bnk.Properties() {
+		names = append(names, string(p))
+	}
+	usage := "When hirc-set is used, a property to set, given as name=value, " +
+		"such as volume=-3. The properties are " + strings.Join(names, ", ") +
+		". Volume and make-up gain are in decibels, pitch is in cents and a " +
+		"loop count of 0 loops infinitely. May be given more than once."
+	const flagName = "set"
+	flag.Var(&hircProperties, flagName, usage)
+}
+
+// hircSet writes a copy of the input SoundBank with the properties given by the
+// set flag changed.
+func hircSet(isSoundBank bool) {
+	if !isSoundBank {
+		fatal(exitUsage, "hirc-set only supports SoundBank files")
+	}
+	id, err := strconv.ParseUint(hircSetId, 10, 32)
+	if err != nil {
+		fatalf(exitUsage, "\"%s\" is not a valid object ID", hircSetId)
+	}
+	if len(hircProperties) == 0 {
+		fatal(exitUsage, "hirc-set requires at least one property, given by set")
+	}
+
+	b, err := openSoundBank(filePath)
+	if err != nil {
+		fatalln(exitParse, "Could not parse .bnk file:", err)
+	}
+	defer b.Close()
+	if b.ObjectSection == nil {
+		fatal(exitValidation, "The SoundBank does not contain a HIRC section")
+	}
+
+	for _, prop := range hircProperties {
+		parts := strings.SplitN(prop, "=", 2)
+		if len(parts) != 2 {
+			fatalf(exitUsage, "Could not set \"%s\": expected name=value", prop)
+		}
+		value, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			fatalf(exitUsage, "Could not set \"%s\": %s is not a number", prop,
+				parts[1])
+		}
+		p := bnk.Property(parts[0])
+		old, hadValue := b.ObjectSection.Property(uint32(id), p)
+		err = b.ObjectSection.SetProperty(uint32(id), p, value)
+		if err != nil {
+			fatalln(exitValidation, "Could not set property:", err)
+		}
+		if hadValue {
+			fmt.Printf("Set %s of object %d from %g to %g\n", p, id, old, value)
+		} else {
+			fmt.Printf("Set %s of object %d to %g\n", p, id, value)
+		}
+	}
+
+	total, err := writeOutput(output, b)
+	if err != nil {
+		fatalln(exitIO, "Could not write output to file: ", err)
+	}
+	fmt.Println("Successfully set properties! Output file written to:", output)
+	fmt.Printf("Wrote %d bytes in total\n", total)
+}
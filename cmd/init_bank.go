@@ -0,0 +1,108 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+)
+
+var shouldListInit bool
+
+func init() {
+	const (
+		usage = "print the global settings of the Init.bnk specified by " +
+			"filepath: its state groups, switch groups driven by game " +
+			"parameters, game parameters, obstruction and occlusion curves and " +
+			"buses."
+		flagName = "init"
+	)
+	flag.BoolVar(&shouldListInit, flagName, false, usage)
+	registerMode(&mode{name: flagName, selected: &shouldListInit,
+		needsFile: true, run: listInit})
+}
+
+// The names of the properties that obstruction and occlusion may drive, in
+// the order of the curves of bnk.EnvironmentSection.
+var environmentProperties = []string{"Volume", "Low pass", "High pass"}
+
+// listInit prints the game syncs and global settings of the input Init.bnk.
+func listInit(isSoundBank bool) {
+	if !isSoundBank {
+		fatal(exitUsage, "init only supports SoundBank files")
+	}
+
+	b, err := openSoundBank(filePath)
+	if err != nil {
+		fatalln(exitParse, "Could not parse .bnk file:", err)
+	}
+	defer b.Close()
+	if !b.IsInit() {
+		fatal(exitValidation, "The SoundBank is not an Init.bnk, as it does not "+
+			"contain an STMG section")
+	}
+	if b.PlatformSection != nil {
+		fmt.Printf("Platform: %s\n", b.PlatformSection.Platform)
+	}
+
+	stmg := b.GlobalSettingsSection
+	if stmg == nil {
+		fatal(exitParse, "The layout of the STMG section is not known for this "+
+			"version of Wwise")
+	}
+	fmt.Printf("Volume threshold: %gdB\n", stmg.VolumeThreshold)
+	fmt.Printf("Max voices: %d\n", stmg.MaxVoices)
+	for _, group := range stmg.StateGroups {
+		fmt.Printf("State group %d: default transition %dms\n", group.Id,
+			group.DefaultTransitionTime)
+		for _, t := range group.Transitions {
+			fmt.Printf("  %d -> %d: %dms\n", t.From, t.To, t.Time)
+		}
+	}
+	for _, group := range stmg.SwitchGroups {
+		fmt.Printf("Switch group %d: game parameter %d\n", group.Id,
+			group.RTPCId)
+		for _, p := range group.Points {
+			fmt.Printf("  (%g, %g)\n", p.From, p.To)
+		}
+	}
+	for _, param := range stmg.GameParameters {
+		fmt.Printf("Game parameter %d: default %g", param.Id, param.Value)
+		if param.BuiltIn != 0 {
+			fmt.Printf(", bound to built-in parameter %d", param.BuiltIn)
+		}
+		fmt.Println()
+	}
+
+	if envs := b.EnvironmentSection; envs != nil {
+		for i, name := range environmentProperties {
+			printEnvironmentCurve("Obstruction", name, envs.Obstruction[i].Enabled,
+				envs.Obstruction[i].Points)
+			printEnvironmentCurve("Occlusion", name, envs.Occlusion[i].Enabled,
+				envs.Occlusion[i].Points)
+		}
+	}
+
+	buses := 0
+	if b.ObjectSection != nil {
+		buses = len(b.ObjectSection.Buses())
+	}
+	fmt.Printf("Listed %d state group(s), %d switch group(s), %d game "+
+		"parameter(s) and %d bus(es)\n", len(stmg.StateGroups),
+		len(stmg.SwitchGroups), len(stmg.GameParameters), buses)
+}
+
+// printEnvironmentCurve prints an enabled obstruction or occlusion curve.
+func printEnvironmentCurve(kind, name string, enabled bool,
+	points []bnk.GraphPoint) {
+	if !enabled {
+		return
+	}
+	fmt.Printf("%s %s:", kind, name)
+	for _, p := range points {
+		fmt.Printf(" (%g, %g)", p.From, p.To)
+	}
+	fmt.Println()
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+// The number of bytes shown on each line of a hex dump.
+const inspectLineBytes = 16
+
+var shouldInspect bool
+var inspectTarget string
+var inspectRange string
+
+func init() {
+	const (
+		usage = "print a hex dump of the data of the section with the given " +
+			"identifier, such as HIRC, of the .bnk specified by filepath, or of " +
+			"the wem with the given ID of the .bnk or .pck specified by filepath. " +
+			"Section data is dumped without its header, as dump-sections writes " +
+			"it. Only the bytes given by inspect-range are dumped if it is set."
+		flagName = "inspect"
+	)
+	flag.Var(modeValue{&inspectTarget, &shouldInspect, false}, flagName,
+		usage)
+	registerMode(&mode{name: flagName, selected: &shouldInspect,
+		needsFile: true, run: inspect})
+}
+
+func init() {
+	const (
+		usage = "When inspect is used, the range of bytes to dump, as START-END " +
+			"with END excluded. Either may be left out to dump from the beginning " +
+			"or to the end, and both may be given in hexadecimal with a 0x " +
+			"prefix, e.g. 0x100-0x200."
+		flagName = "inspect-range"
+	)
+	flag.StringVar(&inspectRange, flagName, "", usage)
+}
+
+// inspect prints a hex dump of a section or wem of the input file.
+func inspect(isSoundBank bool) {
+	var ctn wwise.Container
+	var err error
+	if isSoundBank {
+		ctn, err = openSoundBank(filePath)
+	} else {
+		ctn, err = openFilePackage(filePath)
+	}
+	if err != nil {
+		fatalln(exitParse, "Could not parse .bnk or .pck file:", err)
+	}
+	defer ctn.Close()
+
+	var name string
+	var size int64
+	var wt io.WriterTo
+	if b, ok := ctn.(*bnk.File); ok {
+		for _, s := range b.Sections() {
+			if s.Identifier() == inspectTarget {
+				name = "Section " + s.Identifier()
+				size = s.Size() - bnk.SECTION_HEADER_BYTES
+				wt = writerToFunc(func(w io.Writer) (int64, error) {
+					return s.WriteTo(&prefixSkipper{w, bnk.SECTION_HEADER_BYTES})
+				})
+				break
+			}
+		}
+	}
+	if wt == nil {
+		id, err := strconv.ParseUint(inspectTarget, 10, 32)
+		if err != nil {
+			fatalf(exitUsage, "\"%s\" is neither a section identifier of the "+
+				"input file nor a wem ID\n", inspectTarget)
+		}
+		for _, wem := range ctn.Wems() {
+			if wem.Descriptor.WemId == uint32(id) {
+				name = fmt.Sprintf("Wem %d", id)
+				size = int64(wem.Descriptor.Length)
+				wt = wem
+				break
+			}
+		}
+		if wt == nil {
+			fatalf(exitValidation, "The input file holds no section or wem "+
+				"\"%s\"\n", inspectTarget)
+		}
+	}
+
+	start, end, err := parseInspectRange(inspectRange, size)
+	if err != nil {
+		fatalln(exitUsage, "Could not parse inspect-range:", err)
+	}
+	fmt.Printf("%s: bytes 0x%x to 0x%x of %d\n", name, start, end, size)
+	bw := bufio.NewWriter(os.Stdout)
+	d := &hexDumper{w: bw, offset: start}
+	_, err = wt.WriteTo(&prefixSkipper{&rangeWriter{d, end - start}, int(start)})
+	if err == nil {
+		err = d.Close()
+	}
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err != nil {
+		fatalf(exitIO, "Could not read %s: %s\n", strings.ToLower(name), err)
+	}
+}
+
+// parseInspectRange returns the start and the excluded end of the range s
+// within data of the given size.
+func parseInspectRange(s string, size int64) (start, end int64, err error) {
+	end = size
+	if s == "" {
+		return
+	}
+	parts := strings.SplitN(s, "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, errors.New("The range must be given as START-END")
+	}
+	if parts[0] != "" {
+		start, err = strconv.ParseInt(parts[0], 0, 64)
+		if err != nil {
+			return
+		}
+	}
+	if parts[1] != "" {
+		end, err = strconv.ParseInt(parts[1], 0, 64)
+		if err != nil {
+			return
+		}
+	}
+	if start < 0 || start > end || end > size {
+		msg := fmt.Sprintf("The range %d-%d does not lie within the %d bytes of "+
+			"data", start, end, size)
+		return 0, 0, errors.New(msg)
+	}
+	return
+}
+
+// A rangeWriter is a Writer that writes the first n bytes written to it to w,
+// and discards the rest.
+type rangeWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (rw *rangeWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if int64(len(p)) > rw.n {
+		p = p[:rw.n]
+	}
+	if len(p) > 0 {
+		_, err := rw.w.Write(p)
+		if err != nil {
+			return 0, err
+		}
+		rw.n -= int64(len(p))
+	}
+	return n, nil
+}
+
+// A hexDumper is a Writer that writes the bytes written to it to w as a hex
+// dump in the layout of hexdump -C, with offsets counted from offset. Close
+// must be called to write the last, partial line.
+type hexDumper struct {
+	w      io.Writer
+	offset int64
+	line   []byte
+}
+
+func (d *hexDumper) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		take := inspectLineBytes - len(d.line)
+		if take > len(p) {
+			take = len(p)
+		}
+		d.line = append(d.line, p[:take]...)
+		p = p[take:]
+		if len(d.line) == inspectLineBytes {
+			err := d.writeLine()
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// Close writes the bytes that do not fill a whole line.
+func (d *hexDumper) Close() error {
+	if len(d.line) == 0 {
+		return nil
+	}
+	return d.writeLine()
+}
+
+func (d *hexDumper) writeLine() error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%08x  ", d.offset)
+	for i := 0; i < inspectLineBytes; i++ {
+		if i < len(d.line) {
+			fmt.Fprintf(&b, "%02x ", d.line[i])
+		} else {
+			b.WriteString("   ")
+		}
+		if i == inspectLineBytes/2-1 {
+			b.WriteByte(' ')
+		}
+	}
+	b.WriteString(" |")
+	for _, c := range d.line {
+		if c < 0x20 || c > 0x7e {
+			c = '.'
+		}
+		b.WriteByte(c)
+	}
+	b.WriteString("|\n")
+	_, err := io.WriteString(d.w, b.String())
+	d.offset += int64(len(d.line))
+	d.line = d.line[:0]
+	return err
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/pck"
+)
+
+var languageFolders bool
+var unpackLanguage string
+
+func init() {
+	const (
+		usage = "When a .pck is unpacked, write each wem to a directory named " +
+			"after its language, such as sfx or english(us), as the language map " +
+			"of the .pck lists it, rather than writing every wem to the output " +
+			"directory itself. Wems keep their canonical names."
+		flagName = "language-folders"
+	)
+	flag.BoolVar(&languageFolders, flagName, false, usage)
+}
+
+func init() {
+	const (
+		usage = "When a .pck is unpacked, only write the wems of the given " +
+			"language, which is either its name, such as english(us), or its ID " +
+			"within the .pck. When pck-replace is used, replace the file of the " +
+			"given language."
+		flagName = "language"
+	)
+	flag.StringVar(&unpackLanguage, flagName, "", usage)
+}
+
+// packageLanguages returns the directory, relative to the output directory,
+// that each wem of p should be unpacked to, and whether each wem should be
+// unpacked at all, as described by the language-folders and language flags.
+// Either result is nil if its flag is not given.
+func packageLanguages(p *pck.File) (folders []string, included []bool,
+	err error) {
+	languages := p.Header.Languages()
+	if languageFolders {
+		folders = make([]string, len(p.Indexes))
+		for i, idx := range p.Indexes {
+			folders[i] = languageFolder(languages, idx.LanguageId)
+		}
+	}
+	if unpackLanguage == "" {
+		return folders, nil, nil
+	}
+
+	id, ok := findLanguage(languages, unpackLanguage)
+	if !ok {
+		msg := fmt.Sprintf("The .pck does not list the language \"%s\"",
+			unpackLanguage)
+		return nil, nil, errors.New(msg)
+	}
+	included = make([]bool, len(p.Indexes))
+	for i, idx := range p.Indexes {
+		included[i] = idx.LanguageId == id
+	}
+	return folders, included, nil
+}
+
+// languageFolder returns the name of the directory that wems of the language
+// with the given ID are unpacked to.
+func languageFolder(languages map[uint32]string, id uint32) string {
+	name, ok := languages[id]
+	// Names are read from the file, so they must not be able to escape the
+	// output directory.
+	if !ok || name == "" || name == "." || name == ".." ||
+		strings.ContainsAny(name, `/\`) {
+		return strconv.FormatUint(uint64(id), 10)
+	}
+	return name
+}
+
+// findLanguage returns the ID of the language called name, compared without
+// regard to case, or whose ID is name.
+func findLanguage(languages map[uint32]string, name string) (uint32, bool) {
+	for id, language := range languages {
+		if strings.EqualFold(language, name) {
+			return id, true
+		}
+	}
+	id, err := strconv.ParseUint(name, 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	_, ok := languages[uint32(id)]
+	return uint32(id), ok
+}
+
+// createLanguageFolders creates the directories of dir that the wems at the
+// given indices are unpacked to.
+func createLanguageFolders(dir string, folders []string, indices []int) error {
+	if folders == nil {
+		return nil
+	}
+	created := make(map[string]bool)
+	for _, i := range indices {
+		if created[folders[i]] {
+			continue
+		}
+		created[folders[i]] = true
+		err := os.MkdirAll(filepath.Join(dir, folders[i]), 0755)
+		if err != nil {
+			return exitErrorf(exitIO, "Could not create language directory: %s",
+				err)
+		}
+	}
+	return nil
+}
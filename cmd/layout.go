@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// A layout describes where a mod loader expects a repacked file to be placed,
+// relative to the directory specified by output.
+type layout struct {
+	// The name of the directory whose contents the mod loader mirrors. The
+	// repacked file is placed at the path of the input file below the last
+	// directory with this name. If empty, the repacked file is placed directly
+	// within the output directory.
+	anchor string
+	desc   string
+}
+
+// The supported layout presets, keyed by name.
+var layouts = map[string]layout{
+	"flat": {"", "output/<name of the input file>"},
+	"nativepc": {"nativePC", "output/nativePC/..., mirroring the path of the " +
+		"input file below its nativePC directory"},
+	"sound": {"sound", "output/sound/..., mirroring the path of the input file " +
+		"below its sound directory"},
+}
+
+var layoutName string
+
+func init() {
+	var presets []string
+	for name, l := range layouts {
+		presets = append(presets, fmt.Sprintf("%s (%s)", name, l.desc))
+	}
+	sort.Strings(presets)
+	usage := "When a mode that writes a modified copy of the file specified by " +
+		"filepath is used, treat output as the root directory of a mod and " +
+		"place the copy where a mod loader expects it. One of: " +
+		strings.Join(presets, ", ") + "."
+	const flagName = "layout"
+	flag.StringVar(&layoutName, flagName, "", usage)
+}
+
+// layoutOutput returns the path, below the directory root, that the layout
+// named name places a modified copy of the file at path. The directories of
+// the returned path are created if they do not exist.
+func layoutOutput(name, path, root string) (string, error) {
+	l, ok := layouts[name]
+	if !ok {
+		msg := fmt.Sprintf("\"%s\" is not a known layout", name)
+		return "", errors.New(msg)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	out := filepath.Join(root, filepath.Base(abs))
+	if l.anchor != "" {
+		parts := strings.Split(filepath.ToSlash(abs), "/")
+		i := len(parts) - 2
+		for ; i >= 0 && !strings.EqualFold(parts[i], l.anchor); i-- {
+		}
+		if i < 0 {
+			msg := fmt.Sprintf("%s is not within a %s directory", path, l.anchor)
+			return "", errors.New(msg)
+		}
+		out = filepath.Join(append([]string{root}, parts[i:]...)...)
+	}
+	err = os.MkdirAll(filepath.Dir(out), 0755)
+	if err != nil {
+		return "", err
+	}
+	return out, nil
+}
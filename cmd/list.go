@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+	"github.com/hpxro7/wwiseutil/pck"
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+var shouldList bool
+
+func init() {
+	const (
+		usage = "list the index, ID, offset and length of every wem within the " +
+			".bnk or .pck specified by filepath. Only the index of the file is " +
+			"read, so this is fast even for very large files."
+		flagName = "list"
+	)
+	flag.BoolVar(&shouldList, flagName, false, usage)
+	flag.BoolVar(&shouldList, "l", false, shorthandDesc(flagName))
+	registerMode(&mode{name: flagName, selected: &shouldList,
+		needsFile: true, run: list, batch: listBatch})
+}
+
+// list prints a table describing every wem of the input file.
+func list(isSoundBank bool) {
+	descs, err := listFile(filePath, isSoundBank)
+	if err != nil {
+		fatalErr(err)
+	}
+
+	tableParams := []string{"%-7", "%-15", "%-15", "%-15", "\n"}
+	titleFmt := strings.Join(tableParams, "s|")
+	wemFmt := strings.Join(tableParams, "d|")
+	title := fmt.Sprintf(titleFmt, "Index", "Id", "Offset", "Length")
+	fmt.Print(title)
+	fmt.Println(strings.Repeat("-", len(title)-1))
+	total := int64(0)
+	for i, desc := range descs {
+		fmt.Printf(wemFmt, i+1, desc.id, desc.offset, desc.length)
+		total += int64(desc.length)
+	}
+	fmt.Printf("%d wem(s), %d bytes in total\n", len(descs), total)
+}
+
+// listBatch summarizes the wems of the .bnk or .pck at path.
+func listBatch(path string, isSoundBank bool) (string, error) {
+	descs, err := listFile(path, isSoundBank)
+	if err != nil {
+		return "", err
+	}
+	total := int64(0)
+	for _, desc := range descs {
+		total += int64(desc.length)
+	}
+	return fmt.Sprintf("%d wem(s), %d bytes in total", len(descs), total), nil
+}
+
+// A listedWem describes the location of a wem within the file it was listed
+// from. Unlike a WemDescriptor, its offset may lie past 4 GB.
+type listedWem struct {
+	id     uint32
+	offset int64
+	length uint32
+}
+
+// listFile returns the location of every wem of the .bnk or .pck at path.
+func listFile(path string, isSoundBank bool) ([]listedWem, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, exitErrorf(exitIO, "Could not open input file: %s", err)
+	}
+	defer f.Close()
+
+	var descs []listedWem
+	if isSoundBank {
+		var wds []*wwise.WemDescriptor
+		wds, err = bnk.ListWems(f)
+		for _, wd := range wds {
+			descs = append(descs, listedWem{wd.WemId, int64(wd.Offset), wd.Length})
+		}
+	} else {
+		// The offsets of the descriptors of a File Package are cut to 32 bits, so
+		// take them from the data indexes.
+		var idxs []*pck.DataIndex
+		idxs, err = pck.ListIndexes(f)
+		for _, idx := range idxs {
+			descs = append(descs, listedWem{idx.Descriptor.WemId, idx.Offset(),
+				idx.Descriptor.Length})
+		}
+	}
+	if err != nil {
+		return nil, exitErrorf(exitParse, "Could not parse .bnk or .pck file: %s",
+			err)
+	}
+	return descs, nil
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/pck"
+)
+
+var shouldExplode bool
+var shouldImplode bool
+
+// The name of the index file that pck-explode writes next to the loose files.
+const looseIndexName = "pck-index.json"
+
+// The names of the file tables of a File Package, as written to the index file.
+const (
+	looseSoundBank = "soundbank"
+	looseStreamed  = "streamed"
+	looseExternal  = "external"
+)
+
+func init() {
+	const (
+		usage = "write every file of the .pck specified by filepath to the " +
+			"directory specified by output as loose files, in the layout that " +
+			"the game can stream from: SoundBanks as <id>.bnk, streamed files as " +
+			"<id>.wem and external files as externals/<id>.wem, in a directory " +
+			"named after their language unless it is sfx. An index file, " +
+			looseIndexName + ", records what pck-implode needs to rebuild the " +
+			".pck unchanged, provided that its files follow each other as Wwise " +
+			"lays them out."
+		flagName = "pck-explode"
+	)
+	flag.BoolVar(&shouldExplode, flagName, false, usage)
+	registerMode(&mode{name: flagName, selected: &shouldExplode,
+		needsFile: true, needsOutput: true, run: explodePackage})
+}
+
+func init() {
+	const (
+		usage = "rebuild a .pck from the loose files in the directory specified " +
+			"by target, as listed by the " + looseIndexName + " written by " +
+			"pck-explode, and write it to the file specified by output. Loose " +
+			"files may have been replaced with files of another length."
+		flagName = "pck-implode"
+	)
+	flag.BoolVar(&shouldImplode, flagName, false, usage)
+	registerMode(&mode{name: flagName, selected: &shouldImplode,
+		needsOutput: true, run: implodePackage})
+}
+
+// A looseIndex describes a File Package that was written as loose files.
+type looseIndex struct {
+	Version   uint32          `json:"version"`
+	Languages []looseLanguage `json:"languages"`
+	Files     []looseFile     `json:"files"`
+}
+
+// A looseLanguage is a language of the language map of a File Package.
+type looseLanguage struct {
+	Id   uint32 `json:"id"`
+	Name string `json:"name"`
+}
+
+// A looseFile is a file of a File Package that was written as a loose file.
+type looseFile struct {
+	// The file table that lists the file.
+	Table     string `json:"table"`
+	Id        uint64 `json:"id"`
+	Language  uint32 `json:"language"`
+	BlockSize uint32 `json:"block_size"`
+	// The path of the loose file, relative to the index file and separated by
+	// slashes.
+	Path string `json:"path"`
+}
+
+// explodePackage writes every file of the input File Package to the output
+// directory, along with an index of them.
+func explodePackage(isSoundBank bool) {
+	if isSoundBank {
+		fatal(exitUsage, "pck-explode only supports File Package files")
+	}
+	f, err := os.Open(filePath)
+	if err != nil {
+		fatalln(exitIO, "Could not open input file:", err)
+	}
+	defer f.Close()
+	c, err := pck.ListContents(f)
+	if err != nil {
+		fatalln(exitParse, "Could not parse .pck file:", err)
+	}
+	err = createDirIfEmpty(output)
+	if err != nil {
+		fatalln(exitIO, "Could not create output directory:", err)
+	}
+
+	index := looseIndex{Version: c.Version}
+	for id, name := range c.Languages {
+		index.Languages = append(index.Languages, looseLanguage{id, name})
+	}
+	sort.Slice(index.Languages, func(i, j int) bool {
+		return index.Languages[i].Id < index.Languages[j].Id
+	})
+	tables := []struct {
+		name    string
+		entries []*pck.Entry
+	}{{looseSoundBank, c.SoundBanks}, {looseStreamed, c.Streamed},
+		{looseExternal, c.Externals}}
+	total := int64(0)
+	for _, table := range tables {
+		for _, e := range table.entries {
+			rel := loosePath(c.Languages, table.name, e)
+			n, err := writeLooseFile(f, e, filepath.Join(output,
+				filepath.FromSlash(rel)))
+			total += n
+			if err != nil && err != errSkipped {
+				fatalf(exitIO, "Could not write %s: %s\n", rel, err)
+			}
+			index.Files = append(index.Files, looseFile{table.name, e.Id,
+				e.LanguageId, e.BlockSize, rel})
+		}
+	}
+
+	err = writeLooseIndex(filepath.Join(output, looseIndexName), &index)
+	if err != nil && err != errSkipped {
+		fatalln(exitIO, "Could not write index file:", err)
+	}
+	fmt.Printf("Successfully wrote %d file(s) to %s\n", len(index.Files),
+		output)
+	fmt.Printf("Wrote %d bytes in total\n", total)
+}
+
+// loosePath returns the path, separated by slashes, that the entry e of the
+// file table with the given name is written to.
+func loosePath(languages map[uint32]string, table string, e *pck.Entry) string {
+	name := fmt.Sprint(e.Id)
+	switch table {
+	case looseSoundBank:
+		name += ".bnk"
+	case looseStreamed:
+		name += wemExtension
+	case looseExternal:
+		name = path.Join("externals", name+wemExtension)
+	}
+	if strings.EqualFold(languages[e.LanguageId], rootLanguage) {
+		return name
+	}
+	return path.Join(languageFolder(languages, e.LanguageId), name)
+}
+
+// writeLooseFile copies the file described by e from the File Package r to the
+// file at path, and returns the number of bytes written.
+func writeLooseFile(r io.ReaderAt, e *pck.Entry, path string) (int64, error) {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return 0, err
+	}
+	f, err := createFile(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	n, err := io.Copy(f, io.NewSectionReader(r, e.Offset(), int64(e.Length)))
+	opReport.addWritten(n)
+	if err == nil {
+		opReport.addExtracted(1)
+	}
+	return n, err
+}
+
+// writeLooseIndex writes index to the file at path.
+func writeLooseIndex(path string, index *looseIndex) error {
+	f, err := createFile(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(index)
+}
+
+// implodePackage rebuilds a File Package from the loose files of the target
+// directory.
+func implodePackage(bool) {
+	if targetPath == "" {
+		flag.Usage()
+		fatal(exitUsage, "target cannot be empty")
+	}
+	index, err := readLooseIndex(filepath.Join(targetPath, looseIndexName))
+	if err != nil {
+		fatalln(exitParse, "Could not read index file:", err)
+	}
+
+	builder := pck.NewBuilder().SetVersion(index.Version)
+	for _, language := range index.Languages {
+		builder.AddLanguage(language.Id, language.Name)
+	}
+	for _, lf := range index.Files {
+		path := filepath.Join(targetPath, filepath.FromSlash(lf.Path))
+		f, err := os.Open(path)
+		if err != nil {
+			fatalf(exitIO, "Could not open \"%s\": %s\n", path, err)
+		}
+		defer f.Close()
+		fi, err := f.Stat()
+		if err != nil {
+			fatalf(exitIO, "Could not open \"%s\": %s\n", path, err)
+		}
+		src := pck.FileSource{lf.Id, lf.Language, f, fi.Size(), lf.BlockSize}
+		switch lf.Table {
+		case looseSoundBank:
+			builder.AddSoundBank(src)
+		case looseStreamed:
+			builder.AddStreamed(src)
+		case looseExternal:
+			builder.AddExternal(src)
+		default:
+			fatalf(exitParse, "The file table \"%s\" of %s is not one of %s, %s or "+
+				"%s\n", lf.Table, lf.Path, looseSoundBank, looseStreamed,
+				looseExternal)
+		}
+	}
+
+	total, err := writeOutput(output, builder)
+	if err != nil {
+		fatalln(exitCode(err), "Could not write output to file:", err)
+	}
+	fmt.Printf("Successfully rebuilt a File Package of %d file(s) at %s\n",
+		len(index.Files), output)
+	fmt.Printf("Wrote %d bytes in total\n", total)
+}
+
+// readLooseIndex reads the index file at path.
+func readLooseIndex(path string) (*looseIndex, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var index looseIndex
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	err = dec.Decode(&index)
+	if err != nil {
+		return nil, err
+	}
+	return &index, nil
+}
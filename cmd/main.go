@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -8,8 +9,11 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 import (
@@ -22,15 +26,129 @@ import (
 const shorthandSuffix = " (shorthand)"
 const wemExtension = ".wem"
 
+// The extensions of the files that replace accepts as wems. Besides .wem, these
+// are the extensions that codec-naming gives unpacked wems.
+var replacementExtensions = []string{wemExtension,
+	wwise.PCMCodec.Extension(), wwise.OggCodec.Extension()}
+
+const (
+	codecNamingExtension = "extension"
+	codecNamingSuffix    = "suffix"
+)
+
+// The policies for files that already exist, as given to on-conflict.
+const (
+	conflictOverwrite = "overwrite"
+	conflictSkip      = "skip"
+	conflictError     = "error"
+)
+
+// The error returned by createFile when a file that already exists is skipped.
+var errSkipped = errors.New("The file already exists and is skipped")
+
+// The policies that may be given to duplicate-ids.
+var duplicatePolicies = map[string]bnk.DuplicatePolicy{
+	"error":      bnk.DuplicateError,
+	"keep-first": bnk.DuplicateKeepFirst,
+	"keep-all":   bnk.DuplicateKeepAll,
+}
+
 var shouldUnpack bool
 var shouldReplace bool
 var filePath string
 var output string
 var targetPath string
 var verbose bool
+var codecNaming string
+var lenient bool
+var strict bool
+var duplicateIds string
+var skipEmpty bool
+var skipExisting bool
+var alignment int64
+var preservePadding bool
+var threads int
+var memoryMap bool
+var backup bool
+var force bool
+var onConflict string
+var keepGoing bool
 
 type flagError string
 
+// A mode is a single operation of this tool, selected by a boolean flag.
+type mode struct {
+	// The name of the flag that selects this mode.
+	name     string
+	selected *bool
+	// True if this mode operates on the file specified by filepath.
+	needsFile bool
+	// True if this mode writes to the location specified by output.
+	needsOutput bool
+	// True if this mode writes a modified copy of the file specified by
+	// filepath to output, so that output can be placed by the layout flag.
+	repacks bool
+	// Runs this mode. isSoundBank is only valid if needsFile is true.
+	run func(isSoundBank bool)
+	// Runs this mode on a single file of a batch, when filepath names a
+	// directory or a glob pattern, and returns a one line summary of the result.
+	// Modes without batch do not support more than one input file.
+	batch func(path string, isSoundBank bool) (string, error)
+}
+
+// The list of all modes supported by this tool. Exactly one of these must be
+// selected.
+var modes []*mode
+
+func registerMode(m *mode) {
+	modes = append(modes, m)
+}
+
+// A modeValue is the value of a flag that both selects a mode and takes an
+// argument, such as -extract-event <name>. Setting it stores the argument in
+// value and selects the mode.
+type modeValue struct {
+	value    *string
+	selected *bool
+	// True if the argument is a file or directory path, which completion
+	// completes with file names.
+	isPath bool
+}
+
+func (v modeValue) String() string {
+	if v.value == nil {
+		return ""
+	}
+	return *v.value
+}
+
+func (v modeValue) Set(value string) error {
+	*v.value = value
+	*v.selected = true
+	return nil
+}
+
+// The names of the flags defined by pathVar.
+var pathFlags = make(map[string]bool)
+
+// pathVar defines a string flag, as flag.StringVar does, whose value is a file
+// or directory path, which completion completes with file names.
+func pathVar(p *string, name, value, usage string) {
+	flag.StringVar(p, name, value, usage)
+	pathFlags[name] = true
+}
+
+func init() {
+	registerMode(&mode{name: "unpack", selected: &shouldUnpack,
+		needsFile: true, needsOutput: true, run: unpack, batch: unpackBatch})
+	registerMode(&mode{name: "replace", selected: &shouldReplace,
+		needsFile: true, needsOutput: true, repacks: true,
+		run: func(isSoundBank bool) {
+			verifyReplaceFlags()
+			replaceAndWatch(isSoundBank)
+		}})
+}
+
 func init() {
 	const (
 		usage    = "unpack a .bnk or .pck into seperate .wem files"
@@ -56,11 +174,16 @@ func init() {
 		usage = "the path to the source .bnk or .pck. When unpack is used, this " +
 			"is the bnk or pck file to unpack. When replace is used, this .bnk or " +
 			".pck is used as a source; the wem files, offsets and lengths of this " +
-			".bnk or .pck will updated and written to the file specified by output."
+			".bnk or .pck will updated and written to the file specified by output. " +
+			"When unpack, list or verify is used, this may also be a directory, " +
+			"which is searched recursively, or a glob pattern, such as " +
+			"\"banks/*.bnk\", to process every .bnk and .pck it names " +
+			"concurrently; unpack then writes the wems of each file to a " +
+			"directory of output named after its path within the directory."
 		flagName = "filepath"
 	)
-	flag.StringVar(&filePath, flagName, "", usage)
-	flag.StringVar(&filePath, "f", "", shorthandDesc(flagName))
+	pathVar(&filePath, flagName, "", usage)
+	pathVar(&filePath, "f", "", shorthandDesc(flagName))
 }
 
 func init() {
@@ -70,8 +193,8 @@ func init() {
 			"updated .bnk or .pck."
 		flagName = "output"
 	)
-	flag.StringVar(&output, flagName, "", usage)
-	flag.StringVar(&output, "o", "", shorthandDesc(flagName))
+	pathVar(&output, flagName, "", usage)
+	pathVar(&output, "o", "", shorthandDesc(flagName))
 }
 
 func init() {
@@ -85,8 +208,8 @@ func init() {
 			"needed."
 		flagName = "target"
 	)
-	flag.StringVar(&targetPath, flagName, "", usage)
-	flag.StringVar(&targetPath, "t", "", shorthandDesc(flagName))
+	pathVar(&targetPath, flagName, "", usage)
+	pathVar(&targetPath, "t", "", shorthandDesc(flagName))
 }
 
 func init() {
@@ -99,27 +222,203 @@ func init() {
 	flag.BoolVar(&verbose, "v", false, shorthandDesc(flagName))
 }
 
+func init() {
+	const (
+		usage = "When unpack is used, this detects the codec of every wem and " +
+			"names the unpacked files accordingly. \"extension\" gives PCM wems " +
+			"a .wav extension and Ogg streams an .ogg extension, so that players " +
+			"pick them up directly. \"suffix\" keeps the .wem extension but adds " +
+			"the codec name before it, e.g. 01.vorbis.wem. Either way, replace " +
+			"accepts the unpacked files as they are named."
+		flagName = "codec-naming"
+	)
+	flag.StringVar(&codecNaming, flagName, "", usage)
+}
+
+func init() {
+	const (
+		usage = "When unpack is used, do not write files for wems that hold no " +
+			"data. Otherwise, such wems are written as empty files."
+		flagName = "skip-empty"
+	)
+	flag.BoolVar(&skipEmpty, flagName, false, usage)
+}
+
+func init() {
+	const (
+		usage = "When unpack is used, do not rewrite wems whose files already " +
+			"exist in the output directory with the length of the wem, so that an " +
+			"interrupted unpack can be resumed. Files of any other length are " +
+			"rewritten, unless on-conflict is error, which stops instead."
+		flagName = "skip-existing"
+	)
+	flag.BoolVar(&skipExisting, flagName, false, usage)
+}
+
+func init() {
+	const (
+		usage = "When a .bnk is read, tolerate a DIDX section describing wems " +
+			"that overlap or that end past the end of the DATA section, printing " +
+			"a warning for each problem instead of failing."
+		flagName = "lenient"
+	)
+	flag.BoolVar(&lenient, flagName, false, usage)
+}
+
+func init() {
+	const (
+		usage = "When a .bnk is read, fail if it holds a section that is not " +
+			"known or could not be decoded, rather than passing the section " +
+			"through unchanged."
+		flagName = "strict"
+	)
+	flag.BoolVar(&strict, flagName, false, usage)
+}
+
+func init() {
+	const (
+		usage = "How a .bnk whose DIDX section repeats a wem ID is read: " +
+			"\"error\" refuses to read it, \"keep-first\" drops every repeat, and " +
+			"\"keep-all\" keeps every wem, so that repeats are only told apart by " +
+			"their index."
+		flagName = "duplicate-ids"
+	)
+	flag.StringVar(&duplicateIds, flagName, "error", usage)
+}
+
+func init() {
+	const (
+		usage = "The number of bytes that the offsets of wems are aligned to " +
+			"when they are replaced in or added to a .bnk, and that verify " +
+			"expects them to be aligned to."
+		flagName = "align"
+	)
+	flag.Int64Var(&alignment, flagName, 16, usage)
+}
+
+func init() {
+	const (
+		usage = "When a wem is replaced, keep the original bytes of the padding " +
+			"that follows it, rather than filling the padding with zeroes. This " +
+			"keeps byte-level differences against the original file small."
+		flagName = "preserve-padding"
+	)
+	flag.BoolVar(&preservePadding, flagName, false, usage)
+}
+
+func init() {
+	const (
+		usage = "The number of wems that are written at once, when wems are " +
+			"unpacked or a .bnk or .pck is written, and the number of files that " +
+			"are processed at once when filepath names more than one file."
+		flagName = "threads"
+	)
+	flag.IntVar(&threads, flagName, runtime.NumCPU(), usage)
+}
+
+func init() {
+	const (
+		usage = "Map the input .bnk or .pck into memory rather than reading it " +
+			"with system calls, which speeds up reading very large files."
+		flagName = "mmap"
+	)
+	flag.BoolVar(&memoryMap, flagName, false, usage)
+}
+
+func init() {
+	const (
+		usage = "Before a .bnk or .pck is overwritten, copy it to a file next to " +
+			"it named after the time of the copy, such as " +
+			"sfx.bnk.20060102-150405.bak, so that it can be restored. The file " +
+			"may then be overwritten without force."
+		flagName = "backup"
+	)
+	flag.BoolVar(&backup, flagName, false, usage)
+}
+
+func init() {
+	const (
+		usage = "Overwrite a .bnk or .pck output file that already exists. " +
+			"Otherwise, this tool refuses to write to an existing .bnk or .pck, " +
+			"unless backup is used. Other files are overwritten as on-conflict " +
+			"describes."
+		flagName = "force"
+	)
+	flag.BoolVar(&force, flagName, false, usage)
+}
+
+func init() {
+	const (
+		usage = "When filepath names more than one file, or build is used, go " +
+			"on processing the remaining files after one fails, and print a " +
+			"summary of the failures at the end. Otherwise, processing stops at " +
+			"the first failure."
+		flagName = "keep-going"
+	)
+	flag.BoolVar(&keepGoing, flagName, false, usage)
+}
+
+func init() {
+	const (
+		usage = "What to do when unpack, extract-event or dump-sections would " +
+			"write a file that already exists: overwrite it, skip it, or stop " +
+			"with an error."
+		flagName = "on-conflict"
+	)
+	flag.StringVar(&onConflict, flagName, conflictOverwrite, usage)
+}
+
 func shorthandDesc(flagName string) string {
 	return "(shorthand for -" + flagName + ")"
 }
 
-func verifyFlags() {
+// verifyFlags verifies that exactly one mode was specified, along with the
+// flags it requires, and returns that mode.
+func verifyFlags() *mode {
+	var selected []*mode
+	var names []string
+	for _, m := range modes {
+		names = append(names, m.name)
+		if *m.selected {
+			selected = append(selected, m)
+		}
+	}
+
+	_, knownPolicy := duplicatePolicies[duplicateIds]
 	var err flagError
 	switch {
-	case !(shouldUnpack || shouldReplace):
-		err = "Either unpack or replace should be specified"
-	case shouldUnpack && shouldReplace:
-		err = "Both unpack and replace cannot be specified"
-	case filePath == "":
-		err = "bnkpath cannot be empty"
-	case output == "":
+	case len(selected) == 0:
+		err = flagError("One of " + strings.Join(names, ", ") +
+			" should be specified")
+	case len(selected) > 1:
+		err = "Only one of " + flagError(strings.Join(names, ", ")) +
+			" can be specified"
+	case selected[0].needsFile && filePath == "":
+		err = "filepath cannot be empty"
+	case selected[0].needsOutput && output == "":
 		err = "output cannot be empty"
+	case codecNaming != "" && codecNaming != codecNamingExtension &&
+		codecNaming != codecNamingSuffix:
+		err = "codec-naming must be either extension or suffix"
+	case !knownPolicy:
+		err = "duplicate-ids must be one of error, keep-first or keep-all"
+	case alignment < 1:
+		err = "align must be positive"
+	case threads < 1:
+		err = "threads must be positive"
+	case onConflict != conflictOverwrite && onConflict != conflictSkip &&
+		onConflict != conflictError:
+		err = "on-conflict must be one of overwrite, skip or error"
+	case layoutName != "" && !selected[0].repacks:
+		err = "layout can only be used by modes that write a modified copy of " +
+			"filepath"
 	}
 
 	if err != "" {
 		flag.Usage()
-		log.Fatal(err)
+		fatal(exitUsage, err)
 	}
+	return selected[0]
 }
 
 func verifyReplaceFlags() {
@@ -131,7 +430,7 @@ func verifyReplaceFlags() {
 
 	if err != "" {
 		flag.Usage()
-		log.Fatal(err)
+		fatal(exitUsage, err)
 	}
 }
 
@@ -143,102 +442,577 @@ func verifyInputType() bool {
 	isFilePath := fileType == util.FilePackageFileType
 	if !(isSoundBank || isFilePath) {
 		flag.Usage()
-		log.Fatal(ext, ", is not a supported input file type")
+		fatal(exitUsage, ext, ", is not a supported input file type")
 	}
 	return isSoundBank
 }
 
+// openSoundBank opens the SoundBank at path, as described by the lenient,
+// strict, duplicate-ids and mmap flags, and prints any problems that were
+// tolerated while reading it. Wems replaced in or added to the SoundBank are
+// aligned and padded as described by the align and preserve-padding flags.
+func openSoundBank(path string) (*bnk.File, error) {
+	b, err := bnk.OpenWithOptions(path, readOptions())
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range b.Warnings {
+		log.Printf("Warning: %s: %s\n", path, w)
+		opReport.addWarning("%s: %s", path, w)
+	}
+	err = b.SetAlignment(alignment)
+	if err != nil {
+		b.Close()
+		return nil, err
+	}
+	b.SetPreservePadding(preservePadding)
+	return b, nil
+}
+
+// readOptions returns the options that SoundBanks are read with, as described
+// by the lenient, strict, duplicate-ids and mmap flags.
+func readOptions() bnk.ReadOptions {
+	return bnk.ReadOptions{Lenient: lenient, Strict: strict,
+		Duplicates: duplicatePolicies[duplicateIds], MemoryMap: memoryMap}
+}
+
+// openFilePackage opens the File Package at path, as described by the mmap
+// flag.
+func openFilePackage(path string) (*pck.File, error) {
+	if memoryMap {
+		return pck.OpenMapped(path)
+	}
+	return pck.Open(path)
+}
+
 func unpack(isSoundBank bool) {
-	var ctn wwise.Container
-	var err error
+	err := createDirIfEmpty(output)
+	if err != nil {
+		fatalln(exitIO, "Could not create output directory:", err)
+	}
+	count, written, existing, total, err := unpackFile(filePath, output,
+		isSoundBank)
+	if err != nil {
+		fatalErr(err)
+	}
+	fmt.Printf("Successfully wrote %d wem(s) to %s\n", written, output)
+	if existing > 0 {
+		fmt.Printf("Skipped %d wem(s) that were already unpacked\n", existing)
+	}
+	if empty := count - written - existing; empty > 0 {
+		fmt.Printf("Skipped %d empty wem(s)\n", empty)
+	}
+	fmt.Printf("Wrote %d bytes in total\n", total)
+}
 
+// unpackBatch unpacks the file at path into a directory of the output
+// directory, named as described by batchOutputName.
+func unpackBatch(path string, isSoundBank bool) (string, error) {
+	dir := filepath.Join(output, batchOutputName(path))
+	err := createDirIfEmpty(dir)
+	if err != nil {
+		return "", err
+	}
+	count, written, existing, total, err := unpackFile(path, dir, isSoundBank)
+	if err != nil {
+		return "", err
+	}
+	summary := fmt.Sprintf("wrote %d of %d wem(s), %d bytes, to %s", written,
+		count, total, dir)
+	if existing > 0 {
+		summary += fmt.Sprintf(", skipped %d already unpacked", existing)
+	}
+	return summary, nil
+}
+
+// unpackFile writes the wems of the .bnk or .pck at path to the directory dir.
+// It returns the number of wems in the file, the number of wems written, the
+// number of wems skipped as described by the skip-existing flag and the number
+// of bytes written.
+func unpackFile(path, dir string, isSoundBank bool) (count, written,
+	existing int, total int64, err error) {
+	var ctn wwise.Container
 	if isSoundBank {
-		ctn, err = bnk.Open(filePath)
+		ctn, err = openSoundBank(path)
 	} else { // Input is file package
-		ctn, err = pck.Open(filePath)
+		ctn, err = openFilePackage(path)
 	}
-	defer ctn.Close()
-
 	if err != nil {
-		log.Fatalln("Could not parse .bnk or .pck file:", err)
+		return 0, 0, 0, 0, exitErrorf(exitParse,
+			"Could not parse .bnk or .pck file: %s",
+			err)
 	}
+	defer ctn.Close()
 	if verbose {
 		fmt.Println(ctn)
 	}
 
-	err = createDirIfEmpty(output)
-	if err != nil {
-		log.Fatalln("Could not create output directory:", err)
-	}
-	total := int64(0)
-	for i, wem := range ctn.Wems() {
-		filename := util.CanonicalWemName(i, len(ctn.Wems()))
-		f, err := os.Create(filepath.Join(output, filename))
+	wems := ctn.Wems()
+	if stitch {
+		b, ok := ctn.(*bnk.File)
+		if !ok {
+			return 0, 0, 0, 0, exitErrorf(exitUsage,
+				"stitch only supports SoundBank files")
+		}
+		var closeStitched func()
+		wems, closeStitched, err = stitchPrefetched(b)
 		if err != nil {
-			log.Fatalf("Could not create wem file \"%s\": %s", filename, err)
+			return 0, 0, 0, 0, exitErrorf(exitIO, "Could not stitch wems: %s", err)
 		}
-		n, err := io.Copy(f, wem)
+		defer closeStitched()
+	}
+
+	var folders []string
+	var included []bool
+	if p, ok := ctn.(*pck.File); ok {
+		folders, included, err = packageLanguages(p)
 		if err != nil {
-			log.Fatalf("Could not write wem file \"%s\": %s", filename, err)
+			return 0, 0, 0, 0, exitErrorf(exitUsage, "%s", err)
+		}
+	} else if languageFolders || unpackLanguage != "" {
+		return 0, 0, 0, 0, exitErrorf(exitUsage,
+			"language-folders and language only support File Package files")
+	}
+
+	// Wems of other languages are not counted as wems of the file.
+	count = len(wems)
+	var indices []int
+	for i, wem := range wems {
+		if included != nil && !included[i] {
+			count--
+			continue
+		}
+		if skipEmpty && wem.Descriptor.Length == 0 {
+			continue
 		}
+		indices = append(indices, i)
+	}
+	pending, err := pendingWems(dir, wems, folders, indices)
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+	err = createLanguageFolders(dir, folders, pending)
+	if err == nil {
+		total, err = writeUnpackedWems(dir, wems, folders, pending)
+	}
+	if err == nil && writeManifest {
+		err = writeUnpackManifest(dir, wems, folders, indices)
+	}
+	if err != nil {
+		return count, len(pending), len(indices) - len(pending), total, err
+	}
+
+	// Streamed media found in the media directory is counted along with the
+	// wems of the container.
+	streamed := 0
+	if b, ok := ctn.(*bnk.File); ok {
+		var n int64
+		streamed, n, err = unpackStreamedMedia(b, dir)
 		total += n
 	}
-	fmt.Printf("Successfully wrote %d wem(s) to %s\n", len(ctn.Wems()),
-		output)
-	fmt.Printf("Wrote %d bytes in total\n", total)
+	return count + streamed, len(pending) + streamed,
+		len(indices) - len(pending), total, err
+}
+
+// pendingWems returns the indices of wems that should still be unpacked to the
+// directory dir, in the directories given by folders if it is not nil. If
+// on-conflict is skip, wems whose files already exist are not unpacked.
+// Otherwise, if skip-existing is used, wems whose files already exist and have
+// the length of the wem are not unpacked. Files of any other length may have
+// been cut short by an earlier unpack that was interrupted; if on-conflict is
+// overwrite they are removed so that they can be rewritten, and otherwise an
+// error is returned before any file is removed.
+func pendingWems(dir string, wems []*wwise.Wem, folders []string,
+	indices []int) ([]int, error) {
+	skipAny := onConflict == conflictSkip
+	if !skipAny && !skipExisting {
+		return indices, nil
+	}
+	var pending []int
+	for _, i := range indices {
+		path := filepath.Join(dir, unpackedWemPath(wems, folders, i))
+		fi, err := os.Stat(path)
+		if err == nil &&
+			(skipAny || fi.Size() == int64(wems[i].Descriptor.Length)) {
+			continue
+		}
+		if err == nil && onConflict != conflictOverwrite {
+			return nil, exitErrorf(exitIO, "%s already exists with a different "+
+				"length; use -on-conflict overwrite to overwrite it", path)
+		}
+		if err == nil {
+			err = os.Remove(path)
+		}
+		if err != nil && !os.IsNotExist(err) {
+			return nil, exitErrorf(exitIO, "Could not check for an unpacked wem: %s",
+				err)
+		}
+		pending = append(pending, i)
+	}
+	return pending, nil
+}
+
+// writeUnpackedWems writes the wems at the given indices of wems to the
+// directory dir, in the directories given by folders if it is not nil, using as
+// many goroutines as specified by threads, and returns the total number of
+// bytes written. The first error encountered is returned.
+func writeUnpackedWems(dir string, wems []*wwise.Wem, folders []string,
+	indices []int) (int64, error) {
+	jobs := make(chan int)
+	var total int64
+	var firstErr error
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for t := 0; t < threads && t < len(indices); t++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				n, err := writeUnpackedWem(dir, wems, folders, i)
+				mu.Lock()
+				total += n
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, i := range indices {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return total, firstErr
+}
+
+// writeUnpackedWem writes the wem at index i of wems to the directory dir, in
+// the directory given by folders if it is not nil, and returns the number of
+// bytes written.
+func writeUnpackedWem(dir string, wems []*wwise.Wem, folders []string,
+	i int) (int64, error) {
+	wem := wems[i]
+	filename := unpackedWemPath(wems, folders, i)
+	f, err := createFile(filepath.Join(dir, filename))
+	if err != nil {
+		return 0, exitErrorf(exitIO,
+			"Could not create wem file \"%s\": %s", filename,
+			err)
+	}
+	defer f.Close()
+	n, err := io.Copy(f, wem)
+	opReport.addWritten(n)
+	if err != nil {
+		return n, exitErrorf(exitIO, "Could not write wem file \"%s\": %s",
+			filename, err)
+	}
+	opReport.addExtracted(1)
+	return n, nil
+}
+
+// unpackedWemPath returns the path, relative to the output directory, that the
+// wem at index i of wems should be unpacked to. If folders is not nil, the wem
+// is unpacked to the directory it gives for the wem.
+func unpackedWemPath(wems []*wwise.Wem, folders []string, i int) string {
+	name := unpackedWemName(wems[i], i, len(wems))
+	if folders == nil {
+		return name
+	}
+	return filepath.Join(folders[i], name)
+}
+
+// unpackedWemName returns the name of the file that wem, which is stored at
+// index i of a container with wemCount wems, should be unpacked to.
+func unpackedWemName(wem *wwise.Wem, i, wemCount int) string {
+	name := util.CanonicalWemName(i, wemCount)
+	switch codecNaming {
+	case codecNamingExtension:
+		return strings.TrimSuffix(name, wemExtension) + wem.Codec().Extension()
+	case codecNamingSuffix:
+		return strings.TrimSuffix(name, wemExtension) + "." + wem.Codec().String() +
+			wemExtension
+	}
+	return name
 }
 
 func replace(isSoundBank bool) {
+	err := replaceFile(isSoundBank)
+	if err != nil {
+		fatalErr(err)
+	}
+}
+
+// replaceFile replaces the wems of the input file with the wems of the target
+// directory, and writes the result to the output file.
+func replaceFile(isSoundBank bool) error {
 	var ctn wwise.Container
 	var err error
 
 	if isSoundBank {
-		ctn, err = bnk.Open(filePath)
+		ctn, err = openSoundBank(filePath)
 	} else { // Input is file package
-		ctn, err = pck.Open(filePath)
+		var p *pck.File
+		p, err = openFilePackage(filePath)
+		if err == nil {
+			p.SetPreservePadding(preservePadding)
+		}
+		ctn = p
 	}
-	defer ctn.Close()
-
 	if err != nil {
-		log.Fatalln("Could not parse .bnk or .pck file:", err)
+		return exitErrorf(exitParse, "Could not parse .bnk or .pck file: %s", err)
 	}
+	defer ctn.Close()
 	if verbose {
 		fmt.Println(ctn)
 	}
 
 	targetFileInfos, err := ioutil.ReadDir(targetPath)
 	if err != nil {
-		log.Fatalf("Could not open target directory, \"%s\": %s\n", targetPath, err)
+		return exitErrorf(exitIO, "Could not open target directory, \"%s\": %s",
+			targetPath, err)
+	}
+	targets, err := processTargetFiles(ctn, targetFileInfos)
+	if err != nil {
+		return err
+	}
+	defer closeTargets(targets)
+	err = ctn.CheckReplacements(targets...)
+	if err != nil {
+		return fmt.Errorf("Could not replace wems: %w", err)
 	}
-	targets := processTargetFiles(ctn, targetFileInfos)
 
+	replaced := recordReplacements(ctn, targets)
 	ctn.ReplaceWems(targets...)
+	opReport.addReplaced(len(targets))
+	applyRepackFlags(ctn)
 
-	outputFile, err := os.Create(output)
+	input, err := os.Stat(filePath)
 	if err != nil {
-		log.Fatalf("Could not create output file \"%s\": %s\n", output, err)
+		return exitErrorf(exitIO, "Could not open input file: %s", err)
 	}
-	total, err := ctn.WriteTo(outputFile)
+	total, err := writeOutput(output, ctn)
 	if err != nil {
-		log.Fatalln("Could not write output to file: ", err)
+		return exitErrorf(exitIO, "Could not write output to file: %s", err)
 	}
 	fmt.Println("Sucessfuly replaced! Output file written to:", output)
 	fmt.Printf("Wrote %d bytes in total\n", total)
+	printReplacementSummary(ctn, replaced, input.Size(), total)
+	return nil
+}
+
+// writeContainer writes ctn to f, writing its wems with as many goroutines as
+// specified by threads if ctn supports it.
+func writeContainer(f *os.File, ctn io.WriterTo) (int64, error) {
+	if wa, ok := ctn.(interface {
+		WriteToAt(w io.WriterAt, threads int) (int64, error)
+	}); ok {
+		return wa.WriteToAt(f, threads)
+	}
+	return ctn.WriteTo(f)
+}
+
+// writeOutput writes wt to the file at path, as writeContainer does. The file
+// is written atomically: wt is written to a temporary file in the same
+// directory, which only replaces the file at path once it has been fully
+// written, so an interrupted write never leaves a partial file behind. As the
+// file at path is not changed until then, it may also be the input file. If the
+// file already exists, it is only replaced if the force or backup flag is set,
+// and it is backed up first if the backup flag is set.
+func writeOutput(path string, wt io.WriterTo) (int64, error) {
+	err := checkOverwrite(path)
+	if err != nil {
+		return 0, err
+	}
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := ioutil.TempFile(dir, "."+base+".tmp-*")
+	if err != nil {
+		return 0, err
+	}
+	// Remove the temporary file unless it replaced the file at path.
+	committed := false
+	defer func() {
+		if !committed {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	n, err := writeContainer(f, wt)
+	if err == nil {
+		err = f.Sync()
+	}
+	if err == nil {
+		err = f.Close()
+	}
+	if err != nil {
+		return n, err
+	}
+	// Temporary files are only accessible by their owner, so give the output the
+	// mode of the file it replaces, or the usual mode of a new file. The file it
+	// replaces stays at path until the rename, so that path is never left
+	// empty.
+	mode := os.FileMode(0644)
+	if fi, err := os.Stat(path); err == nil {
+		mode = fi.Mode()
+		err = backupFile(path, true)
+		if err != nil {
+			return n, err
+		}
+	}
+	err = os.Chmod(f.Name(), mode)
+	if err != nil {
+		return n, err
+	}
+	err = os.Rename(f.Name(), path)
+	if err != nil {
+		return n, err
+	}
+	committed = true
+	if sessionOutputs != nil {
+		sessionOutputs[outputKey(path)] = true
+	}
+	opReport.addWritten(n)
+	return n, nil
+}
+
+// checkOverwrite returns an error if the file at path exists, and may not be
+// overwritten as the force and backup flags are not set and it was not written
+// by this session.
+func checkOverwrite(path string) error {
+	if force || backup || sessionOutputs[outputKey(path)] {
+		return nil
+	}
+	if _, err := os.Stat(path); err == nil {
+		msg := fmt.Sprintf("%s already exists; use -force to overwrite it", path)
+		return errors.New(msg)
+	}
+	return nil
+}
+
+// outputKey returns the key of the file at path in sessionOutputs.
+func outputKey(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// createFile creates the file at path, as os.Create does. If the file already
+// exists, it is overwritten, errSkipped is returned or an error is returned, as
+// described by the on-conflict flag.
+func createFile(path string) (*os.File, error) {
+	return createFileMode(path, 0666)
+}
+
+// createFileMode is like createFile, but creates the file with the permissions
+// perm, before the umask. An overwritten file is given the permissions perm
+// too, so that a file that must stay private never keeps looser ones.
+func createFileMode(path string, perm os.FileMode) (*os.File, error) {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if onConflict != conflictOverwrite {
+		flags |= os.O_EXCL
+	}
+	f, err := os.OpenFile(path, flags, perm)
+	if os.IsExist(err) {
+		if onConflict == conflictSkip {
+			return nil, errSkipped
+		}
+		msg := fmt.Sprintf("%s already exists; use -on-conflict overwrite to "+
+			"overwrite it", path)
+		return nil, errors.New(msg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if perm != 0666 {
+		err = f.Chmod(perm)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// A writerToFunc is a function that implements io.WriterTo.
+type writerToFunc func(w io.Writer) (int64, error)
+
+func (f writerToFunc) WriteTo(w io.Writer) (int64, error) {
+	return f(w)
+}
+
+// backupFile copies the file at path to a file next to it named after the
+// current time, if the backup flag is set. If link is true, the backup is a
+// hard link to the file where possible, which is only safe if the file is about
+// to be replaced by a rename rather than written to. The file at path is left
+// in place either way.
+func backupFile(path string, link bool) error {
+	if !backup {
+		return nil
+	}
+	backupPath := path + "." + time.Now().Format("20060102-150405") + ".bak"
+	if _, err := os.Stat(backupPath); err == nil {
+		msg := fmt.Sprintf("Could not back up \"%s\": %s already exists", path,
+			backupPath)
+		return errors.New(msg)
+	}
+	if link && os.Link(path, backupPath) == nil {
+		fmt.Println("Backed up the existing file to:", backupPath)
+		return nil
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("Could not back up \"%s\": %s", path, err)
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(backupPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+	if err != nil {
+		return fmt.Errorf("Could not back up \"%s\": %s", path, err)
+	}
+	_, err = io.Copy(dst, src)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("Could not back up \"%s\": %s", path, err)
+	}
+	fmt.Println("Backed up the existing file to:", backupPath)
+	return nil
+}
+
+// isReplacementExtension returns whether a file with the extension ext is
+// accepted as a wem by replace.
+func isReplacementExtension(ext string) bool {
+	for _, e := range replacementExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
 }
 
 func processTargetFiles(c wwise.Container,
-	fis []os.FileInfo) []*wwise.ReplacementWem {
+	fis []os.FileInfo) ([]*wwise.ReplacementWem, error) {
 	var targets []*wwise.ReplacementWem
 	var names []string
 	for _, fi := range fis {
 		name := fi.Name()
 		ext := filepath.Ext(name)
-		if ext != wemExtension {
-			log.Printf("Ignoring %s: It does not have a .wem file extension",
-				name)
+		if !isReplacementExtension(ext) {
+			log.Printf("Ignoring %s: It does not have a %s file extension", name,
+				strings.Join(replacementExtensions, ", "))
 			continue
 		}
-		wemIndex, err := strconv.Atoi(strings.TrimSuffix(name, ext))
+		base := strings.TrimSuffix(name, ext)
+		// Ignore any codec suffix added when the wem was unpacked.
+		if i := strings.IndexByte(base, '.'); i >= 0 {
+			base = base[:i]
+		}
+		wemIndex, err := strconv.Atoi(base)
 		// Wems are indexed internally starting from 0, but the file names start
 		// at 1.
 		wemIndex--
@@ -257,35 +1031,68 @@ func processTargetFiles(c wwise.Container,
 			log.Printf("Ignoring %s: Could not open file: %s", name, err)
 			continue
 		}
+		err = wwise.ValidateWem(f, fi.Size())
+		if err != nil {
+			f.Close()
+			closeTargets(targets)
+			return nil, exitErrorf(exitValidation,
+				"Could not use %s as a replacement: %s", name,
+				err)
+		}
 
 		names = append(names, fi.Name())
 		targets = append(targets, &wwise.ReplacementWem{f, wemIndex, fi.Size()})
 	}
 	if len(targets) == 0 {
-		log.Fatal("There are no replacement wems")
+		return nil, exitErrorf(exitValidation, "There are no replacement wems")
 	}
 	fmt.Printf("Using %d replacement wem(s): %s\n", len(targets),
 		strings.Join(names, ", "))
-	return targets
+	return targets, nil
+}
+
+// closeTargets closes the files opened by processTargetFiles.
+func closeTargets(targets []*wwise.ReplacementWem) {
+	for _, t := range targets {
+		if c, ok := t.Wem.(io.Closer); ok {
+			c.Close()
+		}
+	}
 }
 
 func createDirIfEmpty(path string) error {
 	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return os.Mkdir(output, os.ModePerm)
+		return os.MkdirAll(path, os.ModePerm)
 	}
 	return nil
 }
 
 func main() {
+	err := loadConfig()
+	if err != nil {
+		fatalln(exitUsage, "Could not read configuration:", err)
+	}
 	flag.Parse()
-	verifyFlags()
-	isSoundBank := verifyInputType()
+	m := verifyFlags()
+	opReport.Mode = m.name
 
-	switch {
-	case shouldUnpack:
-		unpack(isSoundBank)
-	case shouldReplace:
-		verifyReplaceFlags()
-		replace(isSoundBank)
+	isSoundBank := false
+	if m.needsFile {
+		paths, isBatch := batchInputs(filePath)
+		if isBatch {
+			runBatch(m, paths)
+			opReport.finish(0, "")
+			return
+		}
+		isSoundBank = verifyInputType()
+	}
+	if layoutName != "" {
+		var err error
+		output, err = layoutOutput(layoutName, filePath, output)
+		if err != nil {
+			fatalln(exitUsage, "Could not place output:", err)
+		}
 	}
+	m.run(isSoundBank)
+	opReport.finish(0, "")
 }
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+// The name of the checksum manifest written to an unpack directory.
+const manifestName = "SHA256SUMS"
+
+var writeManifest bool
+var shouldVerifyManifest bool
+var manifestDir string
+
+func init() {
+	const (
+		usage = "When unpack is used, also write a " + manifestName + " file to " +
+			"the output directory, listing the SHA-256 checksum of every unpacked " +
+			"wem in the format used by sha256sum."
+		flagName = "manifest"
+	)
+	flag.BoolVar(&writeManifest, flagName, false, usage)
+}
+
+func init() {
+	const (
+		usage = "verify the wems within the given directory against the " +
+			manifestName + " file written by unpack -manifest, and report every " +
+			"wem that was modified, is missing, or is not listed."
+		flagName = "verify-manifest"
+	)
+	flag.Var(modeValue{&manifestDir, &shouldVerifyManifest, true}, flagName,
+		usage)
+	registerMode(&mode{name: flagName, selected: &shouldVerifyManifest,
+		run: verifyManifest})
+}
+
+// writeUnpackManifest writes the manifest of the wems at the given indices of
+// wems, which were unpacked to the directory dir and the directories given by
+// folders.
+func writeUnpackManifest(dir string, wems []*wwise.Wem, folders []string,
+	indices []int) error {
+	var lines []string
+	for _, i := range indices {
+		name := unpackedWemPath(wems, folders, i)
+		sum, err := fileChecksum(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		lines = append(lines, fmt.Sprintf("%s  %s\n", sum, name))
+	}
+
+	f, err := createFile(filepath.Join(dir, manifestName))
+	if err != nil {
+		return exitErrorf(exitIO, "Could not create manifest: %s", err)
+	}
+	defer f.Close()
+	_, err = io.WriteString(f, strings.Join(lines, ""))
+	if err != nil {
+		return exitErrorf(exitIO, "Could not write manifest: %s", err)
+	}
+	return nil
+}
+
+// verifyManifest checks every file listed in the manifest of the directory
+// given to verify-manifest, and exits with a non-zero status if any of them
+// differ.
+func verifyManifest(bool) {
+	sums, err := readManifest(filepath.Join(manifestDir, manifestName))
+	if err != nil {
+		fatalln(exitParse, "Could not read manifest:", err)
+	}
+
+	var names []string
+	for name := range sums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	failed := 0
+	for _, name := range names {
+		sum, err := fileChecksum(filepath.Join(manifestDir, name))
+		switch {
+		case os.IsNotExist(err):
+			fmt.Printf("%s: MISSING\n", name)
+			failed++
+		case err != nil:
+			fatalErr(err)
+		case sum != sums[name]:
+			fmt.Printf("%s: FAILED\n", name)
+			failed++
+		default:
+			fmt.Printf("%s: OK\n", name)
+		}
+	}
+
+	fis, err := ioutil.ReadDir(manifestDir)
+	if err != nil {
+		fatalln(exitIO, "Could not read directory:", err)
+	}
+	for _, fi := range fis {
+		name := fi.Name()
+		if _, ok := sums[name]; !ok && !fi.IsDir() && name != manifestName {
+			fmt.Printf("%s: NOT LISTED\n", name)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		fatalf(exitValidation,
+			"%d of %d file(s) did not match the manifest", failed,
+			len(names))
+	}
+	fmt.Printf("All %d file(s) match the manifest\n", len(names))
+}
+
+// readManifest returns the checksums of a manifest, keyed by file name.
+func readManifest(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if text == "" {
+			continue
+		}
+		// sha256sum marks files hashed in binary mode with a '*'.
+		fields := strings.SplitN(text, " ", 2)
+		if len(fields) != 2 || len(fields[1]) < 2 {
+			return nil, fmt.Errorf("Line %d is not of the form \"<sum>  <name>\"",
+				line)
+		}
+		sums[fields[1][1:]] = strings.ToLower(fields[0])
+	}
+	return sums, scanner.Err()
+}
+
+// fileChecksum returns the hex encoded SHA-256 checksum of the file at path.
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+)
+
+var shouldMerge bool
+var mergeObjects bool
+
+func init() {
+	const (
+		usage = "merge the .bnk files given as arguments into a single .bnk, " +
+			"written to the file specified by output. The merged SoundBank has the " +
+			"bank header of the first SoundBank. IDs held by more than one " +
+			"SoundBank are kept from the first SoundBank holding them, and are " +
+			"reported."
+		flagName = "merge"
+	)
+	flag.BoolVar(&shouldMerge, flagName, false, usage)
+	registerMode(&mode{name: flagName, selected: &shouldMerge,
+		needsOutput: true, run: merge})
+}
+
+func init() {
+	const (
+		usage = "When merge is used, also merge the HIRC objects of the " +
+			"SoundBanks. The SoundBanks must all be of the same version."
+		flagName = "merge-objects"
+	)
+	flag.BoolVar(&mergeObjects, flagName, false, usage)
+}
+
+// merge writes a SoundBank holding the contents of every argument SoundBank.
+func merge(bool) {
+	paths := flag.Args()
+	if len(paths) < 2 {
+		flag.Usage()
+		fatal(exitUsage, "merge needs at least two .bnk files as arguments")
+	}
+
+	var banks []*bnk.File
+	for _, path := range paths {
+		b, err := openSoundBank(path)
+		if err != nil {
+			fatalf(exitParse, "Could not parse .bnk file \"%s\": %s\n", path, err)
+		}
+		defer b.Close()
+		banks = append(banks, b)
+	}
+
+	merged, collisions, err := bnk.Merge(banks,
+		bnk.MergeOptions{Objects: mergeObjects})
+	if err != nil {
+		fatalln(exitCode(err), "Could not merge SoundBanks:", err)
+	}
+	for _, c := range collisions {
+		var holders []string
+		for _, i := range c.Banks {
+			holders = append(holders, paths[i])
+		}
+		log.Printf("%s %d is held by %v; keeping it from %s\n", c.Kind, c.Id,
+			holders, holders[0])
+	}
+
+	total, err := writeOutput(output, merged)
+	if err != nil {
+		fatalln(exitIO, "Could not write output to file: ", err)
+	}
+	fmt.Printf("Successfully merged %d SoundBank(s) into %s with %d "+
+		"collision(s)\n", len(banks), output, len(collisions))
+	fmt.Printf("Wrote %d bytes in total\n", total)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+var shouldCreatePatch bool
+var modifiedPath string
+var shouldApplyPatch bool
+var patchPath string
+
+func init() {
+	const (
+		usage = "create a " + bnk.PatchExtension + " patch holding every wem of " +
+			"the given modified .bnk that differs from the original .bnk " +
+			"specified by filepath, and write it to the file specified by " +
+			"output. The patch can be shared instead of the full modified .bnk."
+		flagName = "create-patch"
+	)
+	flag.Var(modeValue{&modifiedPath, &shouldCreatePatch, true}, flagName,
+		usage)
+	registerMode(&mode{name: flagName, selected: &shouldCreatePatch,
+		needsFile: true, needsOutput: true, run: createPatch})
+}
+
+func init() {
+	const (
+		usage = "apply the given " + bnk.PatchExtension + " patch to the .bnk " +
+			"specified by filepath, and write the result to the file specified " +
+			"by output."
+		flagName = "apply-patch"
+	)
+	flag.Var(modeValue{&patchPath, &shouldApplyPatch, true}, flagName,
+		usage)
+	registerMode(&mode{name: flagName, selected: &shouldApplyPatch,
+		needsFile: true, needsOutput: true, repacks: true,
+		run: applyPatch})
+}
+
+// createPatch writes a patch that turns the input SoundBank into the modified
+// SoundBank.
+func createPatch(isSoundBank bool) {
+	if !isSoundBank {
+		fatal(exitUsage, "create-patch only supports SoundBank files")
+	}
+	original, err := openSoundBank(filePath)
+	if err != nil {
+		fatalln(exitParse, "Could not parse original .bnk file:", err)
+	}
+	defer original.Close()
+	modified, err := openSoundBank(modifiedPath)
+	if err != nil {
+		fatalln(exitParse, "Could not parse modified .bnk file:", err)
+	}
+	defer modified.Close()
+
+	p, err := bnk.CreatePatch(original, modified)
+	if err != nil {
+		fatalln(exitValidation, "Could not create patch:", err)
+	}
+	if len(p.Wems) == 0 {
+		fatal(exitValidation,
+			"The modified SoundBank holds the same wems as the original")
+	}
+	data := new(bytes.Buffer)
+	p.WriteTo(data)
+	// The buffer is drained when written, so sign its contents first.
+	contents := data.Bytes()
+	total, err := writeOutput(output, data)
+	if err != nil {
+		fatalln(exitIO, "Could not write output to file: ", err)
+	}
+	if signKeyPath != "" {
+		err = writeSignature(output, contents, signKeyPath)
+		if err != nil {
+			fatalln(exitIO, "Could not sign patch:", err)
+		}
+		fmt.Println("Signed patch with", signKeyPath)
+	}
+	fmt.Printf("Successfully created a patch of %d wem(s)! Output file written "+
+		"to: %s\n", len(p.Wems), output)
+	fmt.Printf("Wrote %d bytes in total\n", total)
+}
+
+// applyPatch applies a patch to the input SoundBank.
+func applyPatch(isSoundBank bool) {
+	if !isSoundBank {
+		fatal(exitUsage, "apply-patch only supports SoundBank files")
+	}
+	data, err := ioutil.ReadFile(patchPath)
+	if err != nil {
+		fatalln(exitIO, "Could not open patch file:", err)
+	}
+	if verifyKeyPath != "" {
+		err = verifySignature(patchPath, data, verifyKeyPath)
+		if err != nil {
+			fatalln(exitValidation, "Could not verify patch:", err)
+		}
+		fmt.Println("Verified patch signature with", verifyKeyPath)
+	}
+	p, err := bnk.ReadPatch(bytes.NewReader(data))
+	if err != nil {
+		fatalln(exitParse, "Could not read patch file:", err)
+	}
+	for _, wem := range p.Wems {
+		err := wwise.ValidateWem(bytes.NewReader(wem.Data), int64(len(wem.Data)))
+		if err != nil {
+			fatalf(exitValidation,
+				"Could not use wem %d of the patch: %s\n", wem.Id, err)
+		}
+	}
+
+	b, err := openSoundBank(filePath)
+	if err != nil {
+		fatalln(exitParse, "Could not parse .bnk file:", err)
+	}
+	defer b.Close()
+	err = b.ApplyPatch(p)
+	if err != nil {
+		fatalln(exitCode(err), "Could not apply patch:", err)
+	}
+	opReport.addReplaced(len(p.Wems))
+
+	total, err := writeOutput(output, b)
+	if err != nil {
+		fatalln(exitIO, "Could not write output to file: ", err)
+	}
+	fmt.Printf("Successfully applied a patch of %d wem(s)! Output file written "+
+		"to: %s\n", len(p.Wems), output)
+	fmt.Printf("Wrote %d bytes in total\n", total)
+}
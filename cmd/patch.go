@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+	"github.com/hpxro7/wwiseutil/pck"
+)
+
+var shouldPatch bool
+var entry int
+
+func init() {
+	const (
+		usage = "replace wems of the .bnk specified by filepath in place, with " +
+			"the wems found in the directory specified by target. Only the " +
+			"replaced wems, their DIDX entries and the HIRC section are " +
+			"written, so this is much faster than replace for large files, but " +
+			"every replacement must fit within the wem it replaces and the " +
+			"padding that follows it. If filepath is a .pck, the .bnk stored in " +
+			"the entry given by entry is patched."
+		flagName = "patch"
+	)
+	flag.BoolVar(&shouldPatch, flagName, false, usage)
+	registerMode(&mode{name: flagName, selected: &shouldPatch,
+		needsFile: true, run: patch})
+}
+
+func init() {
+	const (
+		usage = "When patch is used on a .pck, the index of the entry of the " +
+			"SoundBank table of the .pck that holds the .bnk to patch, as listed " +
+			"by pck-list. The index of the first entry is 1."
+		flagName = "entry"
+	)
+	flag.IntVar(&entry, flagName, 0, usage)
+}
+
+// patch replaces wems of the input SoundBank, or of the SoundBank stored in an
+// entry of the input File Package, in place.
+func patch(isSoundBank bool) {
+	verifyReplaceFlags()
+	f, err := os.OpenFile(filePath, os.O_RDWR, 0)
+	if err != nil {
+		fatalln(exitIO, "Could not open input file for writing:", err)
+	}
+	defer f.Close()
+
+	// The SoundBank to patch is stored in the input file from offset onwards.
+	var b *bnk.File
+	offset := int64(0)
+	if isSoundBank {
+		b, err = openSoundBank(filePath)
+		if err != nil {
+			fatalln(exitParse, "Could not parse .bnk file:", err)
+		}
+		defer b.Close()
+	} else {
+		b, offset = readEmbeddedSoundBank(f)
+	}
+	if verbose {
+		fmt.Println(b)
+	}
+
+	targetFileInfos, err := ioutil.ReadDir(targetPath)
+	if err != nil {
+		fatalf(exitIO,
+			"Could not open target directory, \"%s\": %s\n", targetPath, err)
+	}
+	targets, err := processTargetFiles(b, targetFileInfos)
+	if err != nil {
+		fatalErr(err)
+	}
+	defer closeTargets(targets)
+
+	err = backupFile(filePath, false)
+	if err != nil {
+		fatalErr(err)
+	}
+	err = b.PatchWems(io.NewOffsetWriter(f, offset), targets...)
+	if errors.Is(err, bnk.ErrDoesNotFit) {
+		fatalln(exitOverflow, "Could not patch wems, use replace instead:", err)
+	}
+	if err != nil {
+		fatalln(exitCode(err), "Could not patch wems:", err)
+	}
+	opReport.addReplaced(len(targets))
+	fmt.Printf("Successfully patched %d wem(s) of %s\n", len(targets), filePath)
+}
+
+// readEmbeddedSoundBank reads the SoundBank stored in the entry of the
+// SoundBank table of the File Package f that is selected by the entry flag,
+// and returns it along with the offset into f where it begins.
+func readEmbeddedSoundBank(f *os.File) (*bnk.File, int64) {
+	c, err := pck.ListContents(f)
+	if err != nil {
+		fatalln(exitParse, "Could not parse .pck file:", err)
+	}
+	if len(c.SoundBanks) == 0 {
+		fatal(exitValidation, "The File Package does not hold any SoundBanks")
+	}
+	if entry < 1 || entry > len(c.SoundBanks) {
+		fatalf(exitUsage, "entry must be between %d and %d\n", 1,
+			len(c.SoundBanks))
+	}
+	e := c.SoundBanks[entry-1]
+	offset := e.Offset()
+	sr := io.NewSectionReader(f, offset, int64(e.Length))
+	b, err := bnk.NewFileWithOptions(context.Background(), sr, readOptions())
+	if err != nil {
+		fatalf(exitParse,
+			"Could not parse entry %d of the .pck file as a .bnk: %s\n",
+			entry, err)
+	}
+	for _, w := range b.Warnings {
+		log.Printf("Warning: %s: entry %d: %s\n", filePath, entry, w)
+		opReport.addWarning("%s: entry %d: %s", filePath, entry, w)
+	}
+	return b, offset
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/pck"
+	"github.com/hpxro7/wwiseutil/util"
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+var shouldCreatePackage bool
+var languageMapPath string
+
+// The language of the files stored directly in the directory that a File
+// Package is created from.
+const rootLanguage = "sfx"
+
+func init() {
+	const (
+		usage = "create a new .pck from the directory specified by target, " +
+			"writing it to the file specified by output. The .bnk files of the " +
+			"directory are stored as SoundBanks, and its .wem files, whose names " +
+			"must be their IDs, as streamed files. Files directly in the " +
+			"directory have the sfx language, and files in a subdirectory have " +
+			"the language named after it, such as english(us). Files begin on " +
+			"blocks of the size given by align."
+		flagName = "pck-create"
+	)
+	flag.BoolVar(&shouldCreatePackage, flagName, false, usage)
+	registerMode(&mode{name: flagName, selected: &shouldCreatePackage,
+		needsOutput: true, run: createPackage})
+}
+
+func init() {
+	const (
+		usage = "When pck-create is used, a file giving the ID of each language, " +
+			"with one \"<id> <name>\" line per language. Languages that it does " +
+			"not list are numbered after sfx, which is 0, in the order of their " +
+			"names."
+		flagName = "language-map"
+	)
+	pathVar(&languageMapPath, flagName, "", usage)
+}
+
+// createPackage writes a new File Package holding the files of the target
+// directory.
+func createPackage(bool) {
+	if targetPath == "" {
+		flag.Usage()
+		fatal(exitUsage, "target cannot be empty")
+	}
+	if alignment < 1 || alignment > 1<<31 {
+		fatal(exitUsage, "align must be a positive block size")
+	}
+	languages := make(map[string]uint32)
+	if languageMapPath != "" {
+		var err error
+		languages, err = readLanguageMap(languageMapPath)
+		if err != nil {
+			fatalln(exitParse, "Could not read language map:", err)
+		}
+	}
+
+	fis, err := ioutil.ReadDir(targetPath)
+	if err != nil {
+		fatalf(exitIO,
+			"Could not open target directory, \"%s\": %s\n", targetPath, err)
+	}
+	// The files of a subdirectory named sfx are stored along with the files
+	// directly in the target directory.
+	dirs := map[string][]string{rootLanguage: {targetPath}}
+	names := []string{rootLanguage}
+	for _, fi := range fis {
+		if !fi.IsDir() {
+			continue
+		}
+		if _, ok := dirs[fi.Name()]; !ok {
+			names = append(names, fi.Name())
+		}
+		dirs[fi.Name()] = append(dirs[fi.Name()],
+			filepath.Join(targetPath, fi.Name()))
+	}
+	numberLanguages(languages, names)
+
+	builder := pck.NewBuilder().SetBlockSize(uint32(alignment))
+	banks, wems := 0, 0
+	for _, name := range names {
+		id := languages[name]
+		builder.AddLanguage(id, name)
+		for _, dir := range dirs[name] {
+			b, w := addPackageFiles(builder, dir, id)
+			banks += b
+			wems += w
+		}
+	}
+
+	total, err := writeOutput(output, builder)
+	if err != nil {
+		fatalln(exitCode(err), "Could not write output to file:", err)
+	}
+	fmt.Printf("Successfully created a File Package of %d SoundBank(s) and %d "+
+		"streamed wem(s) at %s\n", banks, wems, output)
+	fmt.Printf("Wrote %d bytes in total\n", total)
+}
+
+// readLanguageMap reads the language map at path, and returns a mapping from
+// the name of each language it lists to its ID.
+func readLanguageMap(path string) (map[string]uint32, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	languages := make(map[string]uint32)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("Line %d is not of the form \"<id> <name>\"",
+				line)
+		}
+		id, err := strconv.ParseUint(fields[0], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("Line %d does not begin with a valid ID", line)
+		}
+		languages[fields[1]] = uint32(id)
+	}
+	return languages, scanner.Err()
+}
+
+// numberLanguages gives every language of names that languages does not list
+// the next ID not used by any other language, in the order of names.
+func numberLanguages(languages map[string]uint32, names []string) {
+	if _, ok := languages[rootLanguage]; !ok {
+		languages[rootLanguage] = 0
+	}
+	used := make(map[uint32]bool)
+	for _, id := range languages {
+		used[id] = true
+	}
+	next := uint32(0)
+	for _, name := range names {
+		if _, ok := languages[name]; ok {
+			continue
+		}
+		for used[next] {
+			next++
+		}
+		languages[name] = next
+		used[next] = true
+	}
+}
+
+// addPackageFiles adds the SoundBanks and wems stored directly in dir to
+// builder, with the language given by languageId, and returns the number of
+// each that were added.
+func addPackageFiles(builder *pck.Builder, dir string,
+	languageId uint32) (banks, wems int) {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		fatalf(exitIO, "Could not open directory, \"%s\": %s\n", dir, err)
+	}
+	for _, fi := range fis {
+		if fi.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, fi.Name())
+		fileType, ext := util.GetFileType(path)
+		switch {
+		case fileType == util.SoundBankFileType:
+			id := packageBankId(path)
+			f := openPackageFile(path)
+			builder.AddSoundBank(pck.FileSource{uint64(id), languageId, f,
+				fi.Size(), 0})
+			banks++
+		case ext == wemExtension:
+			id, ok := looseWemId(fi)
+			if !ok {
+				log.Printf("Ignoring %s: Its name is not a valid wem ID", path)
+				continue
+			}
+			f := openPackageFile(path)
+			err = wwise.ValidateWem(f, fi.Size())
+			if err != nil {
+				fatalf(exitValidation, "Could not use %s: %s", path, err)
+			}
+			builder.AddStreamed(pck.FileSource{uint64(id), languageId, f,
+				fi.Size(), 0})
+			wems++
+		default:
+			log.Printf("Ignoring %s: It is neither a .bnk nor a .wem file", path)
+		}
+	}
+	return banks, wems
+}
+
+// packageBankId returns the ID of the SoundBank at path, as its BKHD section
+// describes it.
+func packageBankId(path string) uint32 {
+	b, err := openSoundBank(path)
+	if err != nil {
+		fatalf(exitParse, "Could not parse %s: %s\n", path, err)
+	}
+	defer b.Close()
+	if b.BankHeaderSection == nil {
+		fatalf(exitValidation, "Could not use %s: It has no BKHD section\n", path)
+	}
+	return b.BankHeaderSection.Descriptor.BankId
+}
+
+// openPackageFile opens the file at path, which is closed when this tool
+// exits.
+func openPackageFile(path string) *os.File {
+	f, err := os.Open(path)
+	if err != nil {
+		fatalf(exitIO, "Could not open \"%s\": %s\n", path, err)
+	}
+	return f
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/pck"
+)
+
+var shouldListPackage bool
+
+func init() {
+	const (
+		usage = "list the languages, SoundBanks, streamed files and external " +
+			"files of the .pck specified by filepath, with the ID, offset, length " +
+			"and language of each file. Only the header of the .pck is read, and " +
+			"nothing is extracted."
+		flagName = "pck-list"
+	)
+	flag.BoolVar(&shouldListPackage, flagName, false, usage)
+	registerMode(&mode{name: flagName, selected: &shouldListPackage,
+		needsFile: true, run: listPackage})
+}
+
+// listPackage prints every file listed by the header of the input File
+// Package.
+func listPackage(isSoundBank bool) {
+	if isSoundBank {
+		fatal(exitUsage, "pck-list only supports File Package files")
+	}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		fatalln(exitIO, "Could not open input file:", err)
+	}
+	defer f.Close()
+	c, err := pck.ListContents(f)
+	if err != nil {
+		fatalln(exitParse, "Could not parse .pck file:", err)
+	}
+
+	fmt.Printf("Version: %d\n", c.Version)
+	var ids []int
+	for id := range c.Languages {
+		ids = append(ids, int(id))
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		fmt.Printf("Language %d: %s\n", id, c.Languages[uint32(id)])
+	}
+	printPackageTable("SoundBanks", c.SoundBanks, c.Languages)
+	printPackageTable("Streamed files", c.Streamed, c.Languages)
+	printPackageTable("External files", c.Externals, c.Languages)
+	fmt.Printf("Listed %d SoundBank(s), %d streamed file(s) and %d external "+
+		"file(s)\n", len(c.SoundBanks), len(c.Streamed), len(c.Externals))
+}
+
+// printPackageTable prints a table describing the entries of a file table.
+func printPackageTable(name string, entries []*pck.Entry,
+	languages map[uint32]string) {
+	if len(entries) == 0 {
+		return
+	}
+	fmt.Printf("\n%s:\n", name)
+	tableParams := []string{"%-7", "%-21", "%-15", "%-12", "%-12", "\n"}
+	titleFmt := strings.Join(tableParams, "s|")
+	entryFmt := strings.Join(tableParams[:5], "d|") + "s|" + tableParams[5]
+	title := fmt.Sprintf(titleFmt, "Index", "Id", "Offset", "Length",
+		"Language")
+	fmt.Print(title)
+	fmt.Println(strings.Repeat("-", len(title)-1))
+	total := int64(0)
+	for i, e := range entries {
+		language, ok := languages[e.LanguageId]
+		if !ok {
+			language = fmt.Sprint(e.LanguageId)
+		}
+		fmt.Printf(entryFmt, i+1, e.Id, e.Offset(), e.Length, language)
+		total += int64(e.Length)
+	}
+	fmt.Printf("%d file(s), %d bytes in total\n", len(entries), total)
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/pck"
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+var shouldReplacePackageWem bool
+var packageWemId string
+var replacementWemPath string
+
+func init() {
+	const (
+		usage = "replace the file with the given ID of the .pck specified by " +
+			"filepath, which is a SoundBank, a streamed wem or an external file, " +
+			"with the file specified by wem. If several files have the ID, such " +
+			"as a wem in several languages, language selects one of them. If " +
+			"output is given, the .pck is rewritten there, and files that follow " +
+			"the replaced file are moved and kept aligned to their blocks. " +
+			"Otherwise, the .pck is patched in place, which requires the new file " +
+			"to fit within the file it replaces and the padding that follows it."
+		flagName = "pck-replace"
+	)
+	flag.Var(modeValue{&packageWemId, &shouldReplacePackageWem, false},
+		flagName, usage)
+	registerMode(&mode{name: flagName, selected: &shouldReplacePackageWem,
+		needsFile: true, run: replacePackageWem})
+}
+
+func init() {
+	const (
+		usage = "When pck-replace is used, the path to the file that replaces " +
+			"the file of the .pck."
+		flagName = "wem"
+	)
+	pathVar(&replacementWemPath, flagName, "", usage)
+}
+
+// replacePackageWem replaces a single file of the input File Package, either in
+// place or by writing a new File Package to the output.
+func replacePackageWem(isSoundBank bool) {
+	if isSoundBank {
+		fatal(exitUsage, "pck-replace only supports File Package files")
+	}
+	if replacementWemPath == "" {
+		flag.Usage()
+		fatal(exitUsage, "wem cannot be empty")
+	}
+	// The IDs of external files are 64 bits long.
+	id, err := strconv.ParseUint(packageWemId, 10, 64)
+	if err != nil {
+		fatalf(exitUsage, "\"%s\" is not a valid file ID\n", packageWemId)
+	}
+
+	wem, err := os.Open(replacementWemPath)
+	if err != nil {
+		fatalln(exitIO, "Could not open replacement wem:", err)
+	}
+	defer wem.Close()
+	fi, err := wem.Stat()
+	if err != nil {
+		fatalln(exitIO, "Could not open replacement wem:", err)
+	}
+
+	if output == "" {
+		patchPackageWem(id, wem, fi.Size())
+		return
+	}
+	p, err := openFilePackage(filePath)
+	if err != nil {
+		fatalln(exitParse, "Could not parse .pck file:", err)
+	}
+	defer p.Close()
+	p.SetPreservePadding(preservePadding)
+	in, err := os.Open(filePath)
+	if err != nil {
+		fatalln(exitIO, "Could not open input file:", err)
+	}
+	defer in.Close()
+	t, i := findPackageFile(in, id)
+	r := &wwise.ReplacementWem{wem, i, fi.Size()}
+	err = p.CheckFiles(t, r)
+	if err != nil {
+		fatalln(exitCode(err), "Could not replace file:", err)
+	}
+	p.ReplaceFiles(t, r)
+	opReport.addReplaced(1)
+
+	total, err := writeOutput(output, p)
+	if err != nil {
+		fatalln(exitIO, "Could not write output to file:", err)
+	}
+	fmt.Printf("Successfully replaced file %d! Output file written to: %s\n",
+		id, output)
+	fmt.Printf("Wrote %d bytes in total\n", total)
+}
+
+// patchPackageWem replaces the file with the given ID of the input File
+// Package in place with the length bytes of wem.
+func patchPackageWem(id uint64, wem *os.File, length int64) {
+	f, err := os.OpenFile(filePath, os.O_RDWR, 0)
+	if err != nil {
+		fatalln(exitIO, "Could not open input file for writing:", err)
+	}
+	defer f.Close()
+	p, err := pck.NewFile(f)
+	if err != nil {
+		fatalln(exitParse, "Could not parse .pck file:", err)
+	}
+	t, i := findPackageFile(f, id)
+
+	err = backupFile(filePath, false)
+	if err != nil {
+		fatalErr(err)
+	}
+	err = p.PatchFiles(f, t, &wwise.ReplacementWem{wem, i, length})
+	if errors.Is(err, pck.ErrDoesNotFit) {
+		fatalln(exitOverflow, "Could not patch file, give output to rewrite the "+
+			".pck instead:", err)
+	}
+	if err != nil {
+		fatalln(exitCode(err), "Could not patch file:", err)
+	}
+	opReport.addReplaced(1)
+	fmt.Printf("Successfully patched file %d of %s\n", id, filePath)
+}
+
+// findPackageFile returns the table and the index within it of the file with
+// the given ID of the File Package r, as ListContents lists it. If several
+// files have the ID, the language flag selects one of them.
+func findPackageFile(r io.ReaderAt, id uint64) (pck.Table, int) {
+	c, err := pck.ListContents(r)
+	if err != nil {
+		fatalln(exitParse, "Could not parse .pck file:", err)
+	}
+	var language uint32
+	if unpackLanguage != "" {
+		var ok bool
+		language, ok = findLanguage(c.Languages, unpackLanguage)
+		if !ok {
+			fatalf(exitUsage, "The .pck does not list the language \"%s\"\n",
+				unpackLanguage)
+		}
+	}
+	tables := [][]*pck.Entry{c.SoundBanks, c.Streamed, c.Externals}
+	var matches [][2]int
+	for t, entries := range tables {
+		for i, e := range entries {
+			if e.Id == id && (unpackLanguage == "" || e.LanguageId == language) {
+				matches = append(matches, [2]int{t, i})
+			}
+		}
+	}
+	switch len(matches) {
+	case 0:
+		fatalf(exitValidation, "The File Package does not hold file %d\n", id)
+	case 1:
+	default:
+		fatalf(exitUsage, "The File Package holds file %d %d times, give language "+
+			"to select one of them\n", id, len(matches))
+	}
+	return pck.Table(matches[0][0]), matches[0][1]
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"log"
+	"strings"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+// A stringList is the value of a flag that may be given more than once.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+var stripSections stringList
+var injectSections stringList
+var bankLanguage string
+
+func init() {
+	const (
+		usage = "When replace is used, remove every section with this " +
+			"identifier, such as HIRC, from the output .bnk. May be given more " +
+			"than once."
+		flagName = "strip-section"
+	)
+	flag.Var(&stripSections, flagName, usage)
+}
+
+func init() {
+	const (
+		usage = "When replace is used, replace the data of a section of the " +
+			"output .bnk with the contents of a file, given as ID=path, such as " +
+			"HIRC=04_HIRC.bin. May be given more than once."
+		flagName = "inject-section"
+	)
+	flag.Var(&injectSections, flagName, usage)
+}
+
+func init() {
+	const (
+		usage = "When replace is used, the language ID recorded in the BKHD " +
+			"section of the output .bnk, given as a number or as the name of the " +
+			"language, such as SFX. Names are encoded as the version of the " +
+			"output .bnk requires: as an index into the standard languages up " +
+			"to version 122, and hashed as Wwise does after it."
+		flagName = "bank-language"
+	)
+	flag.StringVar(&bankLanguage, flagName, "", usage)
+}
+
+// flagGiven returns true if the flag with the given name was set on the
+// command line.
+func flagGiven(name string) bool {
+	given := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			given = true
+		}
+	})
+	return given
+}
+
+// applyBankHeaderFlags rewrites the fields of the BKHD section of b given by
+// the bank-id, bank-version and bank-language flags.
+func applyBankHeaderFlags(b *bnk.File) {
+	hdr := b.BankHeaderSection
+	if bankId != "" {
+		hdr.Descriptor.BankId = parseId(bankId)
+	}
+	if flagGiven("bank-version") {
+		err := hdr.SetVersion(uint32(bankVersion))
+		if err != nil {
+			fatalln(exitValidation, "Could not set the version:", err)
+		}
+	}
+	if bankLanguage != "" {
+		err := hdr.SetLanguage(bankLanguage)
+		if err != nil {
+			fatalln(exitValidation, "Could not set the language:", err)
+		}
+	}
+}
+
+// applyRepackFlags applies the flags that change the structure of a container
+// written by replace, other than its wems.
+func applyRepackFlags(ctn wwise.Container) {
+	b, isSoundBank := ctn.(*bnk.File)
+	if !isSoundBank {
+		if len(stripSections) > 0 {
+			fatal(exitUsage, "strip-section only supports SoundBank files")
+		}
+		if len(injectSections) > 0 {
+			fatal(exitUsage, "inject-section only supports SoundBank files")
+		}
+		if bankId != "" || flagGiven("bank-version") || bankLanguage != "" {
+			fatal(exitUsage, "bank-id, bank-version and bank-language only "+
+				"support SoundBank files")
+		}
+		return
+	}
+
+	for _, id := range stripSections {
+		n, err := b.RemoveSections(id)
+		if err != nil {
+			fatalln(exitCode(err), "Could not strip section:", err)
+		}
+		if n == 0 {
+			log.Printf("There is no %s section to strip\n", id)
+		}
+	}
+
+	for _, injection := range injectSections {
+		parts := strings.SplitN(injection, "=", 2)
+		if len(parts) != 2 {
+			fatalf(exitUsage, "Could not inject section \"%s\": expected ID=path",
+				injection)
+		}
+		data, err := ioutil.ReadFile(parts[1])
+		if err != nil {
+			fatalln(exitIO, "Could not read section data:", err)
+		}
+		err = b.ReplaceSectionData(parts[0], data)
+		if err != nil {
+			fatalln(exitCode(err), "Could not inject section:", err)
+		}
+	}
+	applyBankHeaderFlags(b)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+)
+
+var shouldRepair bool
+
+func init() {
+	const (
+		usage = "rebuild the DIDX section of the .bnk specified by filepath from " +
+			"the wems found in its DATA section, writing the repaired SoundBank " +
+			"to the file specified by output. Use this when the DIDX section is " +
+			"corrupt or missing."
+		flagName = "repair"
+	)
+	flag.BoolVar(&shouldRepair, flagName, false, usage)
+	registerMode(&mode{name: flagName, selected: &shouldRepair,
+		needsFile: true, needsOutput: true, repacks: true,
+		run: repair})
+}
+
+// repair writes a copy of the input SoundBank with a rebuilt DIDX section to
+// output.
+func repair(isSoundBank bool) {
+	if !isSoundBank {
+		fatal(exitUsage, "repair only supports SoundBank files")
+	}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		fatalln(exitIO, "Could not open input file:", err)
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		fatalln(exitIO, "Could not open input file:", err)
+	}
+
+	b, wems, err := bnk.Repair(f, stat.Size())
+	if err != nil {
+		fatalln(exitCode(err), "Could not repair .bnk file:", err)
+	}
+	assigned := 0
+	for _, wem := range wems {
+		if !wem.IdRecovered {
+			assigned++
+		}
+		if verbose || !wem.IdRecovered {
+			fmt.Printf("Found %s\n", wem)
+		}
+	}
+
+	total, err := writeOutput(output, b)
+	if err != nil {
+		fatalln(exitIO, "Could not write output to file: ", err)
+	}
+	fmt.Printf("Successfully recovered %d wem(s), %d of which were assigned "+
+		"new IDs, to %s\n", len(wems), assigned, output)
+	fmt.Printf("Wrote %d bytes in total\n", total)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"sync"
+	"time"
+)
+
+var summaryJsonPath string
+
+func init() {
+	const (
+		usage = "Write a JSON summary of the operation to the given path once it " +
+			"finishes, whether or not it succeeds, holding the mode, the number " +
+			"of wems extracted and replaced, the number of bytes written, the " +
+			"duration, any warnings and the exit status."
+		flagName = "summary-json"
+	)
+	pathVar(&summaryJsonPath, flagName, "", usage)
+}
+
+// A report is the machine readable summary of an operation written to the path
+// given to summary-json.
+type report struct {
+	Mode            string   `json:"mode"`
+	Input           string   `json:"input,omitempty"`
+	Output          string   `json:"output,omitempty"`
+	WemsExtracted   int      `json:"wems_extracted"`
+	WemsReplaced    int      `json:"wems_replaced"`
+	BytesWritten    int64    `json:"bytes_written"`
+	DurationSeconds float64  `json:"duration_seconds"`
+	Warnings        []string `json:"warnings"`
+	ExitStatus      int      `json:"exit_status"`
+	Error           string   `json:"error,omitempty"`
+
+	mu    sync.Mutex
+	start time.Time
+}
+
+// The report of the running operation. Its counts may be updated from any
+// goroutine.
+var opReport = &report{start: time.Now(), Warnings: []string{}}
+
+// addExtracted records that n wems were extracted.
+func (r *report) addExtracted(n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.WemsExtracted += n
+}
+
+// addReplaced records that n wems were replaced.
+func (r *report) addReplaced(n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.WemsReplaced += n
+}
+
+// addWritten records that n bytes were written.
+func (r *report) addWritten(n int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.BytesWritten += n
+}
+
+// addWarning records a warning, formatted as fmt.Sprintf does.
+func (r *report) addWarning(format string, v ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, v...))
+}
+
+// finish writes this report to the path given to summary-json, if any, with
+// the given exit status and error message.
+func (r *report) finish(status int, msg string) {
+	if summaryJsonPath == "" {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Input, r.Output = filePath, output
+	r.DurationSeconds = time.Since(r.start).Seconds()
+	r.ExitStatus, r.Error = status, msg
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err == nil {
+		err = ioutil.WriteFile(summaryJsonPath, append(data, '\n'), 0644)
+	}
+	if err != nil {
+		log.Println("Could not write summary:", err)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+var shouldReverseHash bool
+var wordlistPath string
+
+func init() {
+	const (
+		usage = "find the names of the IDs used within the .bnk specified by " +
+			"filepath. Every line of the file specified by wordlist is hashed and " +
+			"checked against the IDs of the SoundBank and its HIRC objects."
+		flagName = "reverse-hash"
+	)
+	flag.BoolVar(&shouldReverseHash, flagName, false, usage)
+	registerMode(&mode{name: flagName, selected: &shouldReverseHash,
+		needsFile: true, run: reverseHash})
+}
+
+func init() {
+	const (
+		usage = "The path to a file of candidate names, one per line, used by " +
+			"reverse-hash."
+		flagName = "wordlist"
+	)
+	pathVar(&wordlistPath, flagName, "", usage)
+}
+
+// bankIds returns a mapping from every ID used by b to a description of what
+// the ID identifies.
+func bankIds(b *bnk.File) map[uint32]string {
+	ids := make(map[uint32]string)
+	if b.BankHeaderSection != nil {
+		ids[b.BankHeaderSection.Descriptor.BankId] = "SoundBank"
+	}
+	if b.StringIdSection != nil {
+		for _, id := range b.StringIdSection.BankIds {
+			ids[id] = "SoundBank (STID)"
+		}
+	}
+	if b.ObjectSection != nil {
+		for _, obj := range b.ObjectSection.Objects() {
+			ids[obj.Id()] = fmt.Sprintf("HIRC object (type %d)", obj.TypeId())
+		}
+	}
+	return ids
+}
+
+// reverseHash prints every name in the wordlist that hashes to an ID used by
+// the input SoundBank.
+func reverseHash(isSoundBank bool) {
+	if !isSoundBank {
+		fatal(exitUsage, "reverse-hash only supports SoundBank files")
+	}
+	if wordlistPath == "" {
+		flag.Usage()
+		fatal(exitUsage, "wordlist cannot be empty")
+	}
+
+	b, err := openSoundBank(filePath)
+	if err != nil {
+		fatalln(exitParse, "Could not parse .bnk file:", err)
+	}
+	defer b.Close()
+	ids := bankIds(b)
+
+	f, err := os.Open(wordlistPath)
+	if err != nil {
+		fatalf(exitIO, "Could not open wordlist \"%s\": %s\n", wordlistPath, err)
+	}
+	defer f.Close()
+
+	found := make(map[uint32]bool)
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		name := strings.TrimSpace(s.Text())
+		if name == "" {
+			continue
+		}
+		id := wwise.HashName(name)
+		if kind, ok := ids[id]; ok && !found[id] {
+			found[id] = true
+			fmt.Printf("%-10d %-30s %s\n", id, name, kind)
+		}
+	}
+	if err := s.Err(); err != nil {
+		fatalf(exitIO, "Could not read wordlist \"%s\": %s\n", wordlistPath, err)
+	}
+	fmt.Printf("Named %d of %d ID(s)\n", len(found), len(ids))
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+	"github.com/hpxro7/wwiseutil/pck"
+)
+
+var shouldVerifyRoundTrip bool
+
+func init() {
+	const (
+		usage = "parse the file specified by filepath, write it back out in " +
+			"memory, and check that the result is byte for byte identical to the " +
+			"input. Exits with a non-zero status if it is not."
+		flagName = "verify-roundtrip"
+	)
+	flag.BoolVar(&shouldVerifyRoundTrip, flagName, false, usage)
+	registerMode(&mode{name: flagName, selected: &shouldVerifyRoundTrip,
+		needsFile: true, run: verifyRoundTrip})
+}
+
+// verifyRoundTrip re-serializes the input file and reports where, if anywhere,
+// it differs from the input.
+func verifyRoundTrip(isSoundBank bool) {
+	org, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		fatalln(exitIO, "Could not read input file:", err)
+	}
+
+	var ctn io.WriterTo
+	var sections []bnk.Section
+	if isSoundBank {
+		b, err := bnk.NewFileFromBytes(org)
+		if err != nil {
+			fatalln(exitParse, "Could not parse .bnk file:", err)
+		}
+		ctn, sections = b, b.Sections()
+	} else {
+		p, err := pck.NewFile(bytes.NewReader(org))
+		if err != nil {
+			fatalln(exitParse, "Could not parse .pck file:", err)
+		}
+		ctn = p
+	}
+	written := new(bytes.Buffer)
+	_, err = ctn.WriteTo(written)
+	if err != nil {
+		fatalln(exitIO, "Could not write file:", err)
+	}
+
+	out := written.Bytes()
+	if bytes.Equal(org, out) {
+		fmt.Printf("%s round trips to identical bytes (%d bytes)\n", filePath,
+			len(org))
+		return
+	}
+	fmt.Printf("%s does not round trip: %d bytes were read, %d bytes were "+
+		"written\n", filePath, len(org), len(out))
+	i := 0
+	for i < len(org) && i < len(out) && org[i] == out[i] {
+		i++
+	}
+	fmt.Printf("The first difference is at offset %d%s\n", i,
+		sectionAt(sections, int64(i)))
+	exit(exitValidation, "The file does not round trip")
+}
+
+// sectionAt describes the section of sections, laid out in order from the start
+// of a SoundBank, that holds offset. The empty string is returned if there is
+// no such section.
+func sectionAt(sections []bnk.Section, offset int64) string {
+	start := int64(0)
+	for _, s := range sections {
+		if offset < start+s.Size() {
+			return fmt.Sprintf(", %d bytes into the %s section", offset-start,
+				s.Identifier())
+		}
+		start += s.Size()
+	}
+	return ""
+}
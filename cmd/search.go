@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+var shouldSearch bool
+var searchPattern string
+var searchHex bool
+
+func init() {
+	const (
+		usage = "print the offset of every occurrence of the given string within " +
+			"the wems of the .bnk or .pck specified by filepath, and within the " +
+			"data of the sections of a .bnk other than DATA, whose wems are " +
+			"searched on their own. Offsets are counted from the start of each " +
+			"wem or section data, as inspect shows them."
+		flagName = "search"
+	)
+	flag.Var(modeValue{&searchPattern, &shouldSearch, false}, flagName,
+		usage)
+	registerMode(&mode{name: flagName, selected: &shouldSearch,
+		needsFile: true, run: search})
+}
+
+func init() {
+	const (
+		usage = "When search is used, the pattern is given as hexadecimal bytes, " +
+			"which may be separated by spaces, e.g. \"63 75 65 20\"."
+		flagName = "search-hex"
+	)
+	flag.BoolVar(&searchHex, flagName, false, usage)
+}
+
+// search prints every occurrence of the search pattern within the wems and
+// sections of the input file.
+func search(isSoundBank bool) {
+	pattern := []byte(searchPattern)
+	if searchHex {
+		var err error
+		pattern, err = hex.DecodeString(strings.Join(strings.Fields(searchPattern),
+			""))
+		if err != nil {
+			fatalf(exitUsage, "\"%s\" is not a valid hex pattern: %s\n",
+				searchPattern, err)
+		}
+	}
+	if len(pattern) == 0 {
+		fatal(exitUsage, "search cannot be given an empty pattern")
+	}
+
+	var ctn wwise.Container
+	var err error
+	if isSoundBank {
+		ctn, err = openSoundBank(filePath)
+	} else {
+		ctn, err = openFilePackage(filePath)
+	}
+	if err != nil {
+		fatalln(exitParse, "Could not parse .bnk or .pck file:", err)
+	}
+	defer ctn.Close()
+
+	matches := 0
+	if b, ok := ctn.(*bnk.File); ok {
+		for _, s := range b.Sections() {
+			if s == b.DataSection {
+				continue
+			}
+			name := "Section " + s.Identifier()
+			matches += searchData(name, pattern,
+				writerToFunc(func(w io.Writer) (int64, error) {
+					return s.WriteTo(&prefixSkipper{w, bnk.SECTION_HEADER_BYTES})
+				}))
+		}
+	}
+	for _, wem := range ctn.Wems() {
+		name := fmt.Sprintf("Wem %d", wem.Descriptor.WemId)
+		matches += searchData(name, pattern, wem)
+	}
+	fmt.Printf("Found %d occurrence(s)\n", matches)
+}
+
+// searchData prints the offset of every occurrence of pattern within the data
+// written by wt, which is called name, and returns the number of them.
+func searchData(name string, pattern []byte, wt io.WriterTo) int {
+	m := &patternMatcher{pattern: pattern}
+	_, err := wt.WriteTo(m)
+	if err != nil {
+		fatalf(exitIO, "Could not read %s: %s\n", strings.ToLower(name), err)
+	}
+	for _, offset := range m.offsets {
+		fmt.Printf("%s: 0x%x\n", name, offset)
+	}
+	return len(m.offsets)
+}
+
+// A patternMatcher is a Writer that records the offsets of every occurrence of
+// pattern within the bytes written to it, including those that span several
+// writes.
+type patternMatcher struct {
+	pattern []byte
+	// The last bytes written, which may begin an occurrence.
+	tail []byte
+	// The offset of the first byte of tail.
+	offset  int64
+	offsets []int64
+}
+
+func (m *patternMatcher) Write(p []byte) (int, error) {
+	buf := append(m.tail, p...)
+	for i := 0; ; {
+		j := bytes.Index(buf[i:], m.pattern)
+		if j < 0 {
+			break
+		}
+		m.offsets = append(m.offsets, m.offset+int64(i+j))
+		i += j + 1
+	}
+	keep := len(m.pattern) - 1
+	if keep > len(buf) {
+		keep = len(buf)
+	}
+	m.offset += int64(len(buf) - keep)
+	m.tail = append(m.tail[:0], buf[len(buf)-keep:]...)
+	return len(p), nil
+}
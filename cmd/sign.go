@@ -0,0 +1,149 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// The file extension of the detached signature written next to a signed patch.
+const signatureExtension = ".sig"
+
+// The file extensions of generated private and public keys.
+const (
+	privateKeyExtension = ".key"
+	publicKeyExtension  = ".pub"
+)
+
+var shouldGenerateKey bool
+var keyName string
+var signKeyPath string
+var verifyKeyPath string
+
+func init() {
+	const (
+		usage = "generate an ed25519 key pair for signing patches, writing the " +
+			"private key to <name>" + privateKeyExtension + " and the public key " +
+			"to <name>" + publicKeyExtension + ". Existing keys are only " +
+			"overwritten if force is used."
+		flagName = "generate-key"
+	)
+	flag.Var(modeValue{&keyName, &shouldGenerateKey, true}, flagName, usage)
+	registerMode(&mode{name: flagName, selected: &shouldGenerateKey,
+		run: generateKey})
+}
+
+func init() {
+	const (
+		usage = "When create-patch is used, the path to the private key to sign " +
+			"the patch with. The signature is written next to the patch, with a " +
+			signatureExtension + " extension."
+		flagName = "sign-key"
+	)
+	pathVar(&signKeyPath, flagName, "", usage)
+}
+
+func init() {
+	const (
+		usage = "When apply-patch is used, the path to the public key that the " +
+			"patch must be signed with. The patch is not applied unless its " +
+			signatureExtension + " file holds a valid signature."
+		flagName = "verify-key"
+	)
+	pathVar(&verifyKeyPath, flagName, "", usage)
+}
+
+// generateKey writes a new key pair to the files named by generate-key.
+func generateKey(bool) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		fatalln(exitIO, "Could not generate key:", err)
+	}
+	// Only the owner may read the private key.
+	keys := []struct {
+		path string
+		data []byte
+		perm os.FileMode
+	}{
+		{keyName + privateKeyExtension, priv, 0600},
+		{keyName + publicKeyExtension, pub, 0666},
+	}
+	// A lost private key cannot be recovered, so keys are not overwritten as
+	// other files are.
+	for _, key := range keys {
+		if _, err := os.Stat(key.path); err == nil && !force {
+			fatalf(exitIO, "%s already exists; use -force to overwrite it\n",
+				key.path)
+		}
+	}
+	for _, key := range keys {
+		f, err := createFileMode(key.path, key.perm)
+		if err != nil {
+			fatalln(exitIO, "Could not create key file:", err)
+		}
+		_, err = io.WriteString(f, hex.EncodeToString(key.data)+"\n")
+		f.Close()
+		if err != nil {
+			fatalln(exitIO, "Could not write key file:", err)
+		}
+		fmt.Println("Wrote", key.path)
+	}
+	fmt.Printf("Keep %s secret; share %s with those who apply your patches\n",
+		keyName+privateKeyExtension, keyName+publicKeyExtension)
+}
+
+// readKey reads a hex encoded key of the given size from the file at path.
+func readKey(path string, size int) ([]byte, error) {
+	text, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(text)))
+	if err != nil || len(key) != size {
+		msg := fmt.Sprintf("%s does not hold a %d byte hex encoded key", path,
+			size)
+		return nil, errors.New(msg)
+	}
+	return key, nil
+}
+
+// writeSignature signs data with the private key at keyPath, and writes the
+// signature next to the file at path.
+func writeSignature(path string, data []byte, keyPath string) error {
+	key, err := readKey(keyPath, ed25519.PrivateKeySize)
+	if err != nil {
+		return err
+	}
+	sig := ed25519.Sign(ed25519.PrivateKey(key), data)
+	_, err = writeOutput(path+signatureExtension,
+		writerToFunc(func(w io.Writer) (int64, error) {
+			n, err := io.WriteString(w, hex.EncodeToString(sig)+"\n")
+			return int64(n), err
+		}))
+	return err
+}
+
+// verifySignature returns an error unless the signature next to the file at
+// path is a valid signature of data by the public key at keyPath.
+func verifySignature(path string, data []byte, keyPath string) error {
+	key, err := readKey(keyPath, ed25519.PublicKeySize)
+	if err != nil {
+		return err
+	}
+	text, err := ioutil.ReadFile(path + signatureExtension)
+	if err != nil {
+		return err
+	}
+	sig, err := hex.DecodeString(strings.TrimSpace(string(text)))
+	if err != nil || !ed25519.Verify(ed25519.PublicKey(key), data, sig) {
+		return errors.New("The signature does not match the patch and key")
+	}
+	return nil
+}
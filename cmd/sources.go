@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+)
+
+var shouldListSources bool
+
+func init() {
+	const (
+		usage = "list the media played by every sound and music track within " +
+			"the .bnk specified by filepath, along with whether the media is " +
+			"embedded in the .bnk, streamed, or prefetched and streamed, and " +
+			"whether the .bnk holds its wem. If media-dir or streamed is given, " +
+			"where each streamed media was found is listed as well."
+		flagName = "sources"
+	)
+	flag.BoolVar(&shouldListSources, flagName, false, usage)
+	registerMode(&mode{name: flagName, selected: &shouldListSources,
+		needsFile: true, run: listSources})
+}
+
+// listSources prints the media of every sound and music track of the input
+// SoundBank, and where that media is stored.
+func listSources(isSoundBank bool) {
+	if !isSoundBank {
+		fatal(exitUsage, "sources only supports SoundBank files")
+	}
+
+	b, err := openSoundBank(filePath)
+	if err != nil {
+		fatalln(exitParse, "Could not parse .bnk file:", err)
+	}
+	defer b.Close()
+	if b.ObjectSection == nil {
+		fatal(exitValidation, "The SoundBank does not contain a HIRC section")
+	}
+
+	stored := make(map[uint32]bool)
+	for _, wem := range b.Wems() {
+		stored[wem.Descriptor.WemId] = true
+	}
+	loose, err := streamedSources()
+	if err != nil {
+		fatal(exitIO, err)
+	}
+	counts := make(map[bnk.StreamType]int)
+	sources := b.ObjectSection.Sources()
+	for _, src := range sources {
+		missing := ""
+		if src.StreamType.InBank() && !stored[src.MediaId] {
+			missing = ", missing from the SoundBank"
+		}
+		if src.StreamType != bnk.StreamEmbedded && loose != nil {
+			if found, ok := loose[src.MediaId]; ok {
+				missing += ", found at " + found.path
+			} else {
+				missing += ", not found"
+			}
+		}
+		fmt.Printf("Media %d of object %d: %s%s\n", src.MediaId, src.ObjectId,
+			src.StreamType, missing)
+		counts[src.StreamType]++
+	}
+	fmt.Printf("Listed %d source(s): %d embedded, %d prefetched and streamed, "+
+		"%d streamed\n", len(sources), counts[bnk.StreamEmbedded],
+		counts[bnk.StreamPrefetch], counts[bnk.StreamStreamed])
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+var shouldSplit bool
+var splitSize int64
+var splitIdsPath string
+
+func init() {
+	const (
+		usage = "split the .bnk specified by filepath into several smaller .bnk " +
+			"files, written to the directory specified by output. The wems are " +
+			"partitioned by either split-size or split-ids. Each SoundBank is " +
+			"named after the source with a number appended, and is given the ID " +
+			"of its name. Only the wems of the source are kept."
+		flagName = "split"
+	)
+	flag.BoolVar(&shouldSplit, flagName, false, usage)
+	registerMode(&mode{name: flagName, selected: &shouldSplit,
+		needsFile: true, needsOutput: true, run: split})
+}
+
+func init() {
+	const (
+		usage = "When split is used, the maximum size in bytes of each " +
+			"SoundBank. A wem too large to share a SoundBank is written to one of " +
+			"its own."
+		flagName = "split-size"
+	)
+	flag.Int64Var(&splitSize, flagName, 0, usage)
+}
+
+func init() {
+	const (
+		usage = "When split is used, the path to a file describing the wems of " +
+			"each SoundBank. Each line holds the IDs of the wems of one SoundBank, " +
+			"separated by spaces or commas."
+		flagName = "split-ids"
+	)
+	pathVar(&splitIdsPath, flagName, "", usage)
+}
+
+// readIdGroups reads the groups of wem IDs stored in the file at path.
+func readIdGroups(path string) ([][]uint32, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var groups [][]uint32
+	s := bufio.NewScanner(f)
+	for line := 1; s.Scan(); line++ {
+		fields := strings.FieldsFunc(s.Text(), func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})
+		if len(fields) == 0 {
+			continue
+		}
+		var group []uint32
+		for _, field := range fields {
+			id, err := strconv.ParseUint(field, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: \"%s\" is not a valid wem ID", line,
+					field)
+			}
+			group = append(group, uint32(id))
+		}
+		groups = append(groups, group)
+	}
+	return groups, s.Err()
+}
+
+// split writes the parts of the input SoundBank to the output directory.
+func split(isSoundBank bool) {
+	if !isSoundBank {
+		fatal(exitUsage, "split only supports SoundBank files")
+	}
+	if (splitSize > 0) == (splitIdsPath != "") {
+		flag.Usage()
+		fatal(exitUsage,
+			"Exactly one of split-size and split-ids should be specified")
+	}
+
+	b, err := openSoundBank(filePath)
+	if err != nil {
+		fatalln(exitParse, "Could not parse .bnk file:", err)
+	}
+	defer b.Close()
+
+	var parts []*bnk.File
+	if splitSize > 0 {
+		parts, err = b.SplitBySize(splitSize)
+	} else {
+		var groups [][]uint32
+		groups, err = readIdGroups(splitIdsPath)
+		if err != nil {
+			fatalf(exitIO, "Could not read split-ids \"%s\": %s\n", splitIdsPath, err)
+		}
+		parts, err = b.SplitByIds(groups)
+	}
+	if err != nil {
+		fatalln(exitCode(err), "Could not split SoundBank:", err)
+	}
+
+	err = createDirIfEmpty(output)
+	if err != nil {
+		fatalln(exitIO, "Could not create output directory:", err)
+	}
+	ext := filepath.Ext(filePath)
+	base := strings.TrimSuffix(filepath.Base(filePath), ext)
+	total := int64(0)
+	for i, part := range parts {
+		name := fmt.Sprintf("%s_%d", base, i+1)
+		part.BankHeaderSection.Descriptor.BankId = wwise.HashName(name)
+
+		filename := name + ext
+		n, err := writeOutput(filepath.Join(output, filename), part)
+		if err != nil {
+			fatalf(exitIO, "Could not write SoundBank \"%s\": %s", filename, err)
+		}
+		total += n
+	}
+	fmt.Printf("Successfully split into %d SoundBank(s) in %s\n", len(parts),
+		output)
+	fmt.Printf("Wrote %d bytes in total\n", total)
+}
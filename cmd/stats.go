@@ -0,0 +1,127 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+var shouldPrintStats bool
+
+// The number of wems listed by stats as the largest.
+const statsLargestWems = 10
+
+func init() {
+	const (
+		usage = "print statistics of the wems within the .bnk or .pck specified " +
+			"by filepath: the number and size of the wems of each codec, the " +
+			"largest wems, the padding between wems, and how much of the DATA " +
+			"section of a .bnk is taken up by wems."
+		flagName = "stats"
+	)
+	flag.BoolVar(&shouldPrintStats, flagName, false, usage)
+	registerMode(&mode{name: flagName, selected: &shouldPrintStats,
+		needsFile: true, run: printStats})
+}
+
+// codecStats counts the wems of a single codec.
+type codecStats struct {
+	codec wwise.Codec
+	count int
+	bytes int64
+}
+
+// printStats prints statistics of the wems of the input file.
+func printStats(isSoundBank bool) {
+	var ctn wwise.Container
+	var err error
+	if isSoundBank {
+		ctn, err = openSoundBank(filePath)
+	} else {
+		ctn, err = openFilePackage(filePath)
+	}
+	if err != nil {
+		fatalln(exitParse, "Could not parse .bnk or .pck file:", err)
+	}
+	defer ctn.Close()
+
+	wems := ctn.Wems()
+	byCodec := make(map[wwise.Codec]*codecStats)
+	total, padding := int64(0), int64(0)
+	for _, wem := range wems {
+		codec := wem.Codec()
+		s, ok := byCodec[codec]
+		if !ok {
+			s = &codecStats{codec: codec}
+			byCodec[codec] = s
+		}
+		s.count++
+		s.bytes += int64(wem.Descriptor.Length)
+		total += int64(wem.Descriptor.Length)
+		padding += wem.Padding.Size()
+	}
+
+	var codecs []*codecStats
+	for _, s := range byCodec {
+		codecs = append(codecs, s)
+	}
+	sort.Slice(codecs, func(i, j int) bool {
+		return codecs[i].bytes > codecs[j].bytes
+	})
+	tableParams := []string{"%-12", "%-8", "%-15", "%-8", "\n"}
+	titleFmt := strings.Join(tableParams, "s|")
+	title := fmt.Sprintf(titleFmt, "Codec", "Count", "Bytes", "Share")
+	fmt.Print(title)
+	fmt.Println(strings.Repeat("-", len(title)-1))
+	for _, s := range codecs {
+		fmt.Printf(titleFmt, s.codec, fmt.Sprint(s.count), fmt.Sprint(s.bytes),
+			percentage(s.bytes, total))
+	}
+
+	// List the largest wems, as they gain the most from being recompressed.
+	order := make([]int, len(wems))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return wems[order[i]].Descriptor.Length > wems[order[j]].Descriptor.Length
+	})
+	if len(order) > statsLargestWems {
+		order = order[:statsLargestWems]
+	}
+	fmt.Printf("\nLargest wem(s):\n")
+	tableParams = []string{"%-7", "%-15", "%-15", "%-12", "\n"}
+	titleFmt = strings.Join(tableParams, "s|")
+	title = fmt.Sprintf(titleFmt, "Index", "Id", "Length", "Codec")
+	fmt.Print(title)
+	fmt.Println(strings.Repeat("-", len(title)-1))
+	for _, i := range order {
+		wem := wems[i]
+		fmt.Printf(titleFmt, fmt.Sprint(i+1), fmt.Sprint(wem.Descriptor.WemId),
+			fmt.Sprint(wem.Descriptor.Length), wem.Codec())
+	}
+
+	fmt.Println()
+	fmt.Printf("Wems: %d, %d bytes in total\n", len(wems), total)
+	fmt.Printf("Padding: %d bytes, %s of the wem data\n", padding,
+		percentage(padding, total))
+	if b, ok := ctn.(*bnk.File); ok && b.DataSection != nil {
+		length := int64(b.DataSection.Length())
+		fmt.Printf("DATA section: %d bytes, %s taken up by wems\n", length,
+			percentage(total, length))
+	}
+}
+
+// percentage returns n as a percentage of total.
+func percentage(n, total int64) string {
+	if total == 0 {
+		return "0.00%"
+	}
+	return fmt.Sprintf("%.2f%%", float64(n)*100/float64(total))
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+	"github.com/hpxro7/wwiseutil/pck"
+	"github.com/hpxro7/wwiseutil/util"
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+var streamedPaths string
+var mediaDir string
+var stitch bool
+
+func init() {
+	const (
+		usage = "a comma separated list of .pck files, or of directories of " +
+			"loose .wem files named by their ID, holding the streamed media of " +
+			"SoundBanks. If given, verify also reports streamed wems that are " +
+			"stored in none of them, and stitch reads complete wems from them."
+		flagName = "streamed"
+	)
+	pathVar(&streamedPaths, flagName, "", usage)
+}
+
+func init() {
+	const (
+		usage = "a game directory that is searched, along with its " +
+			"subdirectories, for loose .wem files named by their ID. Streamed " +
+			"media found there is listed by sources and, when a .bnk is " +
+			"unpacked, written to the streamed directory of the output. It is " +
+			"also searched as the files given by streamed are."
+		flagName = "media-dir"
+	)
+	pathVar(&mediaDir, flagName, "", usage)
+}
+
+func init() {
+	const (
+		usage = "When a .bnk is unpacked, write each prefetched wem, of which " +
+			"the .bnk only holds the beginning, as the complete wem found in the " +
+			"files given by streamed. Prefetched wems that cannot be found are " +
+			"written as they are stored in the .bnk."
+		flagName = "stitch"
+	)
+	flag.BoolVar(&stitch, flagName, false, usage)
+}
+
+// A streamedSource describes where the complete data of a streamed wem is
+// stored.
+type streamedSource struct {
+	path   string
+	offset int64
+	length int64
+}
+
+var streamedOnce sync.Once
+
+// Where each wem of the files given by the streamed flag is stored, loaded
+// once by streamedSources.
+var streamedByID map[uint32]streamedSource
+var streamedErr error
+
+// streamedSources returns where each wem stored in the files given by the
+// streamed flag, or in the directory given by the media-dir flag, is stored,
+// or nil if neither flag is set. If a wem is stored more than once, the first
+// file that holds it is used.
+func streamedSources() (map[uint32]streamedSource, error) {
+	streamedOnce.Do(func() {
+		if streamedPaths == "" && mediaDir == "" {
+			return
+		}
+		sources := make(map[uint32]streamedSource)
+		var paths []string
+		if streamedPaths != "" {
+			paths = strings.Split(streamedPaths, ",")
+		}
+		for _, path := range paths {
+			err := addStreamedSources(sources, path)
+			if err != nil {
+				msg := fmt.Sprintf("Could not read %s: %s", path, err)
+				streamedErr = errors.New(msg)
+				return
+			}
+		}
+		if mediaDir != "" {
+			err := addMediaDirSources(sources, mediaDir)
+			if err != nil {
+				msg := fmt.Sprintf("Could not search %s: %s", mediaDir, err)
+				streamedErr = errors.New(msg)
+				return
+			}
+		}
+		streamedByID = sources
+	})
+	return streamedByID, streamedErr
+}
+
+// addMediaDirSources adds the loose wems stored in dir, or in any of its
+// subdirectories, to sources.
+func addMediaDirSources(sources map[uint32]streamedSource, dir string) error {
+	return filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		id, ok := looseWemId(fi)
+		if !ok {
+			return nil
+		}
+		if _, ok := sources[id]; !ok {
+			sources[id] = streamedSource{path, 0, fi.Size()}
+		}
+		return nil
+	})
+}
+
+// looseWemId returns the ID of the loose wem described by fi, whose name is
+// its ID, or false if fi does not describe a loose wem.
+func looseWemId(fi os.FileInfo) (uint32, bool) {
+	name := fi.Name()
+	if fi.IsDir() || filepath.Ext(name) != wemExtension {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(strings.TrimSuffix(name, wemExtension), 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint32(id), true
+}
+
+// addStreamedSources adds the wems stored in the .pck file or directory of
+// loose wems at path to sources.
+func addStreamedSources(sources map[uint32]streamedSource, path string) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		fis, err := ioutil.ReadDir(path)
+		if err != nil {
+			return err
+		}
+		for _, fi := range fis {
+			id, ok := looseWemId(fi)
+			if !ok {
+				continue
+			}
+			if _, ok := sources[id]; !ok {
+				sources[id] = streamedSource{filepath.Join(path, fi.Name()), 0,
+					fi.Size()}
+			}
+		}
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	idxs, err := pck.ListIndexes(f)
+	if err != nil {
+		return err
+	}
+	for _, idx := range idxs {
+		desc := idx.Descriptor
+		if _, ok := sources[desc.WemId]; !ok {
+			sources[desc.WemId] = streamedSource{path, idx.Offset(),
+				int64(desc.Length)}
+		}
+	}
+	return nil
+}
+
+// streamedWems returns the IDs of the wems stored in the files given by the
+// streamed flag, or nil if the flag is not set.
+func streamedWems() (map[uint32]bool, error) {
+	sources, err := streamedSources()
+	if sources == nil || err != nil {
+		return nil, err
+	}
+	ids := make(map[uint32]bool)
+	for id := range sources {
+		ids[id] = true
+	}
+	return ids, nil
+}
+
+// stitchPrefetched returns a copy of the wems of b in which every prefetched
+// wem is replaced by its complete wem, as found in the files given by the
+// streamed flag. The returned function closes the files that the complete wems
+// are read from.
+func stitchPrefetched(b *bnk.File) ([]*wwise.Wem, func(), error) {
+	wems := append([]*wwise.Wem(nil), b.Wems()...)
+	var files []*os.File
+	closeFiles := func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+	if b.ObjectSection == nil {
+		return wems, closeFiles, nil
+	}
+	sources, err := streamedSources()
+	if err != nil {
+		return nil, nil, err
+	}
+	if sources == nil {
+		return nil, nil, errors.New("stitch requires the streamed flag")
+	}
+
+	opened := make(map[string]*os.File)
+	types := b.ObjectSection.StreamTypes()
+	for i, wem := range wems {
+		id := wem.Descriptor.WemId
+		if types[id] != bnk.StreamPrefetch {
+			continue
+		}
+		src, ok := sources[id]
+		if !ok {
+			log.Printf("Could not stitch wem %d: It is stored in none of the "+
+				"streamed files\n", id)
+			continue
+		}
+		f := opened[src.path]
+		if f == nil {
+			f, err = os.Open(src.path)
+			if err != nil {
+				closeFiles()
+				return nil, nil, err
+			}
+			opened[src.path] = f
+			files = append(files, f)
+		}
+		complete := util.NewResettingReader(f, src.offset, src.length)
+		if !hasPrefix(complete, wem) {
+			log.Printf("Could not stitch wem %d: The streamed wem does not begin "+
+				"with the prefetched data\n", id)
+			continue
+		}
+		desc := *wem.Descriptor
+		desc.Length = uint32(src.length)
+		empty := util.NewResettingReader(bytes.NewReader(nil), 0, 0)
+		wems[i] = &wwise.Wem{complete, &desc, empty}
+	}
+	return wems, closeFiles, nil
+}
+
+// hasPrefix returns true if the data of complete begins with the data of
+// prefetched.
+func hasPrefix(complete io.ReaderAt, prefetched *wwise.Wem) bool {
+	ra, ok := prefetched.Reader.(io.ReaderAt)
+	if !ok {
+		return false
+	}
+	n := int64(prefetched.Descriptor.Length)
+	want := make([]byte, n)
+	got := make([]byte, n)
+	if _, err := ra.ReadAt(want, 0); err != nil && err != io.EOF {
+		return false
+	}
+	if _, err := complete.ReadAt(got, 0); err != nil && err != io.EOF {
+		return false
+	}
+	return bytes.Equal(want, got)
+}
+
+// The name of the directory of the output of unpack that streamed media found
+// in the directory given by the media-dir flag is written to.
+const streamedDirName = "streamed"
+
+// unpackStreamedMedia writes the media that is streamed by the objects of b,
+// and that is found in the directory given by the media-dir flag, to the
+// streamed directory of dir. Each wem is named by its ID. It returns the
+// number of wems written and the number of bytes written.
+func unpackStreamedMedia(b *bnk.File, dir string) (int, int64, error) {
+	if mediaDir == "" || b.ObjectSection == nil {
+		return 0, 0, nil
+	}
+	sources, err := streamedSources()
+	if err != nil {
+		return 0, 0, exitErrorf(exitIO, "%s", err)
+	}
+
+	written := make(map[uint32]bool)
+	count, total := 0, int64(0)
+	for _, src := range b.ObjectSection.Sources() {
+		loose, ok := sources[src.MediaId]
+		if src.StreamType != bnk.StreamStreamed || !ok || written[src.MediaId] {
+			continue
+		}
+		written[src.MediaId] = true
+		if count == 0 {
+			err := os.MkdirAll(filepath.Join(dir, streamedDirName), 0755)
+			if err != nil {
+				return count, total, exitErrorf(exitIO,
+					"Could not create streamed directory: %s", err)
+			}
+		}
+		n, err := copyStreamedSource(loose,
+			filepath.Join(dir, streamedDirName, fmt.Sprint(src.MediaId)+wemExtension))
+		total += n
+		if err == errSkipped {
+			continue
+		}
+		if err != nil {
+			return count, total, exitErrorf(exitIO,
+				"Could not write streamed wem %d: %s", src.MediaId, err)
+		}
+		count++
+	}
+	return count, total, nil
+}
+
+// copyStreamedSource copies the wem described by src to the file at path.
+func copyStreamedSource(src streamedSource, path string) (int64, error) {
+	in, err := os.Open(src.path)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+	out, err := createFile(path)
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+	n, err := io.Copy(out, io.NewSectionReader(in, src.offset, src.length))
+	opReport.addWritten(n)
+	return n, err
+}
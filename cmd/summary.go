@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+// A replacedWem records the state of a wem before it was replaced.
+type replacedWem struct {
+	index   int
+	id      uint32
+	length  int64
+	padding int64
+}
+
+// recordReplacements records the state of the wems of ctn that are replaced by
+// targets, before they are replaced.
+func recordReplacements(ctn wwise.Container,
+	targets []*wwise.ReplacementWem) []replacedWem {
+	var replaced []replacedWem
+	wems := ctn.Wems()
+	for _, t := range targets {
+		wem := wems[t.WemIndex]
+		replaced = append(replaced, replacedWem{t.WemIndex, wem.Descriptor.WemId,
+			int64(wem.Descriptor.Length), wem.Padding.Size()})
+	}
+	return replaced
+}
+
+// printReplacementSummary prints the old and new size of every replaced wem of
+// ctn, how much of the padding that followed them was consumed, and how the
+// size of the written file differs from the size of the input file.
+func printReplacementSummary(ctn wwise.Container, replaced []replacedWem,
+	inputSize, outputSize int64) {
+	tableParams := []string{"%-7", "%-15", "%-12", "%-12", "%-12", "%-8", "\n"}
+	titleFmt := strings.Join(tableParams, "s|")
+	title := fmt.Sprintf(titleFmt, "Index", "Id", "Old length", "New length",
+		"Change", "In place")
+	fmt.Print(title)
+	fmt.Println(strings.Repeat("-", len(title)-1))
+
+	wems := ctn.Wems()
+	oldPadding, newPadding := int64(0), int64(0)
+	for _, r := range replaced {
+		wem := wems[r.index]
+		length := int64(wem.Descriptor.Length)
+		// A replacement fits in place if it needs no more space than the wem it
+		// replaced and the padding that followed it.
+		fits := "no"
+		if length <= r.length+r.padding {
+			fits = "yes"
+		}
+		fmt.Printf(titleFmt, fmt.Sprint(r.index+1), fmt.Sprint(r.id),
+			fmt.Sprint(r.length), fmt.Sprint(length),
+			fmt.Sprintf("%+d", length-r.length), fits)
+		oldPadding += r.padding
+		newPadding += wem.Padding.Size()
+	}
+	fmt.Printf("Padding after the replaced wems: %d bytes before, %d bytes "+
+		"after (%d consumed)\n", oldPadding, newPadding, oldPadding-newPadding)
+	fmt.Printf("Output is %d bytes, %+d bytes compared to the input\n",
+		outputSize, outputSize-inputSize)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+)
+
+var shouldTextDump bool
+
+// The names of the types of HIRC objects, by their type ID.
+var objectTypeNames = map[byte]string{
+	0x01: "State",
+	0x02: "Sound",
+	0x03: "Action",
+	0x04: "Event",
+	0x05: "Random/Sequence Container",
+	0x06: "Switch Container",
+	0x07: "Actor-Mixer",
+	0x08: "Bus",
+	0x09: "Blend Container",
+	0x0A: "Music Segment",
+	0x0B: "Music Track",
+	0x0C: "Music Switch Container",
+	0x0D: "Music Playlist Container",
+	0x0E: "Attenuation",
+	0x0F: "Dialogue Event",
+	0x10: "Feedback Bus",
+	0x11: "Feedback Node",
+	0x12: "Effect ShareSet",
+	0x13: "Custom Effect",
+	0x14: "Auxiliary Bus",
+	0x15: "LFO Modulator",
+	0x16: "Envelope Modulator",
+	0x17: "Audio Device",
+}
+
+func init() {
+	const (
+		usage = "write a plain text summary of the .bnk specified by filepath " +
+			"to the file specified by output, meant to be committed to version " +
+			"control next to the .bnk so that changes to it can be reviewed as " +
+			"text diffs. It lists the BKHD section, the sizes of the sections, " +
+			"the IDs, lengths and SHA-256 checksums of the wems of the DIDX " +
+			"section, and the IDs, types and checksums of the HIRC objects, in " +
+			"a YAML layout. Offsets are left out, so that the summary only " +
+			"changes where the SoundBank does."
+		flagName = "text-dump"
+	)
+	flag.BoolVar(&shouldTextDump, flagName, false, usage)
+	registerMode(&mode{name: flagName, selected: &shouldTextDump,
+		needsFile: true, needsOutput: true, run: textDump})
+}
+
+// textDump writes a text summary of the input SoundBank to the output file.
+func textDump(isSoundBank bool) {
+	if !isSoundBank {
+		fatal(exitUsage, "text-dump only supports SoundBank files")
+	}
+
+	b, err := openSoundBank(filePath)
+	if err != nil {
+		fatalln(exitParse, "Could not parse .bnk file:", err)
+	}
+	defer b.Close()
+
+	_, err = writeOutput(output, writerToFunc(func(w io.Writer) (int64, error) {
+		bw := bufio.NewWriter(w)
+		err := writeTextDump(bw, b)
+		if err != nil {
+			return 0, err
+		}
+		return 0, bw.Flush()
+	}))
+	if err != nil {
+		fatalln(exitIO, "Could not write output to file:", err)
+	}
+	fmt.Printf("Successfully dumped %d section(s)! Output file written to: %s\n",
+		len(b.Sections()), output)
+}
+
+// writeTextDump writes the text summary of b to w. Every list is written in a
+// fixed order, so that dumping the same SoundBank twice gives the same text.
+func writeTextDump(w io.Writer, b *bnk.File) error {
+	fmt.Fprintln(w, "header:")
+	fields := headerFields(b)
+	for _, f := range []struct{ key, name string }{{"version", "Version"},
+		{"bank_id", "Bank ID"}, {"language", "Language"},
+		{"feedback_in_bank", "Feedback in bank"}, {"alignment", "Alignment"},
+		{"device_allocated", "Device allocated"}, {"project_id", "Project ID"}} {
+		if value, ok := fields[f.name]; ok {
+			fmt.Fprintf(w, "  %s: %q\n", f.key, value)
+		}
+	}
+
+	fmt.Fprintln(w, "sections:")
+	for _, s := range b.Sections() {
+		fmt.Fprintf(w, "  - {id: %s, size: %d}\n", s.Identifier(), s.Size())
+	}
+
+	wems := b.Wems()
+	fmt.Fprintf(w, "wems: # %d\n", len(wems))
+	for _, wem := range wems {
+		fmt.Fprintf(w, "  - {id: %d, length: %d, sha256: %s}\n",
+			wem.Descriptor.WemId, wem.Descriptor.Length, wemSum(wem))
+	}
+
+	if b.ObjectSection == nil {
+		_, err := fmt.Fprintln(w, "objects: []")
+		return err
+	}
+	// Objects are listed by ID, as adding an object to a SoundBank may move the
+	// others.
+	objects := append([]bnk.Object(nil), b.ObjectSection.Objects()...)
+	sort.SliceStable(objects, func(i, j int) bool {
+		return objects[i].Id() < objects[j].Id()
+	})
+	fmt.Fprintf(w, "objects: # %d\n", len(objects))
+	for _, obj := range objects {
+		h := sha256.New()
+		n, err := obj.WriteTo(h)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "  - {id: %d, type: %q, size: %d, sha256: %s}\n",
+			obj.Id(), objectTypeName(obj.TypeId()), n,
+			hex.EncodeToString(h.Sum(nil)))
+	}
+
+	sources := b.ObjectSection.Sources()
+	fmt.Fprintf(w, "sources: # %d\n", len(sources))
+	for _, src := range sources {
+		fmt.Fprintf(w, "  - {object: %d, media: %d, stream_type: %q}\n",
+			src.ObjectId, src.MediaId, src.StreamType)
+	}
+	return nil
+}
+
+// objectTypeName returns the name of the HIRC object type with the given ID.
+func objectTypeName(id byte) string {
+	if name, ok := objectTypeNames[id]; ok {
+		return name
+	}
+	return fmt.Sprintf("Unknown (0x%02X)", id)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+)
+
+var shouldVerify bool
+
+func init() {
+	const (
+		usage = "check the internal consistency of the .bnk specified by " +
+			"filepath: that every section fits within the file and matches its " +
+			"length, that the wems of the DATA section neither overlap nor " +
+			"leave gaps, and that every object, action and embedded wem referred " +
+			"to by the HIRC section exists. Exits with a non-zero status if any " +
+			"problem is found."
+		flagName = "verify"
+	)
+	flag.BoolVar(&shouldVerify, flagName, false, usage)
+	registerMode(&mode{name: flagName, selected: &shouldVerify,
+		needsFile: true, run: verify, batch: verifyBatch})
+}
+
+// verify prints every consistency problem of the input SoundBank, and exits
+// with a non-zero status if there are any.
+func verify(isSoundBank bool) {
+	if !isSoundBank {
+		fatal(exitUsage, "verify only supports SoundBank files")
+	}
+
+	problems, err := verifyFile(filePath)
+	if err != nil {
+		fatalln(exitIO, "Could not open input file:", err)
+	}
+	if len(problems) == 0 {
+		fmt.Printf("%s is consistent\n", filePath)
+		return
+	}
+	fmt.Printf("Found %d problem(s) in %s:\n", len(problems), filePath)
+	for _, p := range problems {
+		fmt.Printf("  %s\n", p)
+	}
+	exit(exitValidation, fmt.Sprintf("%d problem(s) found", len(problems)))
+}
+
+// verifyBatch checks the consistency of the SoundBank at path. Problems are
+// returned as an error, so that the batch fails.
+func verifyBatch(path string, isSoundBank bool) (string, error) {
+	if !isSoundBank {
+		return "", errors.New("verify only supports SoundBank files")
+	}
+	problems, err := verifyFile(path)
+	if err != nil {
+		return "", err
+	}
+	if len(problems) > 0 {
+		var msgs []string
+		for _, p := range problems {
+			msgs = append(msgs, p.Error())
+		}
+		msg := fmt.Sprintf("found %d problem(s): %s", len(problems),
+			strings.Join(msgs, "; "))
+		return "", errors.New(msg)
+	}
+	return "consistent", nil
+}
+
+// verifyFile returns every consistency problem of the SoundBank at path.
+func verifyFile(path string) ([]error, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	problems := bnk.Verify(f, stat.Size(), alignment)
+
+	// Only check the references of SoundBanks that can be read.
+	b, err := bnk.Open(path)
+	if err != nil {
+		return problems, nil
+	}
+	defer b.Close()
+	streamed, err := streamedWems()
+	if err != nil {
+		return nil, err
+	}
+	return append(problems, b.CheckReferences(streamed)...), nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// How often the target directory is checked for changes in watch mode.
+const watchInterval = 500 * time.Millisecond
+
+var watch bool
+var copyToPath string
+
+// The files written by writeOutput that it may overwrite later without force,
+// keyed by outputKey. It is only non-nil in watch mode, where each rebuild
+// replaces the files written by the one before it.
+var sessionOutputs map[string]bool
+
+func init() {
+	const (
+		usage = "When replace is used, keep running after the output is written, " +
+			"and replace the wems again whenever a file in the target directory " +
+			"is added, changed or removed. Each rebuild overwrites the files " +
+			"written by the one before it, but files that already existed are " +
+			"only overwritten if force or backup is set."
+		flagName = "watch"
+	)
+	flag.BoolVar(&watch, flagName, false, usage)
+}
+
+func init() {
+	const (
+		usage = "When replace is used, also copy the output into the given " +
+			"directory, such as the sound directory of a game, each time it is " +
+			"written."
+		flagName = "copy-to"
+	)
+	pathVar(&copyToPath, flagName, "", usage)
+}
+
+// replaceAndWatch replaces the wems of the input file as replace does, copying
+// the output as described by copy-to, and then does so again whenever the
+// target directory changes if the watch flag is set.
+func replaceAndWatch(isSoundBank bool) {
+	if !watch {
+		replace(isSoundBank)
+		if err := copyOutput(); err != nil {
+			fatalErr(err)
+		}
+		return
+	}
+
+	// Later rebuilds may replace the files written by earlier ones, but not files
+	// that existed before this session.
+	sessionOutputs = make(map[string]bool)
+	state := targetState()
+	rebuild := func() {
+		err := replaceFile(isSoundBank)
+		if err == nil {
+			err = copyOutput()
+		}
+		if err != nil {
+			log.Println(err)
+		}
+		fmt.Println("Watching", targetPath, "for changes")
+	}
+	rebuild()
+	for range time.Tick(watchInterval) {
+		s := targetState()
+		if s == state {
+			continue
+		}
+		// Wait for the files to stop changing, so that a wem that is still being
+		// written is not used.
+		time.Sleep(watchInterval)
+		state = targetState()
+		if s != state {
+			continue
+		}
+		fmt.Println("Change detected in", targetPath)
+		rebuild()
+	}
+}
+
+// targetState returns a summary of the names, sizes and modification times of
+// the files in the target directory that changes whenever any of them do.
+func targetState() string {
+	fis, err := ioutil.ReadDir(targetPath)
+	if err != nil {
+		return err.Error()
+	}
+	state := ""
+	for _, fi := range fis {
+		state += fmt.Sprintf("%s/%d/%d;", fi.Name(), fi.Size(),
+			fi.ModTime().UnixNano())
+	}
+	return state
+}
+
+// copyOutput copies the output file into the directory specified by copy-to,
+// if it was given.
+func copyOutput() error {
+	if copyToPath == "" {
+		return nil
+	}
+	src, err := os.Open(output)
+	if err != nil {
+		return exitErrorf(exitIO, "Could not copy output: %s", err)
+	}
+	defer src.Close()
+	dst := filepath.Join(copyToPath, filepath.Base(output))
+	_, err = writeOutput(dst, writerToFunc(func(w io.Writer) (int64, error) {
+		return io.Copy(w, src)
+	}))
+	if err != nil {
+		return exitErrorf(exitIO, "Could not copy output: %s", err)
+	}
+	fmt.Println("Copied output to:", dst)
+	return nil
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+	"github.com/hpxro7/wwiseutil/pck"
+	"github.com/hpxro7/wwiseutil/util"
+)
+
+var shouldCrossReference bool
+var xrefDir string
+var xrefId string
+var xrefJsonPath string
+
+func init() {
+	const (
+		usage = "list, for every wem of the .bnk and .pck files in the given " +
+			"directory and its subdirectories, the files that hold it and the " +
+			"sounds and music tracks that reference it, including those of the " +
+			"SoundBanks stored in a .pck. Only the wem given by xref-id is " +
+			"listed if it is set."
+		flagName = "xref"
+	)
+	flag.Var(modeValue{&xrefDir, &shouldCrossReference, true}, flagName,
+		usage)
+	registerMode(&mode{name: flagName, selected: &shouldCrossReference,
+		run: crossReference})
+}
+
+func init() {
+	const (
+		usage    = "When xref is used, the ID of the only wem to list."
+		flagName = "xref-id"
+	)
+	flag.StringVar(&xrefId, flagName, "", usage)
+}
+
+func init() {
+	const (
+		usage = "When xref is used, also write the cross-reference as JSON to " +
+			"the given path, or to the standard output if it is -."
+		flagName = "xref-json"
+	)
+	pathVar(&xrefJsonPath, flagName, "", usage)
+}
+
+// An xrefUse is a container that holds or references a wem.
+type xrefUse struct {
+	// The path of the .bnk or .pck, relative to the searched directory, followed
+	// by the ID of the SoundBank if it is stored in a .pck.
+	Container string `json:"container"`
+	// Either "holds" or "references".
+	Kind string `json:"kind"`
+	// The sound or music track that references the wem, and where it expects
+	// the wem to be stored.
+	ObjectId   uint32 `json:"object_id,omitempty"`
+	StreamType string `json:"stream_type,omitempty"`
+}
+
+// An xrefEntry lists every use of a wem.
+type xrefEntry struct {
+	Id   uint32    `json:"id"`
+	Uses []xrefUse `json:"uses"`
+}
+
+// crossReference prints which containers of the given directory hold or
+// reference each wem.
+func crossReference(bool) {
+	fi, err := os.Stat(xrefDir)
+	if err != nil || !fi.IsDir() {
+		fatalf(exitUsage, "\"%s\" is not a directory\n", xrefDir)
+	}
+	var only uint32
+	if xrefId != "" {
+		id, err := strconv.ParseUint(xrefId, 10, 32)
+		if err != nil {
+			fatalf(exitUsage, "\"%s\" is not a valid wem ID\n", xrefId)
+		}
+		only = uint32(id)
+	}
+	paths, _ := batchInputs(xrefDir)
+
+	uses := make(map[uint32][]xrefUse)
+	for _, path := range paths {
+		name, err := filepath.Rel(xrefDir, path)
+		if err != nil {
+			name = path
+		}
+		fileType, _ := util.GetFileType(path)
+		if fileType == util.SoundBankFileType {
+			err = crossReferenceSoundBankFile(uses, path, name)
+		} else {
+			err = crossReferencePackage(uses, path, name)
+		}
+		if err != nil {
+			log.Printf("Skipping %s: %s\n", name, err)
+			opReport.addWarning("%s: %s", name, err)
+		}
+	}
+
+	var entries []xrefEntry
+	for id, u := range uses {
+		if xrefId == "" || id == only {
+			entries = append(entries, xrefEntry{id, u})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Id < entries[j].Id
+	})
+	if xrefId != "" && len(entries) == 0 {
+		fatalf(exitValidation, "No file holds or references wem %d\n", only)
+	}
+
+	if xrefJsonPath != "" {
+		err = writeCrossReference(xrefJsonPath, entries)
+		if err != nil && err != errSkipped {
+			fatalln(exitIO, "Could not write cross-reference:", err)
+		}
+	}
+	if xrefJsonPath == "-" {
+		return
+	}
+	for _, e := range entries {
+		fmt.Printf("Wem %d:\n", e.Id)
+		for _, u := range e.Uses {
+			if u.Kind == "holds" {
+				fmt.Printf("  held by %s\n", u.Container)
+			} else {
+				fmt.Printf("  referenced by %s, object %d, %s\n", u.Container,
+					u.ObjectId, u.StreamType)
+			}
+		}
+	}
+	fmt.Printf("Cross-referenced %d wem(s) across %d file(s)\n", len(entries),
+		len(paths))
+}
+
+// crossReferenceSoundBankFile adds the uses of the wems of the SoundBank at
+// path, which is called name, to uses.
+func crossReferenceSoundBankFile(uses map[uint32][]xrefUse, path,
+	name string) error {
+	b, err := openSoundBank(path)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	crossReferenceSoundBank(uses, b, name)
+	return nil
+}
+
+// crossReferenceSoundBank adds the uses of the wems of b, which is called
+// name, to uses.
+func crossReferenceSoundBank(uses map[uint32][]xrefUse, b *bnk.File,
+	name string) {
+	for _, wem := range b.Wems() {
+		id := wem.Descriptor.WemId
+		uses[id] = append(uses[id], xrefUse{Container: name, Kind: "holds"})
+	}
+	if b.ObjectSection == nil {
+		return
+	}
+	for _, src := range b.ObjectSection.Sources() {
+		uses[src.MediaId] = append(uses[src.MediaId], xrefUse{name,
+			"references", src.ObjectId, src.StreamType.String()})
+	}
+}
+
+// crossReferencePackage adds the uses of the wems of the File Package at path,
+// which is called name, and of the SoundBanks it stores, to uses.
+func crossReferencePackage(uses map[uint32][]xrefUse, path,
+	name string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	c, err := pck.ListContents(f)
+	if err != nil {
+		return err
+	}
+	for _, e := range c.Streamed {
+		id := uint32(e.Id)
+		uses[id] = append(uses[id], xrefUse{Container: name, Kind: "holds"})
+	}
+	for _, e := range c.SoundBanks {
+		bankName := fmt.Sprintf("%s, SoundBank %d", name, e.Id)
+		sr := io.NewSectionReader(f, e.Offset(), int64(e.Length))
+		b, err := bnk.NewFileWithOptions(context.Background(), sr, readOptions())
+		if err != nil {
+			log.Printf("Skipping %s: %s\n", bankName, err)
+			opReport.addWarning("%s: %s", bankName, err)
+			continue
+		}
+		crossReferenceSoundBank(uses, b, bankName)
+	}
+	return nil
+}
+
+// writeCrossReference writes entries as JSON to the file at path, or to the
+// standard output if path is -.
+func writeCrossReference(path string, entries []xrefEntry) error {
+	w := io.Writer(os.Stdout)
+	if path != "-" {
+		f, err := createFile(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(entries)
+}
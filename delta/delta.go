@@ -0,0 +1,255 @@
+// Package delta implements compact binary deltas between two versions of a
+// file, such as an original and a modified SoundBank.
+//
+// A delta only holds the bytes of the modified file that cannot be found in the
+// original file, so it can be distributed where redistributing the original
+// file is not allowed. A delta begins with the identifier "BDLT", the format
+// version, and the size and CRC-32 checksum of both files. It is followed by a
+// list of operations, each of which either copies a range of the original file
+// or inserts new bytes. All integers are little endian, except those of the
+// operations, which are unsigned varints.
+package delta
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// ErrChecksum is returned when a delta is applied to a file other than the one
+// it was created from, or produces a file other than the one it was created
+// for.
+var ErrChecksum = errors.New("The checksum does not match")
+
+// The identifier that begins every delta.
+var deltaId = [4]byte{'B', 'D', 'L', 'T'}
+
+// The version of the delta format written by Diff.
+const deltaVersion = 1
+
+// The operations of a delta.
+const (
+	opCopy   byte = 0
+	opInsert byte = 1
+)
+
+// The number of bytes that must match for a range of the original file to be
+// copied rather than inserted.
+const blockSize = 32
+
+// The base of the rolling hash used to find matching blocks.
+const hashBase = 257
+
+// A header begins every delta.
+type header struct {
+	Identifier       [4]byte
+	Version          uint32
+	OriginalSize     uint64
+	OriginalChecksum uint32
+	TargetSize       uint64
+	TargetChecksum   uint32
+}
+
+// Diff writes a delta to w that turns original into modified.
+func Diff(w io.Writer, original, modified []byte) error {
+	bw := bufio.NewWriter(w)
+	hdr := header{deltaId, deltaVersion, uint64(len(original)),
+		crc32.ChecksumIEEE(original), uint64(len(modified)),
+		crc32.ChecksumIEEE(modified)}
+	err := binary.Write(bw, binary.LittleEndian, hdr)
+	if err != nil {
+		return err
+	}
+
+	// Index the blocks of the original file by their hash, keeping the first
+	// block with each hash.
+	index := make(map[uint32]int)
+	for off := 0; off+blockSize <= len(original); off += blockSize {
+		h := hashOf(original[off : off+blockSize])
+		if _, ok := index[h]; !ok {
+			index[h] = off
+		}
+	}
+
+	ops := &opWriter{w: bw}
+	// The start of the bytes of modified that have not yet been copied or
+	// inserted.
+	pending := 0
+	i := 0
+	var h uint32
+	if len(modified) >= blockSize {
+		h = hashOf(modified[:blockSize])
+	}
+	for i+blockSize <= len(modified) {
+		off, ok := index[h]
+		if !ok || !bytes.Equal(original[off:off+blockSize],
+			modified[i:i+blockSize]) {
+			if i+blockSize < len(modified) {
+				h = roll(h, modified[i], modified[i+blockSize])
+			}
+			i++
+			continue
+		}
+
+		// Extend the match as far as it goes in both directions.
+		start, orgStart := i, off
+		for start > pending && orgStart > 0 &&
+			modified[start-1] == original[orgStart-1] {
+			start--
+			orgStart--
+		}
+		end, orgEnd := i+blockSize, off+blockSize
+		for end < len(modified) && orgEnd < len(original) &&
+			modified[end] == original[orgEnd] {
+			end++
+			orgEnd++
+		}
+		ops.insert(modified[pending:start])
+		ops.copy(orgStart, end-start)
+		pending, i = end, end
+		if i+blockSize <= len(modified) {
+			h = hashOf(modified[i : i+blockSize])
+		}
+	}
+	ops.insert(modified[pending:])
+	if ops.err != nil {
+		return ops.err
+	}
+	return bw.Flush()
+}
+
+// Apply writes the file that the delta read from r turns original into to w.
+// An error wrapping ErrChecksum is returned if original is not the file the
+// delta was created from, or if the delta is corrupt.
+func Apply(w io.Writer, original []byte, r io.Reader) error {
+	br := bufio.NewReader(r)
+	var hdr header
+	err := binary.Read(br, binary.LittleEndian, &hdr)
+	if err != nil {
+		return err
+	}
+	if hdr.Identifier != deltaId {
+		return errors.New("The file is not a delta")
+	}
+	if hdr.Version != deltaVersion {
+		msg := fmt.Sprintf("Version %d of the delta format is not supported",
+			hdr.Version)
+		return errors.New(msg)
+	}
+	if hdr.OriginalSize != uint64(len(original)) ||
+		hdr.OriginalChecksum != crc32.ChecksumIEEE(original) {
+		return fmt.Errorf("%w: the delta was not created from this file",
+			ErrChecksum)
+	}
+
+	crc := crc32.NewIEEE()
+	bw := bufio.NewWriter(w)
+	out := io.MultiWriter(bw, crc)
+	written := uint64(0)
+	for written < hdr.TargetSize {
+		op, err := br.ReadByte()
+		if err != nil {
+			return err
+		}
+		switch op {
+		case opCopy:
+			off, err := binary.ReadUvarint(br)
+			if err != nil {
+				return err
+			}
+			length, err := binary.ReadUvarint(br)
+			if err != nil {
+				return err
+			}
+			if off > uint64(len(original)) || length > uint64(len(original))-off {
+				msg := fmt.Sprintf("The delta copies %d bytes from offset %d, past "+
+					"the end of the original file", length, off)
+				return errors.New(msg)
+			}
+			_, err = out.Write(original[off : off+length])
+			if err != nil {
+				return err
+			}
+			written += length
+		case opInsert:
+			length, err := binary.ReadUvarint(br)
+			if err != nil {
+				return err
+			}
+			n, err := io.CopyN(out, br, int64(length))
+			if err != nil {
+				return err
+			}
+			written += uint64(n)
+		default:
+			msg := fmt.Sprintf("%d is not a valid delta operation", op)
+			return errors.New(msg)
+		}
+	}
+	if written != hdr.TargetSize || crc.Sum32() != hdr.TargetChecksum {
+		return fmt.Errorf("%w: the delta did not produce the file it was "+
+			"created for", ErrChecksum)
+	}
+	return bw.Flush()
+}
+
+// hashOf returns the rolling hash of b.
+func hashOf(b []byte) uint32 {
+	h := uint32(0)
+	for _, c := range b {
+		h = h*hashBase + uint32(c)
+	}
+	return h
+}
+
+// roll returns the rolling hash of a block of blockSize bytes with hash h, once
+// out is removed from its start and in is added to its end.
+func roll(h uint32, out, in byte) uint32 {
+	return h*hashBase - uint32(out)*outFactor + uint32(in)
+}
+
+// The factor of the byte that leaves a block when the hash is rolled, which is
+// hashBase to the power of blockSize.
+var outFactor = func() uint32 {
+	f := uint32(1)
+	for i := 0; i < blockSize; i++ {
+		f *= hashBase
+	}
+	return f
+}()
+
+// An opWriter writes the operations of a delta, stopping at the first error.
+type opWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ow *opWriter) copy(off, length int) {
+	ow.write(opCopy, uint64(off), uint64(length))
+}
+
+func (ow *opWriter) insert(b []byte) {
+	if len(b) == 0 {
+		return
+	}
+	ow.write(opInsert, uint64(len(b)))
+	if ow.err == nil {
+		_, ow.err = ow.w.Write(b)
+	}
+}
+
+// write writes the operation op, followed by its arguments as uvarints.
+func (ow *opWriter) write(op byte, args ...uint64) {
+	if ow.err != nil {
+		return
+	}
+	b := []byte{op}
+	for _, arg := range args {
+		b = binary.AppendUvarint(b, arg)
+	}
+	_, ow.err = ow.w.Write(b)
+}
@@ -0,0 +1,87 @@
+// Package delta implements compact binary deltas between two versions of a
+// file, such as an original and a modified SoundBank.
+package delta
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// The SoundBanks that deltas are tested against.
+const (
+	bnkTestDir    = "../bnk/testdata"
+	originalBank  = "complex.bnk"
+	modifiedBank  = "0_replaced_with_smaller.bnk"
+	unrelatedBank = "simple.bnk"
+)
+
+func readTestBank(t *testing.T, name string) []byte {
+	b, err := ioutil.ReadFile(filepath.Join(bnkTestDir, name))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	return b
+}
+
+func TestDiffThenApplyReproducesModified(t *testing.T) {
+	original := readTestBank(t, originalBank)
+	modified := readTestBank(t, modifiedBank)
+
+	d := new(bytes.Buffer)
+	err := Diff(d, original, modified)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	// Only the replaced wem differs, so the delta should be far smaller than
+	// the modified SoundBank.
+	if d.Len() > len(modified)/10 {
+		t.Errorf("Expected a compact delta, but it is %d bytes long", d.Len())
+	}
+
+	out := new(bytes.Buffer)
+	err = Apply(out, original, bytes.NewReader(d.Bytes()))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if !bytes.Equal(out.Bytes(), modified) {
+		t.Error("Expected applying the delta to reproduce the modified file")
+	}
+
+	err = Apply(new(bytes.Buffer), readTestBank(t, unrelatedBank),
+		bytes.NewReader(d.Bytes()))
+	if !errors.Is(err, ErrChecksum) {
+		t.Errorf("Expected applying the delta to another file to fail with "+
+			"ErrChecksum, but got: %v", err)
+	}
+}
+
+func TestDiffOfShortFiles(t *testing.T) {
+	for _, c := range []struct{ original, modified string }{
+		{"", ""},
+		{"", "abc"},
+		{"abc", ""},
+		{"abc", "abd"},
+	} {
+		d := new(bytes.Buffer)
+		err := Diff(d, []byte(c.original), []byte(c.modified))
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		out := new(bytes.Buffer)
+		err = Apply(out, []byte(c.original), d)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		if out.String() != c.modified {
+			t.Errorf("Expected %q, but got %q", c.modified, out.String())
+		}
+	}
+}
@@ -0,0 +1,241 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"unicode/utf16"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/util"
+)
+
+// The version of the File Packages written by a Builder when none is set.
+const DefaultVersion = 1
+
+// A FileSource describes the contents of a file to be stored in a new File
+// Package.
+type FileSource struct {
+	// The ID of the file. Only external files may use more than 32 bits.
+	Id uint64
+	// The ID of the language of the file, which must be added to the Builder.
+	LanguageId uint32
+	Reader     io.ReaderAt
+	Length     int64
+	// The size in bytes of the blocks that the offset of the file is counted in,
+	// or 0 to use the block size of the Builder.
+	BlockSize uint32
+}
+
+// A Builder creates a new File Package from a set of SoundBanks, streamed
+// files and external files, for games that load custom packages. Every method
+// other than WriteTo returns the Builder itself, so that calls can be chained.
+type Builder struct {
+	version   uint32
+	languages map[uint32]string
+	blockSize uint32
+	// The SoundBanks, streamed files and external files, in that order.
+	tables [3][]FileSource
+}
+
+// NewBuilder creates a new Builder of a File Package with DefaultVersion as
+// its version, a block size of 1 and no languages or files.
+func NewBuilder() *Builder {
+	return &Builder{version: DefaultVersion,
+		languages: make(map[uint32]string), blockSize: 1}
+}
+
+// SetVersion sets the version of the File Package. The layout of the header
+// is the same for every version.
+func (b *Builder) SetVersion(version uint32) *Builder {
+	b.version = version
+	return b
+}
+
+// AddLanguage adds the language with the given ID and name, such as sfx or
+// english(us), to the language map of the File Package.
+func (b *Builder) AddLanguage(id uint32, name string) *Builder {
+	b.languages[id] = name
+	return b
+}
+
+// SetBlockSize sets the size in bytes of the blocks that the offsets of files
+// are counted in. Every file begins on a block boundary.
+func (b *Builder) SetBlockSize(size uint32) *Builder {
+	b.blockSize = size
+	return b
+}
+
+// AddSoundBank adds the SoundBank described by src to the File Package.
+func (b *Builder) AddSoundBank(src FileSource) *Builder {
+	b.tables[0] = append(b.tables[0], src)
+	return b
+}
+
+// AddStreamed adds the streamed wem described by src to the File Package.
+func (b *Builder) AddStreamed(src FileSource) *Builder {
+	b.tables[1] = append(b.tables[1], src)
+	return b
+}
+
+// AddExternal adds the external file described by src to the File Package.
+func (b *Builder) AddExternal(src FileSource) *Builder {
+	b.tables[2] = append(b.tables[2], src)
+	return b
+}
+
+// WriteTo writes the File Package to w. Its file tables are sorted by ID and
+// language, and its language map by name, as Wwise looks them up by binary
+// search. Files are stored in the order of their tables.
+func (b *Builder) WriteTo(w io.Writer) (written int64, err error) {
+	err = b.check()
+	if err != nil {
+		return
+	}
+	for _, table := range b.tables {
+		sort.SliceStable(table, func(i, j int) bool {
+			if table[i].Id != table[j].Id {
+				return table[i].Id < table[j].Id
+			}
+			return table[i].LanguageId < table[j].LanguageId
+		})
+	}
+
+	languageMap := b.languageMap()
+	var tables [3][]byte
+	headerBytes := int64(HEADER_BYTES + len(languageMap))
+	// The externals table always uses 64-bit IDs.
+	entryBytes := [3]int{DATA_INDEX_BYTES, DATA_INDEX_BYTES,
+		WIDE_DATA_INDEX_BYTES}
+	for i, table := range b.tables {
+		tables[i] = make([]byte, 4+len(table)*entryBytes[i])
+		headerBytes += int64(len(tables[i]))
+	}
+
+	// Lay out the files after the header, each on a block boundary.
+	offset := headerBytes
+	var paddings [3][]int64
+	for i, table := range b.tables {
+		t := tables[i]
+		binary.LittleEndian.PutUint32(t, uint32(len(table)))
+		t = t[4:]
+		paddings[i] = make([]int64, len(table))
+		for j, src := range table {
+			blockSize := int64(b.blockSize)
+			if src.BlockSize != 0 {
+				blockSize = int64(src.BlockSize)
+			}
+			padding := (blockSize - offset%blockSize) % blockSize
+			offset += padding
+			paddings[i][j] = padding
+			if offset/blockSize > math.MaxUint32 {
+				msg := fmt.Sprintf("File %d begins at offset %d, which cannot be "+
+					"addressed with a block size of %d bytes", src.Id, offset,
+					blockSize)
+				return 0, errors.New(msg)
+			}
+			e := &Entry{src.Id, uint32(blockSize), uint32(src.Length),
+				uint32(offset / blockSize), src.LanguageId}
+			e.encode(t, i == 2)
+			t = t[entryBytes[i]:]
+			offset += src.Length
+		}
+	}
+
+	bw := bufio.NewWriterSize(w, util.COPY_BUFFER_BYTES)
+	header := make([]byte, HEADER_BYTES)
+	copy(header, "AKPK")
+	binary.LittleEndian.PutUint32(header[4:], uint32(headerBytes-8))
+	binary.LittleEndian.PutUint32(header[8:], b.version)
+	binary.LittleEndian.PutUint32(header[12:], uint32(len(languageMap)))
+	for i, t := range tables {
+		binary.LittleEndian.PutUint32(header[16+4*i:], uint32(len(t)))
+	}
+	for _, part := range append([][]byte{header, languageMap}, tables[:]...) {
+		n, err := bw.Write(part)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+
+	for i, table := range b.tables {
+		for j, src := range table {
+			padding := io.NewSectionReader(&util.InfiniteReaderAt{0}, 0,
+				paddings[i][j])
+			n, err := io.Copy(bw, padding)
+			written += n
+			if err != nil {
+				return written, err
+			}
+			n, err = io.Copy(bw, io.NewSectionReader(src.Reader, 0, src.Length))
+			written += n
+			if err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, bw.Flush()
+}
+
+// check returns an error if the File Package cannot be written as it is
+// described.
+func (b *Builder) check() error {
+	if b.blockSize == 0 {
+		return errors.New("The block size of a File Package cannot be 0")
+	}
+	for i, table := range b.tables {
+		for _, src := range table {
+			if i != 2 && src.Id > math.MaxUint32 {
+				msg := fmt.Sprintf("The ID %d does not fit in 32 bits, which only "+
+					"external files may use", src.Id)
+				return errors.New(msg)
+			}
+			if src.Length > math.MaxUint32 {
+				msg := fmt.Sprintf("File %d is %d bytes long, which is longer than a "+
+					"File Package can describe", src.Id, src.Length)
+				return errors.New(msg)
+			}
+			if _, ok := b.languages[src.LanguageId]; !ok {
+				msg := fmt.Sprintf("File %d has the language %d, which was not added",
+					src.Id, src.LanguageId)
+				return errors.New(msg)
+			}
+		}
+	}
+	return nil
+}
+
+// languageMap returns the encoded language map of the File Package. Its entries
+// are followed by the names of the languages, as zero terminated UTF-16
+// strings, and it is padded to a multiple of 4 bytes.
+func (b *Builder) languageMap() []byte {
+	var ids []uint32
+	for id := range b.languages {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return b.languages[ids[i]] < b.languages[ids[j]]
+	})
+
+	m := make([]byte, 4+8*len(ids))
+	binary.LittleEndian.PutUint32(m, uint32(len(ids)))
+	for i, id := range ids {
+		binary.LittleEndian.PutUint32(m[4+8*i:], uint32(len(m)))
+		binary.LittleEndian.PutUint32(m[8+8*i:], id)
+		for _, c := range utf16.Encode([]rune(b.languages[id])) {
+			m = append(m, byte(c), byte(c>>8))
+		}
+		m = append(m, 0, 0)
+	}
+	for len(m)%4 != 0 {
+		m = append(m, 0)
+	}
+	return m
+}
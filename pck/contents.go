@@ -0,0 +1,132 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// The number of bytes used to describe an entry whose ID is 64 bits long, as
+// the entries of the externals table are.
+const WIDE_DATA_INDEX_BYTES = DATA_INDEX_BYTES + 4
+
+// An Entry describes a file listed by one of the file tables of a File
+// Package.
+type Entry struct {
+	// The ID of the file. Only the entries of the externals table, and of
+	// tables written with 64-bit IDs, use more than 32 bits.
+	Id uint64
+	// The size in bytes of the blocks that the offset of the file is counted in.
+	BlockSize uint32
+	Length    uint32
+	// The number of blocks from the start of the File Package that the file
+	// begins at.
+	StartBlock uint32
+	// The ID of the language of the file, as listed by the language map.
+	LanguageId uint32
+}
+
+// Offset returns the offset in bytes from the start of the File Package that
+// the file begins at.
+func (e *Entry) Offset() int64 {
+	return int64(e.StartBlock) * e.blockSize()
+}
+
+// blockSize returns the size in bytes of the blocks that the offset of the
+// file is counted in.
+func (e *Entry) blockSize() int64 {
+	if e.BlockSize == 0 {
+		return 1
+	}
+	return int64(e.BlockSize)
+}
+
+// encode writes e to b, with a 64-bit ID if wide is true.
+func (e *Entry) encode(b []byte, wide bool) {
+	if wide {
+		binary.LittleEndian.PutUint64(b, e.Id)
+		b = b[8:]
+	} else {
+		binary.LittleEndian.PutUint32(b, uint32(e.Id))
+		b = b[4:]
+	}
+	binary.LittleEndian.PutUint32(b[0:], e.BlockSize)
+	binary.LittleEndian.PutUint32(b[4:], e.Length)
+	binary.LittleEndian.PutUint32(b[8:], e.StartBlock)
+	binary.LittleEndian.PutUint32(b[12:], e.LanguageId)
+}
+
+// Contents describes every file listed by the header of a File Package.
+type Contents struct {
+	Version uint32
+	// A mapping from the ID of each language of the File Package to its name.
+	Languages map[uint32]string
+	// The SoundBanks, streamed files and external files of the File Package.
+	SoundBanks []*Entry
+	Streamed   []*Entry
+	Externals  []*Entry
+}
+
+// ListContents reads the language map and every file table of the File
+// Package read from r, without reading the files themselves.
+func ListContents(r io.ReaderAt) (*Contents, error) {
+	sr := io.NewSectionReader(r, 0, math.MaxInt64)
+	hdr, err := NewHeader(sr)
+	if err != nil {
+		return nil, err
+	}
+	tables, _, err := readFileTables(sr, hdr)
+	if err != nil {
+		return nil, err
+	}
+	return &Contents{hdr.Version, hdr.Languages(), tables[SoundBankTable],
+		tables[StreamedTable], tables[ExternalTable]}, nil
+}
+
+// parseFileTable decodes the file table t, and returns whether its entries
+// use 64-bit IDs. If wide is false, the width of the IDs is found from the size
+// of the table.
+func parseFileTable(t []byte, wide bool) ([]*Entry, bool, error) {
+	if len(t) == 0 {
+		return nil, wide, nil
+	}
+	if len(t) < 4 {
+		return nil, wide, errors.New("A file table is too short to hold its count")
+	}
+	count := uint64(binary.LittleEndian.Uint32(t))
+	t = t[4:]
+	if count > 0 && uint64(len(t)) == count*WIDE_DATA_INDEX_BYTES {
+		wide = true
+	}
+	entryBytes := uint64(DATA_INDEX_BYTES)
+	if wide {
+		entryBytes = WIDE_DATA_INDEX_BYTES
+	}
+	if uint64(len(t)) < count*entryBytes {
+		msg := fmt.Sprintf("A file table of %d bytes cannot hold %d entries",
+			len(t)+4, count)
+		return nil, wide, errors.New(msg)
+	}
+
+	var entries []*Entry
+	for i := uint64(0); i < count; i++ {
+		b := t[i*entryBytes:]
+		e := new(Entry)
+		if wide {
+			e.Id = binary.LittleEndian.Uint64(b)
+			b = b[8:]
+		} else {
+			e.Id = uint64(binary.LittleEndian.Uint32(b))
+			b = b[4:]
+		}
+		e.BlockSize = binary.LittleEndian.Uint32(b[0:])
+		e.Length = binary.LittleEndian.Uint32(b[4:])
+		e.StartBlock = binary.LittleEndian.Uint32(b[8:])
+		e.LanguageId = binary.LittleEndian.Uint32(b[12:])
+		entries = append(entries, e)
+	}
+	return entries, wide, nil
+}
@@ -2,12 +2,16 @@
 package pck
 
 import (
+	"bufio"
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"math"
 	"os"
+	"sort"
 	"strings"
 )
 
@@ -16,42 +20,106 @@ import (
 	"github.com/hpxro7/wwiseutil/wwise"
 )
 
-// The number of bytes used to describe the File Package header.
-const HEADER_BYTES = 4 + 4 + 44 + 4
+// The number of bytes of the fields of the File Package header that precede
+// its language map: the identifier, the length of the header, the version and
+// the sizes of the language map and of each file table.
+const HEADER_BYTES = 4 + 4 + 4 + 4*4
 
 // The number of bytes used to describe a single data index entry.
-const DATA_INDEX_BYTES = 4 + 4 + 4 + 4
+const DATA_INDEX_BYTES = 4 + 4 + 4 + 4 + 4
+
+// The most wems that are allocated for up front when a File Package is read.
+const maxPreallocatedWems = 1 << 16
+
+// ErrFileTooLarge is returned when a file cannot be replaced, because the
+// replacement, or the offset it moves a later file to, is too large to be
+// described by an entry.
+var ErrFileTooLarge = errors.New("The file is too large to be stored")
+
+// A Table identifies one of the file tables of a File Package.
+type Table int
+
+// The file tables of a File Package, in the order that the header lists them.
+const (
+	SoundBankTable Table = iota
+	StreamedTable
+	ExternalTable
+)
 
 // A File represents an open Wwise File Package.
 type File struct {
-	closer  io.Closer
-	Header  *Header
+	closer io.Closer
+	Header *Header
+	// The entries of the SoundBank table.
+	SoundBanks []*Entry
+	// The data indexes of the streamed files, which are the wems of this File
+	// Package.
 	Indexes []*DataIndex
-	Padding uint32
-	wems    []*wwise.Wem
+	// The entries of the external file table.
+	Externals []*Entry
+	wems      []*wwise.Wem
+	// Every file of the File Package, in the order that they are stored, and
+	// the files of each table, in the order that the table lists them.
+	files  []*storedFile
+	tables [3][]*storedFile
+	// Whether each table uses 64-bit IDs.
+	wide [3]bool
+	// The number of bytes between the end of the header and the first file.
+	leading int64
+	// True if the padding that follows a replaced wem keeps its original bytes.
+	preservePadding bool
+}
+
+// A storedFile is the data of a file of a File Package, along with the entry
+// of the file table that describes it.
+type storedFile struct {
+	table Table
+	index int
+	data  *wwise.Wem
 }
 
 // A Header represents a single Wwise File Package header.
 type Header struct {
 	Identifier [4]byte
-	Length     uint32
-	Unknown    [44]byte
-	WemCount   uint32
+	// The number of bytes of the header that follow this field.
+	Length  uint32
+	Version uint32
+	// The sizes in bytes of the language map and of each file table, which
+	// follow one another after the fixed fields of the header.
+	LanguageMapSize uint32
+	TableSizes      [3]uint32
+	// The encoded language map, as decoded by Languages.
+	LanguageMap []byte
 }
 
 // A DataIndex represents location and properties of a file within a File
 // Package.
 type DataIndex struct {
-	// The type of data contained at this location.
-	Type uint32
-	// A descriptor of the wem contained at this location, if it is a wem.
+	// The size in bytes of the blocks that the offset of the file is counted in.
+	// Offsets are stored as a number of blocks so that File Packages larger than
+	// 4 GB can address every file. A block size of 0 is read as 1.
+	BlockSize uint32
+	// A descriptor of the wem contained at this location, if it is a wem. The
+	// offset of the descriptor only holds the low 32 bits of the offset of files
+	// that begin past 4 GB; Offset returns the full offset.
 	Descriptor *wwise.WemDescriptor
-	Unknown    uint32
+	// The number of blocks from the start of the File Package that the file
+	// begins at.
+	StartBlock uint32
+	// The ID of the language of the file, as listed by the Languages of the
+	// header.
+	LanguageId uint32
 }
 
 // NewFile creates a new File for access Wwise File Package files. The file is
 // expected to start at position 0 in the io.ReaderAt.
 func NewFile(r io.ReaderAt) (*File, error) {
+	return NewFileContext(context.Background(), r)
+}
+
+// NewFileContext is like NewFile, but stops reading and returns ctx.Err() if
+// ctx is done before every wem has been indexed.
+func NewFileContext(ctx context.Context, r io.ReaderAt) (*File, error) {
 	pck := new(File)
 	sr := io.NewSectionReader(r, 0, math.MaxInt64)
 
@@ -60,72 +128,204 @@ func NewFile(r io.ReaderAt) (*File, error) {
 		return nil, err
 	}
 	pck.Header = hdr
-
-	// Read in the data index.
-	for i := uint32(0); i < pck.Header.WemCount; i++ {
-		idx, err := NewDataIndex(sr)
-		if err != nil {
-			return nil, err
-		}
-		pck.Indexes = append(pck.Indexes, idx)
+	var tables [3][]*Entry
+	tables, pck.wide, err = readFileTables(sr, hdr)
+	if err != nil {
+		return nil, err
 	}
-
-	var padding uint32
-	err = binary.Read(sr, binary.LittleEndian, &padding)
+	pck.SoundBanks, pck.Externals = tables[SoundBankTable], tables[ExternalTable]
+	pck.Indexes, err = dataIndexes(tables[StreamedTable])
 	if err != nil {
 		return nil, err
 	}
-	pck.Padding = padding
-
-	// Read in the data contained within this File Package
-	for i, idx := range pck.Indexes {
-		var nextOffset uint32
-		if i+1 < len(pck.Indexes) {
-			// There is a subsequent wem, use it to find the next offset.
-			nextOffset = pck.Indexes[i+1].Descriptor.Offset
-		} else {
-			// This is the last wem, the next offset will be the end of the wem.
-			nextOffset = idx.Descriptor.Length + idx.Descriptor.Offset
+	// The wem count of a corrupt header cannot be trusted, so only allocate up
+	// front for as many wems as a large File Package holds.
+	capacity := len(pck.Indexes)
+	if capacity > maxPreallocatedWems {
+		capacity = maxPreallocatedWems
+	}
+	pck.wems = make([]*wwise.Wem, 0, capacity)
+
+	// Files are read in the order that they are stored, which need not be the
+	// order of their tables.
+	var offsets []int64
+	for t := range tables {
+		pck.tables[t] = make([]*storedFile, len(tables[t]))
+		for i := range tables[t] {
+			f := &storedFile{table: Table(t), index: i}
+			pck.tables[t][i] = f
+			pck.files = append(pck.files, f)
 		}
+	}
+	for _, f := range pck.files {
+		offsets = append(offsets, pck.entryOffset(f))
+	}
+	sort.Stable(byOffset{pck.files, offsets})
+
+	// If there is a subsequent file holding data, use it to find the next
+	// offset of each file. Otherwise, the next offset will be the end of the
+	// file.
+	nextOffsets := make([]int64, len(pck.files))
+	hasNext := false
+	var next int64
+	for i := len(pck.files) - 1; i >= 0; i-- {
+		length := pck.entryLength(pck.files[i])
+		nextOffsets[i] = offsets[i] + int64(length)
+		if hasNext {
+			nextOffsets[i] = next
+		}
+		if length > 0 {
+			next, hasNext = offsets[i], true
+		}
+	}
 
-		wem, err := newWem(sr, idx, nextOffset)
+	pck.leading = -1
+	end := hdr.Size()
+	for i, f := range pck.files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		length := pck.entryLength(f)
+		if length > 0 && pck.leading < 0 {
+			if offsets[i] < end {
+				msg := fmt.Sprintf("File %d begins at offset %d, within the header "+
+					"that ends at offset %d", pck.entryId(f), offsets[i], end)
+				return nil, errors.New(msg)
+			}
+			pck.leading = offsets[i] - end
+		}
+		f.data, err = newWem(sr, pck.descriptorOf(f), offsets[i], nextOffsets[i])
 		if err != nil {
 			return nil, err
 		}
-		pck.wems = append(pck.wems, wem)
+	}
+	if pck.leading < 0 {
+		pck.leading = 0
+	}
+	for _, f := range pck.tables[StreamedTable] {
+		pck.wems = append(pck.wems, f.data)
 	}
 
 	return pck, nil
 }
 
+// byOffset sorts files by their offsets.
+type byOffset struct {
+	files   []*storedFile
+	offsets []int64
+}
+
+func (s byOffset) Len() int           { return len(s.files) }
+func (s byOffset) Less(i, j int) bool { return s.offsets[i] < s.offsets[j] }
+func (s byOffset) Swap(i, j int) {
+	s.files[i], s.files[j] = s.files[j], s.files[i]
+	s.offsets[i], s.offsets[j] = s.offsets[j], s.offsets[i]
+}
+
+// dataIndexes returns the data index of every entry of the streamed file
+// table.
+func dataIndexes(entries []*Entry) ([]*DataIndex, error) {
+	var idxs []*DataIndex
+	for _, e := range entries {
+		if e.Id > math.MaxUint32 {
+			msg := fmt.Sprintf("The streamed file %d has an ID that does not fit "+
+				"in 32 bits", e.Id)
+			return nil, errors.New(msg)
+		}
+		idx := &DataIndex{e.BlockSize, nil, e.StartBlock, e.LanguageId}
+		idx.Descriptor = &wwise.WemDescriptor{uint32(e.Id), uint32(idx.Offset()),
+			e.Length}
+		idxs = append(idxs, idx)
+	}
+	return idxs, nil
+}
+
+// ListWems returns the descriptor of every wem of the File Package read from r,
+// in the order they are stored. Only the header and data indexes are read, so
+// this is much faster than NewFile for listing the wems of a large File
+// Package.
+func ListWems(r io.ReaderAt) ([]*wwise.WemDescriptor, error) {
+	idxs, err := ListIndexes(r)
+	if err != nil {
+		return nil, err
+	}
+	var descs []*wwise.WemDescriptor
+	for _, idx := range idxs {
+		descs = append(descs, idx.Descriptor)
+	}
+	return descs, nil
+}
+
+// ListIndexes is like ListWems, but returns the data index of every wem, which
+// also describes offsets past 4 GB.
+func ListIndexes(r io.ReaderAt) ([]*DataIndex, error) {
+	sr := io.NewSectionReader(r, 0, math.MaxInt64)
+	hdr, err := NewHeader(sr)
+	if err != nil {
+		return nil, err
+	}
+	tables, _, err := readFileTables(sr, hdr)
+	if err != nil {
+		return nil, err
+	}
+	return dataIndexes(tables[StreamedTable])
+}
+
 // WriteTo writes the full contents of this File to the Writer specified by w.
 func (pck *File) WriteTo(w io.Writer) (written int64, err error) {
-	written, err = pck.Header.WriteTo(w)
+	// The header and indexes are made of many small fields, so buffer them to
+	// avoid a write to w for each.
+	bw := bufio.NewWriterSize(w, util.COPY_BUFFER_BYTES)
+	written, err = pck.writeTo(bw)
 	if err != nil {
 		return
 	}
+	return written, bw.Flush()
+}
 
-	for _, idx := range pck.Indexes {
-		n, err := idx.WriteTo(w)
-		if err != nil {
-			return written, err
-		}
-		written += n
+// WriteToAt writes the full contents of this File to w, as WriteTo would write
+// them, and returns the number of bytes written. As the size of every wem is
+// known up front, the wems are written out of order by as many goroutines as
+// specified by threads, which speeds up writing large File Packages to fast
+// storage. At least one goroutine is used.
+func (pck *File) WriteToAt(w io.WriterAt, threads int) (written int64,
+	err error) {
+	err = pck.layOut()
+	if err != nil {
+		return
+	}
+	// Everything before the files is written as WriteTo would write it.
+	bw := bufio.NewWriterSize(io.NewOffsetWriter(w, 0), util.COPY_BUFFER_BYTES)
+	written, err = pck.writeHeader(bw)
+	if err == nil {
+		err = bw.Flush()
 	}
+	if err != nil {
+		return
+	}
+
+	n, err := wwise.WriteWemsAt(w, written, pck.storedData(), threads)
+	return written + n, err
+}
 
-	err = binary.Write(w, binary.LittleEndian, pck.Padding)
+// writeTo writes the full contents of this File to w.
+func (pck *File) writeTo(w io.Writer) (written int64, err error) {
+	err = pck.layOut()
+	if err != nil {
+		return
+	}
+	written, err = pck.writeHeader(w)
 	if err != nil {
 		return
 	}
-	written += int64(4)
 
-	for _, wem := range pck.wems {
-		n, err := io.Copy(w, wem)
+	for _, f := range pck.files {
+		n, err := f.data.WriteTo(w)
 		if err != nil {
 			return written, err
 		}
 		written += int64(n)
-		n, err = io.Copy(w, wem.Padding)
+		n, err = util.CopyAll(w, f.data.Padding)
 		if err != nil {
 			return written, err
 		}
@@ -135,6 +335,157 @@ func (pck *File) WriteTo(w io.Writer) (written int64, err error) {
 	return written, nil
 }
 
+// writeHeader writes the header, the file tables and the bytes that precede
+// the first file to w.
+func (pck *File) writeHeader(w io.Writer) (written int64, err error) {
+	written, err = pck.Header.WriteTo(w)
+	if err != nil {
+		return
+	}
+	for t, files := range pck.tables {
+		size := pck.Header.TableSizes[t]
+		if size == 0 {
+			continue
+		}
+		table := make([]byte, size)
+		binary.LittleEndian.PutUint32(table, uint32(len(files)))
+		entryBytes := DATA_INDEX_BYTES
+		if pck.wide[t] {
+			entryBytes = WIDE_DATA_INDEX_BYTES
+		}
+		for i, f := range files {
+			pck.entryOf(f).encode(table[4+i*entryBytes:], pck.wide[t])
+		}
+		n, err := w.Write(table)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	n, err := util.CopyAll(w, io.NewSectionReader(&util.InfiniteReaderAt{0}, 0,
+		pck.leading))
+	return written + n, err
+}
+
+// layOut updates the start block of every file that holds data to where it is
+// written, which may have moved since the File Package was read if files were
+// replaced. Offsets are computed in 64 bits, so that files may begin past 4 GB.
+func (pck *File) layOut() error {
+	offset := pck.Header.Size() + pck.leading
+	for _, f := range pck.files {
+		e := pck.entryOf(f)
+		// An empty file is not written, so its offset is kept as it was read.
+		if f.data.Descriptor.Length > 0 {
+			blockSize := e.blockSize()
+			if offset%blockSize != 0 {
+				msg := fmt.Sprintf("File %d begins at offset %d, which is not a "+
+					"multiple of its block size of %d bytes", e.Id, offset, blockSize)
+				return errors.New(msg)
+			}
+			if offset/blockSize > math.MaxUint32 {
+				msg := fmt.Sprintf("File %d begins at offset %d, which cannot be "+
+					"addressed with its block size of %d bytes", e.Id, offset,
+					blockSize)
+				return errors.New(msg)
+			}
+			pck.setStartBlock(f, uint32(offset/blockSize))
+			f.data.Descriptor.Offset = uint32(offset)
+		}
+		pck.setLength(f, f.data.Descriptor.Length)
+		offset += int64(f.data.Descriptor.Length) + f.data.Padding.Size()
+	}
+	return nil
+}
+
+// Size returns the number of bytes that WriteTo would write.
+func (pck *File) Size() int64 {
+	size := pck.Header.Size() + pck.leading
+	for _, f := range pck.files {
+		size += int64(f.data.Descriptor.Length) + f.data.Padding.Size()
+	}
+	return size
+}
+
+// storedData returns the data of every file, in the order that they are
+// stored.
+func (pck *File) storedData() []*wwise.Wem {
+	data := make([]*wwise.Wem, len(pck.files))
+	for i, f := range pck.files {
+		data[i] = f.data
+	}
+	return data
+}
+
+// entryOf returns a copy of the entry that describes f.
+func (pck *File) entryOf(f *storedFile) *Entry {
+	switch f.table {
+	case SoundBankTable:
+		e := *pck.SoundBanks[f.index]
+		return &e
+	case ExternalTable:
+		e := *pck.Externals[f.index]
+		return &e
+	}
+	idx := pck.Indexes[f.index]
+	return &Entry{uint64(idx.Descriptor.WemId), idx.BlockSize,
+		idx.Descriptor.Length, idx.StartBlock, idx.LanguageId}
+}
+
+// entryId, entryOffset and entryLength return the ID, offset and length of
+// the file f, as its entry describes it.
+func (pck *File) entryId(f *storedFile) uint64 {
+	return pck.entryOf(f).Id
+}
+
+func (pck *File) entryOffset(f *storedFile) int64 {
+	return pck.entryOf(f).Offset()
+}
+
+func (pck *File) entryLength(f *storedFile) uint32 {
+	return pck.entryOf(f).Length
+}
+
+// descriptorOf returns the descriptor of the data of f. Streamed files share
+// the descriptor of their data index.
+func (pck *File) descriptorOf(f *storedFile) *wwise.WemDescriptor {
+	if f.table == StreamedTable {
+		return pck.Indexes[f.index].Descriptor
+	}
+	e := pck.entryOf(f)
+	return &wwise.WemDescriptor{uint32(e.Id), uint32(e.Offset()), e.Length}
+}
+
+// setStartBlock sets the start block of the entry that describes f.
+func (pck *File) setStartBlock(f *storedFile, startBlock uint32) {
+	switch f.table {
+	case SoundBankTable:
+		pck.SoundBanks[f.index].StartBlock = startBlock
+	case StreamedTable:
+		pck.Indexes[f.index].StartBlock = startBlock
+	case ExternalTable:
+		pck.Externals[f.index].StartBlock = startBlock
+	}
+}
+
+// setLength sets the length of the entry that describes f.
+func (pck *File) setLength(f *storedFile, length uint32) {
+	switch f.table {
+	case SoundBankTable:
+		pck.SoundBanks[f.index].Length = length
+	case StreamedTable:
+		pck.Indexes[f.index].Descriptor.Length = length
+	case ExternalTable:
+		pck.Externals[f.index].Length = length
+	}
+}
+
+// WriteToContext is like WriteTo, but stops writing and returns ctx.Err() if
+// ctx is done before the full contents have been written.
+func (pck *File) WriteToContext(ctx context.Context,
+	w io.Writer) (written int64, err error) {
+	return pck.WriteTo(util.NewContextWriter(ctx, w))
+}
+
 // Open opens the File at the specified path using os.Open and prepares it for
 // use as a Wwise File Package file.
 func Open(path string) (*File, error) {
@@ -151,6 +502,23 @@ func Open(path string) (*File, error) {
 	return pck, nil
 }
 
+// OpenMapped is like Open, but maps the File into memory rather than reading
+// it with system calls, which speeds up reading very large File Packages. The
+// file must not be modified while it is open.
+func OpenMapped(path string) (*File, error) {
+	m, err := util.OpenMapped(path)
+	if err != nil {
+		return nil, err
+	}
+	pck, err := NewFile(m)
+	if err != nil {
+		m.Close()
+		return nil, err
+	}
+	pck.closer = m
+	return pck, nil
+}
+
 // Close closes the File
 // If the File was created using NewFile directly instead of Open,
 // Close has no effect.
@@ -168,7 +536,98 @@ func (pck *File) Wems() []*wwise.Wem {
 }
 
 func (pck *File) ReplaceWems(rs ...*wwise.ReplacementWem) {
-	wwise.ReplaceWems(pck, 0, rs...)
+	pck.ReplaceFiles(StreamedTable, rs...)
+}
+
+// ReplaceFiles is like ReplaceWems, but replaces files of the given table. The
+// WemIndex of each replacement is the index of a file within that table. Files
+// that follow a replaced file are moved, whichever table lists them.
+func (pck *File) ReplaceFiles(t Table, rs ...*wwise.ReplacementWem) {
+	opts := wwise.ReplaceOptions{Alignment: pck.alignment(),
+		PreservePadding: pck.preservePadding}
+	positions := make(map[*storedFile]int)
+	for i, f := range pck.files {
+		positions[f] = i
+	}
+	var stored []*wwise.ReplacementWem
+	for _, r := range rs {
+		s := *r
+		s.WemIndex = positions[pck.tables[t][r.WemIndex]]
+		stored = append(stored, &s)
+	}
+	wwise.ReplaceWemsWithOptions(storedFiles{pck}, opts, stored...)
+}
+
+// alignment returns the number of bytes that replaced files are padded to.
+// Files must begin on a block boundary, so this is the largest block size, of
+// which the smaller block sizes are expected to be factors.
+func (pck *File) alignment() int64 {
+	alignment := int64(0)
+	for _, f := range pck.files {
+		blockSize := pck.entryOf(f).blockSize()
+		if blockSize > 1 && blockSize > alignment {
+			alignment = blockSize
+		}
+	}
+	return alignment
+}
+
+// CheckReplacements returns an error if the wems of this File Package cannot
+// be replaced with rs. The error wraps ErrFileTooLarge if a replacement is too
+// large to be described by a data index, or would move a file past the offsets
+// its entry can address.
+func (pck *File) CheckReplacements(rs ...*wwise.ReplacementWem) error {
+	return pck.CheckFiles(StreamedTable, rs...)
+}
+
+// CheckFiles is like CheckReplacements, but checks replacements of files of the
+// given table, as ReplaceFiles takes them.
+func (pck *File) CheckFiles(t Table, rs ...*wwise.ReplacementWem) error {
+	alignment := pck.alignment()
+	growth := make(map[*storedFile]int64)
+	for _, r := range rs {
+		if r.WemIndex < 0 || r.WemIndex >= len(pck.tables[t]) {
+			msg := fmt.Sprintf("There is no file at index %d to replace", r.WemIndex)
+			return errors.New(msg)
+		}
+		f := pck.tables[t][r.WemIndex]
+		if r.Length > math.MaxUint32 {
+			return fmt.Errorf("%w: the replacement for file %d is %d bytes long",
+				ErrFileTooLarge, pck.entryId(f), r.Length)
+		}
+		// Account for the worst case of the replacement needing a full alignment
+		// of padding.
+		growth[f] = r.Length - int64(f.data.Descriptor.Length) + alignment
+	}
+	moved := int64(0)
+	for _, f := range pck.files {
+		e := pck.entryOf(f)
+		if f.data.Descriptor.Length > 0 && moved > 0 &&
+			(e.Offset()+moved)/e.blockSize() > math.MaxUint32 {
+			return fmt.Errorf("%w: file %d would begin at offset %d, which "+
+				"cannot be addressed with its block size of %d bytes",
+				ErrFileTooLarge, e.Id, e.Offset()+moved, e.blockSize())
+		}
+		moved += growth[f]
+	}
+	return nil
+}
+
+// storedFiles is a File whose wems are every file it stores, in the order that
+// they are stored.
+type storedFiles struct {
+	*File
+}
+
+func (s storedFiles) Wems() []*wwise.Wem {
+	return s.storedData()
+}
+
+// SetPreservePadding sets whether the padding that follows a wem replaced in
+// this File Package keeps its original bytes, rather than being filled with
+// zeroes.
+func (pck *File) SetPreservePadding(preserve bool) {
+	pck.preservePadding = preserve
 }
 
 func (pck *File) DataStart() uint32 {
@@ -178,74 +637,185 @@ func (pck *File) DataStart() uint32 {
 func (pck *File) String() string {
 	b := new(strings.Builder)
 
-	tableParams := []string{"%-7", "%-15", "%-15", "%-8", "\n"}
+	tableParams := []string{"%-7", "%-15", "%-15", "%-8", "%-12", "\n"}
 	titleFmt := strings.Join(tableParams, "s|")
-	wemFmt := strings.Join(tableParams, "d|")
+	wemFmt := strings.Join(tableParams[:5], "d|") + "s|" + tableParams[5]
 	title := fmt.Sprintf(titleFmt,
-		"Index", "Id", "Offset", "Length")
+		"Index", "Id", "Offset", "Length", "Language")
 	fmt.Fprint(b, title)
 	fmt.Fprintln(b, strings.Repeat("-", len(title)-1))
 
+	languages := pck.Header.Languages()
 	for i, idx := range pck.Indexes {
 		desc := idx.Descriptor
+		language, ok := languages[idx.LanguageId]
+		if !ok {
+			language = fmt.Sprint(idx.LanguageId)
+		}
 
-		fmt.Fprintf(b, wemFmt, i+1, desc.WemId, desc.Offset, desc.Length)
+		fmt.Fprintf(b, wemFmt, i+1, desc.WemId, idx.Offset(), desc.Length,
+			language)
 	}
 
 	return b.String()
 }
 
+// NewHeader reads the fixed fields and the language map of a File Package
+// header from sr, which is left at the start of the file tables.
 func NewHeader(sr util.ReadSeekerAt) (*Header, error) {
 	hdr := new(Header)
-	err := binary.Read(sr, binary.LittleEndian, hdr)
+	for _, field := range []interface{}{&hdr.Identifier, &hdr.Length,
+		&hdr.Version, &hdr.LanguageMapSize, &hdr.TableSizes} {
+		err := binary.Read(sr, binary.LittleEndian, field)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if string(hdr.Identifier[:]) != "AKPK" {
+		return nil, errors.New("The file does not begin with AKPK")
+	}
+	total := uint64(hdr.LanguageMapSize)
+	for _, size := range hdr.TableSizes {
+		total += uint64(size)
+	}
+	// The header length does not include the identifier and itself.
+	if total+HEADER_BYTES-8 > uint64(hdr.Length) {
+		msg := fmt.Sprintf("The tables of the header take %d bytes, but the "+
+			"header is only %d bytes long", total, hdr.Length)
+		return nil, errors.New(msg)
+	}
+	var err error
+	hdr.LanguageMap, err = readFull(sr, hdr.LanguageMapSize)
 	if err != nil {
 		return nil, err
 	}
 	return hdr, nil
 }
 
-func (hdr *Header) WriteTo(w io.Writer) (written int64, err error) {
-	err = binary.Write(w, binary.LittleEndian, hdr)
+// readFull reads exactly n bytes from r. Memory is only allocated as the bytes
+// are read, so that a corrupt size does not allocate a large buffer up front.
+func readFull(r io.Reader, n uint32) ([]byte, error) {
+	b, err := ioutil.ReadAll(io.LimitReader(r, int64(n)))
 	if err != nil {
-		return
+		return nil, err
 	}
-	written = int64(HEADER_BYTES)
-	return
+	if len(b) != int(n) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return b, nil
 }
 
-func NewDataIndex(sr util.ReadSeekerAt) (*DataIndex, error) {
-	var id uint32
-	err := binary.Read(sr, binary.LittleEndian, &id)
-	if err != nil {
-		return nil, err
+// readFileTables reads the file tables described by hdr from r, and returns
+// their entries along with whether each table uses 64-bit IDs.
+func readFileTables(r io.Reader, hdr *Header) (tables [3][]*Entry,
+	wide [3]bool, err error) {
+	for t, size := range hdr.TableSizes {
+		b, err := readFull(r, size)
+		if err != nil {
+			return tables, wide, err
+		}
+		// The externals table always uses 64-bit IDs.
+		tables[t], wide[t], err = parseFileTable(b, Table(t) == ExternalTable)
+		if err != nil {
+			return tables, wide, err
+		}
 	}
+	return tables, wide, nil
+}
 
-	var dataType uint32
-	err = binary.Read(sr, binary.LittleEndian, &dataType)
-	if err != nil {
-		return nil, err
+// Size returns the number of bytes of the header, including its file tables.
+func (hdr *Header) Size() int64 {
+	size := int64(HEADER_BYTES) + int64(hdr.LanguageMapSize)
+	for _, s := range hdr.TableSizes {
+		size += int64(s)
 	}
+	return size
+}
 
-	var length uint32
-	err = binary.Read(sr, binary.LittleEndian, &length)
-	if err != nil {
-		return nil, err
+// Languages returns a mapping from the ID of each language listed by this
+// header to its name, such as sfx or english(us).
+func (hdr *Header) Languages() map[uint32]string {
+	return parseLanguageMap(hdr.LanguageMap)
+}
+
+// parseLanguageMap decodes the language map m into a mapping from the ID of
+// each language to its name.
+func parseLanguageMap(m []byte) map[uint32]string {
+	languages := make(map[uint32]string)
+	size := uint32(len(m))
+	if size < 4 {
+		return languages
+	}
+	count := binary.LittleEndian.Uint32(m)
+	for i := uint32(0); i < count && 4+8*(i+1) <= size; i++ {
+		entry := m[4+8*i:]
+		offset := binary.LittleEndian.Uint32(entry)
+		id := binary.LittleEndian.Uint32(entry[4:])
+		// Names are stored as zero terminated UTF-16 strings.
+		var name []rune
+		for j := offset; j+1 < size; j += 2 {
+			c := binary.LittleEndian.Uint16(m[j:])
+			if c == 0 {
+				break
+			}
+			name = append(name, rune(c))
+		}
+		languages[id] = string(name)
 	}
+	return languages
+}
 
-	var offset uint32
-	err = binary.Read(sr, binary.LittleEndian, &offset)
-	if err != nil {
-		return nil, err
+// WriteTo writes the fixed fields and the language map of this header to w.
+// The file tables are written by the File.
+func (hdr *Header) WriteTo(w io.Writer) (written int64, err error) {
+	for _, field := range []interface{}{hdr.Identifier, hdr.Length,
+		hdr.Version, hdr.LanguageMapSize, hdr.TableSizes} {
+		err = binary.Write(w, binary.LittleEndian, field)
+		if err != nil {
+			return
+		}
 	}
+	written = int64(HEADER_BYTES)
+	n, err := w.Write(hdr.LanguageMap)
+	return written + int64(n), err
+}
+
+func NewDataIndex(sr util.ReadSeekerAt) (*DataIndex, error) {
+	return readDataIndex(sr)
+}
 
-	var unknown uint32
-	err = binary.Read(sr, binary.LittleEndian, &unknown)
+// readDataIndex reads a DataIndex from r. The index is read at once and decoded
+// by hand, as a File Package may hold many thousands of them.
+func readDataIndex(r io.Reader) (*DataIndex, error) {
+	var b [DATA_INDEX_BYTES]byte
+	_, err := io.ReadFull(r, b[:])
 	if err != nil {
 		return nil, err
 	}
+	id := binary.LittleEndian.Uint32(b[0:])
+	blockSize := binary.LittleEndian.Uint32(b[4:])
+	length := binary.LittleEndian.Uint32(b[8:])
+	startBlock := binary.LittleEndian.Uint32(b[12:])
+	languageId := binary.LittleEndian.Uint32(b[16:])
+
+	idx := &DataIndex{blockSize, nil, startBlock, languageId}
+	idx.Descriptor = &wwise.WemDescriptor{id, uint32(idx.Offset()), length}
+	return idx, nil
+}
+
+// blockSize returns the size in bytes of the blocks that the offset of the
+// file is counted in.
+func (idx *DataIndex) blockSize() int64 {
+	if idx.BlockSize == 0 {
+		return 1
+	}
+	return int64(idx.BlockSize)
+}
 
-	desc := wwise.WemDescriptor{id, offset, length}
-	return &DataIndex{dataType, &desc, unknown}, nil
+// Offset returns the offset in bytes from the start of the File Package that
+// the file begins at.
+func (idx *DataIndex) Offset() int64 {
+	return int64(idx.StartBlock) * idx.blockSize()
 }
 
 // WriteTo writes the full contents of this DataIndex to the Writer specified by
@@ -257,7 +827,7 @@ func (idx *DataIndex) WriteTo(w io.Writer) (written int64, err error) {
 	}
 	written = int64(4)
 
-	err = binary.Write(w, binary.LittleEndian, idx.Type)
+	err = binary.Write(w, binary.LittleEndian, idx.BlockSize)
 	if err != nil {
 		return
 	}
@@ -269,13 +839,13 @@ func (idx *DataIndex) WriteTo(w io.Writer) (written int64, err error) {
 	}
 	written += int64(4)
 
-	err = binary.Write(w, binary.LittleEndian, idx.Descriptor.Offset)
+	err = binary.Write(w, binary.LittleEndian, idx.StartBlock)
 	if err != nil {
 		return
 	}
 	written += int64(4)
 
-	err = binary.Write(w, binary.LittleEndian, idx.Unknown)
+	err = binary.Write(w, binary.LittleEndian, idx.LanguageId)
 	if err != nil {
 		return
 	}
@@ -284,21 +854,26 @@ func (idx *DataIndex) WriteTo(w io.Writer) (written int64, err error) {
 	return written, nil
 }
 
-func newWem(sr util.ReadSeekerAt, idx *DataIndex,
-	nextOffset uint32) (*wwise.Wem, error) {
-	startOffset, _ := sr.Seek(0, io.SeekCurrent)
-	desc := idx.Descriptor
-	if uint32(startOffset) != desc.Offset {
-		msg := fmt.Sprintf("Wem %d was expected to start at offset %d "+
-			"but instead started at offset %d", desc.WemId, desc.Offset, startOffset)
-		return nil, errors.New(msg)
+// newWem returns the file described by desc, which begins at offset and is
+// followed by padding up to nextOffset. The padding is read from sr, so that
+// its original bytes are kept.
+func newWem(sr util.ReadSeekerAt, desc *wwise.WemDescriptor, offset,
+	nextOffset int64) (*wwise.Wem, error) {
+	if desc.Length == 0 {
+		// An empty file holds no data, so its offset does not matter.
+		empty := util.NewResettingReader(sr, offset, 0)
+		return &wwise.Wem{empty, desc, empty}, nil
 	}
 
-	wemReader := util.NewResettingReader(sr, startOffset, int64(desc.Length))
-	wemEndOffset := startOffset + int64(desc.Length)
-	remaining := int64(nextOffset) - wemEndOffset
+	wemReader := util.NewResettingReader(sr, offset, int64(desc.Length))
+	wemEndOffset := offset + int64(desc.Length)
+	remaining := nextOffset - wemEndOffset
+	if remaining < 0 {
+		msg := fmt.Sprintf("File %d overlaps with the file that follows it",
+			desc.WemId)
+		return nil, errors.New(msg)
+	}
 
-	padding := util.NewResettingReader(&util.InfiniteReaderAt{0}, 0, remaining)
-	sr.Seek(int64(desc.Length)+remaining, io.SeekCurrent)
+	padding := util.NewResettingReader(sr, wemEndOffset, remaining)
 	return &wwise.Wem{wemReader, desc, padding}, nil
 }
@@ -4,12 +4,16 @@ package pck
 // Large system tests for the bnk package.
 import (
 	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
 )
 
 import (
+	"github.com/hpxro7/wwiseutil/bnk"
 	"github.com/hpxro7/wwiseutil/util"
 	"github.com/hpxro7/wwiseutil/wwise"
 )
@@ -17,8 +21,9 @@ import (
 const (
 	testDir = "testdata"
 
-	simpleFilePackage  = "simple.pck"
-	complexFilePackage = "complex.pck"
+	simpleFilePackage       = "simple.pck"
+	complexFilePackage      = "complex.pck"
+	multilingualFilePackage = "multilingual.pck"
 )
 
 func TestSimpleUnchangedFileIsEqual(t *testing.T) {
@@ -41,6 +46,60 @@ func unchangedFileIsEqual(name string, t *testing.T) {
 	wwise.AssertContainerEqualToFile(t, f, pck)
 }
 
+func TestMappedFileIsEqual(t *testing.T) {
+	util.SkipIfShort(t)
+
+	pck, err := OpenMapped(filepath.Join(testDir, simpleFilePackage))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer pck.Close()
+	f, err := os.Open(filepath.Join(testDir, simpleFilePackage))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	wwise.AssertContainerEqualToFile(t, f, pck)
+}
+
+func TestWriteToAtMatchesFile(t *testing.T) {
+	util.SkipIfShort(t)
+
+	path := filepath.Join(testDir, complexFilePackage)
+	pck, err := Open(path)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer pck.Close()
+	out, err := ioutil.TempFile("", "write-at-*.pck")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer os.Remove(out.Name())
+	defer out.Close()
+	_, err = pck.WriteToAt(out, 4)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	expected, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	actual, err := ioutil.ReadFile(out.Name())
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if !bytes.Equal(actual, expected) {
+		t.Error("Expected WriteToAt to write the File Package unchanged")
+	}
+}
+
 func TestUnchangedWriteFileTwiceIsEqual(t *testing.T) {
 	util.SkipIfShort(t)
 
@@ -116,3 +175,725 @@ func rereadFile(t *testing.T, org *File) *File {
 
 	return ctn
 }
+
+func TestSizeMatchesFileSize(t *testing.T) {
+	path := filepath.Join(testDir, simpleFilePackage)
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	pck, err := Open(path)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer pck.Close()
+
+	if pck.Size() != fi.Size() {
+		t.Errorf("Expected a size of %d but got %d", fi.Size(), pck.Size())
+	}
+}
+
+func TestLanguages(t *testing.T) {
+	pck, err := Open(filepath.Join(testDir, complexFilePackage))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer pck.Close()
+
+	languages := pck.Header.Languages()
+	if len(languages) != 1 || languages[0] != "sfx" {
+		t.Errorf("Expected only the sfx language but got %v", languages)
+	}
+	for _, idx := range pck.Indexes {
+		if _, ok := languages[idx.LanguageId]; !ok {
+			t.Errorf("Wem %d has the unlisted language %d", idx.Descriptor.WemId,
+				idx.LanguageId)
+		}
+	}
+}
+
+func TestOffsetsPast4GB(t *testing.T) {
+	const blockSize = 2048
+	const length = 3 << 30
+	hdr := &Header{TableSizes: [3]uint32{0, 4 + 3*DATA_INDEX_BYTES, 0}}
+	start := hdr.Size()
+	pck := &File{Header: hdr}
+	for i := 0; i < 3; i++ {
+		idx := &DataIndex{BlockSize: blockSize,
+			Descriptor: &wwise.WemDescriptor{WemId: uint32(i + 1), Length: length}}
+		if i == 0 {
+			// The first wem follows the indexes, which do not end on a block, and
+			// ends where the second block aligned wem begins.
+			idx.BlockSize = 1
+			idx.Descriptor.Length = uint32(length - start)
+		}
+		pck.Indexes = append(pck.Indexes, idx)
+		data := util.NewResettingReader(&util.InfiniteReaderAt{0}, 0,
+			int64(idx.Descriptor.Length))
+		padding := util.NewResettingReader(&util.InfiniteReaderAt{0}, 0, 0)
+		f := &storedFile{StreamedTable, i, &wwise.Wem{data, idx.Descriptor,
+			padding}}
+		pck.files = append(pck.files, f)
+		pck.tables[StreamedTable] = append(pck.tables[StreamedTable], f)
+		pck.wems = append(pck.wems, f.data)
+	}
+	err := pck.layOut()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	expected := []int64{start, length, 2 * length}
+	for i, idx := range pck.Indexes {
+		if idx.Offset() != expected[i] {
+			t.Errorf("Expected wem %d to begin at offset %d but it begins at %d",
+				i+1, expected[i], idx.Offset())
+		}
+	}
+	if pck.Size() != 3*length {
+		t.Errorf("Expected a size of %d bytes but got %d", int64(3*length),
+			pck.Size())
+	}
+
+	// The start block of the last wem must be read back as its full offset.
+	b := new(bytes.Buffer)
+	_, err = pck.Indexes[2].WriteTo(b)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	idx, err := readDataIndex(b)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if idx.Offset() != 2*length {
+		t.Errorf("Expected the index to be read with offset %d but got %d",
+			int64(2*length), idx.Offset())
+	}
+}
+
+func TestCheckReplacements(t *testing.T) {
+	pck, err := Open(filepath.Join(testDir, complexFilePackage))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer pck.Close()
+
+	fits := &wwise.ReplacementWem{util.NewConstantReader(100), 0, 100}
+	if err := pck.CheckReplacements(fits); err != nil {
+		t.Errorf("Expected a small replacement to fit but got %q", err)
+	}
+	missing := &wwise.ReplacementWem{util.NewConstantReader(100),
+		len(pck.Wems()), 100}
+	if err := pck.CheckReplacements(missing); err == nil {
+		t.Error("Expected an error for a replacement of a wem that does not exist")
+	}
+	// The wems of the package have a block size of 1, so a wem that grows by 4 GB
+	// moves the wems after it past the offsets that can be addressed.
+	for _, length := range []int64{1 << 32, 1<<32 - 1} {
+		r := &wwise.ReplacementWem{util.NewConstantReader(length), 0, length}
+		if err := pck.CheckReplacements(r); !errors.Is(err, ErrFileTooLarge) {
+			t.Errorf("Expected a replacement of %d bytes to be too large but got "+
+				"%v", length, err)
+		}
+	}
+}
+
+func TestListContents(t *testing.T) {
+	path := filepath.Join(testDir, complexFilePackage)
+	pck, err := Open(path)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer pck.Close()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer f.Close()
+
+	c, err := ListContents(f)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if len(c.SoundBanks) != 0 || len(c.Externals) != 0 {
+		t.Errorf("Expected no SoundBanks or external files but got %d and %d",
+			len(c.SoundBanks), len(c.Externals))
+	}
+	if c.Languages[0] != "sfx" {
+		t.Errorf("Expected the sfx language but got %v", c.Languages)
+	}
+	if len(c.Streamed) != len(pck.Indexes) {
+		t.Errorf("Expected %d streamed files but got %d", len(pck.Indexes),
+			len(c.Streamed))
+		t.FailNow()
+	}
+	for i, e := range c.Streamed {
+		idx := pck.Indexes[i]
+		if e.Id != uint64(idx.Descriptor.WemId) || e.Offset() != idx.Offset() ||
+			e.Length != idx.Descriptor.Length {
+			t.Errorf("Expected streamed file %d to be %d at offset %d but got %d "+
+				"at offset %d", i+1, idx.Descriptor.WemId, idx.Offset(), e.Id,
+				e.Offset())
+		}
+	}
+}
+
+func TestPatchWemsInPlace(t *testing.T) {
+	org, err := ioutil.ReadFile(filepath.Join(testDir, complexFilePackage))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	f, err := ioutil.TempFile("", "patch-*.pck")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	_, err = f.Write(org)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	pck, err := NewFile(f)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	id := pck.Indexes[1].Descriptor.WemId
+	i, ok := pck.IndexOfWem(id)
+	if !ok || i != 1 {
+		t.Errorf("Expected wem %d to be found at index 1 but got %d", id, i)
+		t.FailNow()
+	}
+	wems := pck.Wems()
+	slot := int64(wems[i].Descriptor.Length) + wems[i].Padding.Size()
+	tooLarge := &wwise.ReplacementWem{util.NewConstantReader(slot + 1), i,
+		slot + 1}
+	if err := pck.PatchWems(f, tooLarge); !errors.Is(err, ErrDoesNotFit) {
+		t.Errorf("Expected a replacement that does not fit to be refused, "+
+			"but got: %v", err)
+	}
+
+	length := int64(wems[i].Descriptor.Length) / 2
+	err = pck.PatchWems(f, &wwise.ReplacementWem{util.NewConstantReader(length),
+		i, length})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	patched, err := NewFile(f)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	for j, wem := range patched.Wems() {
+		desc, orgDesc := *wem.Descriptor, *wems[j].Descriptor
+		if j == i {
+			orgDesc.Length = uint32(length)
+		}
+		if desc != orgDesc {
+			t.Errorf("Expected wem %d to be described by %v, but got %v", j,
+				orgDesc, desc)
+		}
+	}
+}
+
+func TestPreservePadding(t *testing.T) {
+	// Files of odd lengths in blocks of 16 bytes are followed by padding.
+	built := new(bytes.Buffer)
+	_, err := NewBuilder().AddLanguage(0, "sfx").SetBlockSize(16).
+		AddStreamed(FileSource{1, 0, util.NewConstantReader(9), 9, 0}).
+		AddStreamed(FileSource{2, 0, util.NewConstantReader(17), 17, 0}).
+		WriteTo(built)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	org := built.Bytes()
+	pck, err := NewFile(bytes.NewReader(org))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	// Fill the padding that follows the first file with non-zero bytes.
+	index := 0
+	wem := pck.Wems()[index]
+	if wem.Padding.Size() == 0 {
+		t.Error("Expected the first file to be followed by padding")
+		t.FailNow()
+	}
+	start := pck.Indexes[index].Offset()
+	end := start + int64(wem.Descriptor.Length)
+	padded := append([]byte(nil), org...)
+	for i := end; i < end+wem.Padding.Size(); i++ {
+		padded[i] = 0xAA
+	}
+
+	// The padding is kept by a File that is written unchanged.
+	unchanged, err := NewFile(bytes.NewReader(padded))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	output := new(bytes.Buffer)
+	_, err = unchanged.WriteTo(output)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if !bytes.Equal(output.Bytes(), padded) {
+		t.Error("Expected the padding of an unchanged file to be kept")
+	}
+
+	// The replacement has the same length, so only the file itself changes.
+	length := int64(wem.Descriptor.Length)
+	expected := append([]byte(nil), padded...)
+	copy(expected[start:end], bytes.Repeat([]byte{'A'}, int(length)))
+	for _, preserve := range []bool{true, false} {
+		p, err := NewFile(bytes.NewReader(padded))
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		p.SetPreservePadding(preserve)
+		p.ReplaceWems(&wwise.ReplacementWem{util.NewConstantReader(length), index,
+			length})
+		output := new(bytes.Buffer)
+		_, err = p.WriteTo(output)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		if bytes.Equal(output.Bytes(), expected) != preserve {
+			t.Errorf("Expected the padding to be preserved to be %t", preserve)
+		}
+	}
+}
+
+func TestBuilderRecreatesFile(t *testing.T) {
+	path := filepath.Join(testDir, complexFilePackage)
+	pck, err := Open(path)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer pck.Close()
+
+	builder := NewBuilder().AddLanguage(0, "sfx")
+	// Add the wems in reverse, as the Builder sorts them by ID.
+	for i := len(pck.Indexes) - 1; i >= 0; i-- {
+		desc := pck.Indexes[i].Descriptor
+		builder.AddStreamed(FileSource{uint64(desc.WemId), 0,
+			pck.Wems()[i].Reader.(io.ReaderAt), int64(desc.Length), 0})
+	}
+	actual := new(bytes.Buffer)
+	_, err = builder.WriteTo(actual)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	expected, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if !bytes.Equal(actual.Bytes(), expected) {
+		t.Error("Expected the built File Package to equal the original")
+	}
+
+	builder.SetBlockSize(2048).AddSoundBank(FileSource{1, 0,
+		bytes.NewReader([]byte("BKHD")), 4, 0}).AddExternal(FileSource{1 << 40,
+		0, bytes.NewReader([]byte("data")), 4, 0})
+	actual.Reset()
+	_, err = builder.WriteTo(actual)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	c, err := ListContents(bytes.NewReader(actual.Bytes()))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if len(c.SoundBanks) != 1 || len(c.Streamed) != len(pck.Indexes) ||
+		len(c.Externals) != 1 || c.Externals[0].Id != 1<<40 {
+		t.Errorf("Expected 1 SoundBank, %d streamed files and 1 external file, "+
+			"but got %d, %d and %d", len(pck.Indexes), len(c.SoundBanks),
+			len(c.Streamed), len(c.Externals))
+		t.FailNow()
+	}
+	bank := make([]byte, 4)
+	_, err = bytes.NewReader(actual.Bytes()).ReadAt(bank, c.SoundBanks[0].Offset())
+	if err != nil || string(bank) != "BKHD" || c.SoundBanks[0].Offset()%2048 != 0 {
+		t.Errorf("Expected the SoundBank to be stored on a block boundary, at "+
+			"offset %d", c.SoundBanks[0].Offset())
+	}
+}
+
+func TestMultilingualFile(t *testing.T) {
+	unchangedFileIsEqual(multilingualFilePackage, t)
+
+	path := filepath.Join(testDir, multilingualFilePackage)
+	pck, err := Open(path)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer pck.Close()
+
+	languages := pck.Header.Languages()
+	if len(languages) != 2 || languages[0] != "sfx" ||
+		languages[1] != "english(us)" {
+		t.Errorf("Expected the sfx and english(us) languages but got %v",
+			languages)
+	}
+	if len(pck.SoundBanks) != 1 || len(pck.Wems()) != 4 ||
+		len(pck.Externals) != 1 || pck.Externals[0].Id != 1<<40|7 {
+		t.Errorf("Expected 1 SoundBank, 4 wems and external file %d, but got "+
+			"%d, %d and %d file(s)", uint64(1<<40|7), len(pck.SoundBanks),
+			len(pck.Wems()), len(pck.Externals))
+		t.FailNow()
+	}
+	for i, idx := range pck.Indexes {
+		expected := uint32(0)
+		if i >= 2 {
+			expected = 1
+		}
+		if idx.LanguageId != expected {
+			t.Errorf("Expected wem %d to have the language %d but got %d", i+1,
+				expected, idx.LanguageId)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer f.Close()
+	idxs, err := ListIndexes(f)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	for i, idx := range idxs {
+		if *idx.Descriptor != *pck.Indexes[i].Descriptor {
+			t.Errorf("Expected index %d to be listed as %v but got %v", i+1,
+				*pck.Indexes[i].Descriptor, *idx.Descriptor)
+		}
+	}
+}
+
+func TestBuiltFileIsReadable(t *testing.T) {
+	sources := [][]byte{[]byte("BKHD bank"), []byte("RIFF streamed sfx"),
+		[]byte("RIFF streamed english"), []byte("RIFF external")}
+	builder := NewBuilder().AddLanguage(0, "sfx").AddLanguage(1, "english(us)").
+		SetBlockSize(16).
+		AddSoundBank(FileSource{10, 0, bytes.NewReader(sources[0]), 9, 0}).
+		AddStreamed(FileSource{20, 0, bytes.NewReader(sources[1]), 17, 0}).
+		AddStreamed(FileSource{20, 1, bytes.NewReader(sources[2]), 21, 0}).
+		AddExternal(FileSource{1 << 40, 1, bytes.NewReader(sources[3]), 13, 0})
+	built := new(bytes.Buffer)
+	_, err := builder.WriteTo(built)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	pck, err := NewFile(bytes.NewReader(built.Bytes()))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	c, err := ListContents(bytes.NewReader(built.Bytes()))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if len(c.Languages) != 2 || len(pck.Header.Languages()) != 2 {
+		t.Errorf("Expected 2 languages but got %v and %v", c.Languages,
+			pck.Header.Languages())
+	}
+	var entries []*Entry
+	entries = append(entries, pck.SoundBanks...)
+	for _, idx := range pck.Indexes {
+		entries = append(entries, &Entry{uint64(idx.Descriptor.WemId),
+			idx.BlockSize, idx.Descriptor.Length, idx.StartBlock, idx.LanguageId})
+	}
+	entries = append(entries, pck.Externals...)
+	var listed []*Entry
+	listed = append(listed, c.SoundBanks...)
+	listed = append(listed, c.Streamed...)
+	listed = append(listed, c.Externals...)
+	if len(entries) != len(sources) || len(listed) != len(sources) {
+		t.Errorf("Expected %d files but read %d and listed %d", len(sources),
+			len(entries), len(listed))
+		t.FailNow()
+	}
+	for i, e := range entries {
+		if *e != *listed[i] {
+			t.Errorf("Expected file %d to be read as %v but got %v", i+1,
+				*listed[i], *e)
+		}
+		data := make([]byte, e.Length)
+		_, err = bytes.NewReader(built.Bytes()).ReadAt(data, e.Offset())
+		if err != nil || !bytes.Equal(data, sources[i]) {
+			t.Errorf("Expected file %d to hold %q but got %q", i+1, sources[i],
+				data)
+		}
+	}
+
+	rewritten := new(bytes.Buffer)
+	_, err = pck.WriteTo(rewritten)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if !bytes.Equal(rewritten.Bytes(), built.Bytes()) {
+		t.Error("Expected the read File Package to be written unchanged")
+	}
+}
+
+func TestReadDataIndexesOfFile(t *testing.T) {
+	path := filepath.Join(testDir, complexFilePackage)
+	org, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	pck, err := NewFile(bytes.NewReader(org))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	// Every entry of the streamed file table of a Wwise File Package is 20
+	// bytes long: its ID, block size, length, start block and language ID.
+	hdr := pck.Header
+	size := hdr.TableSizes[StreamedTable]
+	if size != uint32(4+len(pck.Indexes)*DATA_INDEX_BYTES) {
+		t.Errorf("Expected a streamed file table of %d entries to be %d bytes "+
+			"long, but it is %d", len(pck.Indexes),
+			4+len(pck.Indexes)*DATA_INDEX_BYTES, size)
+	}
+	table := HEADER_BYTES + int64(hdr.LanguageMapSize) +
+		int64(hdr.TableSizes[SoundBankTable]) + 4
+	r := bytes.NewReader(org[table:])
+	for i, expected := range pck.Indexes {
+		idx, err := readDataIndex(r)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		if *idx.Descriptor != *expected.Descriptor ||
+			idx.BlockSize != expected.BlockSize ||
+			idx.LanguageId != expected.LanguageId {
+			t.Errorf("Expected index %d to be %v but got %v", i+1,
+				*expected.Descriptor, *idx.Descriptor)
+		}
+		if idx.Offset()+int64(idx.Descriptor.Length) > int64(len(org)) {
+			t.Errorf("Index %d describes a wem past the end of the file", i+1)
+		}
+	}
+	if pck.Indexes[0].Offset() != hdr.Size() {
+		t.Errorf("Expected the first wem to follow the header at offset %d, but "+
+			"it begins at %d", hdr.Size(), pck.Indexes[0].Offset())
+	}
+}
+
+func TestPatchEmbeddedSoundBank(t *testing.T) {
+	org, err := ioutil.ReadFile(filepath.Join(testDir, multilingualFilePackage))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	f, err := ioutil.TempFile("", "patch-*.pck")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	_, err = f.Write(org)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	// A SoundBank is found through the SoundBank table, not the streamed file
+	// table.
+	c, err := ListContents(f)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	e := c.SoundBanks[0]
+	b, err := bnk.NewFile(io.NewSectionReader(f, e.Offset(), int64(e.Length)))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	length := int64(b.Wems()[0].Descriptor.Length) / 2
+	err = b.PatchWems(io.NewOffsetWriter(f, e.Offset()),
+		&wwise.ReplacementWem{util.NewConstantReader(length), 0, length})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	patched, err := NewFile(f)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	e = patched.SoundBanks[0]
+	b, err = bnk.NewFile(io.NewSectionReader(f, e.Offset(), int64(e.Length)))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if int64(b.Wems()[0].Descriptor.Length) != length {
+		t.Errorf("Expected the wem of the SoundBank to be %d bytes long but it "+
+			"is %d", length, b.Wems()[0].Descriptor.Length)
+	}
+	for i, idx := range patched.Indexes {
+		start, end := idx.Offset(), idx.Offset()+int64(idx.Descriptor.Length)
+		actual := make([]byte, end-start)
+		_, err = f.ReadAt(actual, start)
+		if err != nil || !bytes.Equal(actual, org[start:end]) {
+			t.Errorf("Expected streamed file %d to be unchanged", i+1)
+		}
+	}
+}
+
+func TestReplaceSoundBankAndExternalFile(t *testing.T) {
+	path := filepath.Join(testDir, multilingualFilePackage)
+	pck, err := Open(path)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer pck.Close()
+	streamed := make([][]byte, len(pck.Wems()))
+	for i, wem := range pck.Wems() {
+		b := new(bytes.Buffer)
+		wem.WriteTo(b)
+		streamed[i] = b.Bytes()
+	}
+
+	// The SoundBank grows, which moves every file that follows it.
+	bank := bytes.Repeat([]byte("bank"), 25000)
+	external := []byte("a shorter external file")
+	pck.ReplaceFiles(SoundBankTable, &wwise.ReplacementWem{
+		bytes.NewReader(bank), 0, int64(len(bank))})
+	pck.ReplaceFiles(ExternalTable, &wwise.ReplacementWem{
+		bytes.NewReader(external), 0, int64(len(external))})
+	replaced := new(bytes.Buffer)
+	_, err = pck.WriteTo(replaced)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	c, err := ListContents(bytes.NewReader(replaced.Bytes()))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	read := func(e *Entry) []byte {
+		return replaced.Bytes()[e.Offset() : e.Offset()+int64(e.Length)]
+	}
+	if !bytes.Equal(read(c.SoundBanks[0]), bank) {
+		t.Error("Expected the SoundBank to be replaced")
+	}
+	if c.SoundBanks[0].Offset()%16 != 0 {
+		t.Errorf("Expected the SoundBank to stay on its block boundary, but it "+
+			"begins at offset %d", c.SoundBanks[0].Offset())
+	}
+	if !bytes.Equal(read(c.Externals[0]), external) {
+		t.Error("Expected the external file to be replaced")
+	}
+	for i, e := range c.Streamed {
+		if !bytes.Equal(read(e), streamed[i]) {
+			t.Errorf("Expected streamed file %d to be moved unchanged", i+1)
+		}
+	}
+}
+
+func TestPatchSoundBankAndExternalFile(t *testing.T) {
+	org, err := ioutil.ReadFile(filepath.Join(testDir, multilingualFilePackage))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	f, err := ioutil.TempFile("", "patch-*.pck")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	_, err = f.Write(org)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	pck, err := NewFile(f)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	bank, external := []byte("a shorter bank"), []byte("a shorter external")
+	err = pck.PatchFiles(f, SoundBankTable, &wwise.ReplacementWem{
+		bytes.NewReader(bank), 0, int64(len(bank))})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	err = pck.PatchFiles(f, ExternalTable, &wwise.ReplacementWem{
+		bytes.NewReader(external), 0, int64(len(external))})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	tooLarge := int64(pck.Externals[0].Length) + 1
+	err = pck.PatchFiles(f, ExternalTable, &wwise.ReplacementWem{
+		util.NewConstantReader(tooLarge), 0, tooLarge})
+	if !errors.Is(err, ErrDoesNotFit) {
+		t.Errorf("Expected a replacement that does not fit to be refused, "+
+			"but got: %v", err)
+	}
+
+	patched, err := NewFile(f)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	for _, c := range []struct {
+		e        *Entry
+		expected []byte
+	}{{patched.SoundBanks[0], bank}, {patched.Externals[0], external}} {
+		actual := make([]byte, c.e.Length)
+		_, err = f.ReadAt(actual, c.e.Offset())
+		if err != nil || !bytes.Equal(actual, c.expected) {
+			t.Errorf("Expected file %d to hold %q but got %q", c.e.Id, c.expected,
+				actual)
+		}
+	}
+	if patched.Externals[0].Id != 1<<40|7 {
+		t.Errorf("Expected the ID of the external file to be kept, but got %d",
+			patched.Externals[0].Id)
+	}
+}
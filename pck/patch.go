@@ -0,0 +1,95 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/util"
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+// ErrDoesNotFit is returned when a wem cannot be patched in place, because it
+// is longer than the wem it replaces and the padding that follows it.
+var ErrDoesNotFit = errors.New("The wem does not fit in place")
+
+// IndexOfWem returns the index of the wem with the given ID, or false if this
+// File Package does not hold it.
+func (pck *File) IndexOfWem(id uint32) (int, bool) {
+	for i, idx := range pck.Indexes {
+		if idx.Descriptor.WemId == id {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// PatchWems replaces wems of this File Package in place, by writing only the
+// affected data indexes and wem data to w, which must hold the file this File
+// Package was read from. Every replacement must fit within the wem it replaces
+// and the padding that follows it, so that no other wem moves and every wem
+// stays on its block boundary; the rest of that space is filled with zeroes. An
+// error wrapping ErrDoesNotFit is returned, before anything is written, if a
+// replacement does not fit. After PatchWems returns, this File no longer
+// describes w, and should be read again before it is used.
+func (pck *File) PatchWems(w io.WriterAt, rs ...*wwise.ReplacementWem) error {
+	return pck.PatchFiles(w, StreamedTable, rs...)
+}
+
+// PatchFiles is like PatchWems, but replaces files of the given table. The
+// WemIndex of each replacement is the index of a file within that table.
+func (pck *File) PatchFiles(w io.WriterAt, t Table,
+	rs ...*wwise.ReplacementWem) error {
+	files := pck.tables[t]
+	for _, r := range rs {
+		if r.WemIndex < 0 || r.WemIndex >= len(files) {
+			msg := fmt.Sprintf("There is no file at index %d to replace", r.WemIndex)
+			return errors.New(msg)
+		}
+		data := files[r.WemIndex].data
+		slot := int64(data.Descriptor.Length) + data.Padding.Size()
+		if r.Length > slot {
+			return fmt.Errorf("%w: replacement for file %d is %d bytes long, but "+
+				"there are only %d bytes available", ErrDoesNotFit,
+				pck.entryId(files[r.WemIndex]), r.Length, slot)
+		}
+	}
+
+	// The entries of the table follow its count, and the length of an entry
+	// follows its ID and block size.
+	entryBytes, idBytes := int64(DATA_INDEX_BYTES), int64(4)
+	if pck.wide[t] {
+		entryBytes, idBytes = WIDE_DATA_INDEX_BYTES, 8
+	}
+	table := int64(HEADER_BYTES) + int64(pck.Header.LanguageMapSize)
+	for _, size := range pck.Header.TableSizes[:t] {
+		table += int64(size)
+	}
+	for _, r := range rs {
+		f := files[r.WemIndex]
+		slot := int64(f.data.Descriptor.Length) + f.data.Padding.Size()
+		ow := io.NewOffsetWriter(w, pck.entryOffset(f))
+		_, err := io.Copy(ow, io.NewSectionReader(r.Wem, 0, r.Length))
+		if err != nil {
+			return err
+		}
+		zeroes := io.NewSectionReader(&util.InfiniteReaderAt{0}, 0, slot-r.Length)
+		_, err = io.Copy(ow, zeroes)
+		if err != nil {
+			return err
+		}
+
+		var length [4]byte
+		binary.LittleEndian.PutUint32(length[:], uint32(r.Length))
+		_, err = w.WriteAt(length[:],
+			table+4+int64(r.WemIndex)*entryBytes+idBytes+4)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -2,9 +2,23 @@
 package util
 
 import (
+	"context"
 	"io"
+	"sync"
 )
 
+// The size of the buffers used to copy data, and to buffer the output of
+// containers as they are written.
+const COPY_BUFFER_BYTES = 256 * 1024
+
+// A pool of buffers of COPY_BUFFER_BYTES bytes, shared by every copy.
+var copyBuffers = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, COPY_BUFFER_BYTES)
+		return &b
+	},
+}
+
 type ReadSeekerAt interface {
 	io.ReadSeeker
 	io.ReaderAt
@@ -27,6 +41,69 @@ func (r *ResettingReader) Read(p []byte) (n int, err error) {
 	return
 }
 
+// CloneReader returns a new reader over the same data as r, with its own
+// position, starting at the beginning of the data.
+func CloneReader(r ReadSeekerAt) ReadSeekerAt {
+	return NewResettingReader(r, 0, r.Size())
+}
+
+// CopyAll copies the full contents of r to w, from its start regardless of any
+// earlier reads, so that the same reader can be written any number of times.
+// If r is not an io.Seeker, it is copied from its current position.
+func CopyAll(w io.Writer, r io.Reader) (written int64, err error) {
+	if s, ok := r.(io.Seeker); ok {
+		_, err = s.Seek(0, io.SeekStart)
+		if err != nil {
+			return 0, err
+		}
+	}
+	b := copyBuffers.Get().(*[]byte)
+	defer copyBuffers.Put(b)
+	return io.CopyBuffer(w, r, *b)
+}
+
+// SizeOf returns the number of bytes that CopyAll would copy from r. ok is
+// false if r does not report its size.
+func SizeOf(r io.Reader) (size int64, ok bool) {
+	sr, ok := r.(interface{ Size() int64 })
+	if !ok {
+		return 0, false
+	}
+	return sr.Size(), true
+}
+
+// A zeroExtendedReaderAt is a ReaderAt over the first n bytes of r, followed by
+// an infinite stream of zeroes.
+type zeroExtendedReaderAt struct {
+	r io.ReaderAt
+	n int64
+}
+
+func (z *zeroExtendedReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	read := 0
+	if off < z.n {
+		m := int64(len(p))
+		if off+m > z.n {
+			m = z.n - off
+		}
+		var err error
+		read, err = z.r.ReadAt(p[:m], off)
+		if err != nil && !(err == io.EOF && int64(read) == m) {
+			return read, err
+		}
+	}
+	for i := read; i < len(p); i++ {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+// NewZeroExtendedReader returns a reader over the first size bytes of r. If r
+// holds fewer than size bytes, the remaining bytes are zeroes.
+func NewZeroExtendedReader(r ReadSeekerAt, size int64) ReadSeekerAt {
+	return NewResettingReader(&zeroExtendedReaderAt{r, r.Size()}, 0, size)
+}
+
 // A utility ReaderAt that emits an infinite stream of a specific value.
 type InfiniteReaderAt struct {
 	// The value that this padding writer will write.
@@ -46,3 +123,22 @@ func (r *InfiniteReaderAt) ReadAt(p []byte, off int64) (int, error) {
 func NewConstantReader(size int64) io.ReaderAt {
 	return io.NewSectionReader(&InfiniteReaderAt{'A'}, 0, size)
 }
+
+// A contextWriter is a Writer that stops writing once its context is done.
+type contextWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+// NewContextWriter returns a Writer that writes to w until ctx is done, after
+// which every write fails with ctx.Err().
+func NewContextWriter(ctx context.Context, w io.Writer) io.Writer {
+	return &contextWriter{ctx, w}
+}
+
+func (cw *contextWriter) Write(p []byte) (n int, err error) {
+	if err := cw.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cw.w.Write(p)
+}
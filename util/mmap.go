@@ -0,0 +1,51 @@
+// Package util implements common utility functions.
+package util
+
+import (
+	"io"
+)
+
+// A MappedFile is a ReaderAt over the contents of a file that is mapped into
+// memory, so that reading from it does not require a system call. Where memory
+// mapping is not supported, a MappedFile reads from the file directly.
+type MappedFile struct {
+	data []byte
+	// Where memory mapping is not supported, the file read from instead of data.
+	file   io.ReaderAt
+	size   int64
+	closer io.Closer
+}
+
+// ReadAt reads len(p) bytes of the file, starting at offset off.
+func (m *MappedFile) ReadAt(p []byte, off int64) (int, error) {
+	if m.file != nil {
+		return m.file.ReadAt(p, off)
+	}
+	if off < 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if off >= m.size {
+		return 0, io.EOF
+	}
+	n := copy(p, m.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Size returns the size of the file in bytes.
+func (m *MappedFile) Size() int64 {
+	return m.size
+}
+
+// Close unmaps the file and closes it. The MappedFile, and any reader over it,
+// must not be read from after it is closed.
+func (m *MappedFile) Close() error {
+	if m.closer == nil {
+		return nil
+	}
+	err := m.closer.Close()
+	m.closer = nil
+	return err
+}
@@ -0,0 +1,23 @@
+//go:build !unix
+
+// Package util implements common utility functions.
+package util
+
+import (
+	"os"
+)
+
+// OpenMapped opens the file at path. Memory mapping is not supported on this
+// platform, so the returned MappedFile reads from the file directly.
+func OpenMapped(path string) (*MappedFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &MappedFile{file: f, size: fi.Size(), closer: f}, nil
+}
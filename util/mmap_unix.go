@@ -0,0 +1,40 @@
+//go:build unix
+
+// Package util implements common utility functions.
+package util
+
+import (
+	"os"
+	"syscall"
+)
+
+// OpenMapped opens the file at path and maps its contents into memory.
+func OpenMapped(path string) (*MappedFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := fi.Size()
+	if size == 0 {
+		// An empty file cannot be mapped, but there is nothing to read from it.
+		return &MappedFile{}, nil
+	}
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ,
+		syscall.MAP_SHARED)
+	if err != nil {
+		return nil, &os.PathError{Op: "mmap", Path: path, Err: err}
+	}
+	return &MappedFile{data: data, size: size, closer: unmapper(data)}, nil
+}
+
+// An unmapper unmaps its data when it is closed.
+type unmapper []byte
+
+func (u unmapper) Close() error {
+	return syscall.Munmap(u)
+}
@@ -0,0 +1,23 @@
+// Package util implements common utility functions.
+package util
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestCanonicalWemNamesSortByIndex(t *testing.T) {
+	for _, count := range []int{1, 9, 10, 999, 1000, 1001, 12345} {
+		var names []string
+		for i := 0; i < count; i++ {
+			names = append(names, CanonicalWemName(i, count))
+		}
+		if !sort.StringsAreSorted(names) {
+			t.Errorf("The names of %d wems do not sort in index order", count)
+		}
+		if len(names[0]) != len(names[count-1]) {
+			t.Errorf("The names of %d wems are not padded to the same width: "+
+				"%s and %s", count, names[0], names[count-1])
+		}
+	}
+}
@@ -0,0 +1,274 @@
+// Package wwise implements access and modification iterfaces and functions to
+// common WWise container formats.
+package wwise
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// A Codec identifies the audio encoding of a wem.
+type Codec int
+
+const (
+	UnknownCodec Codec = iota
+	PCMCodec
+	ADPCMCodec
+	PlatformADPCMCodec
+	DSPADPCMCodec
+	VorbisCodec
+	OpusCodec
+	XMA2Codec
+	AACCodec
+	// An Ogg stream that was stored in the container without a RIFF header.
+	OggCodec
+)
+
+// The number of chunks that will be searched for a fmt chunk before the codec
+// is considered unknown.
+const maxRiffChunks = 16
+
+// The number of bytes of a fmt chunk that hold its format tag, channel count
+// and sample rate.
+const minFmtChunkBytes = 8
+
+// ErrInvalidWem is returned when data that should hold a wem does not.
+var ErrInvalidWem = errors.New("The file is not a valid wem")
+
+var codecNames = map[Codec]string{
+	UnknownCodec:       "unknown",
+	PCMCodec:           "pcm",
+	ADPCMCodec:         "adpcm",
+	PlatformADPCMCodec: "ptadpcm",
+	DSPADPCMCodec:      "dsp",
+	VorbisCodec:        "vorbis",
+	OpusCodec:          "opus",
+	XMA2Codec:          "xma2",
+	AACCodec:           "aac",
+	OggCodec:           "ogg",
+}
+
+// A mapping from the format tag of a wem's fmt chunk to its codec.
+var formatTagCodecs = map[uint16]Codec{
+	0x0001: PCMCodec,
+	0xFFFE: PCMCodec,
+	0x0002: ADPCMCodec,
+	0x8311: PlatformADPCMCodec,
+	0xFFF0: DSPADPCMCodec,
+	0xFFFF: VorbisCodec,
+	0x3039: OpusCodec,
+	0x3040: OpusCodec,
+	0x3041: OpusCodec,
+	0x0165: XMA2Codec,
+	0x0166: XMA2Codec,
+	0xAAC0: AACCodec,
+}
+
+func (c Codec) String() string {
+	if name, ok := codecNames[c]; ok {
+		return name
+	}
+	return codecNames[UnknownCodec]
+}
+
+// Extension returns the file extension that audio players and editors expect
+// for audio of this codec. Codecs that are only understood by Wwise tools use
+// the .wem extension.
+func (c Codec) Extension() string {
+	switch c {
+	case PCMCodec:
+		return ".wav"
+	case OggCodec:
+		return ".ogg"
+	default:
+		return ".wem"
+	}
+}
+
+// DetectCodec determines the codec of the wem stored at the start of r by
+// inspecting its RIFF (or RIFX) header and fmt chunk. UnknownCodec is returned
+// if r does not contain a recognizable wem.
+func DetectCodec(r io.ReaderAt) Codec {
+	var hdr [12]byte
+	if _, err := r.ReadAt(hdr[:], 0); err != nil {
+		return UnknownCodec
+	}
+	if string(hdr[0:4]) == "OggS" {
+		return OggCodec
+	}
+	order := riffByteOrder(hdr[:])
+	if order == nil || string(hdr[8:12]) != "WAVE" {
+		return UnknownCodec
+	}
+
+	offset, _, ok := findFmtChunk(r, order)
+	if !ok {
+		return UnknownCodec
+	}
+	var tag [2]byte
+	if _, err := r.ReadAt(tag[:], offset+8); err != nil {
+		return UnknownCodec
+	}
+	return formatTagCodecs[order.Uint16(tag[:])]
+}
+
+// ValidateWem returns an error wrapping ErrInvalidWem if the first length bytes
+// of r do not hold a plausible wem: a RIFF (or RIFX) header describing no more
+// than length bytes, followed by a fmt chunk describing at least one channel
+// and a sample rate. Ogg streams, which some containers store without a RIFF
+// header, are also accepted.
+func ValidateWem(r io.ReaderAt, length int64) error {
+	var hdr [12]byte
+	if _, err := r.ReadAt(hdr[:], 0); err != nil || length < int64(len(hdr)) {
+		return fmt.Errorf("%w: it is only %d bytes long", ErrInvalidWem, length)
+	}
+	if string(hdr[0:4]) == "OggS" {
+		return nil
+	}
+	order := riffByteOrder(hdr[:])
+	if order == nil || string(hdr[8:12]) != "WAVE" {
+		return fmt.Errorf("%w: it does not begin with a RIFF or RIFX header",
+			ErrInvalidWem)
+	}
+	riffLength := 8 + int64(order.Uint32(hdr[4:8]))
+	if riffLength > length {
+		return fmt.Errorf("%w: its RIFF header describes %d bytes, but it is only "+
+			"%d bytes long", ErrInvalidWem, riffLength, length)
+	}
+
+	offset, size, ok := findFmtChunk(r, order)
+	if !ok {
+		return fmt.Errorf("%w: it has no fmt chunk", ErrInvalidWem)
+	}
+	var format [minFmtChunkBytes]byte
+	if size < minFmtChunkBytes {
+		return fmt.Errorf("%w: its fmt chunk is only %d bytes long", ErrInvalidWem,
+			size)
+	}
+	if _, err := r.ReadAt(format[:], offset+8); err != nil {
+		return fmt.Errorf("%w: its fmt chunk is cut off", ErrInvalidWem)
+	}
+	channels, sampleRate := order.Uint16(format[2:4]), order.Uint32(format[4:8])
+	if channels == 0 || sampleRate == 0 {
+		return fmt.Errorf("%w: its fmt chunk describes %d channel(s) at %d Hz",
+			ErrInvalidWem, channels, sampleRate)
+	}
+	return nil
+}
+
+// riffByteOrder returns the byte order of the RIFF (or RIFX) header at the
+// start of hdr, or nil if hdr does not begin with such a header.
+func riffByteOrder(hdr []byte) binary.ByteOrder {
+	switch string(hdr[0:4]) {
+	case "RIFF":
+		return binary.LittleEndian
+	case "RIFX":
+		return binary.BigEndian
+	}
+	return nil
+}
+
+// findFmtChunk walks the chunks of the RIFF stored at the start of r, whose
+// fields are stored in the given byte order, and returns the offset and size of
+// its fmt chunk. ok is false if no fmt chunk was found.
+func findFmtChunk(r io.ReaderAt, order binary.ByteOrder) (offset int64,
+	size int64, ok bool) {
+	return findChunk(r, order, "fmt ")
+}
+
+// findChunk walks the chunks of the RIFF stored at the start of r, whose fields
+// are stored in the given byte order, and returns the offset and size of the
+// first chunk with the given identifier. ok is false if no such chunk was
+// found.
+func findChunk(r io.ReaderAt, order binary.ByteOrder, id string) (offset int64,
+	size int64, ok bool) {
+	offset = 12
+	for i := 0; i < maxRiffChunks; i++ {
+		var chunk [8]byte
+		if _, err := r.ReadAt(chunk[:], offset); err != nil {
+			return 0, 0, false
+		}
+		size = int64(order.Uint32(chunk[4:8]))
+		if string(chunk[0:4]) == id {
+			return offset, size, true
+		}
+		// Chunks are aligned to an even number of bytes.
+		offset += 8 + size + size%2
+	}
+	return 0, 0, false
+}
+
+// Codec returns the codec of this wem, or UnknownCodec if it could not be
+// determined.
+func (wem *Wem) Codec() Codec {
+	ra, ok := wem.Reader.(io.ReaderAt)
+	if !ok {
+		return UnknownCodec
+	}
+	return DetectCodec(ra)
+}
+
+// The offset into the fmt chunk of a Vorbis wem, with an extended fmt chunk, of
+// its sample count.
+const vorbisFmtSamplesOffset = 0x18
+
+// Duration returns the duration of the audio of the wem stored at the start of
+// r. The duration is computed from the sample count of PCM and Vorbis wems, and
+// estimated from the average byte rate of the fmt chunk for other codecs. An
+// error wrapping ErrInvalidWem is returned if it cannot be determined.
+func Duration(r io.ReaderAt) (time.Duration, error) {
+	var hdr [12]byte
+	if _, err := r.ReadAt(hdr[:], 0); err != nil {
+		return 0, fmt.Errorf("%w: it is too short to hold a RIFF header",
+			ErrInvalidWem)
+	}
+	order := riffByteOrder(hdr[:])
+	if order == nil || string(hdr[8:12]) != "WAVE" {
+		return 0, fmt.Errorf("%w: it does not begin with a RIFF or RIFX header",
+			ErrInvalidWem)
+	}
+	fmtOffset, fmtSize, ok := findChunk(r, order, "fmt ")
+	if !ok || fmtSize < 14 {
+		return 0, fmt.Errorf("%w: it has no complete fmt chunk", ErrInvalidWem)
+	}
+	var format [14]byte
+	if _, err := r.ReadAt(format[:], fmtOffset+8); err != nil {
+		return 0, fmt.Errorf("%w: its fmt chunk is cut off", ErrInvalidWem)
+	}
+	codec := formatTagCodecs[order.Uint16(format[0:2])]
+	sampleRate := int64(order.Uint32(format[4:8]))
+	byteRate := int64(order.Uint32(format[8:12]))
+	blockAlign := int64(order.Uint16(format[12:14]))
+	_, dataSize, hasData := findChunk(r, order, "data")
+
+	samples := int64(-1)
+	switch codec {
+	case PCMCodec:
+		if hasData && blockAlign > 0 {
+			samples = dataSize / blockAlign
+		}
+	case VorbisCodec:
+		var count [4]byte
+		if fmtSize >= vorbisFmtSamplesOffset+4 {
+			_, err := r.ReadAt(count[:], fmtOffset+8+vorbisFmtSamplesOffset)
+			if err == nil {
+				samples = int64(order.Uint32(count[:]))
+			}
+		} else if vorbOffset, _, ok := findChunk(r, order, "vorb"); ok {
+			if _, err := r.ReadAt(count[:], vorbOffset+8); err == nil {
+				samples = int64(order.Uint32(count[:]))
+			}
+		}
+	}
+
+	switch {
+	case samples >= 0 && sampleRate > 0:
+		return time.Duration(samples * int64(time.Second) / sampleRate), nil
+	case hasData && byteRate > 0:
+		return time.Duration(dataSize * int64(time.Second) / byteRate), nil
+	}
+	return 0, fmt.Errorf("%w: its duration cannot be determined", ErrInvalidWem)
+}
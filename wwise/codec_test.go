@@ -0,0 +1,165 @@
+// Package wwise implements access and modification iterfaces and functions to
+// common WWise container formats.
+package wwise
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// riff returns a minimal wem with the given RIFF identifier and format tag,
+// preceded by an unrelated chunk.
+func riff(id string, order binary.ByteOrder, formatTag uint16) []byte {
+	b := new(bytes.Buffer)
+	b.WriteString(id)
+	binary.Write(b, order, uint32(0))
+	b.WriteString("WAVE")
+	b.WriteString("junk")
+	binary.Write(b, order, uint32(3))
+	b.Write([]byte{1, 2, 3, 0})
+	b.WriteString("fmt ")
+	binary.Write(b, order, uint32(2))
+	binary.Write(b, order, formatTag)
+	return b.Bytes()
+}
+
+func TestDetectCodec(t *testing.T) {
+	cases := []struct {
+		name  string
+		data  []byte
+		codec Codec
+	}{
+		{"Vorbis", riff("RIFF", binary.LittleEndian, 0xFFFF), VorbisCodec},
+		{"PCM", riff("RIFF", binary.LittleEndian, 0xFFFE), PCMCodec},
+		{"BigEndianXMA2", riff("RIFX", binary.BigEndian, 0x0166), XMA2Codec},
+		{"UnknownFormatTag", riff("RIFF", binary.LittleEndian, 0x1234),
+			UnknownCodec},
+		{"Ogg", []byte("OggS\x00\x02\x00\x00\x00\x00\x00\x00"), OggCodec},
+		{"Truncated", []byte("RIFF"), UnknownCodec},
+	}
+
+	for _, c := range cases {
+		if codec := DetectCodec(bytes.NewReader(c.data)); codec != c.codec {
+			t.Errorf("%s: detected codec %s but expected %s", c.name, codec,
+				c.codec)
+		}
+	}
+}
+
+// wem returns a wem with the given RIFF identifier, whose fmt chunk describes
+// the given number of channels at 48000 Hz.
+func wem(id string, order binary.ByteOrder, channels uint16) []byte {
+	b := new(bytes.Buffer)
+	b.WriteString(id)
+	binary.Write(b, order, uint32(4+8+16+8+4))
+	b.WriteString("WAVE")
+	b.WriteString("fmt ")
+	binary.Write(b, order, uint32(16))
+	binary.Write(b, order, uint16(0xFFFF))
+	binary.Write(b, order, channels)
+	binary.Write(b, order, uint32(48000))
+	b.Write(make([]byte, 8))
+	b.WriteString("data")
+	binary.Write(b, order, uint32(4))
+	b.Write([]byte{1, 2, 3, 4})
+	return b.Bytes()
+}
+
+func TestValidateWem(t *testing.T) {
+	valid := wem("RIFF", binary.LittleEndian, 2)
+	cases := []struct {
+		name  string
+		data  []byte
+		valid bool
+	}{
+		{"Valid", valid, true},
+		{"BigEndian", wem("RIFX", binary.BigEndian, 1), true},
+		{"Ogg", []byte("OggS\x00\x02\x00\x00\x00\x00\x00\x00"), true},
+		{"NotRiff", []byte("This is not a wem at all"), false},
+		{"Truncated", valid[:len(valid)-4], false},
+		{"NoChannels", wem("RIFF", binary.LittleEndian, 0), false},
+	}
+
+	for _, c := range cases {
+		err := ValidateWem(bytes.NewReader(c.data), int64(len(c.data)))
+		if (err == nil) != c.valid {
+			t.Errorf("%s: expected valid to be %t but got error %v", c.name,
+				c.valid, err)
+		}
+	}
+
+	// A complete RIFF file whose only chunk is its data.
+	noFmt := new(bytes.Buffer)
+	noFmt.WriteString("RIFF")
+	binary.Write(noFmt, binary.LittleEndian, uint32(4+8+4))
+	noFmt.WriteString("WAVE")
+	noFmt.WriteString("data")
+	binary.Write(noFmt, binary.LittleEndian, uint32(4))
+	noFmt.Write([]byte{1, 2, 3, 4})
+	err := ValidateWem(bytes.NewReader(noFmt.Bytes()), int64(noFmt.Len()))
+	if !errors.Is(err, ErrInvalidWem) ||
+		!strings.Contains(err.Error(), "no fmt chunk") {
+		t.Errorf("Expected a wem without a fmt chunk to be rejected for it but "+
+			"got %v", err)
+	}
+}
+
+// pcmWem returns a little endian wem with a fmt chunk of the given format tag
+// and fmt chunk extension, and a data chunk of the given size. The fmt chunk
+// describes 2 channels of 16 bit audio at 48000 Hz.
+func pcmWem(formatTag uint16, extension []byte, dataSize int) []byte {
+	b := new(bytes.Buffer)
+	b.WriteString("RIFF")
+	binary.Write(b, binary.LittleEndian, uint32(4+8+16+len(extension)+8+
+		dataSize))
+	b.WriteString("WAVE")
+	b.WriteString("fmt ")
+	binary.Write(b, binary.LittleEndian, uint32(16+len(extension)))
+	binary.Write(b, binary.LittleEndian, formatTag)
+	binary.Write(b, binary.LittleEndian, uint16(2))
+	binary.Write(b, binary.LittleEndian, uint32(48000))
+	binary.Write(b, binary.LittleEndian, uint32(48000*4))
+	binary.Write(b, binary.LittleEndian, uint16(4))
+	binary.Write(b, binary.LittleEndian, uint16(16))
+	b.Write(extension)
+	b.WriteString("data")
+	binary.Write(b, binary.LittleEndian, uint32(dataSize))
+	b.Write(make([]byte, dataSize))
+	return b.Bytes()
+}
+
+func TestDuration(t *testing.T) {
+	// The extended fmt chunk of a Vorbis wem holds its sample count at 0x18.
+	vorbis := make([]byte, 0x42-16)
+	binary.LittleEndian.PutUint32(vorbis[0x18-16:], 24000)
+	cases := []struct {
+		name     string
+		data     []byte
+		duration time.Duration
+	}{
+		{"PCM", pcmWem(0x0001, nil, 48000*4), time.Second},
+		{"Vorbis", pcmWem(0xFFFF, vorbis, 100), 500 * time.Millisecond},
+		{"ByteRate", pcmWem(0x0002, nil, 48000), 250 * time.Millisecond},
+	}
+
+	for _, c := range cases {
+		d, err := Duration(bytes.NewReader(c.data))
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if d != c.duration {
+			t.Errorf("%s: expected a duration of %s but got %s", c.name,
+				c.duration, d)
+		}
+	}
+
+	_, err := Duration(bytes.NewReader([]byte("This is not a wem at all")))
+	if !errors.Is(err, ErrInvalidWem) {
+		t.Errorf("Expected an invalid wem to be rejected but got %v", err)
+	}
+}
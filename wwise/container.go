@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"sort"
+	"sync"
 )
 
 import (
@@ -22,6 +23,10 @@ type Container interface {
 	// modifying the contents of these wems should modify the original container.
 	Wems() []*Wem
 
+	// CheckReplacements returns an error if the wems of this Container cannot be
+	// replaced with rs, in which case ReplaceWems must not be called with them.
+	CheckReplacements(rs ...*ReplacementWem) error
+
 	// ReplaceWems replaces the wems of this Container with all the replacements in
 	// rs. The container is updated to match the new expected lengths and offsets.
 	ReplaceWems(rs ...*ReplacementWem)
@@ -42,6 +47,65 @@ type Wem struct {
 	Padding util.ReadSeekerAt
 }
 
+// WriteTo writes the full contents of this wem, excluding its padding, to the
+// Writer specified by w. The contents are written from the start of the wem,
+// regardless of any earlier reads, so a wem can be written any number of times.
+func (wem *Wem) WriteTo(w io.Writer) (written int64, err error) {
+	return util.CopyAll(w, wem.Reader)
+}
+
+// WriteWemsAt writes wems, each followed by its padding, one after another to w
+// from offset start, and returns the number of bytes written. As the size of
+// every wem is known up front, the wems are written out of order by as many
+// goroutines as specified by threads, or by one if threads is less than one.
+// The first error encountered is returned.
+func WriteWemsAt(w io.WriterAt, start int64, wems []*Wem,
+	threads int) (int64, error) {
+	if threads < 1 {
+		threads = 1
+	}
+	offsets := make([]int64, len(wems))
+	offset := start
+	for i, wem := range wems {
+		offsets[i] = offset
+		offset += int64(wem.Descriptor.Length) + wem.Padding.Size()
+	}
+
+	jobs := make(chan int)
+	var firstErr error
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for t := 0; t < threads && t < len(wems); t++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				ow := io.NewOffsetWriter(w, offsets[i])
+				_, err := wems[i].WriteTo(ow)
+				if err == nil {
+					_, err = util.CopyAll(ow, wems[i].Padding)
+				}
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := range wems {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	if firstErr != nil {
+		return 0, firstErr
+	}
+	return offset - start, nil
+}
+
 // A WemDescriptor represents the location of a single wem entity within the
 // SoundBank DATA section.
 type WemDescriptor struct {
@@ -72,6 +136,18 @@ type ByWemIndex struct {
 	ReplacementWems
 }
 
+// ReplaceOptions describes how the wems of a container are replaced.
+type ReplaceOptions struct {
+	// If Alignment is a non-zero number, padding will be added to the end of
+	// wems so that they are aligned with (offset will be divisible by) this
+	// number.
+	Alignment int64
+	// If PreservePadding is true, the padding that follows a replaced wem keeps
+	// its original bytes, and is only extended with zeroes where it grows.
+	// Otherwise, it is filled with zeroes.
+	PreservePadding bool
+}
+
 // ReplaceWems replaces the wems of ctn with all the replacements in rs. The
 // ctv is updated to match the new expected lengths and offsets. The amount
 // of additional space taken up by the new wems is returned. This should be
@@ -79,6 +155,14 @@ type ByWemIndex struct {
 // a non-zero number, padding will be added to the end of wems so that they are
 // aligned with (offset will be divisible by) this number.
 func ReplaceWems(ctn Container, alignment int64, rs ...*ReplacementWem) int64 {
+	return ReplaceWemsWithOptions(ctn, ReplaceOptions{Alignment: alignment}, rs...)
+}
+
+// ReplaceWemsWithOptions is like ReplaceWems, but replaces wems as described by
+// opts.
+func ReplaceWemsWithOptions(ctn Container, opts ReplaceOptions,
+	rs ...*ReplacementWem) int64 {
+	alignment := opts.Alignment
 	// Ammending offsets in case of a surplus in a single pass, in O(n) time, as
 	// opposed to O(n^2), requires that the replacements happen in the order
 	// that their wem will appear in the file; sorting them by index achives this.
@@ -91,14 +175,21 @@ func ReplaceWems(ctn Container, alignment int64, rs ...*ReplacementWem) int64 {
 
 		newLength, oldLength := r.Length, int64(wem.Descriptor.Length)
 		wem.Reader = util.NewResettingReader(r.Wem, 0, newLength)
+		if oldLength == 0 {
+			// The offset of an empty wem does not matter, so it may not be where the
+			// wem is written. Move it there before the wem gains data.
+			wem.Descriptor.Offset = emptyWemOffset(ctn, r.WemIndex, surplus)
+		}
 
 		padding := wem.Padding.Size()
 		if newLength != oldLength {
 			if alignment != 0 {
 				// Compute the new amount of padding needed to align the next offset
-				// (true end of this wem section) with alignment bytes.
-				padding =
-					(alignment - (int64(wem.Descriptor.Offset)+newLength)%alignment)
+				// (true end of this wem section) with alignment bytes. A wem that
+				// already ends on an aligned offset needs none, rather than a whole
+				// alignment of it.
+				padding = (alignment -
+					(int64(wem.Descriptor.Offset)+newLength)%alignment) % alignment
 			}
 			// Update the new surplus after changing this wem.
 			// Subsequent wem's will need to have their offsets aligned with the end
@@ -111,7 +202,12 @@ func ReplaceWems(ctn Container, alignment int64, rs ...*ReplacementWem) int64 {
 		// updates the descriptor stored in the IndexSection's DescriptorMap, as
 		// well.
 		wem.Descriptor.Length = uint32(newLength)
-		wem.Padding = util.NewResettingReader(&util.InfiniteReaderAt{0}, 0, padding)
+		if opts.PreservePadding {
+			wem.Padding = util.NewZeroExtendedReader(wem.Padding, padding)
+		} else {
+			wem.Padding = util.NewResettingReader(&util.InfiniteReaderAt{0}, 0,
+				padding)
+		}
 
 		if surplus != 0 {
 			// Shift the offsets for the next wems, since the current wem is going to
@@ -134,6 +230,26 @@ func ReplaceWems(ctn Container, alignment int64, rs ...*ReplacementWem) int64 {
 	return surplus
 }
 
+// emptyWemOffset returns the offset where the empty wem at index i of ctn is
+// written, given that the wems that follow it are yet to be moved by surplus
+// bytes.
+func emptyWemOffset(ctn Container, i int, surplus int64) uint32 {
+	wems := ctn.Wems()
+	for _, next := range wems[i+1:] {
+		if next.Descriptor.Length > 0 {
+			return uint32(int64(next.Descriptor.Offset) + surplus)
+		}
+	}
+	for j := i - 1; j >= 0; j-- {
+		prev := wems[j]
+		if prev.Descriptor.Length > 0 {
+			return prev.Descriptor.Offset + prev.Descriptor.Length +
+				uint32(prev.Padding.Size())
+		}
+	}
+	return wems[i].Descriptor.Offset
+}
+
 func (rs ReplacementWems) Len() int {
 	return len(rs)
 }
@@ -0,0 +1,23 @@
+// Package wwise implements access and modification iterfaces and functions to
+// common WWise container formats.
+package wwise
+
+import (
+	"hash/fnv"
+)
+
+// HashName returns the 32-bit ID that Wwise derives from name. Events,
+// SoundBanks, busses, game syncs and most other named objects are identified
+// within containers by the FNV-1 hash of their lowercased name.
+func HashName(name string) uint32 {
+	bs := []byte(name)
+	for i, b := range bs {
+		// Wwise only folds the case of ASCII characters.
+		if 'A' <= b && b <= 'Z' {
+			bs[i] = b + ('a' - 'A')
+		}
+	}
+	h := fnv.New32()
+	h.Write(bs)
+	return h.Sum32()
+}
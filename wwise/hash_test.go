@@ -0,0 +1,24 @@
+// Package wwise implements access and modification iterfaces and functions to
+// common WWise container formats.
+package wwise
+
+import "testing"
+
+func TestHashName(t *testing.T) {
+	cases := []struct {
+		name string
+		id   uint32
+	}{
+		{"", 2166136261},
+		{"sfx", 393239870},
+		{"SFX", 393239870},
+		{"Master Audio Bus", 3803692087},
+		{"English(US)", 684519430},
+	}
+
+	for _, c := range cases {
+		if id := HashName(c.name); id != c.id {
+			t.Errorf("HashName(%q) was %d but expected %d", c.name, id, c.id)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// Package wwise implements access and modification iterfaces and functions to
+// common WWise container formats.
+package wwise
+
+import "strings"
+
+// The names of the standard languages of Wwise. SoundBanks of version 122 and
+// older identify their language by its index in this list, and newer
+// SoundBanks by the hash of its name.
+var Languages = []string{"SFX", "Arabic", "Bulgarian", "Chinese(HK)",
+	"Chinese(PRC)", "Chinese(Taiwan)", "Czech", "Danish", "Dutch",
+	"English(Australia)", "English(India)", "English(UK)", "English(US)",
+	"Finnish", "French(Canada)", "French(France)", "German", "Greek", "Hebrew",
+	"Hungarian", "Indonesian", "Italian", "Japanese", "Korean", "Latin",
+	"Norwegian", "Polish", "Portuguese(Brazil)", "Portuguese(Portugal)",
+	"Romanian", "Russian", "Slovenian", "Spanish(Mexico)", "Spanish(Spain)",
+	"Spanish(US)", "Swedish", "Turkish", "Ukrainian", "Vietnamese"}
+
+// languageOfHash maps the hash of the name of each standard language to its
+// name.
+var languageOfHash = make(map[uint32]string)
+
+func init() {
+	for _, name := range Languages {
+		languageOfHash[HashName(name)] = name
+	}
+}
+
+// LanguageName returns the name of the standard language identified by id. If
+// numeric is true, id is an index into Languages, as in older SoundBanks;
+// otherwise it is the hash of the name of the language. The second result is
+// false if id does not identify a standard language.
+func LanguageName(id uint32, numeric bool) (string, bool) {
+	if numeric {
+		if id >= uint32(len(Languages)) {
+			return "", false
+		}
+		return Languages[id], true
+	}
+	name, ok := languageOfHash[id]
+	return name, ok
+}
+
+// LanguageId returns the ID of the language with the given name, as the inverse
+// of LanguageName. If numeric is true, the ID is the index of the language in
+// Languages, and the second result is false if it is not a standard language;
+// otherwise the ID is the hash of the name, which any language has.
+func LanguageId(name string, numeric bool) (uint32, bool) {
+	if !numeric {
+		return HashName(name), true
+	}
+	for i, language := range Languages {
+		if strings.EqualFold(language, name) {
+			return uint32(i), true
+		}
+	}
+	return 0, false
+}
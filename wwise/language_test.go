@@ -0,0 +1,53 @@
+// Package wwise implements access and modification iterfaces and functions to
+// common WWise container formats.
+package wwise
+
+import "testing"
+
+func TestLanguageName(t *testing.T) {
+	cases := []struct {
+		id      uint32
+		numeric bool
+		name    string
+		ok      bool
+	}{
+		{0, true, "SFX", true},
+		{12, true, "English(US)", true},
+		{uint32(len(Languages)), true, "", false},
+		{393239870, false, "SFX", true},
+		{684519430, false, "English(US)", true},
+		{12, false, "", false},
+	}
+
+	for _, c := range cases {
+		name, ok := LanguageName(c.id, c.numeric)
+		if name != c.name || ok != c.ok {
+			t.Errorf("LanguageName(%d, %t) was (%q, %t) but expected (%q, %t)",
+				c.id, c.numeric, name, ok, c.name, c.ok)
+		}
+	}
+}
+
+func TestLanguageId(t *testing.T) {
+	cases := []struct {
+		name    string
+		numeric bool
+		id      uint32
+		ok      bool
+	}{
+		{"SFX", true, 0, true},
+		{"english(us)", true, 12, true},
+		{"Klingon", true, 0, false},
+		{"SFX", false, 393239870, true},
+		{"English(US)", false, 684519430, true},
+		{"Klingon", false, HashName("Klingon"), true},
+	}
+
+	for _, c := range cases {
+		id, ok := LanguageId(c.name, c.numeric)
+		if id != c.id || ok != c.ok {
+			t.Errorf("LanguageId(%q, %t) was (%d, %t) but expected (%d, %t)",
+				c.name, c.numeric, id, ok, c.id, c.ok)
+		}
+	}
+}